@@ -38,6 +38,13 @@ func Install(typ reflect.Type, adapter Adapter) {
 	// at this time so there should be no performance impact of clearing the
 	// cache.
 	structCache.clear()
+	clearCodecs()
+
+	customEncodingMutex.Lock()
+	for t := range customEncodingCache {
+		delete(customEncodingCache, t)
+	}
+	customEncodingMutex.Unlock()
 }
 
 // AdapterOf returns the adapter for typ, setting ok to true if one was found,
@@ -5,6 +5,7 @@ import (
 	"reflect"
 
 	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/schema"
 )
 
 func init() {
@@ -13,6 +14,18 @@ func init() {
 	objconv.Install(reflect.TypeOf(net.UnixAddr{}), UnixAddrAdapter())
 	objconv.Install(reflect.TypeOf(net.IPAddr{}), IPAddrAdapter())
 	objconv.Install(reflect.TypeOf(net.IP(nil)), IPAdapter())
+
+	schema.Register(reflect.TypeOf(net.TCPAddr{}), &schema.Schema{Type: schema.Types{schema.String}})
+	schema.Register(reflect.TypeOf(net.UDPAddr{}), &schema.Schema{Type: schema.Types{schema.String}})
+	schema.Register(reflect.TypeOf(net.UnixAddr{}), &schema.Schema{Type: schema.Types{schema.String}})
+	schema.Register(reflect.TypeOf(net.IPAddr{}), &schema.Schema{Type: schema.Types{schema.String}})
+	schema.Register(reflect.TypeOf(net.IP(nil)), &schema.Schema{
+		Type: schema.Types{schema.String},
+		AnyOf: []*schema.Schema{
+			{Format: "ipv4"},
+			{Format: "ipv6"},
+		},
+	})
 }
 
 // TCPAddrAdapter returns the adapter to encode and decode net.TCPAddr values.
@@ -5,11 +5,15 @@ import (
 	"reflect"
 
 	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/schema"
 )
 
 func init() {
 	objconv.Install(reflect.TypeOf(mail.Address{}), AddressAdapter())
 	objconv.Install(reflect.TypeOf(([]*mail.Address)(nil)), AddressListAdapter())
+
+	schema.Register(reflect.TypeOf(mail.Address{}), &schema.Schema{Type: schema.Types{schema.String}})
+	schema.Register(reflect.TypeOf(([]*mail.Address)(nil)), &schema.Schema{Type: schema.Types{schema.String}})
 }
 
 // AddressAdapter returns the adapter to encode and decode mail.Address values.
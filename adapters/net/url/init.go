@@ -5,11 +5,15 @@ import (
 	"reflect"
 
 	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/schema"
 )
 
 func init() {
 	objconv.Install(reflect.TypeOf(url.URL{}), URLAdapter())
 	objconv.Install(reflect.TypeOf(url.Values(nil)), QueryAdapter())
+
+	schema.Register(reflect.TypeOf(url.URL{}), &schema.Schema{Type: schema.Types{schema.String}, Format: "uri-reference"})
+	schema.Register(reflect.TypeOf(url.Values(nil)), &schema.Schema{Type: schema.Types{schema.String}})
 }
 
 // URLAdapter returns the adapter to encode and decode url.URL values.
@@ -0,0 +1,170 @@
+package cbor
+
+import (
+	"io"
+	"math"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/objutil"
+)
+
+// CanonicalEmitter implements a CBOR emitter that produces the deterministic
+// encoding defined in section 4.2 of RFC 8949.
+//
+// Integers, lengths and floating point values are written in their shortest
+// form, arrays and maps always have a definite length, and map keys are
+// sorted by the bytewise lexicographic order of their encoding.
+//
+// Maps, and arrays that begin with an unknown length, are buffered in memory
+// until they end.
+type CanonicalEmitter struct {
+	Emitter
+	c objutil.CanonicalWriter // the emitter writes to c, which writes to the output
+}
+
+// NewCanonicalEmitter returns a new emitter which writes the deterministic CBOR
+// representation of values to w.
+func NewCanonicalEmitter(w io.Writer) *CanonicalEmitter {
+	e := &CanonicalEmitter{Emitter: *NewEmitter(w)}
+	e.c.Reset(e.Emitter.w)
+	e.Emitter.w = &e.c
+	return e
+}
+
+func (e *CanonicalEmitter) Reset(w io.Writer) {
+	e.Emitter.Reset(w)
+	e.c.Reset(e.Emitter.w)
+	e.Emitter.w = &e.c
+}
+
+func (e *CanonicalEmitter) Abort() error {
+	e.c.Abort()
+	return e.Emitter.Abort()
+}
+
+func (e *CanonicalEmitter) EmitFloat(v float64, _ int) (err error) {
+	var n int
+
+	if f := float32(v); float64(f) == v || v != v {
+		if h, ok := f32tof16bits(math.Float32bits(f)); ok {
+			n = 3
+			e.b[0] = majorByte(majorType7, svFloat16)
+			putUint16(e.b[1:], h)
+		} else {
+			n = 5
+			e.b[0] = majorByte(majorType7, svFloat32)
+			putUint32(e.b[1:], math.Float32bits(f))
+		}
+	} else {
+		n = 9
+		e.b[0] = majorByte(majorType7, svFloat64)
+		putUint64(e.b[1:], math.Float64bits(v))
+	}
+
+	_, err = e.Emitter.w.Write(e.b[:n])
+	return
+}
+
+func (e *CanonicalEmitter) EmitArrayBegin(n int) (err error) {
+	e.c.BeginArray(n)
+
+	if n >= 0 {
+		e.out.Push()
+		err = e.emitArrayHeader(n)
+	}
+
+	return
+}
+
+func (e *CanonicalEmitter) EmitArrayEnd() (err error) {
+	var buffered bool
+
+	if buffered, err = e.c.EndArray(e.emitArrayHeader); buffered {
+		return
+	}
+
+	return e.out.Pop()
+}
+
+func (e *CanonicalEmitter) EmitArrayNext() (err error) {
+	e.c.ArrayNext()
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapBegin(_ int) (err error) {
+	e.c.BeginMap()
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapEnd() (err error) {
+	return e.c.EndMap(e.emitMapHeader)
+}
+
+func (e *CanonicalEmitter) EmitMapValue() (err error) {
+	e.c.MapValue()
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapNext() (err error) {
+	e.c.MapNext()
+	return
+}
+
+func (e *CanonicalEmitter) CanonicalEmitter() objconv.Emitter {
+	return e
+}
+
+func (e *CanonicalEmitter) emitArrayHeader(n int) error {
+	return e.emitUint(majorType4, uint64(n))
+}
+
+func (e *CanonicalEmitter) emitMapHeader(n int) error {
+	return e.emitUint(majorType5, uint64(n))
+}
+
+// f32tof16bits converts the bits of a single precision float to the bits of a
+// half precision float, the second return value is false if the conversion
+// would lose precision.
+func f32tof16bits(f uint32) (uint16, bool) {
+	s := uint16(f>>16) & 0x8000
+	e := int((f >> 23) & 0xff)
+	m := f & 0x007fffff
+
+	switch {
+	case e == 0xff:
+		if m != 0 { // NaN
+			return 0x7e00, true
+		}
+		return s | 0x7c00, true // Inf
+
+	case e == 0:
+		if m != 0 { // Denormalized single precision float
+			return 0, false
+		}
+		return s, true // +/- 0
+	}
+
+	switch x := e - 127 + 15; {
+	case x >= 31:
+		return 0, false
+
+	case x >= 1:
+		if (m & 0x1fff) != 0 {
+			return 0, false
+		}
+		return s | uint16(x)<<10 | uint16(m>>13), true
+
+	default: // Denormalized half precision float
+		shift := uint(126 - e)
+
+		if shift > 24 {
+			return 0, false
+		}
+
+		if m |= 0x00800000; (m & ((1 << shift) - 1)) != 0 {
+			return 0, false
+		}
+
+		return s | uint16(m>>shift), true
+	}
+}
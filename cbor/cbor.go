@@ -56,6 +56,33 @@ func majorType(b byte) (maj byte, val byte) {
 	return ((b & mask) >> 5), (b & ^mask)
 }
 
+// appendUint appends the header of an item of major type m with the argument v
+// to b, using the shortest encoding.
+func appendUint(b []byte, m byte, v uint64) []byte {
+	switch {
+	case v <= 23:
+		return append(b, majorByte(m, byte(v)))
+
+	case v <= objutil.Uint8Max:
+		return append(b, majorByte(m, iUint8), uint8(v))
+
+	case v <= objutil.Uint16Max:
+		var a [2]byte
+		putUint16(a[:], uint16(v))
+		return append(append(b, majorByte(m, iUint16)), a[:]...)
+
+	case v <= objutil.Uint32Max:
+		var a [4]byte
+		putUint32(a[:], uint32(v))
+		return append(append(b, majorByte(m, iUint32)), a[:]...)
+
+	default:
+		var a [8]byte
+		putUint64(a[:], v)
+		return append(append(b, majorByte(m, iUint64)), a[:]...)
+	}
+}
+
 func putUint16(b []byte, v uint16) {
 	binary.BigEndian.PutUint16(b, v)
 }
@@ -1,8 +1,14 @@
 package cbor
 
 import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math"
+	"reflect"
 	"testing"
 
+	"github.com/segmentio/objconv"
 	"github.com/segmentio/objconv/objtests"
 )
 
@@ -14,6 +20,157 @@ func BenchmarkCodec(b *testing.B) {
 	objtests.BenchmarkCodec(b, Codec)
 }
 
+func TestSequenceCodec(t *testing.T) {
+	objtests.TestCodec(t, SequenceCodec)
+}
+
+func TestSequenceStream(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := SequenceCodec.NewStreamEncoder(b)
+
+	for _, v := range []interface{}{1, "a", []interface{}{true, nil}} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The values are written one after the other, not wrapped in an array.
+	if s := hex.EncodeToString(b.Bytes()); s != "01616182f5f6" {
+		t.Error(s)
+	}
+
+	d := SequenceCodec.NewStreamDecoder(b)
+	values := []interface{}{}
+
+	for {
+		var v interface{}
+
+		if err := d.Decode(&v); err != nil {
+			if err != objconv.End {
+				t.Fatal(err)
+			}
+			break
+		}
+
+		values = append(values, v)
+	}
+
+	expect := []interface{}{uint64(1), "a", []interface{}{true, nil}}
+
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("%#v != %#v", values, expect)
+	}
+}
+
+func TestStreamEncoderPipe(t *testing.T) {
+	r, w := io.Pipe()
+	defer r.Close()
+
+	e := Codec.NewStreamEncoder(w)
+	b := make([]byte, 16)
+
+	for i, expect := range []string{"9f01", "02"} {
+		errc := make(chan error, 1)
+		go func(v int) { errc <- e.Encode(v) }(i + 1)
+
+		// The value must be delivered to the reader without closing the stream
+		// or waiting for more values.
+		n, err := r.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s := hex.EncodeToString(b[:n]); s != expect {
+			t.Errorf("%s != %s", s, expect)
+		}
+
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSequenceTruncated(t *testing.T) {
+	d := SequenceCodec.NewStreamDecoder(bytes.NewReader([]byte{0x01, 0x82, 0x01}))
+	var v interface{}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(v, err)
+	}
+
+	if err := d.Decode(&v); err != io.ErrUnexpectedEOF {
+		t.Errorf("invalid error returned for a truncated value: %v", err)
+	}
+}
+
+func TestStreamDecoderRecover(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec objconv.Codec
+		offs  []int64
+	}{
+		{"Array", Codec, []int64{2, 5}},
+		{"Sequence", SequenceCodec, []int64{1, 4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			e := test.codec.NewStreamEncoder(b)
+
+			for _, v := range []interface{}{1, "a", 2, []int{3}, 4} {
+				if err := e.Encode(v); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := e.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			skipped := []int{}
+			offsets := []int64{}
+			values := []int{}
+
+			d := test.codec.NewStreamDecoder(b)
+			d.Recover = func(e *objconv.StreamError) error {
+				skipped = append(skipped, e.Index)
+				offsets = append(offsets, e.Offset)
+				return nil
+			}
+
+			for {
+				v := 0
+
+				if err := d.Decode(&v); err != nil {
+					if err != objconv.End {
+						t.Fatal(err)
+					}
+					break
+				}
+
+				values = append(values, v)
+			}
+
+			if expect := []int{1, 3}; !reflect.DeepEqual(skipped, expect) {
+				t.Errorf("invalid indexes of skipped values: %v != %v", skipped, expect)
+			}
+
+			if !reflect.DeepEqual(offsets, test.offs) {
+				t.Errorf("invalid offsets of skipped values: %v != %v", offsets, test.offs)
+			}
+
+			if expect := []int{1, 2, 4}; !reflect.DeepEqual(values, expect) {
+				t.Errorf("invalid values decoded: %v != %v", values, expect)
+			}
+		})
+	}
+}
+
 func TestMajorType(t *testing.T) {
 	m, b := majorType(majorByte(majorType7, 24))
 
@@ -25,3 +182,161 @@ func TestMajorType(t *testing.T) {
 		t.Error("bad info value:", b)
 	}
 }
+
+func TestCanonicalCodec(t *testing.T) {
+	objtests.TestCodec(t, CanonicalCodec)
+}
+
+func TestCanonicalEncoding(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		s string
+	}{
+		{1.5, "f93e00"},
+		{float32(1.5), "f93e00"},
+		{100000.0, "fa47c35000"},
+		{1.1, "fb3ff199999999999a"},
+		{5.960464477539063e-8, "f90001"},
+		{0.00006103515625, "f90400"},
+		{65504.0, "f97bff"},
+		{-4.0, "f9c400"},
+		{math.Inf(-1), "f9fc00"},
+		{math.NaN(), "f97e00"},
+		{uint64(500), "1901f4"},
+		{int64(-500), "3901f3"},
+		{map[interface{}]int{"aa": 1, "b": 2, 10: 3}, "a30a0361620262616101"},
+		{struct {
+			B int `objconv:"b"`
+			A int `objconv:"a"`
+		}{1, 2}, "a2616102616201"},
+	}
+
+	for _, test := range tests {
+		b := &bytes.Buffer{}
+
+		if err := NewCanonicalEncoder(b).Encode(test.v); err != nil {
+			t.Error(err)
+		} else if s := hex.EncodeToString(b.Bytes()); s != test.s {
+			t.Errorf("%#v: %s != %s", test.v, s, test.s)
+		}
+	}
+}
+
+func TestCanonicalIndefiniteLength(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewCanonicalEmitter(b)
+
+	e.EmitMapBegin(-1)
+	e.EmitString("b")
+	e.EmitMapValue()
+	e.EmitArrayBegin(-1)
+	e.EmitInt(1, 0)
+	e.EmitArrayNext()
+	e.EmitInt(2, 0)
+	e.EmitArrayEnd()
+	e.EmitMapNext()
+	e.EmitString("a")
+	e.EmitMapValue()
+	e.EmitArrayBegin(-1)
+	e.EmitArrayEnd()
+	e.EmitMapEnd()
+
+	if s := hex.EncodeToString(b.Bytes()); s != "a26161806162820102" {
+		t.Error(s)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	v := []interface{}{"Hello World!", 42}
+
+	b1, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := append(make([]byte, 0, 1024), "..."...)
+
+	b2, err := Append(dst, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b2[:3], dst) || !bytes.Equal(b2[3:], b1) {
+		t.Errorf("%q != %q + %q", b2, dst, b1)
+	}
+
+	if &b2[0] != &dst[0] {
+		t.Error("the value was not written to the buffer passed to Append")
+	}
+}
+
+func TestUnmarshalNoCopy(t *testing.T) {
+	type T struct {
+		S string
+		B []byte
+	}
+
+	b, err := Marshal(T{S: "Hello", B: []byte("World")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v1, v2 T
+
+	if err := Unmarshal(b, &v1); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalNoCopy(b, &v2); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v1, v2) {
+		t.Fatalf("%#v != %#v", v1, v2)
+	}
+
+	if cap(v2.B) != len(v2.B) {
+		t.Errorf("the capacity of the byte slice was not limited to its length: %d != %d", cap(v2.B), len(v2.B))
+	}
+
+	for i := range b {
+		b[i] = 0
+	}
+
+	if v1.S != "Hello" || string(v1.B) != "World" {
+		t.Errorf("Unmarshal returned values referencing the input: %#v", v1)
+	}
+	if v2.S == "Hello" || string(v2.B) == "World" {
+		t.Errorf("UnmarshalNoCopy returned values copied from the input: %#v", v2)
+	}
+}
+
+func TestUnmarshalNoCopyIndefiniteLength(t *testing.T) {
+	// An array of two indefinite-length byte strings made of two chunks each.
+	b := []byte{
+		0x82,
+		0x5f, 0x42, 'A', 'B', 0x41, 'C', 0xff,
+		0x5f, 0x41, 'D', 0x42, 'E', 'F', 0xff,
+	}
+
+	var v [][]byte
+
+	if err := UnmarshalNoCopy(b, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v) != 2 || string(v[0]) != "ABC" || string(v[1]) != "DEF" {
+		t.Errorf("invalid value decoded: %q", v)
+	}
+}
+
+func TestUnmarshalNoCopyTruncated(t *testing.T) {
+	var v []byte
+
+	if err := UnmarshalNoCopy([]byte{0x45, 'A', 'B'}, &v); err != io.ErrUnexpectedEOF {
+		t.Error(err)
+	}
+
+	if err := UnmarshalNoCopy([]byte{0x5f, 0x45, 'A', 'B'}, &v); err != io.ErrUnexpectedEOF {
+		t.Error(err)
+	}
+}
@@ -7,14 +7,19 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/segmentio/objconv"
 	"github.com/segmentio/objconv/objutil"
 )
 
 // Emitter implements a MessagePack emitter that satisfies the objconv.Emitter
 // interface.
+//
+// The output is buffered while arrays and maps are being emitted, see
+// objutil.BufferedWriter.
 type Emitter struct {
-	w io.Writer
-	b [16]byte
+	w   io.Writer
+	out *objutil.BufferedWriter
+	b   [16]byte
 
 	// This stack is used to keep track of the array map lengths being parsed.
 	// The sback array is the initial backend array for the stack.
@@ -23,13 +28,15 @@ type Emitter struct {
 }
 
 func NewEmitter(w io.Writer) *Emitter {
-	e := &Emitter{w: w}
+	b := objutil.NewBufferedWriter(w)
+	e := &Emitter{w: b, out: b}
 	e.stack = e.sback[:0]
 	return e
 }
 
 func (e *Emitter) Reset(w io.Writer) {
-	e.w = w
+	e.out.Reset(w)
+	e.w = e.out
 	e.stack = e.stack[:0]
 }
 
@@ -128,6 +135,7 @@ func (e *Emitter) EmitError(v error) (err error) {
 }
 
 func (e *Emitter) EmitArrayBegin(n int) (err error) {
+	e.out.Push()
 	e.stack = append(e.stack, n)
 
 	if n >= 0 {
@@ -146,9 +154,11 @@ func (e *Emitter) EmitArrayEnd() (err error) {
 
 	if n < 0 {
 		e.b[0] = 0xFF
-		_, err = e.w.Write(e.b[:1])
+		if _, err = e.w.Write(e.b[:1]); err != nil {
+			return
+		}
 	}
-	return
+	return e.out.Pop()
 }
 
 func (e *Emitter) EmitArrayNext() (err error) {
@@ -156,6 +166,7 @@ func (e *Emitter) EmitArrayNext() (err error) {
 }
 
 func (e *Emitter) EmitMapBegin(n int) (err error) {
+	e.out.Push()
 	e.stack = append(e.stack, n)
 
 	if n >= 0 {
@@ -174,9 +185,11 @@ func (e *Emitter) EmitMapEnd() (err error) {
 
 	if n < 0 {
 		e.b[0] = 0xFF
-		_, err = e.w.Write(e.b[:1])
+		if _, err = e.w.Write(e.b[:1]); err != nil {
+			return
+		}
 	}
-	return
+	return e.out.Pop()
 }
 
 func (e *Emitter) EmitMapValue() (err error) {
@@ -187,35 +200,23 @@ func (e *Emitter) EmitMapNext() (err error) {
 	return
 }
 
-func (e *Emitter) emitUint(m byte, v uint64) (err error) {
-	var n int
-
-	switch {
-	case v <= 23:
-		n = 1
-		e.b[0] = majorByte(m, byte(v))
-
-	case v <= objutil.Uint8Max:
-		n = 2
-		e.b[0] = majorByte(m, iUint8)
-		e.b[1] = uint8(v)
-
-	case v <= objutil.Uint16Max:
-		n = 3
-		e.b[0] = majorByte(m, iUint16)
-		putUint16(e.b[1:], uint16(v))
+// Flush writes the buffered output to the underlying writer.
+func (e *Emitter) Flush() error {
+	return e.out.Flush()
+}
 
-	case v <= objutil.Uint32Max:
-		n = 5
-		e.b[0] = majorByte(m, iUint32)
-		putUint32(e.b[1:], uint32(v))
+// Abort writes the buffered output to the underlying writer and discards the
+// state of the arrays and maps that were left open when encoding a value failed.
+func (e *Emitter) Abort() error {
+	e.stack = e.stack[:0]
+	return e.out.Abort()
+}
 
-	default:
-		n = 9
-		e.b[0] = majorByte(m, iUint64)
-		putUint64(e.b[1:], v)
-	}
+func (e *Emitter) CanonicalEmitter() objconv.Emitter {
+	return NewCanonicalEmitter(e.w)
+}
 
-	_, err = e.w.Write(e.b[:n])
+func (e *Emitter) emitUint(m byte, v uint64) (err error) {
+	_, err = e.w.Write(appendUint(e.b[:0], m, v))
 	return
 }
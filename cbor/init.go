@@ -12,6 +12,20 @@ var Codec = objconv.Codec{
 	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
 }
 
+// CanonicalCodec for the CBOR format, producing the deterministic encoding
+// defined by RFC 8949.
+var CanonicalCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewCanonicalEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+}
+
+// SequenceCodec for CBOR sequences (RFC 8742), where the values of a stream are
+// written one after the other instead of being wrapped in an array.
+var SequenceCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewSequenceEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewSequenceParser(r) },
+}
+
 func init() {
 	for _, name := range [...]string{
 		"application/cbor",
@@ -19,4 +33,11 @@ func init() {
 	} {
 		objconv.Register(name, Codec)
 	}
+
+	for _, name := range [...]string{
+		"application/cbor-seq",
+		"cbor-seq",
+	} {
+		objconv.Register(name, SequenceCodec)
+	}
 }
@@ -16,7 +16,23 @@ type Parser struct {
 	i int       // offset of the first unread byte in b
 	j int       // offset + 1 of the last unread byte in b
 	s []byte    // string buffer
-	b [240]byte // read buffer
+	b []byte    // read buffer, a or the input of parsers created by NewBytesParser
+	a [240]byte // backing array of the read buffer
+	n int64     // number of bytes read from r
+
+	// Set when the parser reads from an in-memory input, the byte slices that
+	// it returns are views of the input.
+	stable bool
+
+	// State of ParseRaw, the bytes of the value are accumulated in raw when
+	// the read buffer is refilled, k is the offset of the first byte of the
+	// value that remains in b.
+	raw     []byte
+	k       int
+	capture bool
+
+	// Size in bits of the last number parsed, see BitSize.
+	bits int
 
 	// Last tag loaded while parsing the type of the next available item.
 	tag uint64
@@ -35,18 +51,65 @@ func NewParser(r io.Reader) *Parser {
 	return p
 }
 
+// NewBytesParser returns a new parser which reads values from b.
+//
+// The strings and byte slices returned by the parser are views of b (or newly
+// allocated for strings of indefinite length), which decoders configured with
+// NoCopy retain in the values they decode instead of copying them. The content
+// of b must not be modified while those values are in use.
+func NewBytesParser(b []byte) *Parser {
+	p := &Parser{}
+	p.stack = p.sback[:0]
+	p.ResetBytes(b)
+	return p
+}
+
 func (p *Parser) Reset(r io.Reader) {
 	p.r = r
 	p.i = 0
 	p.j = 0
+	p.n = 0
+	p.b = nil
+	p.stable = false
+	p.tag = noTag
+	p.stack = p.stack[:0]
+}
+
+// ResetBytes resets the parser to read values from b, see NewBytesParser.
+func (p *Parser) ResetBytes(b []byte) {
+	p.r = nil
+	p.i = 0
+	p.j = len(b)
+	p.n = int64(len(b))
+	p.b = b
+	p.stable = true
 	p.tag = noTag
 	p.stack = p.stack[:0]
 }
 
+// StableParser returns true if the parser reads from an in-memory input, see
+// NewBytesParser.
+func (p *Parser) StableParser() bool {
+	return p.stable
+}
+
+// BitSize returns the size in bits of the last number parsed. Integers are not
+// sized in CBOR so their size is 64 bits, floating-point numbers encoded with 16
+// bits are decoded to float32.
+func (p *Parser) BitSize() int {
+	return p.bits
+}
+
 func (p *Parser) Buffered() io.Reader {
 	return bytes.NewReader(p.b[p.i:p.j])
 }
 
+// InputOffset returns the number of bytes of the input that were consumed by
+// the parser.
+func (p *Parser) InputOffset() int64 {
+	return p.n - int64(p.j-p.i)
+}
+
 func (p *Parser) ParseType() (typ objconv.Type, err error) {
 	if p.tag != noTag {
 		typ = p.typ
@@ -157,6 +220,7 @@ func (p *Parser) ParseInt() (v int64, err error) {
 	}
 
 	v = -int64(u + 1)
+	p.bits = 64
 	p.tag = noTag
 	return
 }
@@ -173,6 +237,7 @@ func (p *Parser) ParseUint() (v uint64, err error) {
 		return
 	}
 
+	p.bits = 64
 	p.tag = noTag
 	return
 }
@@ -188,11 +253,11 @@ func (p *Parser) ParseFloat() (v float64, err error) {
 
 	switch b {
 	case svFloat16:
-		n = 2
+		n, p.bits = 2, 32
 	case svFloat32:
-		n = 4
+		n, p.bits = 4, 32
 	default:
-		n = 8
+		n, p.bits = 8, 64
 	}
 
 	if s, err = p.peek(n); err != nil {
@@ -314,8 +379,7 @@ func (p *Parser) ParseArrayBegin() (n int, err error) {
 }
 
 func (p *Parser) ParseArrayEnd(n int) (err error) {
-	p.stack = p.stack[:len(p.stack)-1]
-	return
+	return p.parseEnd()
 }
 
 func (p *Parser) ParseArrayNext(n int) (err error) {
@@ -357,8 +421,7 @@ func (p *Parser) ParseMapBegin() (n int, err error) {
 }
 
 func (p *Parser) ParseMapEnd(n int) (err error) {
-	p.stack = p.stack[:len(p.stack)-1]
-	return
+	return p.parseEnd()
 }
 
 func (p *Parser) ParseMapValue(n int) (err error) {
@@ -380,6 +443,167 @@ func (p *Parser) ParseMapNext(n int) (err error) {
 	return
 }
 
+// Skip discards the next item, byte and text strings are jumped over using
+// their length prefix.
+func (p *Parser) Skip() (err error) {
+	// If ParseType was called on a tagged item the tag was already consumed.
+	p.tag = noTag
+	return p.skip()
+}
+
+// ParseRaw appends the bytes of the next value to b and returns the extended
+// slice, the value is consumed without being decoded.
+func (p *Parser) ParseRaw(b []byte) ([]byte, error) {
+	// If ParseType was called on a tagged item the tag was already consumed,
+	// it is written back to the output.
+	if p.tag != noTag {
+		b = appendUint(b, majorType6, p.tag)
+	}
+
+	p.raw, p.k, p.capture = b, p.i, true
+	err := p.Skip()
+	b = append(p.raw, p.b[p.k:p.i]...)
+	p.raw, p.k, p.capture = nil, 0, false
+	return b, err
+}
+
+func (p *Parser) skip() (err error) {
+	var s []byte
+	var u uint64
+	var indef bool
+
+	if s, err = p.peek(1); err != nil {
+		return
+	}
+
+	switch m, b := majorType(s[0]); m {
+	case majorType0, majorType1:
+		_, _, err = p.parseUint()
+
+	case majorType2, majorType3:
+		if u, indef, err = p.parseUint(); err != nil {
+			return
+		}
+		if !indef {
+			return p.discard(u)
+		}
+		// indefinite length strings are sequences of definite length chunks
+		// terminated by a break code
+		for {
+			if s, err = p.peek(1); err != nil {
+				return
+			}
+			if s[0] == 0xFF {
+				p.i++
+				return
+			}
+			if u, _, err = p.parseUint(); err != nil {
+				return
+			}
+			if err = p.discard(u); err != nil {
+				return
+			}
+		}
+
+	case majorType4, majorType5:
+		if u, indef, err = p.parseUint(); err != nil {
+			return
+		}
+
+		if indef {
+			for {
+				if s, err = p.peek(1); err != nil {
+					return
+				}
+				if s[0] == 0xFF {
+					p.i++
+					return
+				}
+				if err = p.skip(); err != nil {
+					return
+				}
+			}
+		}
+
+		if m == majorType5 {
+			if u > (intMax / 2) {
+				err = fmt.Errorf("objconv/cbor: map of length %d is greater than what an int can represent", u)
+				return
+			}
+			u *= 2
+		}
+
+		for ; u != 0; u-- {
+			if err = p.skip(); err != nil {
+				return
+			}
+		}
+
+	case majorType6:
+		if _, _, err = p.parseUint(); err != nil {
+			return
+		}
+		err = p.skip()
+
+	default:
+		switch b {
+		case svExtension:
+			err = p.discard(2)
+		case svFloat16:
+			err = p.discard(3)
+		case svFloat32:
+			err = p.discard(5)
+		case svFloat64:
+			err = p.discard(9)
+		case svBreak:
+			err = errors.New("objconv/cbor: unexpected break code found while skipping an item")
+		default:
+			err = p.discard(1)
+		}
+	}
+
+	return
+}
+
+// parseEnd completes an array or a map, consuming the break code if it had an
+// indefinite length.
+func (p *Parser) parseEnd() (err error) {
+	i := len(p.stack) - 1
+
+	if p.stack[i] < 0 {
+		var s []byte
+
+		if s, err = p.peek(1); err != nil {
+			return
+		}
+
+		if s[0] != 0xFF {
+			err = fmt.Errorf("objconv/cbor: expected break code at the end of an indefinite length item but found %#x", s[0])
+			return
+		}
+
+		p.i++
+	}
+
+	p.stack = p.stack[:i]
+	return
+}
+
+// discard drops the next n bytes from the input.
+func (p *Parser) discard(n uint64) (err error) {
+	for n > uint64(p.j-p.i) {
+		n -= uint64(p.j - p.i)
+		p.i = p.j
+
+		if err = p.fill(); err != nil {
+			return
+		}
+	}
+
+	p.i += int(n)
+	return
+}
+
 func (p *Parser) parseUint() (v uint64, indef bool, err error) {
 	var s []byte
 	var n int
@@ -445,6 +669,9 @@ func (p *Parser) parseBytes(m byte) (v []byte, err error) {
 			err = fmt.Errorf("objconv/cbor: byte string of length %d is greater than what an int can represent", u)
 			return
 		}
+		if p.stable {
+			return p.view(int(u))
+		}
 		return p.load(int(u))
 	}
 
@@ -467,6 +694,12 @@ func (p *Parser) parseBytes(m byte) (v []byte, err error) {
 		}
 	}
 
+	if p.stable {
+		// The chunks were concatenated in the string buffer, it must not be
+		// reused so the returned value remains valid.
+		p.s = nil
+	}
+
 	return
 }
 
@@ -494,6 +727,11 @@ func (p *Parser) parseType7() (b byte, err error) {
 }
 
 func (p *Parser) load(n int) (b []byte, err error) {
+	if p.stable && n > (p.j-p.i) {
+		err = io.ErrUnexpectedEOF
+		return
+	}
+
 	i := len(p.s)
 	j := i + n
 
@@ -522,8 +760,11 @@ func (p *Parser) load(n int) (b []byte, err error) {
 	}
 
 	if i != j {
-		if _, err = io.ReadFull(p.r, p.s[i:]); err != nil {
-			return
+		m, err := io.ReadFull(p.r, p.s[i:])
+		p.n += int64(m)
+
+		if err != nil {
+			return b, err
 		}
 	}
 
@@ -531,6 +772,18 @@ func (p *Parser) load(n int) (b []byte, err error) {
 	return
 }
 
+// view returns the next n bytes of the input of parsers created by
+// NewBytesParser.
+func (p *Parser) view(n int) (b []byte, err error) {
+	if n > (p.j - p.i) {
+		err = io.ErrUnexpectedEOF
+		return
+	}
+	b = p.b[p.i : p.i+n : p.i+n]
+	p.i += n
+	return
+}
+
 func (p *Parser) peek(n int) (b []byte, err error) {
 	for (p.i + n) > p.j {
 		if err = p.fill(); err != nil {
@@ -542,6 +795,19 @@ func (p *Parser) peek(n int) (b []byte, err error) {
 }
 
 func (p *Parser) fill() (err error) {
+	if p.stable {
+		return io.EOF
+	}
+
+	if p.b == nil {
+		p.b = p.a[:]
+	}
+
+	if p.capture {
+		p.raw = append(p.raw, p.b[p.k:p.i]...)
+		p.k = 0
+	}
+
 	n := p.j - p.i
 	copy(p.b[:], p.b[p.i:p.j])
 	p.i = 0
@@ -550,6 +816,7 @@ func (p *Parser) fill() (err error) {
 	if n, err = p.r.Read(p.b[n:]); n > 0 {
 		err = nil
 		p.j += n
+		p.n += int64(n)
 	} else if err != nil {
 		return
 	} else {
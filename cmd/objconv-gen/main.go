@@ -0,0 +1,549 @@
+// Command objconv-gen generates EncodeValue and DecodeValue methods for struct
+// types, which encode and decode the structs without the use of reflection.
+//
+// The generated methods follow the same rules than the reflection-based
+// encoders and decoders of the objconv package (field names and the omitempty
+// and omitzero options of the `objconv` tag, `json` tags as a fallback, etc...).
+// Fields of types that don't have a direct representation in the objconv
+// package, like slices or maps, are still encoded and decoded by the objconv
+// package.
+//
+// The program is typically invoked from a go:generate directive:
+//
+//	//go:generate objconv-gen -type Config,Server
+//
+// When no types are given, methods are generated for all the struct types of
+// the package that have fields with `objconv` or `json` tags.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/segmentio/objconv/objutil"
+)
+
+func main() {
+	var typeNames string
+	var output string
+
+	flag.StringVar(&typeNames, "type", "", "Comma-separated list of the types to generate methods for")
+	flag.StringVar(&output, "o", "objconv_generated.go", "The name of the generated file in the package directory")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() != 0 {
+		dir = flag.Arg(0)
+	}
+
+	if err := run(dir, output, typeNames); err != nil {
+		fmt.Fprintf(os.Stderr, "objconv-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, output string, typeNames string) (err error) {
+	var pkg *types.Package
+	var list []*types.TypeName
+	var src []byte
+
+	if pkg, err = loadPackage(dir, output); err != nil {
+		return
+	}
+
+	if list, err = lookupTypes(pkg, typeNames); err != nil {
+		return
+	}
+
+	if src, err = generate(pkg, list); err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, output), src, 0644)
+}
+
+// loadPackage parses and type-checks the package in dir, skipping the output
+// file so the methods it declares are not taken into account.
+func loadPackage(dir string, output string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(bp.GoFiles))
+
+	for _, name := range bp.GoFiles {
+		if name == output {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, f)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(bp.ImportPath, fset, files, nil)
+}
+
+// lookupTypes returns the struct types of pkg that methods must be generated
+// for, in the order in which they were declared.
+func lookupTypes(pkg *types.Package, typeNames string) (list []*types.TypeName, err error) {
+	scope := pkg.Scope()
+
+	if len(typeNames) != 0 {
+		for _, name := range strings.Split(typeNames, ",") {
+			obj, ok := scope.Lookup(strings.TrimSpace(name)).(*types.TypeName)
+			if !ok || obj.IsAlias() || !isStruct(obj.Type()) {
+				return nil, fmt.Errorf("%s is not a struct type of package %s", name, pkg.Name())
+			}
+			list = append(list, obj)
+		}
+		return
+	}
+
+	for _, name := range scope.Names() {
+		if obj, ok := scope.Lookup(name).(*types.TypeName); ok && !obj.IsAlias() && isStruct(obj.Type()) {
+			if hasTags(obj.Type().Underlying().(*types.Struct)) {
+				list = append(list, obj)
+			}
+		}
+	}
+
+	// Names are sorted alphabetically, the declaration order produces a more
+	// natural output.
+	for i := 1; i < len(list); i++ {
+		for j := i; j > 0 && list[j].Pos() < list[j-1].Pos(); j-- {
+			list[j], list[j-1] = list[j-1], list[j]
+		}
+	}
+
+	return
+}
+
+func isStruct(t types.Type) bool {
+	if n, ok := t.(*types.Named); ok && n.TypeParams().Len() != 0 {
+		return false // generic types are not supported
+	}
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+func hasTags(s *types.Struct) bool {
+	for i := 0; i != s.NumFields(); i++ {
+		tag := reflect.StructTag(s.Tag(i))
+		if _, ok := tag.Lookup("objconv"); ok {
+			return true
+		}
+		if _, ok := tag.Lookup("json"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// field represents a struct field that is encoded and decoded by the generated
+// methods.
+type field struct {
+	name      string // name of the field in the Go struct
+	key       string // name of the field in the encoded representation
+	typ       types.Type
+	omitempty bool
+	omitzero  bool
+}
+
+// structFields returns the list of fields of s, it must apply the same rules
+// than newStructType in the objconv package.
+func structFields(s *types.Struct) (fields []field) {
+	for i := 0; i != s.NumFields(); i++ {
+		f := s.Field(i)
+
+		if f.Embedded() || !f.Exported() {
+			continue
+		}
+
+		var t objutil.Tag
+		tag := reflect.StructTag(s.Tag(i))
+
+		if objconvTag := tag.Get("objconv"); len(objconvTag) != 0 {
+			t = objutil.ParseTag(objconvTag)
+		} else {
+			t = objutil.ParseTagJSON(tag.Get("json"))
+		}
+
+		key := f.Name()
+		if len(t.Name) != 0 {
+			key = t.Name
+		}
+
+		if key == "-" {
+			continue
+		}
+
+		fields = append(fields, field{
+			name:      f.Name(),
+			key:       key,
+			typ:       f.Type(),
+			omitempty: t.Omitempty,
+			omitzero:  t.Omitzero,
+		})
+	}
+	return
+}
+
+// generator holds the state of the code generation for a package.
+type generator struct {
+	buf       bytes.Buffer
+	pkg       *types.Package
+	generated map[*types.TypeName]bool
+	imports   map[string]bool
+}
+
+func generate(pkg *types.Package, list []*types.TypeName) ([]byte, error) {
+	g := &generator{
+		pkg:       pkg,
+		generated: make(map[*types.TypeName]bool, len(list)),
+		imports:   map[string]bool{"github.com/segmentio/objconv": true},
+	}
+
+	for _, obj := range list {
+		g.generated[obj] = true
+	}
+
+	for _, obj := range list {
+		g.generateType(obj)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by objconv-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", pkg.Name())
+
+	if g.imports["time"] {
+		fmt.Fprintf(&out, "\t\"time\"\n\n")
+	}
+
+	for _, path := range []string{"github.com/segmentio/objconv", "github.com/segmentio/objconv/objutil"} {
+		if g.imports[path] {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+	}
+
+	fmt.Fprintf(&out, ")\n")
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting the generated code: %v", err)
+	}
+	return src, nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) generateType(obj *types.TypeName) {
+	fields := structFields(obj.Type().Underlying().(*types.Struct))
+	g.generateEncoder(obj, fields)
+	g.generateDecoder(obj, fields)
+}
+
+func (g *generator) generateEncoder(obj *types.TypeName, fields []field) {
+	n := 0
+
+	for _, f := range fields {
+		if len(g.keep(f)) == 0 {
+			n++
+		}
+	}
+
+	g.printf("\n// EncodeValue satisfies the objconv.ValueEncoder interface.\n")
+	g.printf("func (x %s) EncodeValue(e objconv.Encoder) error {\n", obj.Name())
+	g.printf("n := %d\n", n)
+
+	for _, f := range fields {
+		if keep := g.keep(f); len(keep) != 0 {
+			g.printf("if %s {\nn++\n}\n", keep)
+		}
+	}
+
+	g.printf("return e.EncodeStruct(n, func(e objconv.Encoder) (err error) {\n")
+
+	if len(fields) != 0 {
+		g.printf("i := 0\n")
+	}
+
+	for _, f := range fields {
+		keep := g.keep(f)
+
+		if len(keep) != 0 {
+			g.printf("if %s {\n", keep)
+		}
+
+		g.printf("if err = e.EncodeField(i, %q); err != nil {\nreturn\n}\n", f.key)
+		g.printf("if err = %s; err != nil {\nreturn\n}\n", g.encode(f))
+		g.printf("i++\n")
+
+		if len(keep) != 0 {
+			g.printf("}\n")
+		}
+	}
+
+	g.printf("return\n})\n}\n")
+}
+
+func (g *generator) generateDecoder(obj *types.TypeName, fields []field) {
+	g.printf("\n// DecodeValue satisfies the objconv.ValueDecoder interface.\n")
+	g.printf("func (x *%s) DecodeValue(d objconv.Decoder) (err error) {\n", obj.Name())
+	g.printf("if err = d.DecodeStruct(%q, func(d objconv.Decoder, name []byte) (bool, error) {\n", g.pkg.Name()+"."+obj.Name())
+	g.printf("var err error\n")
+	g.printf("switch string(name) {\n")
+
+	// When multiple fields have the same name the last one wins, like in the
+	// objconv package.
+	last := make(map[string]int, len(fields))
+	for i, f := range fields {
+		last[f.key] = i
+	}
+
+	for i, f := range fields {
+		if last[f.key] == i {
+			g.printf("case %q:\n", f.key)
+			g.decode(f)
+		}
+	}
+
+	g.printf("default:\nreturn false, nil\n}\n")
+	g.printf("return true, err\n")
+	g.printf("}); err != nil {\n*x = %s{}\n}\nreturn\n}\n", obj.Name())
+}
+
+// keep returns the expression that tests whether f must be encoded, or an
+// empty string if the field is never omitted.
+//
+// The expressions must produce the same results than objutil.IsEmptyValue and
+// objutil.IsZeroValue.
+func (g *generator) keep(f field) string {
+	var conds []string
+	v := "x." + f.name
+
+	add := func(c string) {
+		if len(c) != 0 && (len(conds) == 0 || conds[0] != c) {
+			conds = append(conds, c)
+		}
+	}
+
+	if f.omitempty {
+		switch t := f.typ.Underlying().(type) {
+		case *types.Basic:
+			add(basicNonZero(t, v))
+		case *types.Array, *types.Slice, *types.Map:
+			add("len(" + v + ") != 0")
+		case *types.Pointer, *types.Interface, *types.Chan, *types.Signature:
+			add(v + " != nil")
+		}
+	}
+
+	if f.omitzero {
+		switch t := f.typ.Underlying().(type) {
+		case *types.Basic:
+			add(basicNonZero(t, v))
+		case *types.Slice, *types.Map, *types.Pointer, *types.Interface, *types.Chan, *types.Signature:
+			add(v + " != nil")
+		case *types.Array, *types.Struct:
+			g.imports["github.com/segmentio/objconv/objutil"] = true
+			add("!objutil.IsZero(" + v + ")")
+		}
+	}
+
+	return strings.Join(conds, " && ")
+}
+
+func basicNonZero(t *types.Basic, v string) string {
+	switch info := t.Info(); {
+	case info&types.IsBoolean != 0:
+		return v
+	case info&types.IsString != 0:
+		return "len(" + v + ") != 0"
+	case info&(types.IsInteger|types.IsFloat) != 0:
+		return v + " != 0"
+	case t.Kind() == types.UnsafePointer:
+		return v + " != nil"
+	}
+	return "" // complex numbers are never empty
+}
+
+// encode returns the expression encoding the value of f with the encoder e.
+func (g *generator) encode(f field) string {
+	v := "x." + f.name
+
+	switch t := f.typ.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool:
+			return "e.Emitter.EmitBool(" + v + ")"
+		case types.Int:
+			return "e.Emitter.EmitInt(int64(" + v + "), 0)"
+		case types.Int8:
+			return "e.Emitter.EmitInt(int64(" + v + "), 8)"
+		case types.Int16:
+			return "e.Emitter.EmitInt(int64(" + v + "), 16)"
+		case types.Int32:
+			return "e.Emitter.EmitInt(int64(" + v + "), 32)"
+		case types.Int64:
+			return "e.Emitter.EmitInt(" + v + ", 64)"
+		case types.Uint, types.Uintptr:
+			return "e.Emitter.EmitUint(uint64(" + v + "), 0)"
+		case types.Uint8:
+			return "e.Emitter.EmitUint(uint64(" + v + "), 8)"
+		case types.Uint16:
+			return "e.Emitter.EmitUint(uint64(" + v + "), 16)"
+		case types.Uint32:
+			return "e.Emitter.EmitUint(uint64(" + v + "), 32)"
+		case types.Uint64:
+			return "e.Emitter.EmitUint(" + v + ", 64)"
+		case types.Float32:
+			return "e.Emitter.EmitFloat(float64(" + v + "), 32)"
+		case types.Float64:
+			return "e.Emitter.EmitFloat(" + v + ", 64)"
+		case types.String:
+			return "e.Emitter.EmitString(" + v + ")"
+		}
+
+	case *types.Slice:
+		if isByte(t.Elem()) {
+			return "e.Emitter.EmitBytes(" + v + ")"
+		}
+
+	case *types.Named:
+		switch {
+		case isTimeType(t, "Time"):
+			return "e.Emitter.EmitTime(" + v + ")"
+		case isTimeType(t, "Duration"):
+			return "e.Emitter.EmitDuration(" + v + ")"
+		case g.generated[t.Obj()]:
+			return v + ".EncodeValue(e)"
+		}
+	}
+
+	return "e.Encode(" + v + ")"
+}
+
+// decode writes the statements decoding the value of f with the decoder d.
+func (g *generator) decode(f field) {
+	v := "x." + f.name
+
+	// Values are only assigned when they were successfully decoded, like in
+	// the objconv package.
+	assign := func(typ string, call string, conv string) {
+		g.printf("var v %s\n", typ)
+		g.printf("if v, err = d.%s; err == nil {\n", call)
+		if len(conv) != 0 {
+			g.printf("%s = %s(v)\n}\n", v, conv)
+		} else {
+			g.printf("%s = v\n}\n", v)
+		}
+	}
+
+	switch t := f.typ.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool:
+			assign("bool", "DecodeBool()", "")
+			return
+		case types.Int:
+			assign("int64", "DecodeInt(0)", "int")
+			return
+		case types.Int8:
+			assign("int64", "DecodeInt(8)", "int8")
+			return
+		case types.Int16:
+			assign("int64", "DecodeInt(16)", "int16")
+			return
+		case types.Int32:
+			assign("int64", "DecodeInt(32)", "int32")
+			return
+		case types.Int64:
+			assign("int64", "DecodeInt(64)", "")
+			return
+		case types.Uint:
+			assign("uint64", "DecodeUint(0)", "uint")
+			return
+		case types.Uint8:
+			assign("uint64", "DecodeUint(8)", "uint8")
+			return
+		case types.Uint16:
+			assign("uint64", "DecodeUint(16)", "uint16")
+			return
+		case types.Uint32:
+			assign("uint64", "DecodeUint(32)", "uint32")
+			return
+		case types.Uint64:
+			assign("uint64", "DecodeUint(64)", "")
+			return
+		case types.Uintptr:
+			// The objconv package doesn't check the bounds of uintptr values.
+			assign("uint64", "DecodeUint(64)", "uintptr")
+			return
+		case types.Float32:
+			assign("float64", "DecodeFloat()", "float32")
+			return
+		case types.Float64:
+			assign("float64", "DecodeFloat()", "")
+			return
+		case types.String:
+			assign("string", "DecodeString()", "")
+			return
+		}
+
+	case *types.Slice:
+		if isByte(t.Elem()) {
+			assign("[]byte", "DecodeBytes()", "")
+			return
+		}
+
+	case *types.Named:
+		switch {
+		case isTimeType(t, "Time"):
+			g.imports["time"] = true
+			assign("time.Time", "DecodeTime()", "")
+			return
+		case isTimeType(t, "Duration"):
+			g.imports["time"] = true
+			assign("time.Duration", "DecodeDuration()", "")
+			return
+		case g.generated[t.Obj()]:
+			g.printf("err = %s.DecodeValue(d)\n", v)
+			return
+		}
+	}
+
+	g.printf("err = d.Decode(&%s)\n", v)
+}
+
+func isByte(t types.Type) bool {
+	b, ok := t.(*types.Basic)
+	return ok && b.Kind() == types.Uint8
+}
+
+func isTimeType(t *types.Named, name string) bool {
+	obj := t.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == name
+}
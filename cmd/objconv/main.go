@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"reflect"
 	"sort"
 	"strings"
 
@@ -18,42 +17,6 @@ import (
 	_ "github.com/segmentio/objconv/yaml"
 )
 
-// document is used to preserve the order of keys in maps.
-type document []item
-
-type item struct {
-	K interface{}
-	V interface{}
-}
-
-func (doc document) EncodeValue(e objconv.Encoder) error {
-	i := 0
-	return e.EncodeMap(len(doc), func(k objconv.Encoder, v objconv.Encoder) (err error) {
-		if err = k.Encode(doc[i].K); err != nil {
-			return
-		}
-		if err = v.Encode(doc[i].V); err != nil {
-			return
-		}
-		i++
-		return
-	})
-}
-
-func (doc *document) DecodeValue(d objconv.Decoder) error {
-	return d.DecodeMap(func(k objconv.Decoder, v objconv.Decoder) (err error) {
-		var item item
-		if err = k.Decode(&item.K); err != nil {
-			return
-		}
-		if err = v.Decode(&item.V); err != nil {
-			return
-		}
-		*doc = append(*doc, item)
-		return
-	})
-}
-
 func main() {
 	var r = bufio.NewReader(os.Stdin)
 	var w = bufio.NewWriter(os.Stdout)
@@ -61,11 +24,13 @@ func main() {
 	var output string
 	var list bool
 	var pretty bool
+	var canonical bool
 
 	flag.StringVar(&input, "i", "json", "The format of the input stream")
 	flag.StringVar(&output, "o", "json", "The format of the output stream")
 	flag.BoolVar(&list, "l", false, "Prints a list of all the formats available")
 	flag.BoolVar(&pretty, "p", false, "Prints in pretty format when available")
+	flag.BoolVar(&canonical, "c", false, "Prints in canonical format when available")
 	flag.Parse()
 
 	if list {
@@ -73,7 +38,7 @@ func main() {
 		return
 	}
 
-	if err := conv(w, output, r, input, pretty); err != nil {
+	if err := conv(w, output, r, input, pretty, canonical); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
@@ -93,7 +58,7 @@ func codecs(w io.Writer) {
 	return
 }
 
-func conv(w io.Writer, output string, r io.Reader, input string, pretty bool) (err error) {
+func conv(w io.Writer, output string, r io.Reader, input string, pretty bool, canonical bool) (err error) {
 	var ic objconv.Codec
 	var oc objconv.Codec
 	var ok bool
@@ -108,10 +73,8 @@ func conv(w io.Writer, output string, r io.Reader, input string, pretty bool) (e
 		return
 	}
 
-	var d = objconv.NewStreamDecoder(ic.NewParser(r))
-	var e *objconv.StreamEncoder
-	var v interface{}
-	var m = oc.NewEmitter(w)
+	var c = &countWriter{w: w}
+	var m = oc.NewEmitter(c)
 
 	if pretty {
 		if p, ok := m.(objconv.PrettyEmitter); ok {
@@ -119,34 +82,35 @@ func conv(w io.Writer, output string, r io.Reader, input string, pretty bool) (e
 		}
 	}
 
-	if e, err = d.Encoder(m); err != nil {
-		if err == io.EOF { // empty input
-			err = nil
-		}
-		return
-	}
-
-	// Overwrite the type used for decoding maps so we can preserve the order
-	// of the keys.
-	d.MapType = reflect.TypeOf(document(nil))
-
-	for d.Decode(&v) == nil {
-		if err = e.Encode(v); err != nil {
-			return
+	if canonical {
+		if e, ok := m.(objconv.CanonicalEmitter); ok {
+			m = e.CanonicalEmitter()
 		}
-		v = nil
 	}
 
-	if err = e.Close(); err != nil {
+	if _, err = objconv.CopyStream(m, ic.NewParser(r)); err != nil {
 		return
 	}
 
 	// Not ideal but does the job, if the output is JSON we add a newline
-	// character at the end to make it easier to read in terminals.
-	if strings.Contains(output, "json") {
+	// character at the end to make it easier to read in terminals. Nothing is
+	// written when the input was empty.
+	if c.n != 0 && strings.Contains(output, "json") {
 		fmt.Fprintln(w)
 	}
 
-	err = d.Err()
+	return
+}
+
+// countWriter counts the bytes written to w, it is used to detect whether any
+// output was produced.
+type countWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countWriter) Write(b []byte) (n int, err error) {
+	n, err = c.w.Write(b)
+	c.n += n
 	return
 }
@@ -1,6 +1,7 @@
 package objconv
 
 import (
+	"context"
 	"io"
 	"sync"
 )
@@ -13,22 +14,61 @@ type Codec struct {
 
 // NewEncoder returns a new encoder that outputs to w.
 func (c Codec) NewEncoder(w io.Writer) *Encoder {
-	return NewEncoder(c.NewEmitter(w))
+	enc := NewEncoder(c.NewEmitter(w))
+	enc.newEmitter = c.NewEmitter
+	return enc
 }
 
 // NewDecoder returns a new decoder that takes input from r.
 func (c Codec) NewDecoder(r io.Reader) *Decoder {
-	return NewDecoder(c.NewParser(r))
+	dec := NewDecoder(c.NewParser(r))
+	dec.newParser = c.NewParser
+	return dec
 }
 
 // NewStreamEncoder returns a new stream encoder that outputs to w.
 func (c Codec) NewStreamEncoder(w io.Writer) *StreamEncoder {
-	return NewStreamEncoder(c.NewEmitter(w))
+	enc := NewStreamEncoder(c.NewEmitter(w))
+	enc.newEmitter = c.NewEmitter
+	return enc
 }
 
 // NewStreamDecoder returns a new stream decoder that takes input from r.
 func (c Codec) NewStreamDecoder(r io.Reader) *StreamDecoder {
-	return NewStreamDecoder(c.NewParser(r))
+	dec := NewStreamDecoder(c.NewParser(r))
+	dec.newParser = c.NewParser
+	return dec
+}
+
+// NewStreamEncoderContext returns a new stream encoder that outputs to w, and
+// stops encoding values when ctx is canceled, see NewStreamEncoderContext.
+//
+// If w supports write deadlines (like net.Conn), blocked writes are interrupted
+// when ctx is canceled.
+func (c Codec) NewStreamEncoderContext(ctx context.Context, w io.Writer) *StreamEncoder {
+	cw := newContextWriter(ctx, w)
+	enc := NewStreamEncoderContext(ctx, c.NewEmitter(cw))
+	enc.closing = cw.close
+	enc.newEmitter = c.NewEmitter
+	return enc
+}
+
+// NewStreamDecoderContext returns a new stream decoder that takes input from r,
+// and stops decoding values when ctx is canceled, see NewStreamDecoderContext.
+//
+// If r supports read deadlines (like net.Conn), blocked reads are interrupted
+// when ctx is canceled.
+func (c Codec) NewStreamDecoderContext(ctx context.Context, r io.Reader) *StreamDecoder {
+	cr := newContextReader(ctx, r)
+	dec := NewStreamDecoderContext(ctx, c.NewParser(cr))
+	dec.closing = cr.close
+	dec.newParser = c.NewParser
+	return dec
+}
+
+// NewParallelDecoder returns a new parallel decoder that takes input from r.
+func (c Codec) NewParallelDecoder(r io.Reader) *ParallelDecoder {
+	return NewParallelDecoder(c, r)
 }
 
 // A Registry associates mime types to codecs.
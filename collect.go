@@ -0,0 +1,363 @@
+package objconv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeError represents the failure to decode a value of a document.
+type DecodeError struct {
+	// Path is the JSON Pointer (RFC 6901) to the value that could not be
+	// decoded, the empty string refers to the whole document.
+	Path string
+
+	// Err is the error that occurred while decoding the value.
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e *DecodeError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("objconv: %s: %s", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeErrors is returned by decoders configured to collect errors, it lists
+// all the values of the document that could not be decoded.
+type DecodeErrors []*DecodeError
+
+// Error satisfies the error interface.
+func (e DecodeErrors) Error() string {
+	s := make([]string, len(e))
+
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+
+	return fmt.Sprintf("objconv: %d decode error(s): %s", len(s), strings.Join(s, "; "))
+}
+
+// Unwrap returns the list of errors, it allows the use of errors.Is and
+// errors.As to inspect the errors.
+func (e DecodeErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+
+	for i, err := range e {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// errorCollector is used by decoders configured to collect errors, it records
+// the errors and tracks the path of the value being decoded.
+type errorCollector struct {
+	parser trackingParser
+	path   []interface{} // map keys and array indexes
+	errs   *DecodeErrors // shared with the collectors of derived decoders
+}
+
+// collecting returns a copy of d which collects errors, values that it decodes
+// are reported under path.
+func (d Decoder) collecting(path []interface{}, errs *DecodeErrors) Decoder {
+	c := &errorCollector{
+		parser: trackingParser{Parser: d.Parser},
+		path:   append([]interface{}{}, path...),
+		errs:   errs,
+	}
+	d.Parser = &c.parser
+	d.errs = c
+	return d
+}
+
+// decodeCollect decodes v with a decoder that collects errors.
+func (d Decoder) decodeCollect(v interface{}) error {
+	errs := DecodeErrors{}
+	d = d.collecting(nil, &errs)
+
+	if err := d.decodeCollected(v); err != nil {
+		return err
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// decodeCollected decodes the next value into v. When d collects errors the
+// failure to convert the value is recorded instead of being returned.
+func (d Decoder) decodeCollected(v interface{}) (err error) {
+	if d.errs == nil {
+		return d.Decode(v)
+	}
+
+	if d.off != 0 {
+		if d.off, err = 0, d.Parser.ParseMapValue(d.off-1); err != nil {
+			return
+		}
+	}
+
+	return d.errs.collect(d, nil, func(d Decoder) error { return d.Decode(v) })
+}
+
+// decodeElem is called when a decoder that collects errors decodes a value
+// nested in an array or a map, elem is the index or key of the value.
+func (d Decoder) decodeElem(elem interface{}, to reflect.Value, f decodeFunc) error {
+	return d.errs.collect(d, elem, func(d Decoder) (err error) {
+		_, err = f(d, to)
+		return
+	})
+}
+
+// collect calls f to decode the next value. If f fails converting the value
+// the error is recorded, and the value is skipped if it wasn't consumed so the
+// decoding can continue.
+//
+// Errors returned by the parser, or that occurred while the value was only
+// partially consumed, can't be recovered from and are returned.
+func (c *errorCollector) collect(d Decoder, elem interface{}, f func(Decoder) error) (err error) {
+	var t Type
+
+	if elem != nil {
+		c.path = append(c.path, elem)
+		defer func() { c.path = c.path[:len(c.path)-1] }()
+	}
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	p := &c.parser
+	depth, count := p.depth, p.count
+
+	if err = f(d); err == nil || p.err != nil || p.depth != depth {
+		return
+	}
+
+	if p.count == count {
+		if e := d.skip(t); e != nil {
+			return e
+		}
+	}
+
+	*c.errs = append(*c.errs, &DecodeError{Path: c.pointer(), Err: err})
+	return nil
+}
+
+// pointer returns the current path as a JSON Pointer.
+func (c *errorCollector) pointer() string {
+	var b []byte
+
+	for _, elem := range c.path {
+		b = append(b, '/')
+
+		switch x := elem.(type) {
+		case int:
+			b = strconv.AppendInt(b, int64(x), 10)
+		case string:
+			b = appendPointerElem(b, x)
+		case reflect.Value:
+			b = appendPointerElem(b, fmt.Sprint(x.Interface()))
+		default:
+			b = appendPointerElem(b, fmt.Sprint(x))
+		}
+	}
+
+	return string(b)
+}
+
+func appendPointerElem(b []byte, s string) []byte {
+	for i := 0; i != len(s); i++ {
+		switch c := s[i]; c {
+		case '~':
+			b = append(b, '~', '0')
+		case '/':
+			b = append(b, '~', '1')
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// trackingParser wraps the parser of decoders that collect errors. It records
+// the errors returned by the parser, and the position of the parser in the
+// document, which is used to determine whether a value that could not be
+// decoded was consumed.
+type trackingParser struct {
+	Parser
+	depth int   // number of arrays and maps that were entered
+	count int   // number of values that were parsed
+	err   error // first error returned by the parser
+}
+
+func (p *trackingParser) track(err error, n int) {
+	if err == nil {
+		p.count += n
+	} else if err != End && p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *trackingParser) ParseType() (t Type, err error) {
+	t, err = p.Parser.ParseType()
+	p.track(err, 0)
+	return
+}
+
+func (p *trackingParser) ParseNil() (err error) {
+	err = p.Parser.ParseNil()
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseBool() (v bool, err error) {
+	v, err = p.Parser.ParseBool()
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseInt() (v int64, err error) {
+	v, err = p.Parser.ParseInt()
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseUint() (v uint64, err error) {
+	v, err = p.Parser.ParseUint()
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseFloat() (v float64, err error) {
+	v, err = p.Parser.ParseFloat()
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseString() (v []byte, err error) {
+	v, err = p.Parser.ParseString()
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseBytes() (v []byte, err error) {
+	v, err = p.Parser.ParseBytes()
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseTime() (v time.Time, err error) {
+	v, err = p.Parser.ParseTime()
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseDuration() (v time.Duration, err error) {
+	v, err = p.Parser.ParseDuration()
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseError() (v error, err error) {
+	v, err = p.Parser.ParseError()
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseArrayBegin() (n int, err error) {
+	if n, err = p.Parser.ParseArrayBegin(); err == nil {
+		p.depth++
+	}
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseArrayEnd(n int) (err error) {
+	if err = p.Parser.ParseArrayEnd(n); err == nil {
+		p.depth--
+	}
+	p.track(err, 0)
+	return
+}
+
+func (p *trackingParser) ParseArrayNext(n int) (err error) {
+	err = p.Parser.ParseArrayNext(n)
+	p.track(err, 0)
+	return
+}
+
+func (p *trackingParser) ParseMapBegin() (n int, err error) {
+	if n, err = p.Parser.ParseMapBegin(); err == nil {
+		p.depth++
+	}
+	p.track(err, 1)
+	return
+}
+
+func (p *trackingParser) ParseMapEnd(n int) (err error) {
+	if err = p.Parser.ParseMapEnd(n); err == nil {
+		p.depth--
+	}
+	p.track(err, 0)
+	return
+}
+
+func (p *trackingParser) ParseMapValue(n int) (err error) {
+	err = p.Parser.ParseMapValue(n)
+	p.track(err, 0)
+	return
+}
+
+func (p *trackingParser) ParseMapNext(n int) (err error) {
+	err = p.Parser.ParseMapNext(n)
+	p.track(err, 0)
+	return
+}
+
+// Skip satisfies the skipParser interface, falling back to parsing the value
+// if the underlying parser doesn't support skipping values.
+func (p *trackingParser) Skip() (err error) {
+	if s, ok := p.Parser.(skipParser); ok {
+		err = s.Skip()
+		p.track(err, 1)
+		return
+	}
+	var t Type
+	if t, err = p.ParseType(); err == nil {
+		err = (Decoder{Parser: p}).decodeInterfaceFromType(t, reflect.Value{})
+	}
+	return
+}
+
+// TextParser forwards the information of whether the underlying parser is
+// reading a human-readable format.
+func (p *trackingParser) TextParser() bool {
+	return isTextParser(p.Parser)
+}
+
+// StableParser forwards the information of whether the byte slices returned by
+// the underlying parser can be retained without being copied.
+func (p *trackingParser) StableParser() bool {
+	return isStableParser(p.Parser)
+}
+
+// DecodeBytes forwards to the underlying parser, so byte slices are decoded the
+// same way whether errors are collected or not.
+func (p *trackingParser) DecodeBytes(b []byte) ([]byte, error) {
+	if d, ok := p.Parser.(bytesDecoder); ok {
+		return d.DecodeBytes(b)
+	}
+	return b, nil
+}
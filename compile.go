@@ -0,0 +1,1150 @@
+package objconv
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// typeCodec is the compiled encoder and decoder of a Go type.
+//
+// The encode and decode functions operate on pointers to values of the type,
+// struct fields are accessed at precomputed offsets and slice elements by
+// pointer arithmetic, which avoids the cost of going through reflect.Value
+// (FieldByIndex, Interface, Set...) for every value. Types which need
+// reflection, like types with adapters or implementing the ValueEncoder or
+// encoding.TextMarshaler interfaces, are compiled to functions that wrap the
+// reflection-based encoders and decoders.
+//
+// The reflection-based functions are also used for values that aren't
+// addressable, since there is no pointer to pass to the compiled functions.
+type typeCodec struct {
+	typ reflect.Type
+
+	encode encodePtrFunc
+	decode decodePtrFunc
+
+	// Reflection-based encoder and decoder of the type, used as fallbacks.
+	encodeValue encodeFunc
+	decodeValue decodeFunc
+
+	// Functions returned by encodeFuncOf and decodeFuncOf, they use the
+	// compiled functions when values are addressable (and settable for the
+	// decoder) and the fallbacks otherwise.
+	encodeAny encodeFunc
+	decodeAny decodeFunc
+
+	// For pointer types compiled to encode the value they point to, the
+	// codec of this value.
+	elem *typeCodec
+
+	// Whether values of the type are stored in the data word of interfaces
+	// instead of being pointed to by it.
+	inlined bool
+}
+
+// encodePtrFunc is the prototype of compiled functions that encode the value
+// pointed to by p.
+type encodePtrFunc func(e Encoder, p unsafe.Pointer) error
+
+// decodePtrFunc is the prototype of compiled functions that decode a value to
+// the location pointed to by p.
+type decodePtrFunc func(d Decoder, p unsafe.Pointer) (Type, error)
+
+func (c *typeCodec) encodeReflect(e Encoder, v reflect.Value) error {
+	if v.CanAddr() {
+		return c.encode(e, unsafe.Pointer(v.UnsafeAddr()))
+	}
+	return c.encodeValue(e, v)
+}
+
+func (c *typeCodec) decodeReflect(d Decoder, v reflect.Value) (Type, error) {
+	if v.CanSet() {
+		return c.decode(d, unsafe.Pointer(v.UnsafeAddr()))
+	}
+	return c.decodeValue(d, v)
+}
+
+// compile sets the compiled functions of c, the codecs of the types that c
+// depends on are looked up in the cache or compiled and added to seen.
+func (c *typeCodec) compile(seen map[reflect.Type]*typeCodec) {
+	t := c.typ
+
+	c.encodeValue = makeEncodeFunc(t, encodeFuncOpts{})
+	c.decodeValue = makeDecodeFunc(t, decodeFuncOpts{})
+	c.encodeAny = c.encodeReflect
+	c.decodeAny = c.decodeReflect
+	c.inlined = isInlined(t)
+
+	c.encode = c.compileEncode(seen)
+	c.decode = c.compileDecode(seen)
+}
+
+// compileEncode returns the compiled encoder of the type, the type checks are
+// made in the same order than in makeEncodeFunc.
+func (c *typeCodec) compileEncode(seen map[reflect.Type]*typeCodec) encodePtrFunc {
+	t := c.typ
+
+	if _, ok := AdapterOf(t); ok {
+		return c.encodeFallback
+	}
+
+	switch t {
+	case timeType:
+		return Encoder.encodeTimePtr
+
+	case timePtrType:
+		return Encoder.encodeTimePtrPtr
+
+	case durationType:
+		return Encoder.encodeDurationPtr
+
+	case emptyInterface:
+		return Encoder.encodeInterfacePtr
+	}
+
+	switch {
+	case t.Kind() == reflect.Ptr && t.Elem().Implements(valueEncoderInterface):
+		return c.compileEncodePtr(seen)
+
+	case t.Implements(valueEncoderInterface),
+		t.Implements(binaryMarshalerInterface),
+		t.Implements(textMarshalerInterface),
+		t.Implements(errorInterface):
+		return c.encodeFallback
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s := newStructCodec(t, seen)
+		return func(e Encoder, p unsafe.Pointer) error {
+			return e.encodeStructPtr(p, s)
+		}
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Encoder.encodeBytesPtr
+		}
+		elem := codecOf(t.Elem(), seen)
+		size := t.Elem().Size()
+		return func(e Encoder, p unsafe.Pointer) error {
+			s := (*sliceHeader)(p)
+			return e.encodeArrayPtr(s.data, s.len, size, elem)
+		}
+
+	case reflect.Array:
+		elem := codecOf(t.Elem(), seen)
+		size := t.Elem().Size()
+		n := t.Len()
+		return func(e Encoder, p unsafe.Pointer) error {
+			return e.encodeArrayPtr(p, n, size, elem)
+		}
+
+	case reflect.Map:
+		kc := codecOf(t.Key(), seen)
+		vc := codecOf(t.Elem(), seen)
+		f := compileEncodeMapConv(t)
+		return func(e Encoder, p unsafe.Pointer) error {
+			return e.encodeMapPtr(p, t, kc, vc, f)
+		}
+
+	case reflect.Ptr:
+		return c.compileEncodePtr(seen)
+
+	case reflect.String:
+		return Encoder.encodeStringPtr
+
+	case reflect.Bool:
+		return Encoder.encodeBoolPtr
+
+	case reflect.Int:
+		return Encoder.encodeIntPtr
+
+	case reflect.Int8:
+		return Encoder.encodeInt8Ptr
+
+	case reflect.Int16:
+		return Encoder.encodeInt16Ptr
+
+	case reflect.Int32:
+		return Encoder.encodeInt32Ptr
+
+	case reflect.Int64:
+		return Encoder.encodeInt64Ptr
+
+	case reflect.Uint:
+		return Encoder.encodeUintPtr
+
+	case reflect.Uint8:
+		return Encoder.encodeUint8Ptr
+
+	case reflect.Uint16:
+		return Encoder.encodeUint16Ptr
+
+	case reflect.Uint32:
+		return Encoder.encodeUint32Ptr
+
+	case reflect.Uint64:
+		return Encoder.encodeUint64Ptr
+
+	case reflect.Uintptr:
+		return Encoder.encodeUintptrPtr
+
+	case reflect.Float32:
+		return Encoder.encodeFloat32Ptr
+
+	case reflect.Float64:
+		return Encoder.encodeFloat64Ptr
+
+	default:
+		return c.encodeFallback
+	}
+}
+
+func (c *typeCodec) compileEncodePtr(seen map[reflect.Type]*typeCodec) encodePtrFunc {
+	elem := codecOf(c.typ.Elem(), seen)
+	c.elem = elem
+	return func(e Encoder, p unsafe.Pointer) error {
+		return e.encodePointerPtr(*(*unsafe.Pointer)(p), elem)
+	}
+}
+
+// compileEncodeMapConv returns the encoder of maps of type t that encodeMapWith
+// converts to one of the map types it has optimized encoders for, or nil if t
+// isn't one of those. Convertible map types have the same memory layout, so the
+// pointer to the map is read as a pointer to the optimized type.
+func compileEncodeMapConv(t reflect.Type) encodePtrFunc {
+	switch {
+	case t.ConvertibleTo(mapInterfaceInterfaceType):
+		return func(e Encoder, p unsafe.Pointer) error {
+			return e.encodeMapInterfaceInterface(*(*map[interface{}]interface{})(p))
+		}
+
+	case t.ConvertibleTo(mapStringInterfaceType):
+		return func(e Encoder, p unsafe.Pointer) error {
+			return e.encodeMapStringInterface(*(*map[string]interface{})(p))
+		}
+
+	case t.ConvertibleTo(mapStringStringType):
+		return func(e Encoder, p unsafe.Pointer) error {
+			return e.encodeMapStringString(*(*map[string]string)(p))
+		}
+
+	default:
+		return nil
+	}
+}
+
+func (c *typeCodec) encodeFallback(e Encoder, p unsafe.Pointer) error {
+	return c.encodeValue(e, reflect.NewAt(c.typ, p).Elem())
+}
+
+// compileDecode returns the compiled decoder of the type, the type checks are
+// made in the same order than in makeDecodeFunc.
+func (c *typeCodec) compileDecode(seen map[reflect.Type]*typeCodec) decodePtrFunc {
+	t := c.typ
+
+	if _, ok := AdapterOf(t); ok {
+		return c.decodeFallback
+	}
+
+	switch t {
+	case timeType:
+		return Decoder.decodeTimePtr
+
+	case durationType:
+		return Decoder.decodeDurationPtr
+
+	case emptyInterface:
+		return c.decodeFallback
+	}
+
+	switch p := reflect.PtrTo(t); {
+	case t.Implements(valueDecoderInterface),
+		t.Implements(errorInterface),
+		t.Implements(binaryUnmarshalerInterface),
+		t.Implements(textUnmarshalerInterface),
+		p.Implements(valueDecoderInterface),
+		p.Implements(binaryUnmarshalerInterface),
+		p.Implements(textUnmarshalerInterface):
+		return c.decodeFallback
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s := newStructCodec(t, seen)
+		return func(d Decoder, p unsafe.Pointer) (Type, error) {
+			return d.decodeStructPtr(p, s)
+		}
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Decoder.decodeBytesPtr
+		}
+		elem := codecOf(t.Elem(), seen)
+		return func(d Decoder, p unsafe.Pointer) (Type, error) {
+			return d.decodeSlicePtr(p, t, elem)
+		}
+
+	case reflect.Array:
+		elem := codecOf(t.Elem(), seen)
+		return func(d Decoder, p unsafe.Pointer) (Type, error) {
+			return d.decodeArrayPtr(p, t, elem)
+		}
+
+	case reflect.Map:
+		kc := codecOf(t.Key(), seen)
+		vc := codecOf(t.Elem(), seen)
+		switch t {
+		case mapInterfaceInterfaceType, mapStringInterfaceType, mapStringStringType:
+			return func(d Decoder, p unsafe.Pointer) (Type, error) {
+				return d.decodeMapWith(reflect.NewAt(t, p).Elem(), kc.decodeAny, vc.decodeAny)
+			}
+		}
+		return func(d Decoder, p unsafe.Pointer) (Type, error) {
+			return d.decodeMapPtr(p, t, kc, vc)
+		}
+
+	case reflect.Ptr:
+		elem := codecOf(t.Elem(), seen)
+		return func(d Decoder, p unsafe.Pointer) (Type, error) {
+			return d.decodePointerPtr(p, elem)
+		}
+
+	case reflect.Bool:
+		return Decoder.decodeBoolPtr
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		set := setIntFuncOf(t)
+		return func(d Decoder, p unsafe.Pointer) (Type, error) {
+			return d.decodeIntPtr(p, t, set)
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		set := setUintFuncOf(t)
+		return func(d Decoder, p unsafe.Pointer) (Type, error) {
+			return d.decodeUintPtr(p, t, set)
+		}
+
+	case reflect.Float32:
+		return Decoder.decodeFloat32Ptr
+
+	case reflect.Float64:
+		return Decoder.decodeFloat64Ptr
+
+	case reflect.String:
+		return Decoder.decodeStringPtr
+
+	default:
+		return c.decodeFallback
+	}
+}
+
+func (c *typeCodec) decodeFallback(d Decoder, p unsafe.Pointer) (Type, error) {
+	return c.decodeValue(d, reflect.NewAt(c.typ, p).Elem())
+}
+
+// structCodec is the compiled representation of a struct type, fields are
+// located by their offset in the struct.
+type structCodec struct {
+	typ          reflect.Type
+	fields       []fieldCodec
+	fieldsByName map[string]*fieldCodec
+	omit         bool // whether some fields may be omitted
+}
+
+type fieldCodec struct {
+	*structField
+	codec *typeCodec
+}
+
+func newStructCodec(t reflect.Type, seen map[reflect.Type]*typeCodec) *structCodec {
+	st := structCache.lookup(t)
+	s := &structCodec{
+		typ:          t,
+		fields:       make([]fieldCodec, len(st.fields)),
+		fieldsByName: make(map[string]*fieldCodec, len(st.fields)),
+	}
+
+	for i := range st.fields {
+		f := &st.fields[i]
+		s.fields[i] = fieldCodec{
+			structField: f,
+			codec:       codecOf(t.Field(f.index[0]).Type, seen),
+		}
+		s.fieldsByName[f.name] = &s.fields[i]
+		s.omit = s.omit || f.omitempty || f.omitzero
+	}
+
+	return s
+}
+
+// lookup returns the field named by the key of the entry i of a map, or nil if
+// the struct has no such field. Encoders usually output the fields in order so
+// the field at index i is tried first.
+func (s *structCodec) lookup(name []byte, i int) *fieldCodec {
+	if i < len(s.fields) {
+		if f := &s.fields[i]; f.name == string(name) {
+			return f
+		}
+	}
+	return s.fieldsByName[string(name)]
+}
+
+// field returns a pointer to the field f of the struct pointed to by p.
+func (f *fieldCodec) field(p unsafe.Pointer) unsafe.Pointer {
+	return unsafe.Pointer(uintptr(p) + f.offset)
+}
+
+func (f *fieldCodec) omitPtr(p unsafe.Pointer) bool {
+	return (f.omitempty || f.omitzero) && f.omit(reflect.NewAt(f.codec.typ, p).Elem())
+}
+
+type sliceHeader struct {
+	data unsafe.Pointer
+	len  int
+	cap  int
+}
+
+type eface struct {
+	typ unsafe.Pointer
+	ptr unsafe.Pointer
+}
+
+// efacePtr returns the data word of the interface v.
+func efacePtr(v interface{}) unsafe.Pointer {
+	return (*eface)(unsafe.Pointer(&v)).ptr
+}
+
+// isInlined returns true if values of type t are stored in the data word of
+// interfaces (like pointers, maps, or structs with a single pointer field).
+// Other values are stored in memory pointed to by the data word, which is never
+// nil, even for zero values.
+func isInlined(t reflect.Type) bool {
+	return efacePtr(reflect.Zero(t).Interface()) == nil
+}
+
+func setIntFuncOf(t reflect.Type) func(unsafe.Pointer, int64) {
+	switch t.Kind() {
+	case reflect.Int:
+		return func(p unsafe.Pointer, i int64) { *(*int)(p) = int(i) }
+	case reflect.Int8:
+		return func(p unsafe.Pointer, i int64) { *(*int8)(p) = int8(i) }
+	case reflect.Int16:
+		return func(p unsafe.Pointer, i int64) { *(*int16)(p) = int16(i) }
+	case reflect.Int32:
+		return func(p unsafe.Pointer, i int64) { *(*int32)(p) = int32(i) }
+	default:
+		return func(p unsafe.Pointer, i int64) { *(*int64)(p) = i }
+	}
+}
+
+func setUintFuncOf(t reflect.Type) func(unsafe.Pointer, uint64) {
+	switch t.Kind() {
+	case reflect.Uint:
+		return func(p unsafe.Pointer, u uint64) { *(*uint)(p) = uint(u) }
+	case reflect.Uint8:
+		return func(p unsafe.Pointer, u uint64) { *(*uint8)(p) = uint8(u) }
+	case reflect.Uint16:
+		return func(p unsafe.Pointer, u uint64) { *(*uint16)(p) = uint16(u) }
+	case reflect.Uint32:
+		return func(p unsafe.Pointer, u uint64) { *(*uint32)(p) = uint32(u) }
+	case reflect.Uintptr:
+		return func(p unsafe.Pointer, u uint64) { *(*uintptr)(p) = uintptr(u) }
+	default:
+		return func(p unsafe.Pointer, u uint64) { *(*uint64)(p) = u }
+	}
+}
+
+// codecOf returns the codec of t from the cache, or from seen when it is being
+// compiled. New codecs are added to seen and compiled.
+func codecOf(t reflect.Type, seen map[reflect.Type]*typeCodec) *typeCodec {
+	if c := seen[t]; c != nil {
+		return c
+	}
+
+	codecMutex.RLock()
+	c := codecStore[t]
+	codecMutex.RUnlock()
+
+	if c == nil {
+		// The codec is added to seen before being compiled so recursive types
+		// reference it instead of being compiled again.
+		c = &typeCodec{typ: t}
+		seen[t] = c
+		c.compile(seen)
+	}
+
+	return c
+}
+
+// lookupCodec returns the codec of t, compiling it if it isn't in the cache.
+// This function is safe to call from multiple goroutines.
+func lookupCodec(t reflect.Type) *typeCodec {
+	codecMutex.RLock()
+	c := codecStore[t]
+	codecMutex.RUnlock()
+
+	if c == nil {
+		// Like with the struct cache, codecs may be compiled multiple times
+		// when goroutines race to compile the same type, the codecs are
+		// equivalent so it doesn't matter which one is retained.
+		seen := make(map[reflect.Type]*typeCodec)
+		c = codecOf(t, seen)
+
+		codecMutex.Lock()
+		for typ, tc := range seen {
+			if codecStore[typ] == nil {
+				codecStore[typ] = tc
+			}
+		}
+		codecMutex.Unlock()
+	}
+
+	return c
+}
+
+// clearCodecs empties the codec cache.
+func clearCodecs() {
+	codecMutex.Lock()
+	for typ := range codecStore {
+		delete(codecStore, typ)
+	}
+	codecMutex.Unlock()
+}
+
+var (
+	codecMutex sync.RWMutex
+	codecStore = make(map[reflect.Type]*typeCodec)
+)
+
+func (e Encoder) encodeStructPtr(p unsafe.Pointer, s *structCodec) (err error) {
+	n := len(s.fields)
+
+	if s.omit {
+		for i := range s.fields {
+			if f := &s.fields[i]; f.omitPtr(f.field(p)) {
+				n--
+			}
+		}
+	}
+
+	if err = e.Emitter.EmitMapBegin(n); err != nil {
+		return
+	}
+	n = 0
+
+	for i := range s.fields {
+		f := &s.fields[i]
+		if fp := f.field(p); !f.omitPtr(fp) {
+			if n != 0 {
+				if err = e.Emitter.EmitMapNext(); err != nil {
+					return
+				}
+			}
+			if err = e.Emitter.EmitString(f.name); err != nil {
+				return
+			}
+			if err = e.Emitter.EmitMapValue(); err != nil {
+				return
+			}
+			if err = f.codec.encode(e, fp); err != nil {
+				return
+			}
+			n++
+		}
+	}
+
+	return e.Emitter.EmitMapEnd()
+}
+
+// encodeArrayPtr encodes the n elements of the given size starting at p.
+func (e Encoder) encodeArrayPtr(p unsafe.Pointer, n int, size uintptr, elem *typeCodec) error {
+	i := 0
+	return e.EncodeArray(n, func(e Encoder) (err error) {
+		err = elem.encode(e, unsafe.Pointer(uintptr(p)+uintptr(i)*size))
+		i++
+		return
+	})
+}
+
+// encodeMapPtr encodes the map of type t pointed to by p, f is the result of
+// compileEncodeMapConv for t. The keys and values of maps are copied to
+// temporary locations to be encoded by the compiled encoders of their types.
+func (e Encoder) encodeMapPtr(p unsafe.Pointer, t reflect.Type, kc *typeCodec, vc *typeCodec, f encodePtrFunc) error {
+	v := reflect.NewAt(t, p).Elem()
+
+	switch {
+	case e.SortMapKeys:
+		return e.encodeMapWith(v, kc.encodeAny, vc.encodeAny)
+	case f != nil:
+		return f(e, p)
+	}
+
+	var k []reflect.Value
+	var n = v.Len()
+	var i = 0
+
+	if n == 0 {
+		return e.EncodeMap(0, nil)
+	}
+
+	k = v.MapKeys()
+	kv := reflect.New(kc.typ).Elem()
+	vv := reflect.New(vc.typ).Elem()
+	kp := unsafe.Pointer(kv.UnsafeAddr())
+	vp := unsafe.Pointer(vv.UnsafeAddr())
+
+	return e.EncodeMap(n, func(ke Encoder, ve Encoder) (err error) {
+		kv.Set(k[i])
+		vv.Set(v.MapIndex(k[i]))
+		if err = kc.encode(e, kp); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitMapValue(); err != nil {
+			return
+		}
+		if err = vc.encode(e, vp); err != nil {
+			return
+		}
+		i++
+		return
+	})
+}
+
+// encodePointerPtr encodes the value pointed to by p, or nil if p is nil.
+func (e Encoder) encodePointerPtr(p unsafe.Pointer, elem *typeCodec) error {
+	if p == nil {
+		return e.Emitter.EmitNil()
+	}
+	return elem.encode(e, p)
+}
+
+// encodeBoxed encodes v with the compiled encoder of its dynamic type, reading
+// the value from the memory that the interface points to.
+func (e Encoder) encodeBoxed(v interface{}) error {
+	if v == nil {
+		return e.Emitter.EmitNil()
+	}
+
+	c := lookupCodec(reflect.TypeOf(v))
+	p := efacePtr(v)
+
+	switch {
+	case !c.inlined:
+		return c.encode(e, p)
+	case c.elem != nil:
+		return e.encodePointerPtr(p, c.elem)
+	default:
+		return c.encodeValue(e, reflect.ValueOf(v))
+	}
+}
+
+func (d Decoder) decodeStructPtr(p unsafe.Pointer, s *structCodec) (t Type, err error) {
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+	if err = d.decodeStructFieldsPtr(t, p, s); err != nil {
+		reflect.NewAt(s.typ, p).Elem().Set(zeroValueOf(s.typ))
+	}
+	return
+}
+
+// decodeStructFieldsPtr decodes a map of type t to the fields of the struct
+// pointed to by p. The loop is the one of decodeMapImpl, without the call of a
+// function for each field.
+func (d Decoder) decodeStructFieldsPtr(t Type, p unsafe.Pointer, s *structCodec) (err error) {
+	var n int
+
+	switch t {
+	case Nil:
+		return d.Parser.ParseNil()
+
+	case Map:
+		if n, err = d.Parser.ParseMapBegin(); err != nil {
+			return
+		}
+
+	default:
+		return typeConversionError(t, Map)
+	}
+
+	i := 0
+
+	for n < 0 || i < n {
+		if n < 0 || i != 0 {
+			if err = d.Parser.ParseMapNext(i); err != nil {
+				if err == End {
+					err = nil
+					break
+				}
+				return
+			}
+		}
+		if err = d.decodeFieldPtr(p, s, i); err != nil {
+			return
+		}
+		i++
+	}
+
+	return d.Parser.ParseMapEnd(i)
+}
+
+// decodeFieldPtr decodes the entry i of a map to the field of the struct
+// pointed to by p that the key of the entry names.
+func (d Decoder) decodeFieldPtr(p unsafe.Pointer, s *structCodec, i int) (err error) {
+	var b []byte
+
+	if _, b, err = d.decodeTypeAndString(); err != nil {
+		return
+	}
+	f := s.lookup(b, i)
+
+	if err = d.Parser.ParseMapValue(i); err != nil {
+		return
+	}
+
+	if f == nil {
+		if d.strict {
+			err = fmt.Errorf("objconv: unknown field %q in %s", b, s.typ)
+			if d.errs != nil {
+				err = d.errs.collect(d, string(b), func(Decoder) error { return err })
+			}
+			return
+		}
+		_, err = d.decodeInterface(reflect.Value{}) // discard
+		return
+	}
+
+	if d.errs != nil {
+		return d.decodeElem(f.name, reflect.NewAt(f.codec.typ, f.field(p)).Elem(), f.codec.decodeAny)
+	}
+
+	_, err = f.codec.decode(d, f.field(p))
+	return
+}
+
+// decodeSlicePtr decodes an array to the slice pointed to by p, the elements
+// are decoded in place by the compiled decoder of their type.
+func (d Decoder) decodeSlicePtr(p unsafe.Pointer, t reflect.Type, elem *typeCodec) (typ Type, err error) {
+	if typ, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	// Collecting errors needs the reflect.Value of elements, and coercions of
+	// scalars are rare enough to not be worth compiling.
+	if d.errs != nil || (typ != Nil && typ != Array && typ != Map && d.coerce(ScalarToSlice)) {
+		err = d.decodeSliceFromTypeWith(typ, reflect.NewAt(t, p).Elem(), elem.decodeAny)
+		return
+	}
+
+	var n int
+
+	switch typ {
+	case Nil:
+		if err = d.Parser.ParseNil(); err == nil {
+			*(*sliceHeader)(p) = sliceHeader{}
+		}
+		return
+
+	case Array:
+		if n, err = d.Parser.ParseArrayBegin(); err != nil {
+			return
+		}
+
+	default:
+		err = typeConversionError(typ, Array)
+		return
+	}
+
+	// When the length of the array is known the slice is allocated once, up to
+	// a limit so a corrupted length doesn't allocate large amounts of memory.
+	var s sliceHeader
+	var size = elem.typ.Size()
+	var i = 0
+
+	if c := preallocLen(n, size); c != 0 {
+		s = growSlicePtr(t, s, c)
+	}
+
+	for n < 0 || i < n {
+		if n < 0 || i != 0 {
+			if err = d.Parser.ParseArrayNext(i); err != nil {
+				if err == End {
+					err = nil
+					break
+				}
+				return
+			}
+		}
+		if s.len == s.cap {
+			c := 5 * s.cap
+			if c == 0 {
+				c = 10
+			}
+			s = growSlicePtr(t, s, c)
+		}
+		if _, err = elem.decode(d, unsafe.Pointer(uintptr(s.data)+uintptr(i)*size)); err != nil {
+			return
+		}
+		s.len++
+		i++
+	}
+
+	if err = d.Parser.ParseArrayEnd(i); err != nil {
+		return
+	}
+
+	if s.data == nil { // empty arrays are decoded to empty non-nil slices
+		s = growSlicePtr(t, s, 0)
+	}
+
+	*(*sliceHeader)(p) = s
+	return
+}
+
+// preallocLen returns the number of elements of the given size to allocate for
+// an array of length n, or zero if it is unknown or too large to be trusted.
+func preallocLen(n int, size uintptr) int {
+	const limit = 64 * 1024
+
+	if n <= 0 || uintptr(n)*size > limit || (size == 0 && n > limit) {
+		return 0
+	}
+
+	return n
+}
+
+// growSlicePtr returns the header of a copy of s, a slice of type t, with a
+// capacity of c elements.
+func growSlicePtr(t reflect.Type, s sliceHeader, c int) sliceHeader {
+	a := reflect.MakeSlice(t, s.len, c)
+	if s.len != 0 {
+		reflect.Copy(a, reflect.NewAt(t, unsafe.Pointer(&s)).Elem())
+	}
+	return sliceHeader{data: unsafe.Pointer(a.Pointer()), len: s.len, cap: c}
+}
+
+// decodeArrayPtr decodes an array to the Go array of type t pointed to by p.
+func (d Decoder) decodeArrayPtr(p unsafe.Pointer, t reflect.Type, elem *typeCodec) (typ Type, err error) {
+	if typ, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	if d.errs != nil {
+		err = d.decodeArrayFromTypeWith(typ, reflect.NewAt(t, p).Elem(), elem.decodeAny)
+		return
+	}
+
+	reflect.NewAt(t, p).Elem().Set(zeroValueOf(t))
+	size := elem.typ.Size()
+	n := t.Len()
+	i := 0
+
+	if err = d.decodeArrayImpl(typ, func(d Decoder) (err error) {
+		if i < n {
+			if _, err = elem.decode(d, unsafe.Pointer(uintptr(p)+uintptr(i)*size)); err != nil {
+				return
+			}
+		}
+		i++
+		return
+	}); err != nil {
+		return
+	}
+
+	if typ != Nil && i != n {
+		err = fmt.Errorf("objconv: array length mismatch, expected %d but only %d elements were decoded", n, i)
+	}
+	return
+}
+
+// decodeMapPtr decodes a map to the map of type t pointed to by p, keys and
+// values are decoded by the compiled decoders of their types to temporary
+// locations before being inserted in the map.
+func (d Decoder) decodeMapPtr(p unsafe.Pointer, t reflect.Type, kc *typeCodec, vc *typeCodec) (typ Type, err error) {
+	if typ, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	if d.errs != nil {
+		err = d.decodeMapFromTypeWith(typ, reflect.NewAt(t, p).Elem(), kc.decodeAny, vc.decodeAny)
+		return
+	}
+
+	var n int
+	var to = reflect.NewAt(t, p).Elem()
+
+	switch typ {
+	case Nil:
+		if err = d.Parser.ParseNil(); err == nil {
+			to.Set(zeroValueOf(t))
+		}
+		return
+
+	case Map:
+		if n, err = d.Parser.ParseMapBegin(); err != nil {
+			return
+		}
+
+	default:
+		err = typeConversionError(typ, Map)
+		return
+	}
+
+	m := reflect.MakeMapWithSize(t, preallocLen(n, kc.typ.Size()+vc.typ.Size()))
+
+	kz := zeroValueOf(kc.typ)
+	kv := reflect.New(kc.typ).Elem()
+	kp := unsafe.Pointer(kv.UnsafeAddr())
+
+	vz := zeroValueOf(vc.typ)
+	vv := reflect.New(vc.typ).Elem()
+	vp := unsafe.Pointer(vv.UnsafeAddr())
+
+	i := 0
+
+	for n < 0 || i < n {
+		if n < 0 || i != 0 {
+			if err = d.Parser.ParseMapNext(i); err != nil {
+				if err == End {
+					err = nil
+					break
+				}
+				return
+			}
+		}
+		kv.Set(kz) // reset the key to its zero-value
+		vv.Set(vz) // reset the value to its zero-value
+		if _, err = kc.decode(d, kp); err != nil {
+			return
+		}
+		if err = d.Parser.ParseMapValue(i); err != nil {
+			return
+		}
+		if _, err = vc.decode(d, vp); err != nil {
+			return
+		}
+		m.SetMapIndex(kv, vv)
+		i++
+	}
+
+	if err = d.Parser.ParseMapEnd(i); err == nil {
+		to.Set(m)
+	}
+	return
+}
+
+// decodePointerPtr decodes a value to the pointer pointed to by p, allocating
+// the value if the pointer is nil, or setting it to nil if the value is nil.
+func (d Decoder) decodePointerPtr(p unsafe.Pointer, elem *typeCodec) (typ Type, err error) {
+	v := *(*unsafe.Pointer)(p)
+	alloc := v == nil
+
+	if alloc {
+		v = unsafe.Pointer(reflect.New(elem.typ).Pointer())
+	}
+
+	if typ, err = elem.decode(d, v); err != nil {
+		return
+	}
+
+	switch {
+	case typ == Nil:
+		*(*unsafe.Pointer)(p) = nil
+	case alloc:
+		*(*unsafe.Pointer)(p) = v
+	}
+
+	return
+}
+
+// The compiled encoders of basic types.
+
+func (e Encoder) encodeBoolPtr(p unsafe.Pointer) error {
+	return e.Emitter.EmitBool(*(*bool)(p))
+}
+
+func (e Encoder) encodeIntPtr(p unsafe.Pointer) error {
+	return e.Emitter.EmitInt(int64(*(*int)(p)), 0)
+}
+
+func (e Encoder) encodeInt8Ptr(p unsafe.Pointer) error {
+	return e.Emitter.EmitInt(int64(*(*int8)(p)), 8)
+}
+
+func (e Encoder) encodeInt16Ptr(p unsafe.Pointer) error {
+	return e.Emitter.EmitInt(int64(*(*int16)(p)), 16)
+}
+
+func (e Encoder) encodeInt32Ptr(p unsafe.Pointer) error {
+	return e.Emitter.EmitInt(int64(*(*int32)(p)), 32)
+}
+
+func (e Encoder) encodeInt64Ptr(p unsafe.Pointer) error {
+	return e.Emitter.EmitInt(*(*int64)(p), 64)
+}
+
+func (e Encoder) encodeUintPtr(p unsafe.Pointer) error {
+	return e.Emitter.EmitUint(uint64(*(*uint)(p)), 0)
+}
+
+func (e Encoder) encodeUint8Ptr(p unsafe.Pointer) error {
+	return e.Emitter.EmitUint(uint64(*(*uint8)(p)), 8)
+}
+
+func (e Encoder) encodeUint16Ptr(p unsafe.Pointer) error {
+	return e.Emitter.EmitUint(uint64(*(*uint16)(p)), 16)
+}
+
+func (e Encoder) encodeUint32Ptr(p unsafe.Pointer) error {
+	return e.Emitter.EmitUint(uint64(*(*uint32)(p)), 32)
+}
+
+func (e Encoder) encodeUint64Ptr(p unsafe.Pointer) error {
+	return e.Emitter.EmitUint(*(*uint64)(p), 64)
+}
+
+func (e Encoder) encodeUintptrPtr(p unsafe.Pointer) error {
+	return e.Emitter.EmitUint(uint64(*(*uintptr)(p)), 0)
+}
+
+func (e Encoder) encodeFloat32Ptr(p unsafe.Pointer) error {
+	return e.Emitter.EmitFloat(float64(*(*float32)(p)), 32)
+}
+
+func (e Encoder) encodeFloat64Ptr(p unsafe.Pointer) error {
+	return e.Emitter.EmitFloat(*(*float64)(p), 64)
+}
+
+func (e Encoder) encodeStringPtr(p unsafe.Pointer) error {
+	return e.Emitter.EmitString(*(*string)(p))
+}
+
+func (e Encoder) encodeBytesPtr(p unsafe.Pointer) error {
+	return e.Emitter.EmitBytes(*(*[]byte)(p))
+}
+
+func (e Encoder) encodeTimePtr(p unsafe.Pointer) error {
+	return e.Emitter.EmitTime(*(*time.Time)(p))
+}
+
+func (e Encoder) encodeTimePtrPtr(p unsafe.Pointer) error {
+	if t := *(**time.Time)(p); t != nil {
+		return e.Emitter.EmitTime(*t)
+	}
+	return e.Emitter.EmitNil()
+}
+
+func (e Encoder) encodeDurationPtr(p unsafe.Pointer) error {
+	return e.Emitter.EmitDuration(*(*time.Duration)(p))
+}
+
+func (e Encoder) encodeInterfacePtr(p unsafe.Pointer) error {
+	return e.encodeBoxed(*(*interface{})(p))
+}
+
+// The compiled decoders of basic types.
+
+func (d Decoder) decodeBoolPtr(p unsafe.Pointer) (t Type, err error) {
+	var v bool
+
+	if t, err = d.Parser.ParseType(); err == nil {
+		if v, err = d.parseBool(t); err == nil {
+			*(*bool)(p) = v
+		}
+	}
+	return
+}
+
+func (d Decoder) decodeIntPtr(p unsafe.Pointer, typ reflect.Type, set func(unsafe.Pointer, int64)) (t Type, err error) {
+	var i int64
+
+	if t, err = d.Parser.ParseType(); err == nil {
+		if i, err = d.parseInt(t, typ); err == nil {
+			set(p, i)
+		}
+	}
+	return
+}
+
+func (d Decoder) decodeUintPtr(p unsafe.Pointer, typ reflect.Type, set func(unsafe.Pointer, uint64)) (t Type, err error) {
+	var u uint64
+
+	if t, err = d.Parser.ParseType(); err == nil {
+		if u, err = d.parseUint(t, typ); err == nil {
+			set(p, u)
+		}
+	}
+	return
+}
+
+func (d Decoder) decodeFloat32Ptr(p unsafe.Pointer) (t Type, err error) {
+	var f float64
+
+	if t, err = d.Parser.ParseType(); err == nil {
+		if f, err = d.parseFloat(t); err == nil {
+			*(*float32)(p) = float32(f)
+		}
+	}
+	return
+}
+
+func (d Decoder) decodeFloat64Ptr(p unsafe.Pointer) (t Type, err error) {
+	var f float64
+
+	if t, err = d.Parser.ParseType(); err == nil {
+		if f, err = d.parseFloat(t); err == nil {
+			*(*float64)(p) = f
+		}
+	}
+	return
+}
+
+func (d Decoder) decodeStringPtr(p unsafe.Pointer) (t Type, err error) {
+	var b []byte
+
+	// No buffer is given to parseString, it would escape to the heap through
+	// the parser on every call while only coercions of scalars need one.
+	if t, err = d.Parser.ParseType(); err == nil {
+		if b, err = d.parseString(t, nil); err == nil {
+			*(*string)(p) = d.makeString(t, b)
+		}
+	}
+	return
+}
+
+func (d Decoder) decodeBytesPtr(p unsafe.Pointer) (t Type, err error) {
+	var b []byte
+
+	if t, err = d.Parser.ParseType(); err == nil {
+		if b, err = d.parseBytes(t); err == nil {
+			*(*[]byte)(p) = d.makeBytes(t, b)
+		}
+	}
+	return
+}
+
+func (d Decoder) decodeTimePtr(p unsafe.Pointer) (t Type, err error) {
+	var v time.Time
+
+	if t, err = d.Parser.ParseType(); err == nil {
+		if v, err = d.parseTime(t); err == nil {
+			*(*time.Time)(p) = v
+		}
+	}
+	return
+}
+
+func (d Decoder) decodeDurationPtr(p unsafe.Pointer) (t Type, err error) {
+	var v time.Duration
+
+	if t, err = d.Parser.ParseType(); err == nil {
+		if v, err = d.parseDuration(t); err == nil {
+			*(*time.Duration)(p) = v
+		}
+	}
+	return
+}
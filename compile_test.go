@@ -0,0 +1,213 @@
+package objconv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type compileNode struct {
+	Value int16
+	Next  *compileNode `objconv:",omitempty"`
+}
+
+type compileInlined struct {
+	P *int
+}
+
+type compileStruct struct {
+	Bool   bool
+	Int    TInt8
+	Uint   uint32
+	Float  float32
+	String TString
+	Bytes  TBytes
+	Time   time.Time
+	Dur    time.Duration
+	Ptr    *int
+	Nil    *int
+	Iface  interface{}
+	Slice  []compileNode
+	Array  [2]string
+	Map    map[string]compileInlined
+	Node   compileNode
+	Empty  string `objconv:",omitempty"`
+	Skip   int    `objconv:"-"`
+	hidden int
+}
+
+func makeCompileStruct() compileStruct {
+	date := time.Date(2016, 12, 20, 0, 20, 1, 0, time.UTC)
+	answer := 42
+
+	return compileStruct{
+		Bool:   true,
+		Int:    -1,
+		Uint:   2,
+		Float:  0.5,
+		String: "Hello World!",
+		Bytes:  TBytes("Hello World!"),
+		Time:   date,
+		Dur:    time.Second,
+		Ptr:    &answer,
+		Iface:  compileNode{Value: 1},
+		Slice:  []compileNode{{Value: 1}, {Value: 2, Next: &compileNode{Value: 3}}},
+		Array:  [2]string{"A", "B"},
+		Map:    map[string]compileInlined{"answer": {P: &answer}},
+		Node:   compileNode{Value: 4, Next: &compileNode{Value: 5}},
+	}
+}
+
+func TestCompiledEncoder(t *testing.T) {
+	date := time.Date(2016, 12, 20, 0, 20, 1, 0, time.UTC)
+	v := makeCompileStruct()
+
+	encode := func(f func(Encoder) error) interface{} {
+		e := &ValueEmitter{}
+		if err := f(Encoder{Emitter: e}); err != nil {
+			t.Fatal(err)
+		}
+		return e.Value()
+	}
+
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"value", v},
+		{"pointer", &v},
+		{"nil pointer", (*compileStruct)(nil)},
+		{"inlined", compileInlined{P: new(int)}},
+		{"time pointers", struct{ T, N *time.Time }{T: &date}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The reflection-based encoder is used as reference, the compiled
+			// encoder must produce the same values.
+			ref := encode(func(e Encoder) error {
+				v := reflect.ValueOf(test.v)
+				return makeEncodeFunc(v.Type(), encodeFuncOpts{})(e, v)
+			})
+
+			for _, x := range []interface{}{
+				encode(func(e Encoder) error { return e.Encode(test.v) }),
+				encode(func(e Encoder) error { return e.Encode([]interface{}{test.v}) }).([]interface{})[0],
+			} {
+				if !reflect.DeepEqual(x, ref) {
+					t.Errorf("%#v != %#v", x, ref)
+				}
+			}
+		})
+	}
+}
+
+func TestCompiledDecoder(t *testing.T) {
+	v1 := makeCompileStruct()
+	v1.Iface = nil // decoded as a map
+
+	e := &ValueEmitter{}
+	if err := (Encoder{Emitter: e}).Encode(v1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Values which are not encoded, or are nil, must be reset by the decoder.
+	previous := 0
+	v2 := compileStruct{Skip: 1, hidden: 2, Nil: &previous}
+
+	if err := NewDecoder(NewValueParser(e.Value())).Decode(&v2); err != nil {
+		t.Fatal(err)
+	}
+
+	v1.Skip, v1.hidden = 1, 2
+
+	if !reflect.DeepEqual(v1, v2) {
+		t.Errorf("%#v != %#v", v1, v2)
+	}
+}
+
+func TestCompiledDecoderReusePointer(t *testing.T) {
+	n := &compileNode{Value: 1}
+	v := compileNode{Next: n}
+
+	if err := NewDecoder(NewValueParser(map[string]interface{}{
+		"Next": map[string]interface{}{"Value": 2},
+	})).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Next != n {
+		t.Error("the decoder allocated a new value instead of reusing the one that the pointer referenced")
+	}
+
+	if n.Value != 2 {
+		t.Error("invalid value decoded:", n.Value)
+	}
+}
+
+func TestCompiledDecoderIntBounds(t *testing.T) {
+	var v struct{ A int8 }
+
+	err := NewDecoder(NewValueParser(map[string]interface{}{"A": 1000})).Decode(&v)
+
+	if err == nil {
+		t.Error("no error returned when decoding an integer out of the bounds of its destination")
+	}
+}
+
+func TestIsInlined(t *testing.T) {
+	tests := []struct {
+		v       interface{}
+		inlined bool
+	}{
+		{0, false},
+		{"", false},
+		{struct{}{}, false},
+		{[0]int{}, false},
+		{compileNode{}, false},
+		{(*int)(nil), true},
+		{map[int]int(nil), true},
+		{(chan int)(nil), true},
+		{compileInlined{}, true},
+		{[1]*int{}, true},
+		{struct{ P, Q *int }{}, false},
+	}
+
+	for _, test := range tests {
+		if inlined := isInlined(reflect.TypeOf(test.v)); inlined != test.inlined {
+			t.Errorf("%T: inlined = %t", test.v, inlined)
+		}
+	}
+}
+
+type compileAdapted struct {
+	A int
+}
+
+func TestCompiledInstall(t *testing.T) {
+	v := struct{ X compileAdapted }{compileAdapted{A: 1}}
+
+	encode := func() interface{} {
+		e := &ValueEmitter{}
+		if err := (Encoder{Emitter: e}).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		return e.Value()
+	}
+
+	encode() // compiles the codec before the adapter is installed
+
+	Install(reflect.TypeOf(compileAdapted{}), Adapter{
+		Encode: func(e Encoder, v reflect.Value) error {
+			return e.Encode(v.Field(0).Int())
+		},
+		Decode: func(d Decoder, v reflect.Value) error {
+			return errors.New("not implemented")
+		},
+	})
+
+	if x, ok := encode().(map[interface{}]interface{}); !ok || x["X"] != int64(1) {
+		t.Errorf("the adapter installed on the type was not used by the compiled encoder: %#v", x)
+	}
+}
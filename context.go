@@ -0,0 +1,186 @@
+package objconv
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// NewStreamEncoderContext returns a new stream encoder that outputs to e, and
+// stops encoding values when ctx is canceled.
+//
+// The context is checked before encoding each value of the stream, once it is
+// canceled the Encode method returns a *StreamError wrapping ctx.Err(), and no
+// partial value is written to e so the stream can still be closed.
+//
+// The function panics if e is nil.
+func NewStreamEncoderContext(ctx context.Context, e Emitter) *StreamEncoder {
+	enc := NewStreamEncoder(e)
+	enc.ctx = ctx
+	return enc
+}
+
+// NewStreamDecoderContext returns a new stream decoder that takes input from
+// p, and stops decoding values when ctx is canceled.
+//
+// The context is checked before decoding each value of the stream, once it is
+// canceled the Decode method returns a *StreamError wrapping ctx.Err().
+//
+// The function panics if p is nil.
+func NewStreamDecoderContext(ctx context.Context, p Parser) *StreamDecoder {
+	dec := NewStreamDecoder(p)
+	dec.ctx = ctx
+	return dec
+}
+
+// contextError returns the error of ctx wrapped in a *StreamError carrying the
+// index i, or nil if ctx is nil or was not canceled.
+func contextError(ctx context.Context, i int) error {
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return &StreamError{Index: i, Err: err}
+		}
+	}
+	return nil
+}
+
+// aLongTimeAgo is a deadline set on readers and writers to interrupt blocked
+// operations.
+var aLongTimeAgo = time.Unix(1, 0)
+
+type readDeadliner interface {
+	SetReadDeadline(time.Time) error
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(time.Time) error
+}
+
+// contextDeadline sets a deadline in the past on a reader or writer when a
+// context is canceled, to interrupt the operations blocked on it.
+type contextDeadline struct {
+	set     func(time.Time) error
+	done    chan struct{} // closed to stop waiting for the context
+	mutex   sync.Mutex
+	closed  bool
+	expired bool // whether the deadline was set
+}
+
+// newContextDeadline starts a goroutine waiting for ctx to be canceled, which
+// calls set with a deadline in the past unless close was called first. It
+// returns nil if ctx can never be canceled.
+func newContextDeadline(ctx context.Context, set func(time.Time) error) *contextDeadline {
+	if ctx.Done() == nil {
+		return nil
+	}
+	d := &contextDeadline{set: set, done: make(chan struct{})}
+	go d.wait(ctx)
+	return d
+}
+
+func (d *contextDeadline) wait(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-d.done:
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.closed {
+		d.set(aLongTimeAgo)
+		d.expired = true
+	}
+}
+
+// close stops the goroutine waiting for the context, and clears the deadline if
+// it was set, so the reader or writer can be reused by the program.
+func (d *contextDeadline) close() {
+	if d == nil {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.closed {
+		close(d.done)
+		if d.expired {
+			d.set(time.Time{})
+		}
+		d.closed = true
+	}
+}
+
+// contextReader wraps a reader to stop reading when a context is canceled. If
+// the reader supports deadlines blocked reads are interrupted as well.
+type contextReader struct {
+	ctx      context.Context
+	r        io.Reader
+	deadline *contextDeadline
+}
+
+func newContextReader(ctx context.Context, r io.Reader) *contextReader {
+	c := &contextReader{ctx: ctx, r: r}
+
+	if d, ok := r.(readDeadliner); ok {
+		c.deadline = newContextDeadline(ctx, d.SetReadDeadline)
+	}
+
+	return c
+}
+
+func (r *contextReader) Read(b []byte) (n int, err error) {
+	if err = r.ctx.Err(); err != nil {
+		return
+	}
+	if n, err = r.r.Read(b); err != nil && r.ctx.Err() != nil {
+		err = r.ctx.Err()
+	}
+	return
+}
+
+// close is called when the stream ends or the decoder is reset, the reader may
+// be reused by the program after this point so its deadline is not modified
+// anymore.
+func (r *contextReader) close() {
+	r.deadline.close()
+}
+
+// contextWriter wraps a writer to interrupt blocked writes when a context is
+// canceled, if the writer supports deadlines.
+//
+// Writes are not rejected after the context was canceled, stream encoders check
+// the context before each value and still need to write the end of the stream
+// when they are closed.
+type contextWriter struct {
+	ctx      context.Context
+	w        io.Writer
+	deadline *contextDeadline
+}
+
+func newContextWriter(ctx context.Context, w io.Writer) *contextWriter {
+	c := &contextWriter{ctx: ctx, w: w}
+
+	if d, ok := w.(writeDeadliner); ok {
+		c.deadline = newContextDeadline(ctx, d.SetWriteDeadline)
+	}
+
+	return c
+}
+
+func (w *contextWriter) Write(b []byte) (n int, err error) {
+	if n, err = w.w.Write(b); err != nil && w.ctx.Err() != nil {
+		err = w.ctx.Err()
+	}
+	return
+}
+
+// close is called before writing the end of the stream, or when the encoder is
+// reset, it clears the deadline that may have been set to interrupt blocked
+// writes.
+func (w *contextWriter) close() {
+	w.deadline.close()
+}
@@ -0,0 +1,52 @@
+package objconv
+
+import "reflect"
+
+// ConvertOptions carries the options that can be used to configure the
+// behavior of ConvertWith.
+type ConvertOptions struct {
+	// Coerce configures the conversions applied to values which don't match
+	// the type of their destination, see Decoder.Coerce. WeakCoercions may be
+	// used to accept weakly-typed sources.
+	Coerce Coercion
+
+	// Strict makes the conversion fail when the source has map keys that don't
+	// match any field of the destination struct, instead of ignoring them.
+	Strict bool
+
+	// Rename, if not nil, is called on each string key of the maps and structs
+	// of the source, the returned value is used as key in the destination. It
+	// can be used to convert between naming conventions, for example to lower
+	// case the names of struct fields that have no tags.
+	Rename func(string) string
+
+	// MapType is used to override the type of maps produced by the conversion
+	// when there is no destination type (when converting to an empty
+	// interface), see Decoder.MapType.
+	MapType reflect.Type
+}
+
+// Convert loads the value of src into dst, which must be a pointer or a value
+// implementing ValueDecoder.
+//
+// The conversion follows the same rules as encoding src and decoding the
+// result into dst, struct fields are named after their objconv or json tags,
+// types with adapters or marshalers are converted to their encoded form, etc...
+// but src is read directly by the decoder instead of being serialized. This is
+// useful to load a struct from a generic map (like a configuration loaded from
+// a file), or to turn a struct into a map.
+func Convert(dst interface{}, src interface{}) error {
+	return ConvertWith(dst, src, ConvertOptions{})
+}
+
+// ConvertWith is like Convert but the conversion is configured with opts.
+func ConvertWith(dst interface{}, src interface{}, opts ConvertOptions) error {
+	p := NewValueParser(src)
+	p.rename = opts.Rename
+	return (Decoder{
+		Parser:  p,
+		MapType: opts.MapType,
+		Coerce:  opts.Coerce,
+		strict:  opts.Strict,
+	}).Decode(dst)
+}
@@ -0,0 +1,162 @@
+package objconv
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type convertConfig struct {
+	Name    string            `objconv:"name"`
+	Port    uint16            `objconv:"port"`
+	Debug   bool              `objconv:"debug,omitempty"`
+	Timeout time.Duration     `objconv:"timeout"`
+	Hosts   []string          `objconv:"hosts"`
+	Labels  map[string]string `objconv:"labels,omitempty"`
+	Addr    net.IP            `objconv:"addr,omitempty"`
+	Limits  *convertLimits    `objconv:"limits"`
+	Ignored int               `objconv:"-"`
+}
+
+type convertLimits struct {
+	CPU    float64 `json:"cpu"`
+	Memory int64   `json:"memory"`
+}
+
+func TestConvert(t *testing.T) {
+	src := map[string]interface{}{
+		"name":    "api",
+		"port":    8080,
+		"timeout": "1s",
+		"hosts":   []interface{}{"a", "b"},
+		"addr":    "127.0.0.1",
+		"limits":  map[string]interface{}{"cpu": 0.5, "memory": uint64(1024)},
+		"unknown": true,
+	}
+
+	var c convertConfig
+
+	if err := Convert(&c, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(c, convertConfig{
+		Name:    "api",
+		Port:    8080,
+		Timeout: time.Second,
+		Hosts:   []string{"a", "b"},
+		Addr:    net.ParseIP("127.0.0.1"),
+		Limits:  &convertLimits{CPU: 0.5, Memory: 1024},
+	}) {
+		t.Errorf("bad struct: %#v", c)
+	}
+
+	if err := ConvertWith(&c, src, ConvertOptions{Strict: true}); err == nil || !strings.Contains(err.Error(), `"unknown"`) {
+		t.Error("expected an error for the unknown field but got", err)
+	}
+}
+
+func TestConvertStructToMap(t *testing.T) {
+	c := convertConfig{
+		Name:    "api",
+		Port:    8080,
+		Timeout: time.Second,
+		Addr:    net.ParseIP("10.0.0.1"),
+		Ignored: 42,
+	}
+
+	var m map[string]interface{}
+
+	if err := Convert(&m, c); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(m, map[string]interface{}{
+		"name":    "api",
+		"port":    uint64(8080),
+		"timeout": time.Second,
+		"hosts":   []interface{}{},
+		"addr":    "10.0.0.1",
+		"limits":  nil,
+	}) {
+		t.Errorf("bad map: %#v", m)
+	}
+}
+
+func TestConvertStructToStruct(t *testing.T) {
+	type limits struct {
+		CPU    string `objconv:"cpu"`
+		Memory string `objconv:"memory"`
+	}
+
+	var l limits
+
+	if err := Convert(&l, &convertLimits{CPU: 1.5, Memory: 64}); err != nil {
+		t.Fatal(err)
+	}
+
+	if l != (limits{CPU: "1.5", Memory: "64"}) {
+		t.Errorf("bad struct: %#v", l)
+	}
+}
+
+func TestConvertWeaklyTyped(t *testing.T) {
+	type config struct {
+		Enabled bool     `objconv:"enabled"`
+		Verbose bool     `objconv:"verbose"`
+		Count   int8     `objconv:"count"`
+		Ratio   float64  `objconv:"ratio"`
+		Tags    []string `objconv:"tags"`
+	}
+
+	src := map[string]interface{}{
+		"enabled": "true",
+		"verbose": 1,
+		"count":   3.0,
+		"ratio":   true,
+		"tags":    "a",
+	}
+
+	var c config
+
+	if err := Convert(&c, src); err == nil {
+		t.Error("expected an error converting without weak typing")
+	}
+
+	if err := ConvertWith(&c, src, ConvertOptions{Coerce: WeakCoercions}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(c, config{Enabled: true, Verbose: true, Count: 3, Ratio: 1, Tags: []string{"a"}}) {
+		t.Errorf("bad struct: %#v", c)
+	}
+
+	for _, x := range []interface{}{1.5, 1000.0} {
+		if err := ConvertWith(&c, map[string]interface{}{"count": x}, ConvertOptions{Coerce: WeakCoercions}); err == nil {
+			t.Errorf("expected an error converting %v to an int8", x)
+		}
+	}
+}
+
+func TestConvertRename(t *testing.T) {
+	type user struct {
+		FirstName string
+		LastName  string
+	}
+
+	var u user
+
+	if err := ConvertWith(&u, map[string]string{"first_name": "Luke", "last_name": "Skywalker"}, ConvertOptions{
+		Rename: func(s string) string {
+			return strings.Replace(strings.Title(strings.Replace(s, "_", " ", -1)), " ", "", -1)
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if u != (user{FirstName: "Luke", LastName: "Skywalker"}) {
+		t.Errorf("bad struct: %#v", u)
+	}
+}
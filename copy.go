@@ -0,0 +1,308 @@
+package objconv
+
+import (
+	"io"
+	"time"
+)
+
+// Copy reads the next value from p and writes it to e.
+//
+// The value is transferred without being loaded into an intermediate Go value,
+// which preserves the exact types reported by the parser (unsigned integers
+// stay unsigned, byte arrays are not converted to strings, etc...) as well as
+// the order of keys in maps.
+//
+// Arrays and maps are written with the lengths returned by the parser. When the
+// length of a container isn't known and the emitter needs it before writing the
+// elements (like msgpack for example), the container is buffered in memory.
+func Copy(e Emitter, p Parser) error {
+	return copier{e: e, p: p, length: isLengthEmitter(e)}.copy()
+}
+
+// CopyStream reads the stream of values from p and writes it to e, returning
+// the number of values that were copied.
+//
+// If p produces an array its elements are treated as a stream, as done by
+// StreamDecoder, and are copied one at a time. The function returns no error
+// if p is empty.
+func CopyStream(e Emitter, p Parser) (n int, err error) {
+	d := NewStreamDecoder(p)
+	s := (*StreamEncoder)(nil)
+
+	if s, err = d.Encoder(e); err != nil {
+		if err == io.EOF { // empty input
+			err = nil
+		}
+		return
+	}
+
+	if err = s.Open(d.Len()); err != nil {
+		return
+	}
+
+	for {
+		err = d.decodeWith(func(dec Decoder) error {
+			// Check that a value is available before starting to encode it,
+			// the stream decoder needs to see this error to detect the end of
+			// arrays of unknown length.
+			if _, err := dec.Parser.ParseType(); err != nil {
+				return err
+			}
+			return s.encodeWith(func(enc Encoder) error {
+				return Copy(enc.Emitter, dec.Parser)
+			})
+		})
+
+		if err != nil {
+			break
+		}
+
+		n++
+	}
+
+	if err != End {
+		return
+	}
+
+	err = s.Close()
+	return
+}
+
+type copier struct {
+	e      Emitter
+	p      Parser
+	length bool // whether e requires the length of arrays and maps
+}
+
+func (c copier) copy() (err error) {
+	var t Type
+
+	if t, err = c.p.ParseType(); err != nil {
+		return
+	}
+
+	switch t {
+	case Nil:
+		if err = c.p.ParseNil(); err == nil {
+			err = c.e.EmitNil()
+		}
+
+	case Bool:
+		var v bool
+		if v, err = c.p.ParseBool(); err == nil {
+			err = c.e.EmitBool(v)
+		}
+
+	case Int:
+		var v int64
+		if v, err = c.p.ParseInt(); err == nil {
+			err = c.e.EmitInt(v, bitSizeOf(c.p))
+		}
+
+	case Uint:
+		var v uint64
+		if v, err = c.p.ParseUint(); err == nil {
+			err = c.e.EmitUint(v, bitSizeOf(c.p))
+		}
+
+	case Float:
+		var v float64
+		if v, err = c.p.ParseFloat(); err == nil {
+			err = c.e.EmitFloat(v, bitSizeOf(c.p))
+		}
+
+	case String:
+		var v []byte
+		if v, err = c.p.ParseString(); err == nil {
+			err = c.e.EmitString(string(v))
+		}
+
+	case Bytes:
+		var v []byte
+		if v, err = c.p.ParseBytes(); err == nil {
+			err = c.e.EmitBytes(append([]byte(nil), v...))
+		}
+
+	case Time:
+		var v time.Time
+		if v, err = c.p.ParseTime(); err == nil {
+			err = c.e.EmitTime(v)
+		}
+
+	case Duration:
+		var v time.Duration
+		if v, err = c.p.ParseDuration(); err == nil {
+			err = c.e.EmitDuration(v)
+		}
+
+	case Error:
+		var v error
+		if v, err = c.p.ParseError(); err == nil {
+			err = c.e.EmitError(v)
+		}
+
+	case Array:
+		err = c.copyArray()
+
+	case Map:
+		err = c.copyMap()
+
+	default:
+		panic("objconv: parser returned an unsupported value type: " + t.String())
+	}
+
+	return
+}
+
+func (c copier) copyArray() (err error) {
+	var n int
+
+	if n, err = c.p.ParseArrayBegin(); err != nil {
+		return
+	}
+
+	if n < 0 && c.length {
+		return c.copyBuffered(Array)
+	}
+
+	if err = c.e.EmitArrayBegin(n); err != nil {
+		return
+	}
+
+	i := 0
+
+	for n < 0 || i < n {
+		if n < 0 || i != 0 {
+			if err = c.p.ParseArrayNext(i); err != nil {
+				if err == End {
+					err = nil
+					break
+				}
+				return
+			}
+		}
+
+		if i != 0 {
+			if err = c.e.EmitArrayNext(); err != nil {
+				return
+			}
+		}
+
+		if err = c.copy(); err != nil {
+			return
+		}
+
+		i++
+	}
+
+	if err = c.p.ParseArrayEnd(i); err != nil {
+		return
+	}
+
+	return c.e.EmitArrayEnd()
+}
+
+func (c copier) copyMap() (err error) {
+	var n int
+
+	if n, err = c.p.ParseMapBegin(); err != nil {
+		return
+	}
+
+	if n < 0 && c.length {
+		return c.copyBuffered(Map)
+	}
+
+	if err = c.e.EmitMapBegin(n); err != nil {
+		return
+	}
+
+	i := 0
+
+	for n < 0 || i < n {
+		if n < 0 || i != 0 {
+			if err = c.p.ParseMapNext(i); err != nil {
+				if err == End {
+					err = nil
+					break
+				}
+				return
+			}
+		}
+
+		if i != 0 {
+			if err = c.e.EmitMapNext(); err != nil {
+				return
+			}
+		}
+
+		if err = c.copy(); err != nil {
+			return
+		}
+
+		if err = c.p.ParseMapValue(i); err != nil {
+			return
+		}
+
+		if err = c.e.EmitMapValue(); err != nil {
+			return
+		}
+
+		if err = c.copy(); err != nil {
+			return
+		}
+
+		i++
+	}
+
+	if err = c.p.ParseMapEnd(i); err != nil {
+		return
+	}
+
+	return c.e.EmitMapEnd()
+}
+
+// copyBuffered loads the array or map of type t that the parser has started
+// reading, then writes it to the emitter with the lengths of all the nested
+// containers set.
+func (c copier) copyBuffered(t Type) (err error) {
+	begin := Token{Kind: ArrayBeginToken, Type: t}
+
+	if t == Map {
+		begin.Kind = MapBeginToken
+	}
+
+	r := TokenReader{Parser: c.p, stack: []tokenFrame{{typ: t, len: -1}}}
+	tokens := []Token{begin}
+	opened := []int{0}
+
+	for len(opened) != 0 {
+		var tok Token
+
+		if tok, err = r.Next(); err != nil {
+			return
+		}
+
+		switch tok.Kind {
+		case ArrayBeginToken, MapBeginToken:
+			opened = append(opened, len(tokens))
+
+		case ArrayEndToken, MapEndToken:
+			last := len(opened) - 1
+			tokens[opened[last]].Len = tok.Len
+			opened = opened[:last]
+		}
+
+		tokens = append(tokens, tok)
+	}
+
+	w := TokenWriter{Emitter: c.e}
+
+	for _, tok := range tokens {
+		if err = w.WriteToken(tok); err != nil {
+			return
+		}
+	}
+
+	return
+}
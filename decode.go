@@ -1,11 +1,16 @@
 package objconv
 
 import (
+	"bytes"
+	"context"
 	"encoding"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 	"unsafe"
 
@@ -24,7 +29,100 @@ type Decoder struct {
 	// there is not destination type (when decoding to an empty interface).
 	MapType reflect.Type
 
-	off int // offset of the value when decoding a map
+	// Coerce configures the conversions that the decoder applies to values
+	// which don't match the type of their destination.
+	//
+	// When zero, the decoder uses DefaultCoercions. When set, only the listed
+	// coercions are applied, so DefaultCoercions must be included to keep the
+	// default behavior. Setting it to NoCoercions disables all coercions.
+	Coerce Coercion
+
+	// CollectErrors configures the decoder to keep decoding the remaining
+	// values of a document when some of them could not be converted to the
+	// type of their destination. The Decode method then returns a DecodeErrors
+	// value listing all the failures with the path of the values in the
+	// document.
+	//
+	// Errors returned by the parser, like syntax errors, still abort the
+	// decoding.
+	CollectErrors bool
+
+	// NoCopy configures the decoder to retain the strings and byte slices
+	// returned by the parser in the values it decodes instead of copying them,
+	// when the parser guarantees that they are not reused. The msgpack and
+	// cbor parsers created by NewBytesParser (and their UnmarshalNoCopy
+	// functions) give this guarantee, the values they decode point into the
+	// input.
+	//
+	// The input must then remain unmodified for as long as the decoded values
+	// are in use, modifying it would change decoded strings, which Go expects
+	// to be immutable.
+	NoCopy bool
+
+	off    int             // offset of the value when decoding a map
+	strict bool            // reject unknown struct fields, see ConvertOptions
+	errs   *errorCollector // set when collecting errors
+
+	newParser func(io.Reader) Parser // set on decoders created by a codec
+}
+
+// Coercion is a set of flags representing conversions that decoders may apply
+// to values which don't match the type of their destination.
+type Coercion uint
+
+const (
+	// StringToNumber allows numeric strings like "42" or "1.5" to be decoded
+	// into integers and floats. Values are checked to fit in the destination.
+	StringToNumber Coercion = 1 << iota
+
+	// ScalarToString allows numbers and booleans to be decoded into strings.
+	ScalarToString
+
+	// StringToBool allows the strings "true", "false", "yes", "no", "1" and
+	// "0" to be decoded into booleans, the comparison is case-insensitive.
+	StringToBool
+
+	// NumberToBool allows numbers to be decoded into booleans, zero is false,
+	// any other value is true.
+	NumberToBool
+
+	// BoolToNumber allows booleans to be decoded into numbers, true is 1 and
+	// false is 0.
+	BoolToNumber
+
+	// FloatToInt allows floats to be decoded into integers when they have no
+	// fractional part.
+	FloatToInt
+
+	// ScalarToSlice allows a single value to be decoded into a slice of one
+	// element.
+	ScalarToSlice
+
+	// DefaultCoercions is the set of coercions applied by decoders when none
+	// were configured.
+	DefaultCoercions = StringToNumber | ScalarToString
+
+	// WeakCoercions is the set of all coercions, it can be used to configure
+	// decoders that accept weakly-typed inputs like configuration values
+	// loaded from environment variables or query strings.
+	WeakCoercions = StringToNumber | ScalarToString | StringToBool | NumberToBool | BoolToNumber | FloatToInt | ScalarToSlice
+)
+
+// NoCoercions is a sentinel value of the Coerce field of decoders which disables
+// all coercions, since decoders with a zero Coerce field use DefaultCoercions.
+// It is not a conversion flag, when combined with other coercions it takes
+// precedence and none of them are applied.
+const NoCoercions Coercion = 1 << 31
+
+// coerce returns true if the decoder is configured to apply c.
+func (d Decoder) coerce(c Coercion) bool {
+	switch {
+	case d.Coerce == 0:
+		return (DefaultCoercions & c) != 0
+	case (d.Coerce & NoCoercions) != 0:
+		return false
+	}
+	return (d.Coerce & c) != 0
 }
 
 // NewDecoder returns a decoder object that uses p, will panic if p is nil.
@@ -35,6 +133,30 @@ func NewDecoder(p Parser) *Decoder {
 	return &Decoder{Parser: p}
 }
 
+// derive returns a decoder configured like d which reads values from p. When d
+// collects errors, the errors of the values read from p are reported with the
+// others, under the path of the value that d is decoding.
+func (d Decoder) derive(p Parser) Decoder {
+	d.Parser, d.off = p, 0
+
+	if c := d.errs; c != nil {
+		d.errs = nil
+		d = d.collecting(c.path, c.errs)
+	}
+
+	return d
+}
+
+// Reset sets the input of the decoder to r, so it can be reused to decode
+// values from a different reader.
+//
+// The parser is reset if it implements the ResetParser interface, otherwise
+// decoders created by a Codec use it to create a new parser. The method panics
+// if the parser can be neither reset nor recreated.
+func (d *Decoder) Reset(r io.Reader) {
+	d.Parser = resetParser(d.Parser, r, d.newParser)
+}
+
 // Decode expects v to be a pointer to a value in which the decoder will load
 // the next parsed data.
 //
@@ -50,6 +172,10 @@ func (d Decoder) Decode(v interface{}) error {
 		}
 	}
 
+	if d.CollectErrors && d.errs == nil {
+		return d.decodeCollect(v)
+	}
+
 	if !to.IsValid() {
 		// This special case for a nil value is used to make it possible to
 		// discard decoded values.
@@ -78,7 +204,7 @@ func (d Decoder) Decode(v interface{}) error {
 }
 
 func (d Decoder) decode(to reflect.Value) (Type, error) {
-	return decodeFuncOf(to.Type())(d, to)
+	return lookupCodec(to.Type()).decodeReflect(d, to)
 }
 
 func (d Decoder) decodeBool(to reflect.Value) (t Type, err error) {
@@ -91,6 +217,13 @@ func (d Decoder) decodeBool(to reflect.Value) (t Type, err error) {
 func (d Decoder) decodeBoolFromType(t Type, to reflect.Value) (err error) {
 	var v bool
 
+	if v, err = d.parseBool(t); err == nil && to.IsValid() {
+		to.SetBool(v)
+	}
+	return
+}
+
+func (d Decoder) parseBool(t Type) (v bool, err error) {
 	switch t {
 	case Nil:
 		err = d.Parser.ParseNil()
@@ -98,17 +231,26 @@ func (d Decoder) decodeBoolFromType(t Type, to reflect.Value) (err error) {
 	case Bool:
 		v, err = d.Parser.ParseBool()
 
+	case Int, Uint, Float:
+		if d.coerce(NumberToBool) {
+			var f float64
+			f, err = d.parseWeakFloat(t)
+			v = f != 0
+		} else {
+			err = typeConversionError(t, Bool)
+		}
+
+	case String, Bytes:
+		if d.coerce(StringToBool) {
+			v, err = d.parseWeakBool(t)
+		} else {
+			err = typeConversionError(t, Bool)
+		}
+
 	default:
 		err = typeConversionError(t, Bool)
 	}
 
-	if err != nil {
-		return
-	}
-
-	if to.IsValid() {
-		to.SetBool(v)
-	}
 	return
 }
 
@@ -120,8 +262,22 @@ func (d Decoder) decodeInt(to reflect.Value) (t Type, err error) {
 }
 
 func (d Decoder) decodeIntFromType(t Type, to reflect.Value) (err error) {
-	var valid = to.IsValid()
+	var typ reflect.Type
 	var i int64
+
+	if to.IsValid() {
+		typ = to.Type()
+	}
+
+	if i, err = d.parseInt(t, typ); err == nil && typ != nil {
+		to.SetInt(i)
+	}
+	return
+}
+
+// parseInt parses an integer of type t, the value is checked to be within the
+// bounds of typ unless it is nil.
+func (d Decoder) parseInt(t Type, typ reflect.Type) (i int64, err error) {
 	var u uint64
 
 	switch t {
@@ -133,17 +289,8 @@ func (d Decoder) decodeIntFromType(t Type, to reflect.Value) (err error) {
 			return
 		}
 
-		if valid {
-			switch t := to.Type(); t.Kind() {
-			case reflect.Int:
-				err = objutil.CheckInt64Bounds(i, int64(objutil.IntMin), uint64(objutil.IntMax), t)
-			case reflect.Int8:
-				err = objutil.CheckInt64Bounds(i, objutil.Int8Min, objutil.Int8Max, t)
-			case reflect.Int16:
-				err = objutil.CheckInt64Bounds(i, objutil.Int16Min, objutil.Int16Max, t)
-			case reflect.Int32:
-				err = objutil.CheckInt64Bounds(i, objutil.Int32Min, objutil.Int32Max, t)
-			}
+		if typ != nil {
+			err = checkIntBounds(i, typ)
 		}
 
 	case Uint:
@@ -151,27 +298,32 @@ func (d Decoder) decodeIntFromType(t Type, to reflect.Value) (err error) {
 			return
 		}
 
-		if valid {
-			switch t := to.Type(); t.Kind() {
+		if typ != nil {
+			switch typ.Kind() {
 			case reflect.Int:
-				err = objutil.CheckUint64Bounds(u, uint64(objutil.IntMax), t)
+				err = objutil.CheckUint64Bounds(u, uint64(objutil.IntMax), typ)
 			case reflect.Int8:
-				err = objutil.CheckUint64Bounds(u, objutil.Int8Max, t)
+				err = objutil.CheckUint64Bounds(u, objutil.Int8Max, typ)
 			case reflect.Int16:
-				err = objutil.CheckUint64Bounds(u, objutil.Int16Max, t)
+				err = objutil.CheckUint64Bounds(u, objutil.Int16Max, typ)
 			case reflect.Int32:
-				err = objutil.CheckUint64Bounds(u, objutil.Int32Max, t)
+				err = objutil.CheckUint64Bounds(u, objutil.Int32Max, typ)
 			case reflect.Int64:
-				err = objutil.CheckUint64Bounds(u, objutil.Int64Max, t)
+				err = objutil.CheckUint64Bounds(u, objutil.Int64Max, typ)
 			}
 		}
 
 		i = int64(u)
 
-	case String:
+	case String, Bytes:
 		var b []byte
 
-		if b, err = d.Parser.ParseString(); err != nil {
+		if !d.coerce(StringToNumber) {
+			err = typeConversionError(t, Int)
+			break
+		}
+
+		if b, err = d.parseStringOrBytes(t); err != nil {
 			return
 		}
 
@@ -181,17 +333,15 @@ func (d Decoder) decodeIntFromType(t Type, to reflect.Value) (err error) {
 			_, err = strconv.ParseInt(string(b), 10, 64)
 		}
 
-	case Bytes:
-		var b []byte
-
-		if b, err = d.Parser.ParseBytes(); err != nil {
-			return
+		if err == nil && typ != nil {
+			err = checkIntBounds(i, typ)
 		}
 
-		i, err = strconv.ParseInt(unsafeString(b), 10, 64)
-		// if an error is received, reparse with a "safe" string in case it is retained in the error
-		if err != nil {
-			_, err = strconv.ParseInt(string(b), 10, 64)
+	case Bool, Float:
+		if !d.coerce(integerCoercion(t)) {
+			err = typeConversionError(t, Int)
+		} else if i, err = d.parseWeakInt(t); err == nil && typ != nil {
+			err = checkIntBounds(i, typ)
 		}
 
 	default:
@@ -199,11 +349,7 @@ func (d Decoder) decodeIntFromType(t Type, to reflect.Value) (err error) {
 	}
 
 	if err != nil {
-		return
-	}
-
-	if valid {
-		to.SetInt(i)
+		i = 0
 	}
 	return
 }
@@ -216,10 +362,24 @@ func (d Decoder) decodeUint(to reflect.Value) (t Type, err error) {
 }
 
 func (d Decoder) decodeUintFromType(t Type, to reflect.Value) (err error) {
-	var valid = to.IsValid()
-	var i int64
+	var typ reflect.Type
 	var u uint64
 
+	if to.IsValid() {
+		typ = to.Type()
+	}
+
+	if u, err = d.parseUint(t, typ); err == nil && typ != nil {
+		to.SetUint(u)
+	}
+	return
+}
+
+// parseUint parses an unsigned integer of type t, the value is checked to be
+// within the bounds of typ unless it is nil.
+func (d Decoder) parseUint(t Type, typ reflect.Type) (u uint64, err error) {
+	var i int64
+
 	switch t {
 	case Nil:
 		err = d.Parser.ParseNil()
@@ -229,18 +389,18 @@ func (d Decoder) decodeUintFromType(t Type, to reflect.Value) (err error) {
 			return
 		}
 
-		if valid {
-			switch t := to.Type(); t.Kind() {
+		if typ != nil {
+			switch typ.Kind() {
 			case reflect.Uint:
-				err = objutil.CheckInt64Bounds(i, 0, uint64(objutil.UintMax), t)
+				err = objutil.CheckInt64Bounds(i, 0, uint64(objutil.UintMax), typ)
 			case reflect.Uint8:
-				err = objutil.CheckInt64Bounds(i, 0, objutil.Uint8Max, t)
+				err = objutil.CheckInt64Bounds(i, 0, objutil.Uint8Max, typ)
 			case reflect.Uint16:
-				err = objutil.CheckInt64Bounds(i, 0, objutil.Uint16Max, t)
+				err = objutil.CheckInt64Bounds(i, 0, objutil.Uint16Max, typ)
 			case reflect.Uint32:
-				err = objutil.CheckInt64Bounds(i, 0, objutil.Uint32Max, t)
+				err = objutil.CheckInt64Bounds(i, 0, objutil.Uint32Max, typ)
 			case reflect.Uint64:
-				err = objutil.CheckInt64Bounds(i, 0, objutil.Uint64Max, t)
+				err = objutil.CheckInt64Bounds(i, 0, objutil.Uint64Max, typ)
 			}
 		}
 
@@ -251,23 +411,19 @@ func (d Decoder) decodeUintFromType(t Type, to reflect.Value) (err error) {
 			return
 		}
 
-		if valid {
-			switch t := to.Type(); t.Kind() {
-			case reflect.Uint:
-				err = objutil.CheckUint64Bounds(u, uint64(objutil.UintMax), t)
-			case reflect.Uint8:
-				err = objutil.CheckUint64Bounds(u, objutil.Uint8Max, t)
-			case reflect.Uint16:
-				err = objutil.CheckUint64Bounds(u, objutil.Uint16Max, t)
-			case reflect.Uint32:
-				err = objutil.CheckUint64Bounds(u, objutil.Uint32Max, t)
-			}
+		if typ != nil {
+			err = checkUintBounds(u, typ)
 		}
 
-	case String:
+	case String, Bytes:
 		var b []byte
 
-		if b, err = d.Parser.ParseString(); err != nil {
+		if !d.coerce(StringToNumber) {
+			err = typeConversionError(t, Uint)
+			break
+		}
+
+		if b, err = d.parseStringOrBytes(t); err != nil {
 			return
 		}
 
@@ -277,17 +433,15 @@ func (d Decoder) decodeUintFromType(t Type, to reflect.Value) (err error) {
 			_, err = strconv.ParseUint(string(b), 10, 64)
 		}
 
-	case Bytes:
-		var b []byte
-
-		if b, err = d.Parser.ParseBytes(); err != nil {
-			return
+		if err == nil && typ != nil {
+			err = checkUintBounds(u, typ)
 		}
 
-		u, err = strconv.ParseUint(unsafeString(b), 10, 64)
-		// if an error is received, reparse with a "safe" string in case it is retained in the error
-		if err != nil {
-			_, err = strconv.ParseUint(string(b), 10, 64)
+	case Bool, Float:
+		if !d.coerce(integerCoercion(t)) {
+			err = typeConversionError(t, Uint)
+		} else if u, err = d.parseWeakUint(t); err == nil && typ != nil {
+			err = checkUintBounds(u, typ)
 		}
 
 	default:
@@ -295,11 +449,7 @@ func (d Decoder) decodeUintFromType(t Type, to reflect.Value) (err error) {
 	}
 
 	if err != nil {
-		return
-	}
-
-	if valid {
-		to.SetUint(u)
+		u = 0
 	}
 	return
 }
@@ -312,9 +462,17 @@ func (d Decoder) decodeFloat(to reflect.Value) (t Type, err error) {
 }
 
 func (d Decoder) decodeFloatFromType(t Type, to reflect.Value) (err error) {
+	var f float64
+
+	if f, err = d.parseFloat(t); err == nil && to.IsValid() {
+		to.SetFloat(f)
+	}
+	return
+}
+
+func (d Decoder) parseFloat(t Type) (f float64, err error) {
 	var i int64
 	var u uint64
-	var f float64
 
 	switch t {
 	case Nil:
@@ -337,23 +495,15 @@ func (d Decoder) decodeFloatFromType(t Type, to reflect.Value) (err error) {
 	case Float:
 		f, err = d.Parser.ParseFloat()
 
-	case String:
+	case String, Bytes:
 		var b []byte
 
-		if b, err = d.Parser.ParseString(); err != nil {
-			return
-		}
-
-		f, err = strconv.ParseFloat(unsafeString(b), 64)
-		// if an error is received, reparse with a "safe" string in case it is retained in the error
-		if err != nil {
-			_, err = strconv.ParseFloat(string(b), 64)
+		if !d.coerce(StringToNumber) {
+			err = typeConversionError(t, Float)
+			break
 		}
 
-	case Bytes:
-		var b []byte
-
-		if b, err = d.Parser.ParseBytes(); err != nil {
+		if b, err = d.parseStringOrBytes(t); err != nil {
 			return
 		}
 
@@ -363,16 +513,19 @@ func (d Decoder) decodeFloatFromType(t Type, to reflect.Value) (err error) {
 			_, err = strconv.ParseFloat(string(b), 64)
 		}
 
+	case Bool:
+		if d.coerce(BoolToNumber) {
+			f, err = d.parseWeakFloat(t)
+		} else {
+			err = typeConversionError(t, Float)
+		}
+
 	default:
 		err = typeConversionError(t, Float)
 	}
 
 	if err != nil {
-		return
-	}
-
-	if to.IsValid() {
-		to.SetFloat(f)
+		f = 0
 	}
 	return
 }
@@ -388,6 +541,15 @@ func (d Decoder) decodeStringFromType(t Type, to reflect.Value) (err error) {
 	var a [64]byte
 	var b []byte
 
+	if b, err = d.parseString(t, a[:0]); err == nil && to.IsValid() {
+		to.SetString(d.makeString(t, b))
+	}
+	return
+}
+
+// parseString parses a value of type t as a string, the returned byte slice
+// may be the parser's buffer or a, and must be copied to be retained.
+func (d Decoder) parseString(t Type, a []byte) (b []byte, err error) {
 	switch t {
 	case Nil:
 		err = d.Parser.ParseNil()
@@ -398,63 +560,35 @@ func (d Decoder) decodeStringFromType(t Type, to reflect.Value) (err error) {
 	case Bytes:
 		b, err = d.Parser.ParseBytes()
 
-	case Bool:
-		var v bool
-		if v, err = d.Parser.ParseBool(); err == nil {
-			if v {
-				b = append(a[:0], "true"...)
-			} else {
-				b = append(a[:0], "false"...)
-			}
-		}
-
-	case Int:
-		var v int64
-		if v, err = d.Parser.ParseInt(); err == nil {
-			b = strconv.AppendInt(a[:0], v, 10)
-		}
-
-	case Uint:
-		var v uint64
-		if v, err = d.Parser.ParseUint(); err == nil {
-			b = strconv.AppendUint(a[:0], v, 10)
-		}
-
-	case Float:
-		var v float64
-		if v, err = d.Parser.ParseFloat(); err == nil {
-			b = strconv.AppendFloat(a[:0], v, 'g', -1, 64)
+	case Bool, Int, Uint, Float:
+		if !d.coerce(ScalarToString) {
+			err = typeConversionError(t, String)
+			break
 		}
+		b, err = d.appendScalar(a, t)
 
 	case Time:
 		var v time.Time
 		if v, err = d.Parser.ParseTime(); err == nil {
-			b = v.AppendFormat(a[:0], time.RFC3339Nano)
+			b = v.AppendFormat(a, time.RFC3339Nano)
 		}
 
 	case Duration:
 		var v time.Duration
 		if v, err = d.Parser.ParseDuration(); err == nil {
-			b = objutil.AppendDuration(a[:0], v)
+			b = objutil.AppendDuration(a, v)
 		}
 
 	case Error:
 		var v error
 		if v, err = d.Parser.ParseError(); err == nil {
-			b = append(a[:0], v.Error()...)
+			b = append(a, v.Error()...)
 		}
 
 	default:
 		err = typeConversionError(t, String)
 	}
 
-	if err != nil {
-		return
-	}
-
-	if to.IsValid() {
-		to.SetString(string(b))
-	}
 	return
 }
 
@@ -468,6 +602,15 @@ func (d Decoder) decodeBytes(to reflect.Value) (t Type, err error) {
 func (d Decoder) decodeBytesFromType(t Type, to reflect.Value) (err error) {
 	var b []byte
 
+	if b, err = d.parseBytes(t); err == nil && to.IsValid() {
+		to.SetBytes(d.makeBytes(t, b))
+	}
+	return
+}
+
+// parseBytes parses a value of type t as a byte slice, the returned value may
+// be the parser's buffer and must be copied with copyBytes to be retained.
+func (d Decoder) parseBytes(t Type) (b []byte, err error) {
 	switch t {
 	case Nil:
 		err = d.Parser.ParseNil()
@@ -487,21 +630,44 @@ func (d Decoder) decodeBytesFromType(t Type, to reflect.Value) (err error) {
 	}
 
 	if bd, ok := d.Parser.(bytesDecoder); ok {
-		if b, err = bd.DecodeBytes(b); err != nil {
-			return
-		}
+		b, err = bd.DecodeBytes(b)
 	}
+	return
+}
 
-	if to.IsValid() {
-		if t == Nil {
-			to.SetBytes(nil)
-		} else {
-			v := make([]byte, len(b))
-			copy(v, b)
-			to.SetBytes(v)
-		}
+// makeString returns the string value of b, which was parsed from a value of
+// type t. The string points to b when the decoder is configured with NoCopy.
+func (d Decoder) makeString(t Type, b []byte) string {
+	if d.noCopy(t) {
+		return unsafeString(b)
 	}
-	return
+	return string(b)
+}
+
+// makeBytes returns the byte slice value of b, which was parsed from a value of
+// type t. b is returned as-is when the decoder is configured with NoCopy.
+func (d Decoder) makeBytes(t Type, b []byte) []byte {
+	if d.noCopy(t) {
+		return b
+	}
+	return copyBytes(t, b)
+}
+
+// noCopy returns true if the bytes of a value of type t can be retained without
+// being copied, only the values returned by ParseString and ParseBytes can.
+func (d Decoder) noCopy(t Type) bool {
+	return d.NoCopy && (t == String || t == Bytes) && isStableParser(d.Parser)
+}
+
+// copyBytes returns a copy of b, which was parsed from a value of type t, nil
+// values produce nil slices.
+func copyBytes(t Type, b []byte) []byte {
+	if t == Nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
 }
 
 func (d Decoder) decodeTime(to reflect.Value) (t Type, err error) {
@@ -512,9 +678,17 @@ func (d Decoder) decodeTime(to reflect.Value) (t Type, err error) {
 }
 
 func (d Decoder) decodeTimeFromType(t Type, to reflect.Value) (err error) {
-	var s []byte
 	var v time.Time
 
+	if v, err = d.parseTime(t); err == nil && to.IsValid() {
+		*(to.Addr().Interface().(*time.Time)) = v
+	}
+	return
+}
+
+func (d Decoder) parseTime(t Type) (v time.Time, err error) {
+	var s []byte
+
 	switch t {
 	case Nil:
 		err = d.Parser.ParseNil()
@@ -527,21 +701,21 @@ func (d Decoder) decodeTimeFromType(t Type, to reflect.Value) (err error) {
 
 	case Time:
 		v, err = d.Parser.ParseTime()
+
+	default:
+		err = typeConversionError(t, Time)
 	}
 
 	if err != nil {
 		return
 	}
 
-	if to.IsValid() {
-		if t == String || t == Bytes {
-			v, err = time.Parse(time.RFC3339Nano, unsafeString(s))
-			// if an error is received, reparse with a "safe" string in case it is retained in the error
-			if err != nil {
-				_, err = time.Parse(time.RFC3339Nano, string(t))
-			}
+	if t == String || t == Bytes {
+		v, err = time.Parse(time.RFC3339Nano, unsafeString(s))
+		// if an error is received, reparse with a "safe" string in case it is retained in the error
+		if err != nil {
+			_, err = time.Parse(time.RFC3339Nano, string(s))
 		}
-		*(to.Addr().Interface().(*time.Time)) = v
 	}
 	return
 }
@@ -554,9 +728,17 @@ func (d Decoder) decodeDuration(to reflect.Value) (t Type, err error) {
 }
 
 func (d Decoder) decodeDurationFromType(t Type, to reflect.Value) (err error) {
-	var s []byte
 	var v time.Duration
 
+	if v, err = d.parseDuration(t); err == nil && to.IsValid() {
+		to.SetInt(int64(v))
+	}
+	return
+}
+
+func (d Decoder) parseDuration(t Type) (v time.Duration, err error) {
+	var s []byte
+
 	switch t {
 	case Nil:
 		err = d.Parser.ParseNil()
@@ -582,10 +764,6 @@ func (d Decoder) decodeDurationFromType(t Type, to reflect.Value) (err error) {
 			_, err = time.ParseDuration(string(s))
 		}
 	}
-
-	if to.IsValid() {
-		to.SetInt(int64(v))
-	}
 	return
 }
 
@@ -647,6 +825,10 @@ func (d Decoder) decodeSliceFromType(typ Type, to reflect.Value) (err error) {
 }
 
 func (d Decoder) decodeSliceFromTypeWith(typ Type, to reflect.Value, f decodeFunc) (err error) {
+	if typ != Nil && typ != Array && typ != Map && d.coerce(ScalarToSlice) {
+		return d.decodeSliceFromScalar(to, f)
+	}
+
 	if !to.IsValid() {
 		return d.decodeArrayImpl(typ, func(d Decoder) (err error) {
 			_, err = f(d, reflect.Value{})
@@ -668,7 +850,12 @@ func (d Decoder) decodeSliceFromTypeWith(typ Type, to reflect.Value, f decodeFun
 			reflect.Copy(sc, s)
 			s = sc
 		}
-		if _, err = f(d, s.Index(i)); err != nil {
+		if d.errs != nil {
+			err = d.decodeElem(i, s.Index(i), f)
+		} else {
+			_, err = f(d, s.Index(i))
+		}
+		if err != nil {
 			return
 		}
 		i++
@@ -713,7 +900,12 @@ func (d Decoder) decodeArrayFromTypeWith(typ Type, to reflect.Value, f decodeFun
 
 	if err = d.decodeArrayImpl(typ, func(d Decoder) (err error) {
 		if i < n {
-			if _, err = f(d, to.Index(i)); err != nil {
+			if d.errs != nil {
+				err = d.decodeElem(i, to.Index(i), f)
+			} else {
+				_, err = f(d, to.Index(i))
+			}
+			if err != nil {
 				return
 			}
 		}
@@ -801,7 +993,12 @@ func (d Decoder) decodeMapFromTypeWith(typ Type, to reflect.Value, kf decodeFunc
 		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
 			return
 		}
-		if _, err = vf(d, vv); err != nil {
+		if d.errs != nil {
+			err = d.decodeElem(kv, vv, vf)
+		} else {
+			_, err = vf(d, vv)
+		}
+		if err != nil {
 			return
 		}
 		m.SetMapIndex(kv, vv)
@@ -859,14 +1056,15 @@ func (d Decoder) decodeMapStringInterface(typ Type, to reflect.Value) (err error
 	}
 
 	return d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
+		var t Type
 		var b []byte
 		var k string
 		var v interface{}
 
-		if _, b, err = d.decodeTypeAndString(); err != nil {
+		if t, b, err = d.decodeTypeAndString(); err != nil {
 			return
 		}
-		k = string(b)
+		k = d.makeString(t, b)
 
 		if err = vd.Decode(&v); err != nil {
 			return
@@ -890,23 +1088,29 @@ func (d Decoder) decodeMapStringString(typ Type, to reflect.Value) (err error) {
 	}
 
 	return d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
+		var t Type
 		var b []byte
 		var k string
 		var v string
 
-		if _, b, err = d.decodeTypeAndString(); err != nil {
+		if t, b, err = d.decodeTypeAndString(); err != nil {
 			return
 		}
-		k = string(b)
+		k = d.makeString(t, b)
 
 		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
 			return
 		}
 
-		if _, b, err = d.decodeTypeAndString(); err != nil {
+		if d.errs != nil {
+			if err = d.decodeElem(k, reflect.ValueOf(&v).Elem(), Decoder.decodeString); err != nil {
+				return
+			}
+		} else if t, b, err = d.decodeTypeAndString(); err != nil {
 			return
+		} else {
+			v = d.makeString(t, b)
 		}
-		v = string(b)
 
 		m[k] = v
 		return
@@ -938,10 +1142,21 @@ func (d Decoder) decodeStructFromTypeWith(typ Type, to reflect.Value, s *structT
 		}
 
 		if f == nil {
+			if d.strict {
+				err = fmt.Errorf("objconv: unknown field %q in %s", b, to.Type())
+				if d.errs != nil {
+					err = d.errs.collect(d, string(b), func(Decoder) error { return err })
+				}
+				return
+			}
 			_, err = d.decodeInterface(reflect.Value{}) // discard
 			return
 		}
 
+		if d.errs != nil {
+			return d.decodeElem(f.name, to.FieldByIndex(f.index), f.decode)
+		}
+
 		_, err = f.decode(d, to.FieldByIndex(f.index))
 		return
 	}); err != nil {
@@ -950,6 +1165,173 @@ func (d Decoder) decodeStructFromTypeWith(typ Type, to reflect.Value, s *structT
 	return
 }
 
+// decodeSliceFromScalar decodes a single scalar value into a slice of one
+// element, see ScalarToSlice.
+func (d Decoder) decodeSliceFromScalar(to reflect.Value, f decodeFunc) (err error) {
+	if !to.IsValid() {
+		_, err = f(d, reflect.Value{})
+		return
+	}
+
+	s := reflect.MakeSlice(to.Type(), 1, 1)
+
+	if _, err = f(d, s.Index(0)); err == nil {
+		to.Set(s)
+	}
+	return
+}
+
+// parseWeakBool parses a string or byte array of type t as a boolean.
+func (d Decoder) parseWeakBool(t Type) (v bool, err error) {
+	var b []byte
+
+	if b, err = d.parseStringOrBytes(t); err != nil {
+		return
+	}
+
+	switch s := unsafeString(b); {
+	case s == "1" || strings.EqualFold(s, "true") || strings.EqualFold(s, "yes"):
+		v = true
+	case s == "0" || strings.EqualFold(s, "false") || strings.EqualFold(s, "no"):
+		v = false
+	default:
+		err = fmt.Errorf("objconv: cannot convert %q to a boolean", string(b))
+	}
+	return
+}
+
+// parseStringOrBytes parses a value of type t, which must be String or Bytes.
+func (d Decoder) parseStringOrBytes(t Type) ([]byte, error) {
+	if t == String {
+		return d.Parser.ParseString()
+	}
+	return d.Parser.ParseBytes()
+}
+
+// appendScalar parses a boolean or numeric value of type t and appends its
+// string representation to b.
+func (d Decoder) appendScalar(b []byte, t Type) (_ []byte, err error) {
+	switch t {
+	case Bool:
+		var v bool
+		if v, err = d.Parser.ParseBool(); err == nil {
+			b = strconv.AppendBool(b, v)
+		}
+
+	case Int:
+		var v int64
+		if v, err = d.Parser.ParseInt(); err == nil {
+			b = strconv.AppendInt(b, v, 10)
+		}
+
+	case Uint:
+		var v uint64
+		if v, err = d.Parser.ParseUint(); err == nil {
+			b = strconv.AppendUint(b, v, 10)
+		}
+
+	default:
+		var v float64
+		if v, err = d.Parser.ParseFloat(); err == nil {
+			b = strconv.AppendFloat(b, v, 'g', -1, 64)
+		}
+	}
+	return b, err
+}
+
+// integerCoercion returns the coercion needed to decode a value of type t into
+// an integer.
+func integerCoercion(t Type) Coercion {
+	if t == Bool {
+		return BoolToNumber
+	}
+	return FloatToInt
+}
+
+// parseWeakFloat parses a numeric or boolean value of type t as a float, see
+// NumberToBool and BoolToNumber.
+func (d Decoder) parseWeakFloat(t Type) (f float64, err error) {
+	switch t {
+	case Bool:
+		var v bool
+		if v, err = d.Parser.ParseBool(); err == nil && v {
+			f = 1
+		}
+
+	case Int:
+		var v int64
+		v, err = d.Parser.ParseInt()
+		f = float64(v)
+
+	case Uint:
+		var v uint64
+		v, err = d.Parser.ParseUint()
+		f = float64(v)
+
+	default:
+		f, err = d.Parser.ParseFloat()
+	}
+	return
+}
+
+// parseWeakInt parses a float or boolean value of type t as an integer, floats
+// must have no fractional part.
+func (d Decoder) parseWeakInt(t Type) (i int64, err error) {
+	var f float64
+
+	if f, err = d.parseWeakFloat(t); err == nil {
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= -math.MinInt64 {
+			err = fmt.Errorf("objconv: %g cannot be converted to an integer", f)
+		} else {
+			i = int64(f)
+		}
+	}
+	return
+}
+
+// parseWeakUint parses a float or boolean value of type t as an unsigned
+// integer, floats must be positive and have no fractional part.
+func (d Decoder) parseWeakUint(t Type) (u uint64, err error) {
+	var f float64
+
+	if f, err = d.parseWeakFloat(t); err == nil {
+		if f != math.Trunc(f) || f < 0 || f >= 2*-math.MinInt64 {
+			err = fmt.Errorf("objconv: %g cannot be converted to an unsigned integer", f)
+		} else {
+			u = uint64(f)
+		}
+	}
+	return
+}
+
+func checkIntBounds(i int64, t reflect.Type) (err error) {
+	switch t.Kind() {
+	case reflect.Int:
+		err = objutil.CheckInt64Bounds(i, int64(objutil.IntMin), uint64(objutil.IntMax), t)
+	case reflect.Int8:
+		err = objutil.CheckInt64Bounds(i, objutil.Int8Min, objutil.Int8Max, t)
+	case reflect.Int16:
+		err = objutil.CheckInt64Bounds(i, objutil.Int16Min, objutil.Int16Max, t)
+	case reflect.Int32:
+		err = objutil.CheckInt64Bounds(i, objutil.Int32Min, objutil.Int32Max, t)
+	}
+	return
+}
+
+func checkUintBounds(u uint64, t reflect.Type) (err error) {
+	switch t.Kind() {
+	case reflect.Uint:
+		err = objutil.CheckUint64Bounds(u, uint64(objutil.UintMax), t)
+	case reflect.Uint8:
+		err = objutil.CheckUint64Bounds(u, objutil.Uint8Max, t)
+	case reflect.Uint16:
+		err = objutil.CheckUint64Bounds(u, objutil.Uint16Max, t)
+	case reflect.Uint32:
+		err = objutil.CheckUint64Bounds(u, objutil.Uint32Max, t)
+	}
+	return
+}
+
 func (d Decoder) decodePointer(to reflect.Value) (Type, error) {
 	return d.decodePointerWith(to, decodeFuncOf(to.Type().Elem()))
 }
@@ -982,7 +1364,27 @@ func (d Decoder) decodeDecoderPointer(to reflect.Value) (Type, error) {
 	return Unknown /* just needs to not be Nil */, to.Addr().Interface().(ValueDecoder).DecodeValue(d)
 }
 
-func (d Decoder) decodeDecoder(to reflect.Value) (Type, error) {
+func (d Decoder) decodeDecoder(to reflect.Value) (t Type, err error) {
+	if to.Kind() == reflect.Ptr && to.CanSet() {
+		if t, err = d.Parser.ParseType(); err != nil {
+			return
+		}
+
+		// Like other pointers, decoding a nil value sets the pointer to nil
+		// instead of calling the method.
+		if t == Nil {
+			if err = d.Parser.ParseNil(); err == nil {
+				to.Set(zeroValueOf(to.Type()))
+			}
+			return
+		}
+
+		if to.IsNil() {
+			// The method can't be called on a nil pointer, a new value has to
+			// be allocated first.
+			to.Set(reflect.New(to.Type().Elem()))
+		}
+	}
 	return Unknown /* just needs to not be Nil */, to.Interface().(ValueDecoder).DecodeValue(d)
 }
 
@@ -1039,11 +1441,24 @@ func (d Decoder) decodeTextUnmarshaler(to reflect.Value) (t Type, err error) {
 
 func (d Decoder) decodeInterface(to reflect.Value) (t Type, err error) {
 	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeInterfaceFromType(t, to)
+		if to.IsValid() {
+			err = d.decodeInterfaceFromType(t, to)
+		} else {
+			err = d.skip(t)
+		}
 	}
 	return
 }
 
+// skip discards the next value of type t, using the Skip method of the parser
+// if it has one, or falling back to parsing the value and dropping the result.
+func (d Decoder) skip(t Type) error {
+	if p, ok := d.Parser.(skipParser); ok {
+		return p.Skip()
+	}
+	return d.decodeInterfaceFromType(t, reflect.Value{})
+}
+
 func (d Decoder) decodeInterfaceFromType(t Type, to reflect.Value) (err error) {
 	switch t {
 	case Nil:
@@ -1258,6 +1673,183 @@ func (d Decoder) decodeMapImpl(t Type, f func(Decoder, Decoder) error) (err erro
 	return
 }
 
+// DecodeStruct provides the implementation of the algorithm for decoding
+// structs, where typ is the name of the struct type and f is called to decode
+// each field.
+//
+// The function f receives the name of the field and must decode its value with
+// the decoder it was given, or return false without decoding anything if the
+// struct has no field with this name, in which case the value is skipped (or
+// reported as an error by strict decoders).
+//
+// DecodeStruct is used by the code generated by objconv-gen, it decodes fields
+// the same way than the reflection-based decoder does.
+func (d Decoder) DecodeStruct(typ string, f func(Decoder, []byte) (bool, error)) (err error) {
+	var t Type
+
+	if t, err = d.parseType(); err != nil {
+		return
+	}
+
+	return d.decodeMapImpl(t, func(kd Decoder, vd Decoder) (err error) {
+		var b []byte
+
+		if _, b, err = d.decodeTypeAndString(); err != nil {
+			return
+		}
+
+		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
+			return
+		}
+
+		field := func(d Decoder) (err error) {
+			var ok bool
+
+			if ok, err = f(d, b); err == nil && !ok {
+				if d.strict {
+					err = fmt.Errorf("objconv: unknown field %q in %s", b, typ)
+				} else {
+					_, err = d.decodeInterface(reflect.Value{}) // discard
+				}
+			}
+
+			return
+		}
+
+		if d.errs != nil {
+			return d.errs.collect(d, string(b), field)
+		}
+
+		return field(d)
+	})
+}
+
+// DecodeBool decodes a boolean value.
+//
+// This method and the other methods decoding scalar values apply the same
+// conversions than Decode, they let programs decode values without going
+// through reflection.
+func (d Decoder) DecodeBool() (v bool, err error) {
+	var t Type
+	if t, err = d.parseType(); err == nil {
+		v, err = d.parseBool(t)
+	}
+	return
+}
+
+// DecodeInt decodes a signed integer, checking that it fits in an integer of
+// the given size in bits, or an int if bits is zero.
+func (d Decoder) DecodeInt(bits int) (v int64, err error) {
+	var t Type
+	if t, err = d.parseType(); err == nil {
+		v, err = d.parseInt(t, intTypeOfSize(bits))
+	}
+	return
+}
+
+// DecodeUint decodes an unsigned integer, checking that it fits in an integer
+// of the given size in bits, or a uint if bits is zero.
+func (d Decoder) DecodeUint(bits int) (v uint64, err error) {
+	var t Type
+	if t, err = d.parseType(); err == nil {
+		v, err = d.parseUint(t, uintTypeOfSize(bits))
+	}
+	return
+}
+
+// DecodeFloat decodes a floating point number.
+func (d Decoder) DecodeFloat() (v float64, err error) {
+	var t Type
+	if t, err = d.parseType(); err == nil {
+		v, err = d.parseFloat(t)
+	}
+	return
+}
+
+// DecodeString decodes a string.
+func (d Decoder) DecodeString() (v string, err error) {
+	var a [64]byte
+	var b []byte
+	var t Type
+	if t, err = d.parseType(); err == nil {
+		if b, err = d.parseString(t, a[:0]); err == nil {
+			v = d.makeString(t, b)
+		}
+	}
+	return
+}
+
+// DecodeBytes decodes a byte slice, the returned value is nil if the decoder
+// read a nil value.
+func (d Decoder) DecodeBytes() (v []byte, err error) {
+	var t Type
+	if t, err = d.parseType(); err == nil {
+		if v, err = d.parseBytes(t); err == nil {
+			v = d.makeBytes(t, v)
+		}
+	}
+	return
+}
+
+// DecodeTime decodes a time value.
+func (d Decoder) DecodeTime() (v time.Time, err error) {
+	var t Type
+	if t, err = d.parseType(); err == nil {
+		v, err = d.parseTime(t)
+	}
+	return
+}
+
+// DecodeDuration decodes a duration.
+func (d Decoder) DecodeDuration() (v time.Duration, err error) {
+	var t Type
+	if t, err = d.parseType(); err == nil {
+		v, err = d.parseDuration(t)
+	}
+	return
+}
+
+// parseType returns the type of the next value, first parsing the separator of
+// map keys and values if d was passed as value decoder by DecodeMap.
+func (d Decoder) parseType() (t Type, err error) {
+	if d.off != 0 {
+		if err = d.Parser.ParseMapValue(d.off - 1); err != nil {
+			return
+		}
+	}
+	return d.Parser.ParseType()
+}
+
+func intTypeOfSize(bits int) reflect.Type {
+	switch bits {
+	case 8:
+		return int8Type
+	case 16:
+		return int16Type
+	case 32:
+		return int32Type
+	case 64:
+		return int64Type
+	default:
+		return intType
+	}
+}
+
+func uintTypeOfSize(bits int) reflect.Type {
+	switch bits {
+	case 8:
+		return uint8Type
+	case 16:
+		return uint16Type
+	case 32:
+		return uint32Type
+	case 64:
+		return uint64Type
+	default:
+		return uintType
+	}
+}
+
 // StreamDecoder decodes values in a streaming fashion, allowing an array to be
 // consumed without loading it fully in memory.
 //
@@ -1270,10 +1862,51 @@ type StreamDecoder struct {
 	// there is not destination type (when decoding to an empty interface).
 	MapType reflect.Type
 
+	// Coerce configures the conversions applied to values which don't match
+	// the type of their destination, see Decoder.Coerce.
+	Coerce Coercion
+
+	// NoCopy configures the decoder to retain the strings and byte slices
+	// returned by the parser instead of copying them, see Decoder.NoCopy.
+	NoCopy bool
+
+	// Recover is called when a value of the stream could not be decoded and
+	// the decoder is able to resume at the next value. The value is skipped if
+	// the function returns nil, otherwise the error it returned is reported by
+	// the decoder.
+	//
+	// The NDJSON parser resumes at the next line. With parsers able to read
+	// the bytes of a value without decoding it, like the json, msgpack and
+	// cbor parsers, each value is read in full before being decoded, so the
+	// decoder can resume after the end of a value that failed to be decoded.
+	// This requires the stream decoder to be created by a Codec. The json
+	// parser also resumes after malformed values of arrays, at the next comma
+	// of the array. Other errors that occur while reading a value, like a
+	// truncated input, cannot be recovered from.
+	//
+	// When Recover is nil decoding stops at the first error.
+	Recover func(*StreamError) error
+
 	err error
 	typ Type
 	cnt int
 	max int
+	seq bool            // set when the parser reads a sequence of values
+	ctx context.Context // set on stream decoders created with a context
+
+	// Set on stream decoders created by Codec.NewStreamDecoderContext, closing
+	// is called when the stream ends.
+	closing func()
+
+	// State of the recovery from errors, off is the offset of the value being
+	// decoded, consumed is set when the value was read in full from the input
+	// (in raw), then decoded by the sub-parser.
+	off       int64
+	consumed  bool
+	raw       []byte
+	sub       Parser
+	subr      bytes.Reader
+	newParser func(io.Reader) Parser // set on stream decoders created by a codec
 }
 
 // NewStreamDecoder returns a new stream decoder that takes input from p.
@@ -1286,6 +1919,32 @@ func NewStreamDecoder(p Parser) *StreamDecoder {
 	return &StreamDecoder{Parser: p}
 }
 
+// Reset sets the input of the stream decoder to r, so it can be reused to read
+// a new stream. The configuration of the decoder is retained.
+//
+// The parser is reset if it implements the ResetParser interface, otherwise
+// stream decoders created by a Codec use it to create a new parser. The method
+// panics if the parser can be neither reset nor recreated.
+func (d *StreamDecoder) Reset(r io.Reader) {
+	// Stream decoders created by Codec.NewStreamDecoderContext wrap their
+	// input to stop reading when the context is canceled.
+	if d.closing != nil {
+		d.closing()
+		cr := newContextReader(d.ctx, r)
+		d.closing = cr.close
+		r = cr
+	}
+
+	d.Parser = resetParser(d.Parser, r, d.newParser)
+	d.err = nil
+	d.typ = Unknown
+	d.cnt = 0
+	d.max = 0
+	d.seq = false
+	d.off = 0
+	d.consumed = false
+}
+
 // Len returns the number of values remaining to be read from the stream, which
 // may be -1 if the underlying format doesn't provide this information. If an
 // error occurred while decoding the stream the method returns zero because no
@@ -1301,6 +1960,10 @@ func (d *StreamDecoder) Len() int {
 		}
 	}
 
+	if d.max < 0 {
+		return -1
+	}
+
 	return d.max - d.cnt
 }
 
@@ -1316,52 +1979,194 @@ func (d *StreamDecoder) Err() error {
 
 // Decodes the next value from the stream into v.
 func (d *StreamDecoder) Decode(v interface{}) error {
+	return d.decodeWith(func(dec Decoder) error { return dec.Decode(v) })
+}
+
+// DecodeChan decodes the values of the stream and sends them to ch, which must
+// be a channel that values can be sent to. The method returns when the end of
+// the stream is reached, or when an error occurs. The channel isn't closed by
+// the method.
+//
+// The method panics if ch is not a channel.
+func (d *StreamDecoder) DecodeChan(ch interface{}) error {
+	c := reflect.ValueOf(ch)
+
+	if c.Kind() != reflect.Chan || (c.Type().ChanDir()&reflect.SendDir) == 0 {
+		panic(fmt.Sprintf("objconv: the value passed to DecodeChan must be a channel that values can be sent to, found %T", ch))
+	}
+
+	t := c.Type().Elem()
+
+	for {
+		v := reflect.New(t)
+
+		if err := d.Decode(v.Interface()); err != nil {
+			if err == End {
+				err = nil
+			}
+			return err
+		}
+
+		c.Send(v.Elem())
+	}
+}
+
+// decodeWith positions the parser on the next value of the stream and calls f
+// to decode it.
+func (d *StreamDecoder) decodeWith(f func(Decoder) error) error {
 	if d.err != nil {
 		return d.err
 	}
 
-	err := error(nil)
-	cnt := d.cnt
-	max := d.max
+	if err := contextError(d.ctx, d.cnt); err != nil {
+		d.setError(err)
+		return err
+	}
+
 	dec := Decoder{
 		Parser:  d.Parser,
 		MapType: d.MapType,
+		Coerce:  d.Coerce,
+		NoCopy:  d.NoCopy,
 	}
 
-	switch d.typ {
-	case Unknown:
-		err = d.init()
-		max = d.max
-	case Array:
-		if cnt == max {
-			err = dec.Parser.ParseArrayEnd(cnt)
-		} else if cnt != 0 {
-			err = dec.Parser.ParseArrayNext(cnt)
+	err := d.next(dec, f)
+
+	for err != nil && err != End && d.Recover != nil && d.recover() {
+		if err = d.Recover(&StreamError{Index: d.cnt, Offset: d.off, Err: err}); err != nil {
+			break
+		}
+
+		if err = contextError(d.ctx, d.cnt+1); err != nil {
+			break
+		}
+
+		d.cnt++
+		err = d.next(dec, f)
+	}
+
+	if err != nil && err != End {
+		if e := contextError(d.ctx, d.cnt); e != nil {
+			err = e
 		}
 	}
 
-	if err == nil {
-		if cnt == max {
+	d.setError(err)
+	return err
+}
+
+// setError sets the error of the stream decoder, the stream ends when it is not
+// nil.
+func (d *StreamDecoder) setError(err error) {
+	if d.err = err; err != nil && d.closing != nil {
+		d.closing()
+	}
+}
+
+// next positions the parser on the next value of the stream and calls f to
+// decode it with dec.
+func (d *StreamDecoder) next(dec Decoder, f func(Decoder) error) (err error) {
+	d.off, d.consumed = -1, false
+
+	switch {
+	case d.typ == Unknown:
+		err = d.init()
+	case d.seq:
+		if _, err = d.Parser.ParseType(); err == io.EOF {
 			err = End
-		} else {
-			switch err = dec.Decode(v); err {
-			case nil:
-				cnt++
-			case End:
-				cnt++
-				max = cnt
-			default:
-				if max < 0 && dec.Parser.ParseArrayEnd(cnt) == nil {
-					err = End
-				}
+		}
+	case d.typ == Array:
+		if d.cnt == d.max {
+			err = d.Parser.ParseArrayEnd(d.cnt)
+		} else if d.cnt != 0 {
+			err = d.Parser.ParseArrayNext(d.cnt)
+		}
+	}
+
+	if err != nil {
+		return
+	}
+
+	if d.cnt == d.max {
+		return End
+	}
+
+	if d.Recover != nil {
+		err = d.decodeRecover(dec, f)
+	} else {
+		err = f(dec)
+	}
+
+	switch err {
+	case nil:
+		d.cnt++
+	case End:
+		d.cnt++
+		d.max = d.cnt
+	default:
+		switch {
+		case d.seq:
+			// The end of the input was reached in the middle of a value.
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+		case d.max < 0 && !d.consumed:
+			if d.Parser.ParseArrayEnd(d.cnt) == nil {
+				err = End
 			}
 		}
 	}
 
-	d.err = err
-	d.cnt = cnt
-	d.max = max
-	return err
+	return
+}
+
+// decodeRecover calls f to decode the next value, keeping track of the state
+// needed to recover if it fails.
+func (d *StreamDecoder) decodeRecover(dec Decoder, f func(Decoder) error) error {
+	if p, ok := d.Parser.(offsetParser); ok {
+		// The bytes preceding the value, like white spaces in text formats,
+		// are skipped by ParseType.
+		if _, err := d.Parser.ParseType(); err == nil {
+			d.off = p.InputOffset()
+		}
+	}
+
+	p, ok := asRawParser(d.Parser)
+
+	if !ok || d.newParser == nil {
+		return f(dec)
+	}
+
+	if _, err := d.Parser.ParseType(); err != nil {
+		return err
+	}
+
+	raw, err := p.ParseRaw(d.raw[:0])
+	d.raw = raw
+
+	if err != nil {
+		return err
+	}
+
+	d.consumed = true
+	d.subr.Reset(raw)
+
+	d.sub = resetParser(d.sub, &d.subr, d.newParser)
+	return f(dec.derive(d.sub))
+}
+
+// recover positions the parser on the next value of the stream after decoding
+// a value failed, returning false if it isn't possible.
+func (d *StreamDecoder) recover() bool {
+	if d.consumed {
+		return true
+	}
+
+	if p, ok := d.Parser.(recoverParser); ok {
+		return p.Recover() == nil
+	}
+
+	return false
 }
 
 // Encoder returns a new StreamEncoder which can be used to re-encode the stream
@@ -1373,7 +2178,7 @@ func (d *StreamDecoder) Encoder(e Emitter) (enc *StreamEncoder, err error) {
 
 	if typ, err = d.Parser.ParseType(); err == nil {
 		enc = NewStreamEncoder(e)
-		enc.oneshot = typ != Array
+		enc.oneshot = typ != Array && !isSequenceParser(d.Parser)
 	}
 
 	return
@@ -1384,16 +2189,24 @@ func (d *StreamDecoder) init() error {
 	typ := Unknown
 	max := 0
 
+	// Parsers of sequences have no array wrapping the values of the stream,
+	// they are read until the end of the input.
+	d.seq = isSequenceParser(d.Parser)
+
 	if typ, err = d.Parser.ParseType(); err == nil {
-		switch typ {
+		switch {
+		case d.seq:
+			max = -1
+		case typ == Array:
+			max, err = d.Parser.ParseArrayBegin()
 		default:
 			max = 1
-		case Array:
-			max, err = d.Parser.ParseArrayBegin()
 		}
+	} else if err == io.EOF && d.seq {
+		err = End
 	}
 
-	d.err = err
+	d.setError(err)
 	d.typ = typ
 	d.max = max
 	return err
@@ -1422,8 +2235,10 @@ type decodeFuncOpts struct {
 
 type decodeFunc func(Decoder, reflect.Value) (Type, error)
 
+// decodeFuncOf returns a decoder function for t, the function uses the
+// compiled decoder of t when the values it receives are addressable.
 func decodeFuncOf(t reflect.Type) decodeFunc {
-	return makeDecodeFunc(t, decodeFuncOpts{})
+	return lookupCodec(t).decodeAny
 }
 
 func makeDecodeFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
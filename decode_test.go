@@ -1,9 +1,11 @@
 package objconv
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
@@ -261,6 +263,149 @@ func TestDecoderDecodeType(t *testing.T) {
 	}
 }
 
+func TestDecoderCoerce(t *testing.T) {
+	tests := []struct {
+		in     interface{}
+		out    interface{}
+		coerce Coercion
+	}{
+		// string -> number
+		{"42", int8(42), StringToNumber},
+		{"42", uint16(42), StringToNumber},
+		{"1.5", float32(1.5), StringToNumber},
+		{[]byte("-1"), int(-1), StringToNumber},
+
+		// scalar -> string
+		{42, "42", ScalarToString},
+		{uint(42), "42", ScalarToString},
+		{1.5, "1.5", ScalarToString},
+		{true, "true", ScalarToString},
+
+		// string -> bool
+		{"true", true, StringToBool},
+		{"FALSE", false, StringToBool},
+		{"yes", true, StringToBool},
+		{"No", false, StringToBool},
+		{"1", true, StringToBool},
+		{[]byte("0"), false, StringToBool},
+
+		// number -> bool
+		{0, false, NumberToBool},
+		{uint(2), true, NumberToBool},
+		{0.5, true, NumberToBool},
+
+		// bool -> number
+		{true, int(1), BoolToNumber},
+		{false, uint(0), BoolToNumber},
+		{true, float64(1), BoolToNumber},
+
+		// float -> int
+		{float64(42), int(42), FloatToInt},
+		{float64(-1), int8(-1), FloatToInt},
+		{float64(42), uint(42), FloatToInt},
+
+		// scalar -> slice
+		{"A", []string{"A"}, ScalarToSlice},
+		{"42", []int{42}, ScalarToSlice | StringToNumber},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T->%T", test.in, test.out), func(t *testing.T) {
+			val := reflect.New(reflect.TypeOf(test.out))
+			dec := Decoder{Parser: NewValueParser(test.in), Coerce: test.coerce}
+
+			if err := dec.Decode(val.Interface()); err != nil {
+				t.Fatal(err)
+			}
+
+			if v := val.Elem().Interface(); !reflect.DeepEqual(v, test.out) {
+				t.Errorf("%T => %#v != %v", v, v, test.out)
+			}
+
+			// The conversion must fail when the coercion is not enabled.
+			dec = Decoder{Parser: NewValueParser(test.in), Coerce: WeakCoercions &^ test.coerce}
+
+			if err := dec.Decode(reflect.New(reflect.TypeOf(test.out)).Interface()); err == nil {
+				t.Error("expected an error decoding without the coercion")
+			}
+
+			// NoCoercions takes precedence over the coercions it is combined with.
+			dec = Decoder{Parser: NewValueParser(test.in), Coerce: NoCoercions | test.coerce}
+
+			if err := dec.Decode(reflect.New(reflect.TypeOf(test.out)).Interface()); err == nil {
+				t.Error("expected an error decoding with coercions disabled")
+			}
+		})
+	}
+}
+
+func TestDecoderNoCoercions(t *testing.T) {
+	// A zero Coerce field selects DefaultCoercions, NoCoercions selects none.
+	for c := Coercion(1); c <= WeakCoercions; c <<= 1 {
+		if (Decoder{Coerce: NoCoercions}).coerce(c) {
+			t.Errorf("coercion %#x applied by a decoder configured with NoCoercions", c)
+		}
+	}
+
+	tests := []struct {
+		in  interface{}
+		out interface{}
+	}{
+		{"42", int(42)},
+		{42, "42"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T->%T", test.in, test.out), func(t *testing.T) {
+			// The value is converted by the default coercions.
+			val := reflect.New(reflect.TypeOf(test.out))
+			dec := Decoder{Parser: NewValueParser(test.in)}
+
+			if err := dec.Decode(val.Interface()); err != nil {
+				t.Fatal(err)
+			}
+
+			if v := val.Elem().Interface(); !reflect.DeepEqual(v, test.out) {
+				t.Errorf("%T => %#v != %v", v, v, test.out)
+			}
+
+			dec = Decoder{Parser: NewValueParser(test.in), Coerce: NoCoercions}
+
+			if err := dec.Decode(reflect.New(reflect.TypeOf(test.out)).Interface()); err == nil {
+				t.Error("expected an error decoding with coercions disabled")
+			}
+		})
+	}
+}
+
+func TestDecoderCoerceError(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out interface{}
+	}{
+		{"128", int8(0)},
+		{"-1", uint(0)},
+		{"256", uint8(0)},
+		{"1.5", int(0)},
+		{"maybe", false},
+		{1.5, int(0)},
+		{-1.0, uint(0)},
+		{1e300, int64(0)},
+		{300.0, uint8(0)},
+		{[]interface{}{1}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v->%T", test.in, test.out), func(t *testing.T) {
+			dec := Decoder{Parser: NewValueParser(test.in), Coerce: WeakCoercions}
+
+			if err := dec.Decode(reflect.New(reflect.TypeOf(test.out)).Interface()); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
 func TestDecoderDecodeToEmptyInterface(t *testing.T) {
 	tests := []interface{}{
 		// nil -> interface{}
@@ -324,6 +469,33 @@ func TestDecoderDecodeToEmptyInterface(t *testing.T) {
 	}
 }
 
+// decodeCounter implements ValueDecoder on its pointer type, fields of type
+// *decodeCounter are nil pointers which the decoder has to allocate.
+type decodeCounter struct {
+	N int
+}
+
+func (c *decodeCounter) DecodeValue(d Decoder) error {
+	return d.Decode(&c.N)
+}
+
+func TestDecoderDecodeNilPointerToValueDecoder(t *testing.T) {
+	var v struct{ P, Q *decodeCounter }
+	v.Q = &decodeCounter{N: 1}
+
+	if err := NewDecoder(NewValueParser(map[string]interface{}{"P": 42, "Q": nil})).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.P == nil || v.P.N != 42 {
+		t.Errorf("the pointer was not allocated before calling DecodeValue: %#v", v.P)
+	}
+
+	if v.Q != nil {
+		t.Errorf("decoding a nil value did not set the pointer to nil: %#v", v.Q)
+	}
+}
+
 func TestStreamDecoder(t *testing.T) {
 	tests := [][]interface{}{
 		{},
@@ -360,6 +532,60 @@ func TestStreamDecoder(t *testing.T) {
 	}
 }
 
+func TestStreamDecoderDecodeChan(t *testing.T) {
+	dec := NewStreamDecoder(NewValueParser([]interface{}{1, 2, 3}))
+	ch := make(chan int, 3)
+
+	if err := dec.DecodeChan(ch); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	var values []int
+
+	for v := range ch {
+		values = append(values, v)
+	}
+
+	if !reflect.DeepEqual(values, []int{1, 2, 3}) {
+		t.Error(values)
+	}
+
+	dec = NewStreamDecoder(NewValueParser([]interface{}{1, "A"}))
+	ch = make(chan int, 2)
+
+	if err := dec.DecodeChan(ch); err == nil {
+		t.Error("expected an error when decoding a string to an int")
+	}
+
+	if n := len(ch); n != 1 {
+		t.Error("invalid number of values sent to the channel:", n)
+	}
+}
+
+func TestStreamDecoderContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	dec := NewStreamDecoderContext(ctx, NewValueParser([]interface{}{1, 2, 3}))
+
+	var v int
+
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	err := dec.Decode(&v)
+
+	if e, ok := err.(*StreamError); !ok || e.Index != 1 || e.Err != context.Canceled {
+		t.Errorf("invalid error returned after canceling the context: %v", err)
+	}
+
+	if dec.Err() != err {
+		t.Errorf("invalid error returned by Err: %v", dec.Err())
+	}
+}
+
 func TestStreamRencode(t *testing.T) {
 	tests := []interface{}{
 		nil,
@@ -420,3 +646,84 @@ func TestStreamRencode(t *testing.T) {
 		})
 	}
 }
+
+func TestDecoderCollectErrors(t *testing.T) {
+	type point struct {
+		X int `objconv:"x"`
+		Y int `objconv:"y"`
+	}
+
+	type config struct {
+		Name   string            `objconv:"name"`
+		Port   uint16            `objconv:"port"`
+		Points []point           `objconv:"points"`
+		Sizes  [2]int            `objconv:"sizes"`
+		Limits map[string]int    `objconv:"limits"`
+		Labels map[string]string `objconv:"labels"`
+		Debug  bool              `objconv:"debug"`
+	}
+
+	src := map[string]interface{}{
+		"name": "api",
+		"port": 100000,
+		"points": []interface{}{
+			map[string]interface{}{"x": 1, "y": 2},
+			map[string]interface{}{"x": "a", "y": []interface{}{1}},
+		},
+		"sizes":  []interface{}{true, 2},
+		"limits": map[string]interface{}{"a/b": 1, "c": "d"},
+		"labels": map[string]interface{}{"a": []interface{}{}},
+		"debug":  true,
+	}
+
+	var c config
+	d := Decoder{Parser: NewValueParser(src), CollectErrors: true}
+	err := d.Decode(&c)
+
+	errs, ok := err.(DecodeErrors)
+	if !ok {
+		t.Fatal("expected DecodeErrors but got", err)
+	}
+
+	paths := make([]string, len(errs))
+	for i, e := range errs {
+		paths[i] = e.Path
+	}
+	sort.Strings(paths)
+
+	if !reflect.DeepEqual(paths, []string{
+		"/labels/a",
+		"/limits/c",
+		"/points/1/x",
+		"/points/1/y",
+		"/port",
+		"/sizes/0",
+	}) {
+		t.Errorf("bad error paths: %q (%v)", paths, err)
+	}
+
+	if len(err.(interface{ Unwrap() []error }).Unwrap()) != len(errs) {
+		t.Error("bad list of unwrapped errors")
+	}
+
+	if !reflect.DeepEqual(c, config{
+		Name:   "api",
+		Points: []point{{X: 1, Y: 2}, {}},
+		Sizes:  [2]int{0, 2},
+		Limits: map[string]int{"a/b": 1, "c": 0},
+		Labels: map[string]string{"a": ""},
+		Debug:  true,
+	}) {
+		t.Errorf("bad value: %#v", c)
+	}
+}
+
+func TestDecoderCollectErrorsRoot(t *testing.T) {
+	var x int
+	d := Decoder{Parser: NewValueParser("hello"), CollectErrors: true}
+	err := d.Decode(&x)
+
+	if errs, ok := err.(DecodeErrors); !ok || len(errs) != 1 || errs[0].Path != "" {
+		t.Error("expected a single error for the root value but got", err)
+	}
+}
@@ -0,0 +1,828 @@
+package objconv
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Value is an in-memory representation of a document that can be decoded from
+// and encoded to any format supported by objconv.
+//
+// Values record the Type reported by the parser they were loaded from, which
+// means that unsigned integers stay unsigned, byte arrays are not converted to
+// strings, etc... Maps retain the order in which their keys were decoded or
+// inserted.
+//
+// The accessor methods never panic, when a value doesn't have the expected
+// type they return the zero-value of the result type instead. Looking up a
+// missing array element or map key returns the zero Value, which reports false
+// from IsValid.
+//
+// Arrays and maps have reference semantics, like Go slices and maps: copies of
+// a Value share the same elements, and mutations made through one copy are
+// visible through the others.
+//
+// The zero Value is invalid, it is encoded as a nil value.
+type Value struct {
+	typ Type
+	val interface{} // scalar value, *valueArray, or *valueMap
+}
+
+// MapItem represents a key/value pair of a map Value.
+type MapItem struct {
+	Key   Value
+	Value Value
+}
+
+type valueArray struct {
+	elems []Value
+}
+
+type valueMap struct {
+	items []MapItem
+	index map[string]int // position of the string keys in items
+}
+
+// NilValue returns a Value of type Nil.
+func NilValue() Value { return Value{typ: Nil} }
+
+// BoolValue returns a Value of type Bool holding b.
+func BoolValue(b bool) Value { return Value{typ: Bool, val: b} }
+
+// IntValue returns a Value of type Int holding i.
+func IntValue(i int64) Value { return Value{typ: Int, val: i} }
+
+// UintValue returns a Value of type Uint holding u.
+func UintValue(u uint64) Value { return Value{typ: Uint, val: u} }
+
+// FloatValue returns a Value of type Float holding f.
+func FloatValue(f float64) Value { return Value{typ: Float, val: f} }
+
+// StringValue returns a Value of type String holding s.
+func StringValue(s string) Value { return Value{typ: String, val: s} }
+
+// BytesValue returns a Value of type Bytes holding b.
+func BytesValue(b []byte) Value { return Value{typ: Bytes, val: b} }
+
+// TimeValue returns a Value of type Time holding t.
+func TimeValue(t time.Time) Value { return Value{typ: Time, val: t} }
+
+// DurationValue returns a Value of type Duration holding d.
+func DurationValue(d time.Duration) Value { return Value{typ: Duration, val: d} }
+
+// ErrorValue returns a Value of type Error holding err.
+func ErrorValue(err error) Value { return Value{typ: Error, val: err} }
+
+// ArrayValue returns a Value of type Array holding elems.
+func ArrayValue(elems ...Value) Value {
+	return Value{typ: Array, val: &valueArray{elems: append([]Value{}, elems...)}}
+}
+
+// MapValue returns a Value of type Map holding items. If the same key appears
+// multiple times the last value wins.
+func MapValue(items ...MapItem) Value {
+	m := &valueMap{items: make([]MapItem, 0, len(items))}
+
+	for _, item := range items {
+		m.set(item.Key, item.Value)
+	}
+
+	return Value{typ: Map, val: m}
+}
+
+// ValueOf returns a Value representing x, which may be any Go value supported
+// by the Encoder. Struct fields appear in the map in the order they are
+// declared.
+func ValueOf(x interface{}) (Value, error) {
+	switch v := x.(type) {
+	case Value:
+		return v, nil
+	case *Value:
+		if v != nil {
+			return *v, nil
+		}
+	}
+
+	var v Value
+
+	if err := (Decoder{Parser: NewValueParser(x)}).Decode(&v); err != nil {
+		return Value{}, err
+	}
+
+	return v, nil
+}
+
+// IsValid returns false if v is the zero Value.
+func (v Value) IsValid() bool { return v.typ != Unknown }
+
+// Type returns the type of v, or Unknown if v is the zero Value.
+func (v Value) Type() Type { return v.typ }
+
+// IsNil returns true if v is a Nil value.
+func (v Value) IsNil() bool { return v.typ == Nil }
+
+// Bool returns the boolean held by v, or false if v is not a Bool.
+func (v Value) Bool() bool {
+	b, _ := v.val.(bool)
+	return b
+}
+
+// Int returns the integer held by v. Unsigned integers and floats are converted
+// when they can be represented exactly, otherwise the method returns zero.
+func (v Value) Int() int64 {
+	switch x := v.val.(type) {
+	case int64:
+		return x
+	case uint64:
+		if x <= math.MaxInt64 {
+			return int64(x)
+		}
+	case float64:
+		if x == math.Trunc(x) && x >= math.MinInt64 && x < math.MaxInt64 {
+			return int64(x)
+		}
+	}
+	return 0
+}
+
+// Uint returns the unsigned integer held by v. Signed integers and floats are
+// converted when they can be represented exactly, otherwise the method returns
+// zero.
+func (v Value) Uint() uint64 {
+	switch x := v.val.(type) {
+	case uint64:
+		return x
+	case int64:
+		if x >= 0 {
+			return uint64(x)
+		}
+	case float64:
+		if x == math.Trunc(x) && x >= 0 && x < math.MaxUint64 {
+			return uint64(x)
+		}
+	}
+	return 0
+}
+
+// Float returns the floating point number held by v, integers are converted.
+// The method returns zero if v is not a number.
+func (v Value) Float() float64 {
+	switch x := v.val.(type) {
+	case float64:
+		return x
+	case int64:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	}
+	return 0
+}
+
+// String returns the string held by v. Like reflect.Value, if v is not a String
+// the method returns a string of the form "<T Value>" where T is the type of v.
+func (v Value) String() string {
+	if s, ok := v.val.(string); ok {
+		return s
+	}
+	return "<" + v.typ.String() + " Value>"
+}
+
+// Bytes returns the byte slice held by v, or nil if v is not of type Bytes. The
+// returned slice is not a copy, it must not be modified by the program.
+func (v Value) Bytes() []byte {
+	b, _ := v.val.([]byte)
+	return b
+}
+
+// Time returns the time held by v, or the zero time if v is not of type Time.
+func (v Value) Time() time.Time {
+	t, _ := v.val.(time.Time)
+	return t
+}
+
+// Duration returns the duration held by v, or zero if v is not of type
+// Duration.
+func (v Value) Duration() time.Duration {
+	d, _ := v.val.(time.Duration)
+	return d
+}
+
+// Err returns the error held by v, or nil if v is not of type Error.
+func (v Value) Err() error {
+	if v.typ == Error {
+		err, _ := v.val.(error)
+		return err
+	}
+	return nil
+}
+
+// Len returns the number of elements of an array or map, or the length of a
+// string or byte slice. It returns zero for other types.
+func (v Value) Len() int {
+	switch x := v.val.(type) {
+	case *valueArray:
+		return len(x.elems)
+	case *valueMap:
+		return len(x.items)
+	case string:
+		return len(x)
+	case []byte:
+		return len(x)
+	}
+	return 0
+}
+
+// Index returns the element at index i of an array. It returns the zero Value
+// if v is not an array or i is out of range.
+func (v Value) Index(i int) Value {
+	if a, ok := v.val.(*valueArray); ok && i >= 0 && i < len(a.elems) {
+		return a.elems[i]
+	}
+	return Value{}
+}
+
+// Get returns the value associated with key in a map. It returns the zero Value
+// if v is not a map or the key doesn't exist.
+func (v Value) Get(key string) Value {
+	if m, ok := v.val.(*valueMap); ok {
+		if i, ok := m.index[key]; ok {
+			return m.items[i].Value
+		}
+	}
+	return Value{}
+}
+
+// Has returns true if v is a map which has key.
+func (v Value) Has(key string) bool {
+	if m, ok := v.val.(*valueMap); ok {
+		_, ok = m.index[key]
+		return ok
+	}
+	return false
+}
+
+// Elems returns a copy of the list of elements of an array, or nil if v is not
+// an array.
+func (v Value) Elems() []Value {
+	if a, ok := v.val.(*valueArray); ok {
+		return append([]Value{}, a.elems...)
+	}
+	return nil
+}
+
+// Items returns a copy of the list of key/value pairs of a map, in order, or
+// nil if v is not a map.
+func (v Value) Items() []MapItem {
+	if m, ok := v.val.(*valueMap); ok {
+		return append([]MapItem{}, m.items...)
+	}
+	return nil
+}
+
+// Lookup returns the value found at path within v, the syntax of path is
+// described in ParsePath.
+//
+// If the path doesn't match any value the method returns a *PathError wrapping
+// ErrPathNotFound.
+func (v Value) Lookup(path string) (Value, error) {
+	elems, err := ParsePath(path)
+	if err != nil {
+		return Value{}, err
+	}
+
+	for _, elem := range elems {
+		if v = v.child(elem); !v.IsValid() {
+			return Value{}, &PathError{Path: path, Err: ErrPathNotFound}
+		}
+	}
+
+	return v, nil
+}
+
+func (v Value) child(elem string) Value {
+	switch x := v.val.(type) {
+	case *valueArray:
+		if i, err := strconv.Atoi(elem); err == nil {
+			return v.Index(i)
+		}
+
+	case *valueMap:
+		if i := x.lookup(elem); i >= 0 {
+			return x.items[i].Value
+		}
+	}
+	return Value{}
+}
+
+// Set associates x with key in a map. If the key already exists its value is
+// replaced, otherwise the key is added at the end of the map.
+//
+// The method panics if v is not a map.
+func (v Value) Set(key string, x Value) {
+	v.mapOf("Set").set(StringValue(key), x)
+}
+
+// Delete removes key from a map, returning true if the key existed.
+//
+// The method panics if v is not a map.
+func (v Value) Delete(key string) bool {
+	m := v.mapOf("Delete")
+	i, ok := m.index[key]
+	if ok {
+		m.remove(i)
+	}
+	return ok
+}
+
+// SetIndex sets the element at index i of an array to x.
+//
+// The method panics if v is not an array or i is out of range.
+func (v Value) SetIndex(i int, x Value) {
+	v.arrayOf("SetIndex").elems[i] = x
+}
+
+// Append adds elems at the end of an array.
+//
+// The method panics if v is not an array.
+func (v Value) Append(elems ...Value) {
+	a := v.arrayOf("Append")
+	a.elems = append(a.elems, elems...)
+}
+
+// Insert inserts x in an array at index i, shifting the following elements.
+// The index may be equal to the length of the array, in which case x is added
+// at the end.
+//
+// The method panics if v is not an array or i is out of range.
+func (v Value) Insert(i int, x Value) {
+	a := v.arrayOf("Insert")
+	a.elems = append(a.elems, Value{})
+	copy(a.elems[i+1:], a.elems[i:])
+	a.elems[i] = x
+}
+
+// Remove removes the element at index i from an array, shifting the following
+// elements.
+//
+// The method panics if v is not an array or i is out of range.
+func (v Value) Remove(i int) {
+	a := v.arrayOf("Remove")
+	copy(a.elems[i:], a.elems[i+1:])
+	a.elems[len(a.elems)-1] = Value{}
+	a.elems = a.elems[:len(a.elems)-1]
+}
+
+// SetPath sets the value found at path within v to x, the syntax of path is
+// described in ParsePath. Setting the empty path replaces v itself.
+//
+// Maps that are missing on the path are created. Array elements must exist,
+// except for the last element of the path which may be equal to the length of
+// the array, or "-" as defined by JSON Pointer, in which case x is appended to
+// the array.
+func (v *Value) SetPath(path string, x Value) error {
+	elems, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+
+	if len(elems) == 0 {
+		*v = x
+		return nil
+	}
+
+	p := *v
+
+	for i, elem := range elems {
+		last := i == len(elems)-1
+
+		switch c := p.val.(type) {
+		case *valueArray:
+			j := len(c.elems)
+
+			if elem != "-" {
+				if j, err = strconv.Atoi(elem); err != nil || j < 0 || j > len(c.elems) || (j == len(c.elems) && !last) {
+					return &PathError{Path: path, Err: ErrPathNotFound}
+				}
+			} else if !last {
+				return &PathError{Path: path, Err: ErrPathNotFound}
+			}
+
+			switch {
+			case j == len(c.elems):
+				c.elems = append(c.elems, x)
+			case last:
+				c.elems[j] = x
+			default:
+				p = c.elems[j]
+			}
+
+		case *valueMap:
+			j := c.lookup(elem)
+
+			switch {
+			case last && j >= 0:
+				c.items[j].Value = x
+			case last:
+				c.set(StringValue(elem), x)
+			case j >= 0:
+				p = c.items[j].Value
+			default:
+				p = MapValue()
+				c.set(StringValue(elem), p)
+			}
+
+		default:
+			return &PathError{
+				Path: path,
+				Err:  fmt.Errorf("cannot set an element of a value of type %s", p.typ),
+			}
+		}
+	}
+
+	return nil
+}
+
+func (v Value) arrayOf(method string) *valueArray {
+	a, ok := v.val.(*valueArray)
+	if !ok {
+		panic("objconv: Value." + method + " called on a value of type " + v.typ.String())
+	}
+	return a
+}
+
+func (v Value) mapOf(method string) *valueMap {
+	m, ok := v.val.(*valueMap)
+	if !ok {
+		panic("objconv: Value." + method + " called on a value of type " + v.typ.String())
+	}
+	return m
+}
+
+// Interface returns the natural Go representation of v, using the same types
+// than the decoder when decoding to an empty interface ([]interface{} for
+// arrays, map[interface{}]interface{} for maps, etc...).
+func (v Value) Interface() interface{} {
+	e := NewValueEmitter()
+
+	if err := (Encoder{Emitter: e}).Encode(v); err != nil {
+		// The value emitter never fails, and neither does encoding a Value.
+		panic(err)
+	}
+
+	return e.Value()
+}
+
+// Decode decodes v into the Go value pointed by to, like if v had been parsed
+// from a serialized representation.
+//
+// The values are exposed with their recorded types, so conversions that are
+// specific to text formats (like decoding base64 strings to byte slices in
+// JSON) are not applied.
+func (v Value) Decode(to interface{}) error {
+	return (Decoder{Parser: NewValueParser(v.Interface())}).Decode(to)
+}
+
+// Equal returns true if v and x represent the same document.
+//
+// Because different formats may represent the same values with different
+// types, numbers are compared by value regardless of being signed, unsigned or
+// floating point, and strings are equal to byte slices of the same content.
+// Maps are equal if they have the same set of keys, in any order.
+func (v Value) Equal(x Value) bool {
+	switch a := v.val.(type) {
+	case int64, uint64, float64:
+		return numberEqual(v.val, x.val) || numberEqual(x.val, v.val)
+
+	case string:
+		switch b := x.val.(type) {
+		case string:
+			return a == b
+		case []byte:
+			return a == string(b)
+		}
+		return false
+
+	case []byte:
+		switch b := x.val.(type) {
+		case string:
+			return string(a) == b
+		case []byte:
+			return string(a) == string(b)
+		}
+		return false
+
+	case time.Time:
+		b, ok := x.val.(time.Time)
+		return ok && a.Equal(b)
+
+	case error:
+		b, ok := x.val.(error)
+		return ok && a.Error() == b.Error()
+
+	case *valueArray:
+		b, ok := x.val.(*valueArray)
+		if !ok || len(a.elems) != len(b.elems) {
+			return false
+		}
+		for i := range a.elems {
+			if !a.elems[i].Equal(b.elems[i]) {
+				return false
+			}
+		}
+		return true
+
+	case *valueMap:
+		b, ok := x.val.(*valueMap)
+		if !ok || len(a.items) != len(b.items) {
+			return false
+		}
+		for _, item := range a.items {
+			if i := b.find(item.Key); i < 0 || !item.Value.Equal(b.items[i].Value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// nil, bool and time.Duration values are comparable
+	return v.typ == x.typ && v.val == x.val
+}
+
+// numberEqual compares a and b, the function only needs to handle the cases
+// where the type of a is lower than the type of b in the order int64, uint64,
+// float64.
+func numberEqual(a interface{}, b interface{}) bool {
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return x == y
+		case uint64:
+			return x >= 0 && uint64(x) == y
+		case float64:
+			return y == math.Trunc(y) && y >= math.MinInt64 && y < math.MaxInt64 && int64(y) == x
+		}
+
+	case uint64:
+		switch y := b.(type) {
+		case uint64:
+			return x == y
+		case float64:
+			return y == math.Trunc(y) && y >= 0 && y < math.MaxUint64 && uint64(y) == x
+		}
+
+	case float64:
+		y, ok := b.(float64)
+		return ok && x == y
+	}
+	return false
+}
+
+// Clone returns a deep copy of v.
+func (v Value) Clone() Value {
+	switch x := v.val.(type) {
+	case []byte:
+		return BytesValue(append([]byte{}, x...))
+
+	case *valueArray:
+		a := &valueArray{elems: make([]Value, len(x.elems))}
+		for i, elem := range x.elems {
+			a.elems[i] = elem.Clone()
+		}
+		return Value{typ: Array, val: a}
+
+	case *valueMap:
+		m := &valueMap{items: make([]MapItem, 0, len(x.items))}
+		for _, item := range x.items {
+			m.set(item.Key.Clone(), item.Value.Clone())
+		}
+		return Value{typ: Map, val: m}
+	}
+	return v
+}
+
+// EncodeValue satisfies the ValueEncoder interface.
+//
+// Maps are always encoded in the order of their keys, regardless of the
+// SortMapKeys option of the encoder.
+func (v Value) EncodeValue(e Encoder) error {
+	switch x := v.val.(type) {
+	case *valueArray:
+		i := 0
+		return e.EncodeArray(len(x.elems), func(e Encoder) error {
+			i++
+			return x.elems[i-1].EncodeValue(e)
+		})
+
+	case *valueMap:
+		i := 0
+		return e.EncodeMap(len(x.items), func(ke Encoder, ve Encoder) error {
+			item := x.items[i]
+			i++
+			if err := item.Key.EncodeValue(ke); err != nil {
+				return err
+			}
+			return item.Value.EncodeValue(ve)
+		})
+	}
+
+	// Encoding nil for invalid values.
+	return e.Encode(v.val)
+}
+
+// DecodeValue satisfies the ValueDecoder interface.
+func (v *Value) DecodeValue(d Decoder) (err error) {
+	var t Type
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	switch t {
+	case Nil:
+		if err = d.Parser.ParseNil(); err == nil {
+			*v = NilValue()
+		}
+
+	case Bool:
+		var x bool
+		if x, err = d.Parser.ParseBool(); err == nil {
+			*v = BoolValue(x)
+		}
+
+	case Int:
+		var x int64
+		if x, err = d.Parser.ParseInt(); err == nil {
+			*v = IntValue(x)
+		}
+
+	case Uint:
+		var x uint64
+		if x, err = d.Parser.ParseUint(); err == nil {
+			*v = UintValue(x)
+		}
+
+	case Float:
+		var x float64
+		if x, err = d.Parser.ParseFloat(); err == nil {
+			*v = FloatValue(x)
+		}
+
+	case String:
+		var x []byte
+		if x, err = d.Parser.ParseString(); err == nil {
+			*v = StringValue(d.makeString(String, x))
+		}
+
+	case Bytes:
+		var x []byte
+		if x, err = d.Parser.ParseBytes(); err == nil {
+			*v = BytesValue(d.makeBytes(Bytes, x))
+		}
+
+	case Time:
+		var x time.Time
+		if x, err = d.Parser.ParseTime(); err == nil {
+			*v = TimeValue(x)
+		}
+
+	case Duration:
+		var x time.Duration
+		if x, err = d.Parser.ParseDuration(); err == nil {
+			*v = DurationValue(x)
+		}
+
+	case Error:
+		var x error
+		if x, err = d.Parser.ParseError(); err == nil {
+			*v = ErrorValue(x)
+		}
+
+	case Array:
+		a := &valueArray{elems: []Value{}}
+		err = d.decodeArrayImpl(t, func(d Decoder) error {
+			var elem Value
+			if err := d.Decode(&elem); err != nil {
+				return err
+			}
+			a.elems = append(a.elems, elem)
+			return nil
+		})
+		if err == nil {
+			*v = Value{typ: Array, val: a}
+		}
+
+	case Map:
+		m := &valueMap{}
+		err = d.decodeMapImpl(t, func(kd Decoder, vd Decoder) error {
+			var key, val Value
+			if err := kd.Decode(&key); err != nil {
+				return err
+			}
+			if err := vd.Decode(&val); err != nil {
+				return err
+			}
+			m.set(key, val)
+			return nil
+		})
+		if err == nil {
+			*v = Value{typ: Map, val: m}
+		}
+
+	default:
+		panic("objconv: parser returned an unsupported value type: " + t.String())
+	}
+
+	return
+}
+
+func (m *valueMap) set(key Value, val Value) {
+	if s, ok := key.val.(string); ok {
+		if i, ok := m.index[s]; ok {
+			m.items[i].Value = val
+			return
+		}
+		if m.index == nil {
+			m.index = make(map[string]int)
+		}
+		m.index[s] = len(m.items)
+	}
+	m.items = append(m.items, MapItem{Key: key, Value: val})
+}
+
+func (m *valueMap) remove(i int) {
+	if s, ok := m.items[i].Key.val.(string); ok {
+		delete(m.index, s)
+	}
+
+	copy(m.items[i:], m.items[i+1:])
+	m.items[len(m.items)-1] = MapItem{}
+	m.items = m.items[:len(m.items)-1]
+
+	for j := i; j != len(m.items); j++ {
+		if s, ok := m.items[j].Key.val.(string); ok {
+			m.index[s] = j
+		}
+	}
+}
+
+// find returns the position of the key equal to key, or -1 if none was found.
+func (m *valueMap) find(key Value) int {
+	if s, ok := key.val.(string); ok {
+		if i, ok := m.index[s]; ok {
+			return i
+		}
+	}
+
+	if len(m.index) == len(m.items) {
+		if _, ok := key.val.(string); ok {
+			return -1 // all keys are strings
+		}
+	}
+
+	for i, item := range m.items {
+		if item.Key.Equal(key) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// lookup returns the position of the key matching the path element elem, or -1
+// if none was found. Keys that aren't strings are compared to the element using
+// their decimal or raw byte representation.
+func (m *valueMap) lookup(elem string) int {
+	if i, ok := m.index[elem]; ok {
+		return i
+	}
+
+	if len(m.index) == len(m.items) {
+		return -1 // all keys are strings
+	}
+
+	var a [32]byte
+
+	for i, item := range m.items {
+		var k []byte
+
+		switch x := item.Key.val.(type) {
+		case int64:
+			k = strconv.AppendInt(a[:0], x, 10)
+		case uint64:
+			k = strconv.AppendUint(a[:0], x, 10)
+		case []byte:
+			k = x
+		default:
+			continue
+		}
+
+		if string(k) == elem {
+			return i
+		}
+	}
+
+	return -1
+}
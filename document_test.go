@@ -0,0 +1,248 @@
+package objconv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValueOf(t *testing.T) {
+	type point struct {
+		X int
+		Y int
+	}
+
+	data := []byte("Hello")
+
+	v, err := ValueOf(map[string]interface{}{
+		"name":   "objconv",
+		"count":  uint(42),
+		"points": []point{{1, 2}, {3, 4}},
+		"data":   data,
+		"at":     time.Unix(1, 0).UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if typ := v.Type(); typ != Map {
+		t.Error("bad type:", typ)
+	}
+
+	if s := v.Get("name").String(); s != "objconv" {
+		t.Error("bad name:", s)
+	}
+
+	if c := v.Get("count"); c.Type() != Uint || c.Uint() != 42 || c.Int() != 42 || c.Float() != 42 {
+		t.Error("bad count:", c.Type(), c.Uint())
+	}
+
+	p := v.Get("points").Index(1)
+
+	if keys := p.Items(); len(keys) != 2 || keys[0].Key.String() != "X" || keys[1].Key.String() != "Y" {
+		t.Error("struct fields were not decoded in order:", keys)
+	}
+
+	if y := p.Get("Y").Int(); y != 4 {
+		t.Error("bad point:", y)
+	}
+
+	if at := v.Get("at"); at.Type() != Time || !at.Time().Equal(time.Unix(1, 0)) {
+		t.Error("bad time:", at.Type(), at.Time())
+	}
+
+	data[0] = 'J'
+
+	if b := v.Get("data"); b.Type() != Bytes || string(b.Bytes()) != "Hello" {
+		t.Errorf("bad bytes: %s %q", b.Type(), b.Bytes())
+	}
+}
+
+func TestValueAccessors(t *testing.T) {
+	v := ArrayValue(IntValue(-1), StringValue("A"), FloatValue(1.5), NilValue())
+
+	if n := v.Len(); n != 4 {
+		t.Error("bad length:", n)
+	}
+
+	if x := v.Index(0); x.Uint() != 0 || x.Int() != -1 || x.String() != "<int Value>" {
+		t.Error("bad integer:", x.Uint(), x.Int(), x.String())
+	}
+
+	if x := v.Index(2); x.Int() != 0 || x.Float() != 1.5 {
+		t.Error("bad float:", x.Int(), x.Float())
+	}
+
+	if x := v.Index(3); !x.IsValid() || !x.IsNil() {
+		t.Error("bad nil value:", x.Type())
+	}
+
+	for _, x := range []Value{v.Index(-1), v.Index(4), v.Get("A"), v.Index(1).Index(0), Value{}.Get("")} {
+		if x.IsValid() {
+			t.Error("unexpected valid value:", x.Type())
+		}
+	}
+}
+
+func TestValueMutations(t *testing.T) {
+	v := MapValue(
+		MapItem{Key: StringValue("C"), Value: IntValue(1)},
+		MapItem{Key: StringValue("A"), Value: IntValue(2)},
+		MapItem{Key: StringValue("B"), Value: ArrayValue()},
+	)
+
+	v.Set("A", IntValue(3))
+	v.Set("D", IntValue(4))
+
+	a := v.Get("B")
+	a.Append(IntValue(1), IntValue(3))
+	a.Insert(1, IntValue(2))
+	a.Remove(0)
+	a.SetIndex(1, StringValue("x"))
+
+	if !v.Delete("C") || v.Delete("C") {
+		t.Error("bad delete result")
+	}
+
+	if err := v.SetPath("/E/F", BoolValue(true)); err != nil {
+		t.Error(err)
+	}
+
+	if err := v.SetPath("B.-", IntValue(5)); err != nil {
+		t.Error(err)
+	}
+
+	if err := v.SetPath("A.B", IntValue(0)); err == nil {
+		t.Error("expected an error when setting an element of an integer")
+	}
+
+	if err := v.SetPath("B[10]", IntValue(0)); !errors.Is(err, ErrPathNotFound) {
+		t.Error("bad error:", err)
+	}
+
+	x := v.Interface()
+	y := map[interface{}]interface{}{
+		"A": int64(3),
+		"B": []interface{}{int64(2), "x", int64(5)},
+		"D": int64(4),
+		"E": map[interface{}]interface{}{"F": true},
+	}
+
+	if !reflect.DeepEqual(x, y) {
+		t.Errorf("%#v", x)
+	}
+
+	var keys []string
+	for _, item := range v.Items() {
+		keys = append(keys, item.Key.String())
+	}
+
+	if !reflect.DeepEqual(keys, []string{"A", "B", "D", "E"}) {
+		t.Error("bad key order:", keys)
+	}
+
+	if x, err := v.Lookup(`E["F"]`); err != nil || !x.Bool() {
+		t.Error("bad lookup:", x, err)
+	}
+
+	if _, err := v.Lookup("B[3]"); !errors.Is(err, ErrPathNotFound) {
+		t.Error("bad error:", err)
+	}
+}
+
+func TestValueClone(t *testing.T) {
+	v := MapValue(MapItem{Key: StringValue("A"), Value: ArrayValue(IntValue(1))})
+	c := v.Clone()
+
+	v.Get("A").SetIndex(0, IntValue(2))
+	v.Set("B", NilValue())
+
+	if x := c.Interface(); !reflect.DeepEqual(x, map[interface{}]interface{}{"A": []interface{}{int64(1)}}) {
+		t.Errorf("the clone was modified: %#v", x)
+	}
+}
+
+func TestValueDecodeEncode(t *testing.T) {
+	type document struct {
+		Name  string
+		Value Value
+		Tags  []string
+	}
+
+	var v Value
+
+	if err := (Decoder{Parser: NewValueParser(document{
+		Name: "A",
+		Tags: []string{"x", "y"},
+	})}).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	v.Set("Value", UintValue(2))
+
+	var d document
+
+	if err := v.Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Name != "A" || d.Value.Uint() != 2 || !reflect.DeepEqual(d.Tags, []string{"x", "y"}) {
+		t.Errorf("%#v", d)
+	}
+
+	e := NewValueEmitter()
+
+	if err := (Encoder{Emitter: e}).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+
+	if x := e.Value(); !reflect.DeepEqual(x, map[interface{}]interface{}{
+		"Name":  "A",
+		"Value": uint64(2),
+		"Tags":  []interface{}{"x", "y"},
+	}) {
+		t.Errorf("%#v", x)
+	}
+}
+
+func TestValueEqual(t *testing.T) {
+	m1 := MapValue(
+		MapItem{Key: StringValue("A"), Value: IntValue(1)},
+		MapItem{Key: StringValue("B"), Value: ArrayValue(StringValue("x"), NilValue())},
+	)
+	m2 := MapValue(
+		MapItem{Key: BytesValue([]byte("B")), Value: ArrayValue(BytesValue([]byte("x")), NilValue())},
+		MapItem{Key: StringValue("A"), Value: FloatValue(1)},
+	)
+
+	tests := []struct {
+		a     Value
+		b     Value
+		equal bool
+	}{
+		{Value{}, Value{}, true},
+		{Value{}, NilValue(), false},
+		{NilValue(), NilValue(), true},
+		{BoolValue(true), BoolValue(true), true},
+		{BoolValue(true), IntValue(1), false},
+		{IntValue(1), UintValue(1), true},
+		{IntValue(-1), UintValue(1<<64 - 1), false},
+		{UintValue(2), FloatValue(2), true},
+		{FloatValue(2.5), IntValue(2), false},
+		{StringValue("A"), BytesValue([]byte("A")), true},
+		{DurationValue(1), IntValue(1), false},
+		{ArrayValue(IntValue(1)), ArrayValue(IntValue(1), IntValue(2)), false},
+		{m1, m2, true},
+		{m1, MapValue(), false},
+	}
+
+	for _, test := range tests {
+		if eq := test.a.Equal(test.b); eq != test.equal {
+			t.Errorf("%v == %v: %t", test.a.Interface(), test.b.Interface(), eq)
+		}
+		if eq := test.b.Equal(test.a); eq != test.equal {
+			t.Errorf("%v == %v: %t", test.b.Interface(), test.a.Interface(), eq)
+		}
+	}
+}
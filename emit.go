@@ -1,6 +1,9 @@
 package objconv
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 // The Emitter interface must be implemented by types that provide encoding
 // of a specific format (like json, resp, ...).
@@ -69,6 +72,44 @@ type PrettyEmitter interface {
 	PrettyEmitter() Emitter
 }
 
+// The CanonicalEmitter interface may be implemented by emitters supporting a
+// canonical format, where a value has a single possible serialized form.
+type CanonicalEmitter interface {
+	// CanonicalEmitter returns a new emitter that outputs to the same writer
+	// in a canonical format.
+	CanonicalEmitter() Emitter
+}
+
+// The ResetEmitter interface is implemented by emitters that can be reused to
+// write to a different writer. The emitters of all the codecs provided by the
+// objconv subpackages implement it.
+type ResetEmitter interface {
+	// Reset discards the state of the emitter and sets its output to w.
+	Reset(w io.Writer)
+}
+
+// The abortEmitter interface may be implemented by emitters which keep state
+// for the arrays and maps they are emitting. Encoders call Abort when they fail
+// to encode a value, the emitter writes what it buffered to its output and
+// discards the state of the containers that were left open.
+type abortEmitter interface {
+	Abort() error
+}
+
+func abortEmitting(emitter Emitter) error {
+	if e, ok := emitter.(abortEmitter); ok {
+		return e.Abort()
+	}
+	return nil
+}
+
+// The Flusher interface may be implemented by emitters that buffer their
+// output.
+type Flusher interface {
+	// Flush writes the buffered output to the underlying writer.
+	Flush() error
+}
+
 // The textEmitter interface may be implemented by emitters of human-readable
 // formats. Such emitters instruct the encoder to prefer using
 // encoding.TextMarshaler over encoding.BinaryMarshaler for example.
@@ -82,6 +123,50 @@ func isTextEmitter(emitter Emitter) bool {
 	return e != nil && e.TextEmitter()
 }
 
+// The lengthEmitter interface may be implemented by emitters of formats that
+// need to know the number of elements in arrays and maps before writing them.
+// Such emitters instruct algorithms that don't know the length of a container
+// ahead of time to buffer its elements.
+type lengthEmitter interface {
+	// LengthEmitter returns true if the emitter requires the length of arrays
+	// and maps to be known when they begin.
+	LengthEmitter() bool
+}
+
+func isLengthEmitter(emitter Emitter) bool {
+	e, _ := emitter.(lengthEmitter)
+	return e != nil && e.LengthEmitter()
+}
+
+// The sequenceEmitter interface may be implemented by emitters of formats that
+// represent streams as a sequence of values written one after the other (like
+// NDJSON or CBOR sequences) instead of an array. Such emitters instruct stream
+// encoders not to wrap the values they write in an array.
+type sequenceEmitter interface {
+	// SequenceEmitter returns true if the emitter writes streams as sequences
+	// of values.
+	SequenceEmitter() bool
+}
+
+func isSequenceEmitter(emitter Emitter) bool {
+	e, _ := emitter.(sequenceEmitter)
+	return e != nil && e.SequenceEmitter()
+}
+
+// resetEmitter resets e to write to w, or calls newEmitter to create a new
+// emitter if e doesn't implement ResetEmitter. The function panics if neither
+// is possible.
+func resetEmitter(e Emitter, w io.Writer, newEmitter func(io.Writer) Emitter) Emitter {
+	if r, ok := e.(ResetEmitter); ok {
+		r.Reset(w)
+		return e
+	}
+	if newEmitter == nil {
+		panic("objconv: the emitter cannot be reset")
+	}
+	return newEmitter(w)
+}
+
 type discardEmitter struct{}
 
 func (e discardEmitter) EmitNil() error                     { return nil }
@@ -1,10 +1,12 @@
 package objconv
 
 import (
+	"context"
 	"encoding"
 	"fmt"
 	"io"
 	"reflect"
+	"sync"
 	"time"
 	"unsafe"
 )
@@ -17,7 +19,16 @@ import (
 type Encoder struct {
 	Emitter     Emitter // the emitter used by this encoder
 	SortMapKeys bool    // whether map keys should be sorted
-	key         bool
+
+	// BufferUnknownLengths enables buffering the elements of arrays and maps
+	// of unknown length in memory when the emitter needs to know the length of
+	// containers before writing them (like msgpack or RESP). By default those
+	// are streamed to the emitter, which may not support it.
+	BufferUnknownLengths bool
+
+	key bool
+
+	newEmitter func(io.Writer) Emitter // set on encoders created by a codec
 }
 
 // NewEncoder returns a new encoder that outputs values to e.
@@ -34,8 +45,29 @@ func NewEncoder(e Emitter) *Encoder {
 	return &Encoder{Emitter: e}
 }
 
+// Reset sets the output of the encoder to w, so it can be reused to encode
+// values to a different writer.
+//
+// The emitter is reset if it implements the ResetEmitter interface, otherwise
+// encoders created by a Codec use it to create a new emitter. The method panics
+// if the emitter can be neither reset nor recreated.
+func (e *Encoder) Reset(w io.Writer) {
+	e.Emitter = resetEmitter(e.Emitter, w, e.newEmitter)
+	e.key = false
+}
+
 // Encode encodes the generic value v.
+//
+// When encoding the value fails, the emitter discards the state of the arrays
+// and maps that were left open so it can be used to encode other values.
 func (e Encoder) Encode(v interface{}) (err error) {
+	if err = e.encodeGeneric(v); err != nil {
+		abortEmitting(e.Emitter)
+	}
+	return
+}
+
+func (e Encoder) encodeGeneric(v interface{}) (err error) {
 	if err = e.encodeMapValueMaybe(); err != nil {
 		return
 	}
@@ -222,10 +254,13 @@ func (e Encoder) Encode(v interface{}) (err error) {
 		return e.encodeMapInterfaceInterface(*x)
 
 	case ValueEncoder:
+		if v := reflect.ValueOf(x); v.Kind() == reflect.Ptr && v.IsNil() {
+			return e.encode(v)
+		}
 		return x.EncodeValue(e)
 
 	default:
-		return e.encode(reflect.ValueOf(v))
+		return e.encodeBoxed(v)
 	}
 }
 
@@ -237,7 +272,7 @@ func (e *Encoder) encodeMapValueMaybe() (err error) {
 }
 
 func (e Encoder) encode(v reflect.Value) error {
-	return encodeFuncOf(v.Type())(e, v)
+	return lookupCodec(v.Type()).encodeReflect(e, v)
 }
 
 func (e Encoder) encodeBool(v reflect.Value) error {
@@ -349,6 +384,80 @@ func (e Encoder) encodeArrayWith(v reflect.Value, f encodeFunc) error {
 	})
 }
 
+func (e Encoder) encodeChan(v reflect.Value) error {
+	return e.encodeChanWith(v, encodeFuncOf(v.Type().Elem()))
+}
+
+// encodeChanWith encodes the values received from the channel v as an array,
+// until the channel is closed.
+func (e Encoder) encodeChanWith(v reflect.Value, f encodeFunc) error {
+	if v.IsNil() {
+		return e.Emitter.EmitNil()
+	}
+	return e.encodeLazyArray(func() (reflect.Value, error) {
+		x, ok := v.Recv()
+		if !ok {
+			return x, End
+		}
+		return x, nil
+	}, f)
+}
+
+// encodeLazyArray encodes an array of unknown length, next is called to produce
+// the elements until it returns End, and f is called to encode each of them.
+//
+// When BufferUnknownLengths is set and the emitter needs to know the length of
+// arrays before writing them the elements are buffered until the array ends.
+func (e Encoder) encodeLazyArray(next func() (reflect.Value, error), f encodeFunc) (err error) {
+	if err = e.encodeMapValueMaybe(); err != nil {
+		return
+	}
+
+	if e.BufferUnknownLengths && isLengthEmitter(e.Emitter) {
+		return e.encodeBuffered(func(e Encoder) error { return e.encodeLazyArray(next, f) })
+	}
+
+	if err = e.Emitter.EmitArrayBegin(-1); err != nil {
+		return
+	}
+
+	for i := 0; true; i++ {
+		var v reflect.Value
+
+		if v, err = next(); err != nil {
+			if err == End {
+				break
+			}
+			return
+		}
+
+		if i != 0 {
+			if err = e.Emitter.EmitArrayNext(); err != nil {
+				return
+			}
+		}
+
+		if err = f(e, v); err != nil {
+			return
+		}
+	}
+
+	return e.Emitter.EmitArrayEnd()
+}
+
+// encodeBuffered calls f with an encoder which records the values it encodes,
+// then writes them to the emitter of e with the lengths of all arrays and maps
+// set.
+func (e Encoder) encodeBuffered(f func(Encoder) error) error {
+	b := &tokenBuffer{text: isTextEmitter(e.Emitter)}
+
+	if err := f(Encoder{Emitter: b, SortMapKeys: e.SortMapKeys}); err != nil {
+		return err
+	}
+
+	return b.writeTo(e.Emitter)
+}
+
 func (e Encoder) encodeSliceOfString(a []string) error {
 	i := 0
 	return e.EncodeArray(len(a), func(e Encoder) (err error) {
@@ -520,7 +629,13 @@ func (e Encoder) encodeStruct(v reflect.Value) error {
 	return e.encodeStructWith(v, structCache.lookup(v.Type()))
 }
 
-func (e Encoder) encodeStructWith(v reflect.Value, s *structType) (err error) {
+func (e Encoder) encodeStructWith(v reflect.Value, s *structType) error {
+	return e.encodeStructVersionWith(v, s, nil)
+}
+
+// encodeStructVersionWith encodes the struct value v, when ver is not nil the
+// version key and value are written before the struct fields.
+func (e Encoder) encodeStructVersionWith(v reflect.Value, s *structType, ver *Versioning) (err error) {
 	n := 0
 
 	for i := range s.fields {
@@ -530,11 +645,28 @@ func (e Encoder) encodeStructWith(v reflect.Value, s *structType) (err error) {
 		}
 	}
 
+	if ver != nil {
+		n++
+	}
+
 	if err = e.Emitter.EmitMapBegin(n); err != nil {
 		return
 	}
 	n = 0
 
+	if ver != nil {
+		if err = e.Emitter.EmitString(ver.key()); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitMapValue(); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitInt(int64(ver.Version), 0); err != nil {
+			return
+		}
+		n++
+	}
+
 	for i := range s.fields {
 		f := &s.fields[i]
 		if fv := v.FieldByIndex(f.index); !f.omit(fv) {
@@ -614,7 +746,9 @@ func (e Encoder) encodeUnsupported(v reflect.Value) error {
 //
 // The n argument can be set to a negative value to indicate that the program
 // doesn't know how many elements it will output to the array. Be mindful that
-// not all emitters support encoding arrays of unknown lengths.
+// not all emitters support encoding arrays of unknown lengths, unless
+// BufferUnknownLengths is set to buffer the elements in memory until the array
+// ends.
 //
 // The f function is called to encode each element of the array.
 func (e Encoder) EncodeArray(n int, f func(Encoder) error) (err error) {
@@ -624,6 +758,10 @@ func (e Encoder) EncodeArray(n int, f func(Encoder) error) (err error) {
 		}
 	}
 
+	if n < 0 && e.BufferUnknownLengths && isLengthEmitter(e.Emitter) {
+		return e.encodeBuffered(func(e Encoder) error { return e.EncodeArray(n, f) })
+	}
+
 	if err = e.Emitter.EmitArrayBegin(n); err != nil {
 		return
 	}
@@ -653,7 +791,9 @@ encodeArray:
 //
 // The n argument can be set to a negative value to indicate that the program
 // doesn't know how many elements it will output to the map. Be mindful that not
-// all emitters support encoding maps of unknown length.
+// all emitters support encoding maps of unknown length, unless
+// BufferUnknownLengths is set to buffer the elements in memory until the map
+// ends.
 //
 // The f function is called to encode each element of the map, it is expected to
 // encode two values, the first one being the key, follow by the associated value.
@@ -665,6 +805,10 @@ func (e Encoder) EncodeMap(n int, f func(Encoder, Encoder) error) (err error) {
 		}
 	}
 
+	if n < 0 && e.BufferUnknownLengths && isLengthEmitter(e.Emitter) {
+		return e.encodeBuffered(func(e Encoder) error { return e.EncodeMap(n, f) })
+	}
+
 	if err = e.Emitter.EmitMapBegin(n); err != nil {
 		return
 	}
@@ -678,8 +822,8 @@ encodeMap:
 		}
 		e.key = true
 		err = f(
-			Encoder{Emitter: e.Emitter, SortMapKeys: e.SortMapKeys},
-			Encoder{Emitter: e.Emitter, SortMapKeys: e.SortMapKeys, key: true},
+			Encoder{Emitter: e.Emitter, SortMapKeys: e.SortMapKeys, BufferUnknownLengths: e.BufferUnknownLengths},
+			Encoder{Emitter: e.Emitter, SortMapKeys: e.SortMapKeys, BufferUnknownLengths: e.BufferUnknownLengths, key: true},
 		)
 		// Because internal calls don't use the exported methods they may not
 		// reset this flag to false when expected, forcing the value here.
@@ -697,19 +841,91 @@ encodeMap:
 	return e.Emitter.EmitMapEnd()
 }
 
+// EncodeStruct provides the implementation of the algorithm for encoding
+// structs, where n is the number of fields that will be encoded and f is called
+// to encode them. The function f must call EncodeField before encoding the value
+// of each field.
+//
+// EncodeStruct is used by the code generated by objconv-gen, which encodes
+// fields the same way than the reflection-based encoder does.
+func (e Encoder) EncodeStruct(n int, f func(Encoder) error) (err error) {
+	if err = e.encodeMapValueMaybe(); err != nil {
+		return
+	}
+
+	if err = e.Emitter.EmitMapBegin(n); err != nil {
+		return
+	}
+
+	if err = f(e); err != nil {
+		return
+	}
+
+	return e.Emitter.EmitMapEnd()
+}
+
+// EncodeField encodes the name of the i-th field of a struct encoded with
+// EncodeStruct, the value of the field must be encoded next.
+func (e Encoder) EncodeField(i int, name string) (err error) {
+	if i != 0 {
+		if err = e.Emitter.EmitMapNext(); err != nil {
+			return
+		}
+	}
+
+	if err = e.Emitter.EmitString(name); err != nil {
+		return
+	}
+
+	return e.Emitter.EmitMapValue()
+}
+
 // A StreamEncoder encodes and writes a stream of values to an output stream.
 //
+// Emitters may buffer the values written to the stream. By default the emitter
+// is flushed after each value, so values are delivered to the output as soon as
+// they are encoded. When FlushCount or FlushInterval are set the values are
+// written to the output when the stream is closed, when Flush is called, or when
+// the flush policy applies.
+//
 // Instances of StreamEncoder are not safe for use by multiple goroutines.
 type StreamEncoder struct {
 	Emitter     Emitter // the emitter used by this encoder
 	SortMapKeys bool    // whether map keys should be sorted
 
+	// BufferUnknownLengths is passed to the encoders of the values written to
+	// the stream, see Encoder.
+	BufferUnknownLengths bool
+
+	// FlushCount, when positive, is the number of values written to the stream
+	// after which the emitter is flushed.
+	FlushCount int
+
+	// FlushInterval, when positive, is the maximum amount of time that values
+	// written to the stream remain buffered, the emitter is flushed by a timer
+	// running in the background.
+	FlushInterval time.Duration
+
 	err     error
 	max     int
 	cnt     int
 	opened  bool
 	closed  bool
 	oneshot bool
+
+	// Set on stream encoders created with a context, closing is called before
+	// writing the end of the stream.
+	ctx     context.Context
+	closing func()
+
+	newEmitter func(io.Writer) Emitter // set on stream encoders created by a codec
+
+	// State of the flush policy, the mutex synchronizes the encoder with the
+	// flush timer.
+	mutex   sync.Mutex
+	timer   *time.Timer
+	armed   bool // whether the timer is running
+	pending int  // number of values written since the last flush
 }
 
 // NewStreamEncoder returns a new stream encoder that outputs to e.
@@ -722,6 +938,41 @@ func NewStreamEncoder(e Emitter) *StreamEncoder {
 	return &StreamEncoder{Emitter: e}
 }
 
+// Reset sets the output of the stream encoder to w, so it can be reused to
+// write a new stream. The configuration of the encoder is retained, the state
+// of the previous stream is discarded without writing its end, see Close.
+//
+// The emitter is reset if it implements the ResetEmitter interface, otherwise
+// stream encoders created by a Codec use it to create a new emitter. The method
+// panics if the emitter can be neither reset nor recreated.
+func (e *StreamEncoder) Reset(w io.Writer) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+
+	// Stream encoders created by Codec.NewStreamEncoderContext wrap their
+	// output to interrupt blocked writes when the context is canceled.
+	if e.closing != nil {
+		e.closing()
+		cw := newContextWriter(e.ctx, w)
+		e.closing = cw.close
+		w = cw
+	}
+
+	e.Emitter = resetEmitter(e.Emitter, w, e.newEmitter)
+	e.err = nil
+	e.max = 0
+	e.cnt = 0
+	e.opened = false
+	e.closed = false
+	e.oneshot = false
+	e.armed = false
+	e.pending = 0
+}
+
 // Open explicitly tells the encoder to start the stream, setting the number
 // of values to n.
 //
@@ -729,6 +980,12 @@ func NewStreamEncoder(e Emitter) *StreamEncoder {
 // may not have to be accurate, some formats also support passing a negative
 // value to indicate that the number of elements is unknown.
 func (e *StreamEncoder) Open(n int) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.open(n)
+}
+
+func (e *StreamEncoder) open(n int) error {
 	if err := e.err; err != nil {
 		return err
 	}
@@ -741,6 +998,12 @@ func (e *StreamEncoder) Open(n int) error {
 		e.max = n
 		e.opened = true
 
+		// Emitters of sequences write the values of the stream one after
+		// the other, without wrapping them in an array.
+		if isSequenceEmitter(e.Emitter) {
+			e.oneshot = true
+		}
+
 		if !e.oneshot {
 			e.err = e.Emitter.EmitArrayBegin(n)
 		}
@@ -749,27 +1012,125 @@ func (e *StreamEncoder) Open(n int) error {
 	return e.err
 }
 
-// Close terminates the stream encoder.
+// Close terminates the stream encoder, the values buffered by the emitter are
+// flushed.
 func (e *StreamEncoder) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.close()
+}
+
+func (e *StreamEncoder) close() error {
 	if !e.closed {
-		if err := e.Open(-1); err != nil {
+		if e.closing != nil {
+			e.closing()
+		}
+
+		if err := e.open(-1); err != nil {
 			return err
 		}
 
 		e.closed = true
 
+		if e.timer != nil {
+			e.timer.Stop()
+		}
+
 		if !e.oneshot {
 			e.err = e.Emitter.EmitArrayEnd()
 		}
+
+		if e.err == nil {
+			e.err = e.flush()
+		}
+	}
+
+	return e.err
+}
+
+// Flush writes the values buffered by the emitter to the output. The method
+// has no effect if the emitter doesn't implement the Flusher interface.
+func (e *StreamEncoder) Flush() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.err == nil {
+		e.err = e.flush()
 	}
 
 	return e.err
 }
 
+func (e *StreamEncoder) flush() error {
+	e.pending = 0
+
+	if f, ok := e.Emitter.(Flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+// written applies the flush policy after a value was written to the stream.
+func (e *StreamEncoder) written() error {
+	e.pending++
+
+	if e.FlushCount <= 0 && e.FlushInterval <= 0 {
+		return e.flush()
+	}
+
+	if e.FlushCount > 0 && e.pending >= e.FlushCount {
+		return e.flush()
+	}
+
+	if e.FlushInterval > 0 && !e.armed {
+		e.armed = true
+
+		if e.timer == nil {
+			e.timer = time.AfterFunc(e.FlushInterval, e.flushTimer)
+		} else {
+			e.timer.Reset(e.FlushInterval)
+		}
+	}
+
+	return nil
+}
+
+// flushTimer is called by the timer started when FlushInterval is set.
+func (e *StreamEncoder) flushTimer() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.armed = false
+
+	if e.err == nil && !e.closed && e.pending != 0 {
+		e.err = e.flush()
+	}
+}
+
 // Encode writes v to the stream, encoding it based on the emitter configured
 // on e.
 func (e *StreamEncoder) Encode(v interface{}) error {
-	if err := e.Open(-1); err != nil {
+	return e.encodeWith(func(enc Encoder) error { return enc.Encode(v) })
+}
+
+// encodeWith positions the emitter on the next value of the stream and calls f
+// to encode it.
+func (e *StreamEncoder) encodeWith(f func(Encoder) error) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	// The context is checked before writing anything, so the stream can still
+	// be closed after it was canceled.
+	i := e.cnt
+
+	if e.err == nil {
+		if err := contextError(e.ctx, i); err != nil {
+			return err
+		}
+	}
+
+	if err := e.open(-1); err != nil {
 		return err
 	}
 
@@ -782,13 +1143,24 @@ func (e *StreamEncoder) Encode(v interface{}) error {
 	}
 
 	if e.err == nil {
-		e.err = (Encoder{
-			Emitter:     e.Emitter,
-			SortMapKeys: e.SortMapKeys,
-		}).Encode(v)
+		e.err = f(Encoder{
+			Emitter:              e.Emitter,
+			SortMapKeys:          e.SortMapKeys,
+			BufferUnknownLengths: e.BufferUnknownLengths,
+		})
 
 		if e.cnt++; e.max >= 0 && e.cnt >= e.max {
-			e.Close()
+			e.close()
+		} else if e.err == nil {
+			e.err = e.written()
+		}
+	}
+
+	if e.err != nil {
+		// The write may have been interrupted because the context was
+		// canceled, in which case the error of the context is returned.
+		if err := contextError(e.ctx, i); err != nil {
+			e.err = err
 		}
 	}
 
@@ -811,6 +1183,19 @@ type ValueEncoderFunc func(Encoder) error
 // EncodeValue calls f(e).
 func (f ValueEncoderFunc) EncodeValue(e Encoder) error { return f(e) }
 
+// GeneratorFunc is a ValueEncoder which encodes the values returned by
+// successive calls to the function as an array, until it returns End. It allows
+// the encoding of arrays whose elements are produced lazily.
+type GeneratorFunc func() (interface{}, error)
+
+// EncodeValue encodes the values produced by f as an array.
+func (f GeneratorFunc) EncodeValue(e Encoder) error {
+	return e.encodeLazyArray(func() (reflect.Value, error) {
+		v, err := f()
+		return reflect.ValueOf(&v).Elem(), err
+	}, Encoder.encodeInterface)
+}
+
 // encodeFuncOpts is used to configure how the encodeFuncOf behaves.
 type encodeFuncOpts struct {
 	recurse bool
@@ -820,9 +1205,10 @@ type encodeFuncOpts struct {
 // encodeFunc is the prototype of functions that encode values.
 type encodeFunc func(Encoder, reflect.Value) error
 
-// encodeFuncOf returns an encoder function for t.
+// encodeFuncOf returns an encoder function for t, the function uses the
+// compiled encoder of t when the values it receives are addressable.
 func encodeFuncOf(t reflect.Type) encodeFunc {
-	return makeEncodeFunc(t, encodeFuncOpts{})
+	return lookupCodec(t).encodeAny
 }
 
 func makeEncodeFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
@@ -890,6 +1276,11 @@ func makeEncodeFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
 	}
 
 	switch {
+	case t.Kind() == reflect.Ptr && t.Elem().Implements(valueEncoderInterface):
+		// The method is declared on the value receiver, nil pointers must be
+		// encoded as nil values instead of calling it.
+		return makeEncodePtrFunc(t, opts)
+
 	case t.Implements(valueEncoderInterface):
 		return Encoder.encodeEncoder
 
@@ -925,6 +1316,12 @@ func makeEncodeFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
 	case reflect.Array:
 		return makeEncodeArrayFunc(t, opts)
 
+	case reflect.Chan:
+		if (t.ChanDir() & reflect.RecvDir) != 0 {
+			return makeEncodeChanFunc(t, opts)
+		}
+		return Encoder.encodeUnsupported
+
 	case reflect.String:
 		return Encoder.encodeString
 
@@ -985,6 +1382,16 @@ func makeEncodeArrayFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
 	}
 }
 
+func makeEncodeChanFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
+	if !opts.recurse {
+		return Encoder.encodeChan
+	}
+	f := makeEncodeFunc(t.Elem(), opts)
+	return func(e Encoder, v reflect.Value) error {
+		return e.encodeChanWith(v, f)
+	}
+}
+
 func makeEncodeMapFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
 	if !opts.recurse {
 		return Encoder.encodeMap
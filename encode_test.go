@@ -1,6 +1,7 @@
 package objconv
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
@@ -330,3 +331,258 @@ func TestStreamEncoderVar(t *testing.T) {
 		t.Error(x1, "!=", x2)
 	}
 }
+
+func TestStreamEncoderContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	val := &ValueEmitter{}
+	enc := NewStreamEncoderContext(ctx, val)
+
+	if err := enc.Encode(1); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	err := enc.Encode(2)
+
+	if e, ok := err.(*StreamError); !ok || e.Index != 1 || e.Err != context.Canceled {
+		t.Errorf("invalid error returned after canceling the context: %v", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Error("the stream could not be closed after canceling the context:", err)
+	}
+
+	if x := val.Value(); !reflect.DeepEqual(x, []interface{}{int64(1)}) {
+		t.Error(x)
+	}
+}
+
+// flushValueEmitter is a ValueEmitter which reports the number of values that
+// were emitted each time it is flushed.
+type flushValueEmitter struct {
+	ValueEmitter
+	n       int
+	flushes chan int
+}
+
+func (e *flushValueEmitter) EmitInt(v int64, bitSize int) error {
+	e.n++
+	return e.ValueEmitter.EmitInt(v, bitSize)
+}
+
+func (e *flushValueEmitter) Flush() error {
+	e.flushes <- e.n
+	return nil
+}
+
+func TestStreamEncoderFlush(t *testing.T) {
+	val := &flushValueEmitter{flushes: make(chan int, 10)}
+	enc := NewStreamEncoder(val)
+	enc.FlushCount = 2
+
+	for i := 0; i != 5; i++ {
+		if err := enc.Encode(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	close(val.flushes)
+	flushes := []int{}
+
+	for n := range val.flushes {
+		flushes = append(flushes, n)
+	}
+
+	// Flushed after the 2nd and 4th values, by the call to Flush, then when
+	// the stream was closed.
+	if expect := []int{2, 4, 5, 5}; !reflect.DeepEqual(flushes, expect) {
+		t.Errorf("invalid flushes: %v != %v", flushes, expect)
+	}
+}
+
+func TestStreamEncoderFlushDefault(t *testing.T) {
+	val := &flushValueEmitter{flushes: make(chan int, 10)}
+	enc := NewStreamEncoder(val)
+
+	for i := 0; i != 3; i++ {
+		if err := enc.Encode(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	close(val.flushes)
+	flushes := []int{}
+
+	for n := range val.flushes {
+		flushes = append(flushes, n)
+	}
+
+	// Without a flush policy the emitter is flushed after each value, then
+	// when the stream is closed.
+	if expect := []int{1, 2, 3, 3}; !reflect.DeepEqual(flushes, expect) {
+		t.Errorf("invalid flushes: %v != %v", flushes, expect)
+	}
+}
+
+func TestStreamEncoderFlushInterval(t *testing.T) {
+	val := &flushValueEmitter{flushes: make(chan int, 10)}
+	enc := NewStreamEncoder(val)
+	enc.FlushInterval = 10 * time.Millisecond
+
+	for i := 0; i != 2; i++ {
+		if err := enc.Encode(i); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case n := <-val.flushes:
+			if n != i+1 {
+				t.Errorf("invalid number of values flushed by the timer: %d != %d", n, i+1)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the emitter was not flushed by the timer")
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSortInterfaceValues(t *testing.T) {
+	keys := []interface{}{"b", 2, nil, uint8(1), true, []byte("x"), "a", 1.5, int64(-1), false, [2]int{1, 2}}
+	want := []interface{}{nil, false, true, int64(-1), uint8(1), 1.5, 2, "a", "b", []byte("x"), [2]int{1, 2}}
+	values := make([]reflect.Value, len(keys))
+
+	for i := range keys {
+		values[i] = reflect.ValueOf(&keys[i]).Elem()
+	}
+
+	sortValues(values[0].Type(), values)
+
+	for i, v := range values {
+		if !reflect.DeepEqual(v.Interface(), want[i]) {
+			t.Errorf("bad value at index %d: %#v != %#v", i, v.Interface(), want[i])
+		}
+	}
+}
+
+func TestEncodeChan(t *testing.T) {
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+
+	empty := make(chan string)
+	close(empty)
+
+	i := 0
+	gen := GeneratorFunc(func() (interface{}, error) {
+		if i++; i > 3 {
+			return nil, End
+		}
+		return i, nil
+	})
+
+	tests := []struct {
+		in  interface{}
+		out interface{}
+	}{
+		{(<-chan int)(ch), []interface{}{int64(1), int64(2), int64(3)}},
+		{(<-chan int)(nil), nil},
+		{gen, []interface{}{int64(1), int64(2), int64(3)}},
+		{struct{ C chan string }{empty}, map[interface{}]interface{}{"C": []interface{}{}}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T", test.in), func(t *testing.T) {
+			val := &ValueEmitter{}
+
+			if err := NewEncoder(val).Encode(test.in); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if v := val.Value(); !reflect.DeepEqual(v, test.out) {
+				t.Errorf("%#v != %#v", test.out, v)
+			}
+		})
+	}
+}
+
+// lengthValueEmitter is a ValueEmitter which requires the lengths of arrays and
+// maps to be known when they begin.
+type lengthValueEmitter struct {
+	ValueEmitter
+}
+
+func (e *lengthValueEmitter) EmitArrayBegin(n int) error {
+	if n < 0 {
+		return errors.New("unknown array length")
+	}
+	return e.ValueEmitter.EmitArrayBegin(n)
+}
+
+func (e *lengthValueEmitter) EmitMapBegin(n int) error {
+	if n < 0 {
+		return errors.New("unknown map length")
+	}
+	return e.ValueEmitter.EmitMapBegin(n)
+}
+
+func (e *lengthValueEmitter) LengthEmitter() bool { return true }
+
+func TestEncodeUnknownLengthBuffered(t *testing.T) {
+	ch := make(chan []int, 2)
+	ch <- []int{1}
+	ch <- []int{2, 3}
+	close(ch)
+
+	f := func(k Encoder, v Encoder) (err error) {
+		if err = k.Encode("A"); err != nil {
+			return
+		}
+		if err = v.Encode(ch); err != nil {
+			return
+		}
+		return End
+	}
+
+	// Buffering is opt-in, by default the map is streamed to the emitter.
+	if err := NewEncoder(&lengthValueEmitter{}).EncodeMap(-1, f); err == nil || err.Error() != "unknown map length" {
+		t.Fatal("expected an error from the emitter but got", err)
+	}
+
+	val := &lengthValueEmitter{}
+	enc := NewEncoder(val)
+	enc.BufferUnknownLengths = true
+
+	if err := enc.EncodeMap(-1, f); err != nil {
+		t.Fatal(err)
+	}
+
+	x1 := map[interface{}]interface{}{
+		"A": []interface{}{
+			[]interface{}{int64(1)},
+			[]interface{}{int64(2), int64(3)},
+		},
+	}
+
+	if x2 := val.Value(); !reflect.DeepEqual(x1, x2) {
+		t.Errorf("%#v != %#v", x1, x2)
+	}
+}
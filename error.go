@@ -9,6 +9,31 @@ func typeConversionError(from Type, to Type) error {
 	return fmt.Errorf("objconv: cannot convert from %s to %s", from, to)
 }
 
+// StreamError is returned when reading, decoding or encoding a value of a stream
+// failed, it carries the index of the value in the stream.
+type StreamError struct {
+	// Index of the value in the stream.
+	Index int
+
+	// Offset of the value in the input of a stream decoder, in bytes. It is
+	// only set on errors passed to StreamDecoder.Recover, and is -1 if the
+	// parser doesn't report its position in the input.
+	Offset int64
+
+	// Err is the error that occurred while processing the value.
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("objconv: value %d of the stream: %s", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
 var (
 	// End is expected to be returned to indicate that a function has completed
 	// its work, this is usually employed in generic algorithms.
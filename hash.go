@@ -0,0 +1,260 @@
+package objconv
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"io"
+	"math"
+	"time"
+)
+
+// Hash writes a structural representation of v to h.
+//
+// The representation is designed so that values holding the same content
+// produce the same hash regardless of the Go types they were represented with.
+// For example a struct, a map[string]interface{} and a JSON document decoded
+// into an interface{} or a Value will all produce the same hash if they have
+// the same fields and values.
+//
+// Numbers are compared by value, so signed, unsigned and floating point
+// numbers that are equal produce the same hash. Strings and byte slices with
+// the same content are also hashed the same, and times are hashed as instants
+// regardless of their location. Values of different kinds, like the string
+// "1" and the number 1, always produce different hashes.
+//
+// The order of map keys and struct fields has no effect on the result, but
+// the order of array elements does.
+func Hash(h hash.Hash, v interface{}) error {
+	return (Encoder{Emitter: NewHashEmitter(h)}).Encode(v)
+}
+
+// HashEmitter is an emitter that writes a type-tagged canonical representation
+// of the values it receives to a hash, see Hash for a description of the
+// properties of the generated representation.
+//
+// The emitter doesn't buffer the values. Map entries are hashed individually
+// and their hashes combined so the order in which they are emitted doesn't
+// matter.
+type HashEmitter struct {
+	h      hash.Hash
+	b      [16]byte
+	s      [sha256.Size]byte
+	frames []hashFrame
+}
+
+type hashFrame struct {
+	parent  hash.Hash         // hash that the map is written to
+	entry   hash.Hash         // hash of the current map entry
+	sum     [sha256.Size]byte // sum of the hashes of the map entries
+	count   uint64            // number of entries in the map
+	pending bool              // whether the current entry must be added to sum
+}
+
+const (
+	hashNil byte = iota + 1
+	hashFalse
+	hashTrue
+	hashPositive
+	hashNegative
+	hashFloat
+	hashString
+	hashTime
+	hashDuration
+	hashError
+	hashArrayBegin
+	hashArrayEnd
+	hashMap
+)
+
+// NewHashEmitter returns a new emitter which writes the values it receives to
+// h.
+func NewHashEmitter(h hash.Hash) *HashEmitter {
+	return &HashEmitter{h: h}
+}
+
+// Reset resets the emitter's state and sets it to write to h.
+func (e *HashEmitter) Reset(h hash.Hash) {
+	e.h = h
+	e.frames = e.frames[:0]
+}
+
+func (e *HashEmitter) EmitNil() error {
+	return e.emitTag(hashNil)
+}
+
+func (e *HashEmitter) EmitBool(v bool) error {
+	if v {
+		return e.emitTag(hashTrue)
+	}
+	return e.emitTag(hashFalse)
+}
+
+func (e *HashEmitter) EmitInt(v int64, _ int) error {
+	if v < 0 {
+		return e.emitUint(hashNegative, uint64(-(v+1))+1)
+	}
+	return e.emitUint(hashPositive, uint64(v))
+}
+
+func (e *HashEmitter) EmitUint(v uint64, _ int) error {
+	return e.emitUint(hashPositive, v)
+}
+
+func (e *HashEmitter) EmitFloat(v float64, _ int) error {
+	const maxUint64 = 1 << 64
+
+	switch {
+	case v != v:
+		return e.emitUint(hashFloat, 0x7FF8000000000001)
+
+	case v != math.Trunc(v) || math.IsInf(v, 0):
+		return e.emitUint(hashFloat, math.Float64bits(v))
+
+	case v >= 0 && v < maxUint64: // includes -0
+		return e.emitUint(hashPositive, uint64(v))
+
+	case v < 0 && -v < maxUint64:
+		return e.emitUint(hashNegative, uint64(-v))
+
+	default:
+		return e.emitUint(hashFloat, math.Float64bits(v))
+	}
+}
+
+func (e *HashEmitter) EmitString(v string) error {
+	e.emitUint(hashString, uint64(len(v)))
+	io.WriteString(e.h, v)
+	return nil
+}
+
+func (e *HashEmitter) EmitBytes(v []byte) error {
+	e.emitUint(hashString, uint64(len(v)))
+	e.h.Write(v)
+	return nil
+}
+
+func (e *HashEmitter) EmitTime(v time.Time) error {
+	e.b[0] = hashTime
+	binary.BigEndian.PutUint64(e.b[1:], uint64(v.Unix()))
+	binary.BigEndian.PutUint32(e.b[9:], uint32(v.Nanosecond()))
+	e.h.Write(e.b[:13])
+	return nil
+}
+
+func (e *HashEmitter) EmitDuration(v time.Duration) error {
+	return e.emitUint(hashDuration, uint64(v))
+}
+
+func (e *HashEmitter) EmitError(v error) error {
+	s := v.Error()
+	e.emitUint(hashError, uint64(len(s)))
+	io.WriteString(e.h, s)
+	return nil
+}
+
+func (e *HashEmitter) EmitArrayBegin(_ int) error {
+	return e.emitTag(hashArrayBegin)
+}
+
+func (e *HashEmitter) EmitArrayEnd() error {
+	return e.emitTag(hashArrayEnd)
+}
+
+func (e *HashEmitter) EmitArrayNext() error {
+	return nil
+}
+
+func (e *HashEmitter) EmitMapBegin(_ int) error {
+	var f *hashFrame
+
+	if n := len(e.frames); n < cap(e.frames) {
+		e.frames = e.frames[:n+1]
+		f = &e.frames[n]
+		// The spare capacity left by append holds zero frames which were never
+		// used and have no entry hash yet.
+		if f.entry == nil {
+			f.entry = sha256.New()
+		} else {
+			f.entry.Reset()
+		}
+		f.sum = [sha256.Size]byte{}
+		f.count = 0
+		f.pending = false
+	} else {
+		e.frames = append(e.frames, hashFrame{entry: sha256.New()})
+		f = &e.frames[n]
+	}
+
+	f.parent, e.h = e.h, f.entry
+	return nil
+}
+
+func (e *HashEmitter) EmitMapEnd() error {
+	i := len(e.frames) - 1
+	f := &e.frames[i]
+
+	if f.pending {
+		e.addEntry(f)
+	}
+
+	e.frames = e.frames[:i]
+	e.h = f.parent
+	e.emitUint(hashMap, f.count)
+	e.h.Write(f.sum[:])
+	return nil
+}
+
+func (e *HashEmitter) EmitMapValue() error {
+	e.frames[len(e.frames)-1].pending = true
+	return nil
+}
+
+func (e *HashEmitter) EmitMapNext() error {
+	e.addEntry(&e.frames[len(e.frames)-1])
+	return nil
+}
+
+// Abort discards the maps that were left open when encoding a value failed, the
+// next values are written to the hash again.
+func (e *HashEmitter) Abort() error {
+	if len(e.frames) != 0 {
+		e.h = e.frames[0].parent
+		e.frames = e.frames[:0]
+	}
+	return nil
+}
+
+func (e *HashEmitter) CanonicalEmitter() Emitter {
+	return e
+}
+
+func (e *HashEmitter) addEntry(f *hashFrame) {
+	s := f.entry.Sum(e.s[:0])
+	c := uint(0)
+
+	// The hashes of map entries are added so the result doesn't depend on the
+	// order of the entries.
+	for i := len(s) - 1; i >= 0; i-- {
+		c += uint(f.sum[i]) + uint(s[i])
+		f.sum[i] = byte(c)
+		c >>= 8
+	}
+
+	f.entry.Reset()
+	f.count++
+	f.pending = false
+}
+
+func (e *HashEmitter) emitTag(tag byte) error {
+	e.b[0] = tag
+	e.h.Write(e.b[:1])
+	return nil
+}
+
+func (e *HashEmitter) emitUint(tag byte, v uint64) error {
+	e.b[0] = tag
+	binary.BigEndian.PutUint64(e.b[1:], v)
+	e.h.Write(e.b[:9])
+	return nil
+}
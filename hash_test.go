@@ -0,0 +1,146 @@
+package objconv
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func hashOf(t *testing.T, v interface{}) string {
+	h := sha256.New()
+	if err := Hash(h, v); err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func TestHashEqual(t *testing.T) {
+	type point struct {
+		X int     `objconv:"x"`
+		Y uint8   `objconv:"y"`
+		Z float64 `objconv:"z,omitempty"`
+	}
+
+	type shape struct {
+		Name   string  `objconv:"name"`
+		Points []point `objconv:"points"`
+	}
+
+	date := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	doc, _ := ValueOf(map[string]interface{}{
+		"points": []interface{}{map[string]interface{}{"y": 2, "x": 1}},
+		"name":   "A",
+	})
+
+	tests := [][]interface{}{
+		{1, uint8(1), int64(1), uint64(1), 1.0, float32(1)},
+		{-1, int8(-1), -1.0},
+		{0, uint(0), 0.0, -0.0},
+		{uint64(1 << 63), float64(1 << 63)},
+		{"hello", []byte("hello"), StringValue("hello")},
+		{date, date.In(time.FixedZone("UTC+2", 7200))},
+		{[]int{1, 2, 3}, []uint{1, 2, 3}, []interface{}{1, uint(2), 3.0}, [3]int{1, 2, 3}},
+		{
+			shape{Name: "A", Points: []point{{X: 1, Y: 2}}},
+			map[string]interface{}{"name": "A", "points": []map[string]int{{"x": 1, "y": 2}}},
+			map[interface{}]interface{}{"points": []interface{}{map[interface{}]interface{}{"y": 2, "x": 1}}, "name": "A"},
+			doc,
+		},
+	}
+
+	for _, test := range tests {
+		h := hashOf(t, test[0])
+
+		for _, v := range test[1:] {
+			if s := hashOf(t, v); s != h {
+				t.Errorf("%#v and %#v have different hashes", test[0], v)
+			}
+		}
+	}
+}
+
+func TestHashNested(t *testing.T) {
+	type level struct {
+		A int               `objconv:"a"`
+		B *level            `objconv:"b,omitempty"`
+		C map[string]string `objconv:"c"`
+	}
+
+	v := map[string]interface{}{"e": 1}
+	s := &level{A: 1, C: map[string]string{"k": "v"}}
+
+	for i := 0; i != 10; i++ {
+		v = map[string]interface{}{"d": v}
+		s = &level{A: i, B: s, C: map[string]string{"k": "v"}}
+		hashOf(t, v)
+		hashOf(t, s)
+	}
+
+	// A reset emitter keeps the frames of the values it hashed before, make
+	// sure it produces the same hashes than a new emitter.
+	h1 := sha256.New()
+	h2 := sha256.New()
+	e := NewHashEmitter(h1)
+
+	for _, x := range []interface{}{v, s} {
+		h1.Reset()
+		h2.Reset()
+		e.Reset(h1)
+
+		if err := (Encoder{Emitter: e}).Encode(x); err != nil {
+			t.Fatal(err)
+		}
+		if err := Hash(h2, x); err != nil {
+			t.Fatal(err)
+		}
+		if string(h1.Sum(nil)) != string(h2.Sum(nil)) {
+			t.Errorf("%#v hashed differently by a reused emitter", x)
+		}
+	}
+}
+
+func TestHashNotEqual(t *testing.T) {
+	values := []interface{}{
+		nil,
+		false,
+		true,
+		0,
+		1,
+		-1,
+		1.5,
+		"",
+		"1",
+		"a",
+		[]interface{}{},
+		[]interface{}{nil},
+		[]interface{}{[]interface{}{}},
+		[]interface{}{[]interface{}{}, []interface{}{}},
+		[]interface{}{[]interface{}{[]interface{}{}}},
+		[]interface{}{"ab"},
+		[]interface{}{"a", "b"},
+		map[string]interface{}{},
+		map[string]interface{}{"a": nil},
+		map[string]interface{}{"a": 1},
+		map[string]interface{}{"a": 1, "b": 2},
+		map[string]interface{}{"a": 2, "b": 1},
+		map[string]interface{}{"a": map[string]interface{}{}},
+		map[string]interface{}{"ab": ""},
+		map[string]interface{}{"a": "b"},
+		time.Second,
+		time.Unix(1, 0),
+		fmt.Errorf("a"),
+	}
+
+	hashes := make(map[string]interface{})
+
+	for _, v := range values {
+		h := hashOf(t, v)
+
+		if x, ok := hashes[h]; ok {
+			t.Errorf("%#v and %#v have the same hash", x, v)
+		}
+
+		hashes[h] = v
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"strings"
 	"sync"
 	"testing"
+
+	"github.com/segmentio/objconv"
 )
 
 type codeResponse struct {
@@ -151,6 +153,69 @@ func BenchmarkCodeUnmarshalReuse(b *testing.B) {
 	b.SetBytes(int64(len(codeJSON)))
 }
 
+func BenchmarkCodeConvert(b *testing.B) {
+	codeInit()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var r codeResponse
+		if err := objconv.Convert(&r, &codeStruct); err != nil {
+			b.Fatal("Convert:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
+
+func BenchmarkCodeMarshalUnmarshal(b *testing.B) {
+	codeInit()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var r codeResponse
+		data, err := Marshal(&codeStruct)
+		if err != nil {
+			b.Fatal("Marshal:", err)
+		}
+		if err := Unmarshal(data, &r); err != nil {
+			b.Fatal("Unmarshal:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
+
+var benchConfig = map[string]interface{}{
+	"username": "agl",
+	"tree": map[string]interface{}{
+		"name":      "/",
+		"cl_weight": 1.5,
+		"touches":   42,
+		"kids": []interface{}{
+			map[string]interface{}{"name": "src", "min_t": 1, "max_t": 10, "mean_t": 5},
+			map[string]interface{}{"name": "doc", "min_t": 2, "max_t": 20, "mean_t": 10},
+		},
+	},
+}
+
+func BenchmarkConfigConvert(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var r codeResponse
+		if err := objconv.Convert(&r, benchConfig); err != nil {
+			b.Fatal("Convert:", err)
+		}
+	}
+}
+
+func BenchmarkConfigMarshalUnmarshal(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var r codeResponse
+		data, err := Marshal(benchConfig)
+		if err != nil {
+			b.Fatal("Marshal:", err)
+		}
+		if err := Unmarshal(data, &r); err != nil {
+			b.Fatal("Unmarshal:", err)
+		}
+	}
+}
+
 func BenchmarkUnmarshalString(b *testing.B) {
 	data := []byte(`"hello, world"`)
 	var s string
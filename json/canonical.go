@@ -0,0 +1,372 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/segmentio/objconv"
+)
+
+// CanonicalEmitter implements a JSON emitter that produces the canonical form
+// defined by the JSON Canonicalization Scheme (RFC 8785).
+//
+// The emitter outputs no whitespace, sorts map keys by their UTF-16 code
+// units, formats numbers like ECMAScript does, and only escapes the characters
+// that must be escaped in JSON strings. Regardless of the order in which map
+// keys or struct fields are emitted, the output is the same for equal values.
+//
+// Integers that cannot be represented exactly by a double precision float,
+// which are not valid in I-JSON, are written in full rather than being
+// rounded.
+//
+// Maps are buffered in memory until they end so their keys can be sorted.
+type CanonicalEmitter struct {
+	Emitter
+	w     io.Writer    // the output writer, while e.Emitter.w points to the buffer
+	b     bytes.Buffer // buffer where the maps are written before being sorted
+	c     []byte       // copy of a map being sorted
+	stack []canonicalFrame
+	maps  int // number of maps being buffered
+}
+
+type canonicalFrame struct {
+	typ  objconv.Type // Array or Map
+	key  bool         // whether the next value is a map key
+	offs []int        // offsets of map keys and values in the buffer
+	keys []string     // map keys
+}
+
+type canonicalEntry struct {
+	key   string
+	entry []byte
+	sep   int // offset of the value in entry
+}
+
+// NewCanonicalEmitter returns a new emitter which writes the canonical JSON
+// representation of values to w.
+func NewCanonicalEmitter(w io.Writer) *CanonicalEmitter {
+	e := &CanonicalEmitter{Emitter: *NewEmitter(w)}
+	e.Emitter.s = e.Emitter.a[:0]
+	e.w = e.Emitter.w
+	return e
+}
+
+func (e *CanonicalEmitter) Reset(w io.Writer) {
+	e.Emitter.Reset(w)
+	e.w = e.Emitter.w
+	e.b.Reset()
+	e.stack = e.stack[:0]
+	e.maps = 0
+}
+
+func (e *CanonicalEmitter) Abort() error {
+	e.Emitter.w = e.w
+	e.b.Reset()
+	e.stack = e.stack[:0]
+	e.maps = 0
+	return e.Emitter.Abort()
+}
+
+func (e *CanonicalEmitter) EmitInt(v int64, _ int) (err error) {
+	_, err = e.Emitter.w.Write(strconv.AppendInt(e.s[:0], v, 10))
+	return
+}
+
+func (e *CanonicalEmitter) EmitUint(v uint64, _ int) (err error) {
+	_, err = e.Emitter.w.Write(strconv.AppendUint(e.s[:0], v, 10))
+	return
+}
+
+func (e *CanonicalEmitter) EmitFloat(v float64, _ int) (err error) {
+	var s []byte
+
+	if s, err = appendCanonicalFloat(e.s[:0], v); err == nil {
+		_, err = e.Emitter.w.Write(s)
+	}
+
+	return
+}
+
+func (e *CanonicalEmitter) EmitString(v string) (err error) {
+	if i := len(e.stack) - 1; i >= 0 {
+		if f := &e.stack[i]; f.key {
+			f.keys = append(f.keys, v)
+			f.key = false
+		}
+	}
+
+	var s []byte
+
+	if s, err = appendCanonicalString(e.s[:0], v); err == nil {
+		e.s = s[:0] // in case the buffer was reallocated
+		_, err = e.Emitter.w.Write(s)
+	}
+
+	return
+}
+
+func (e *CanonicalEmitter) EmitTime(v time.Time) error {
+	return e.EmitString(v.Format(time.RFC3339Nano))
+}
+
+func (e *CanonicalEmitter) EmitError(v error) error {
+	return e.EmitString(v.Error())
+}
+
+func (e *CanonicalEmitter) EmitArrayBegin(n int) error {
+	e.push(objconv.Array)
+	return e.Emitter.EmitArrayBegin(n)
+}
+
+func (e *CanonicalEmitter) EmitArrayEnd() error {
+	e.pop()
+	return e.Emitter.EmitArrayEnd()
+}
+
+func (e *CanonicalEmitter) EmitMapBegin(_ int) (err error) {
+	if e.maps++; e.maps == 1 {
+		e.Emitter.w = &e.b
+	}
+
+	f := e.push(objconv.Map)
+	f.key = true
+	f.offs = append(f.offs, e.b.Len())
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapValue() (err error) {
+	f := e.top()
+	f.key = false
+	f.offs = append(f.offs, e.b.Len())
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapNext() (err error) {
+	f := e.top()
+	f.key = true
+	f.offs = append(f.offs, e.b.Len())
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapEnd() (err error) {
+	f := e.pop()
+	start := f.offs[0]
+	e.c = append(e.c[:0], e.b.Bytes()[start:]...)
+	e.b.Truncate(start)
+
+	entries := make([]canonicalEntry, len(f.offs)/2)
+
+	for i := range entries {
+		k := f.offs[2*i] - start
+		v := f.offs[2*i+1] - start
+		end := len(e.c)
+
+		if j := 2*i + 2; j < len(f.offs) {
+			end = f.offs[j] - start
+		}
+
+		entries[i] = canonicalEntry{entry: e.c[k:end], sep: v - k}
+
+		if len(f.keys) == len(entries) {
+			entries[i].key = f.keys[i]
+		} else {
+			// Some keys were not strings, falling back to sorting by their
+			// serialized representation.
+			entries[i].key = string(e.c[k:v])
+		}
+	}
+
+	sort.Slice(entries, func(i int, j int) bool {
+		return lessUTF16(entries[i].key, entries[j].key)
+	})
+
+	e.b.Write(mapOpen[:])
+
+	for i, entry := range entries {
+		if i != 0 {
+			e.b.Write(comma[:])
+		}
+		e.b.Write(entry.entry[:entry.sep])
+		e.b.Write(column[:])
+		e.b.Write(entry.entry[entry.sep:])
+	}
+
+	e.b.Write(mapClose[:])
+
+	if e.maps--; e.maps == 0 {
+		e.Emitter.w = e.w
+		_, err = e.b.WriteTo(e.w)
+		e.b.Reset()
+	}
+
+	return
+}
+
+func (e *CanonicalEmitter) CanonicalEmitter() objconv.Emitter {
+	return e
+}
+
+func (e *CanonicalEmitter) PrettyEmitter() objconv.Emitter {
+	return e
+}
+
+func (e *CanonicalEmitter) push(t objconv.Type) *canonicalFrame {
+	e.stack = append(e.stack, canonicalFrame{typ: t})
+	return e.top()
+}
+
+func (e *CanonicalEmitter) pop() canonicalFrame {
+	i := len(e.stack) - 1
+	f := e.stack[i]
+	e.stack = e.stack[:i]
+	return f
+}
+
+func (e *CanonicalEmitter) top() *canonicalFrame {
+	return &e.stack[len(e.stack)-1]
+}
+
+// appendCanonicalString appends the JSON representation of s to b, escaping
+// only the characters required by RFC 8785.
+func appendCanonicalString(b []byte, s string) ([]byte, error) {
+	const hex = "0123456789abcdef"
+
+	if !utf8.ValidString(s) {
+		return b, errors.New("objconv/json: invalid UTF-8 sequence in string of canonical JSON")
+	}
+
+	b = append(b, '"')
+	i := 0
+
+	for j := 0; j != len(s); j++ {
+		c := s[j]
+
+		if c >= 0x20 && c != '"' && c != '\\' {
+			continue
+		}
+
+		b = append(b, s[i:j]...)
+		i = j + 1
+
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		}
+	}
+
+	b = append(b, s[i:]...)
+	b = append(b, '"')
+	return b, nil
+}
+
+// appendCanonicalFloat appends the representation of f to b, using the
+// algorithm of ECMAScript's Number.prototype.toString as required by RFC 8785.
+func appendCanonicalFloat(b []byte, f float64) ([]byte, error) {
+	switch {
+	case math.IsNaN(f):
+		return b, errors.New("NaN has no json representation")
+	case math.IsInf(f, 0):
+		return b, errors.New("Inf has no json representation")
+	case f == 0: // includes -0
+		return append(b, '0'), nil
+	case f < 0:
+		b, f = append(b, '-'), -f
+	}
+
+	// The shortest representation of f is formatted in scientific notation,
+	// then split into its significant digits and exponent.
+	var a [32]byte
+	var d [24]byte
+	s := strconv.AppendFloat(a[:0], f, 'e', -1, 64)
+	i := bytes.IndexByte(s, 'e')
+	x, _ := strconv.Atoi(string(s[i+1:]))
+	digits := append(d[:0], s[0])
+
+	if i > 1 { // skip the decimal point
+		digits = append(digits, s[2:i]...)
+	}
+
+	n := x + 1 // position of the decimal point
+	k := len(digits)
+
+	switch {
+	case k <= n && n <= 21:
+		b = append(b, digits...)
+		for ; k != n; k++ {
+			b = append(b, '0')
+		}
+
+	case 0 < n && n <= 21:
+		b = append(b, digits[:n]...)
+		b = append(b, '.')
+		b = append(b, digits[n:]...)
+
+	case -6 < n && n <= 0:
+		b = append(b, '0', '.')
+		for ; n != 0; n++ {
+			b = append(b, '0')
+		}
+		b = append(b, digits...)
+
+	default:
+		b = append(b, digits[0])
+		if k > 1 {
+			b = append(b, '.')
+			b = append(b, digits[1:]...)
+		}
+		b = append(b, 'e')
+		if x >= 0 {
+			b = append(b, '+')
+		}
+		b = strconv.AppendInt(b, int64(x), 10)
+	}
+
+	return b, nil
+}
+
+// lessUTF16 compares two strings by their UTF-16 code units.
+func lessUTF16(a string, b string) bool {
+	for len(a) != 0 && len(b) != 0 {
+		r1, n1 := utf8.DecodeRuneInString(a)
+		r2, n2 := utf8.DecodeRuneInString(b)
+
+		if r1 != r2 {
+			h1, l1 := utf16Units(r1)
+			h2, l2 := utf16Units(r2)
+
+			if h1 != h2 {
+				return h1 < h2
+			}
+			return l1 < l2
+		}
+
+		a, b = a[n1:], b[n2:]
+	}
+	return len(a) < len(b)
+}
+
+func utf16Units(r rune) (rune, rune) {
+	if r < 0x10000 {
+		return r, 0
+	}
+	return utf16.EncodeRune(r)
+}
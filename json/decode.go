@@ -15,7 +15,7 @@ func NewDecoder(r io.Reader) *objconv.Decoder {
 
 // NewStreamDecoder returns a new JSON stream decoder that parses values from r.
 func NewStreamDecoder(r io.Reader) *objconv.StreamDecoder {
-	return objconv.NewStreamDecoder(NewParser(r))
+	return Codec.NewStreamDecoder(r)
 }
 
 // Unmarshal decodes a JSON representation of v from b.
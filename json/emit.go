@@ -32,20 +32,26 @@ var (
 
 // Emitter implements a JSON emitter that satisfies the objconv.Emitter
 // interface.
+//
+// The output is buffered while arrays and maps are being emitted, see
+// objutil.BufferedWriter.
 type Emitter struct {
-	w io.Writer
-	s []byte
-	a [128]byte
+	w   io.Writer
+	out *objutil.BufferedWriter
+	s   []byte
+	a   [128]byte
 }
 
 func NewEmitter(w io.Writer) *Emitter {
-	e := &Emitter{w: w}
+	b := objutil.NewBufferedWriter(w)
+	e := &Emitter{w: b, out: b}
 	e.s = e.a[:0]
 	return e
 }
 
 func (e *Emitter) Reset(w io.Writer) {
-	e.w = w
+	e.out.Reset(w)
+	e.w = e.out
 }
 
 func (e *Emitter) EmitNil() (err error) {
@@ -182,12 +188,15 @@ func (e *Emitter) EmitError(v error) (err error) {
 }
 
 func (e *Emitter) EmitArrayBegin(_ int) (err error) {
+	e.out.Push()
 	_, err = e.w.Write(arrayOpen[:])
 	return
 }
 
 func (e *Emitter) EmitArrayEnd() (err error) {
-	_, err = e.w.Write(arrayClose[:])
+	if _, err = e.w.Write(arrayClose[:]); err == nil {
+		err = e.out.Pop()
+	}
 	return
 }
 
@@ -197,12 +206,15 @@ func (e *Emitter) EmitArrayNext() (err error) {
 }
 
 func (e *Emitter) EmitMapBegin(_ int) (err error) {
+	e.out.Push()
 	_, err = e.w.Write(mapOpen[:])
 	return
 }
 
 func (e *Emitter) EmitMapEnd() (err error) {
-	_, err = e.w.Write(mapClose[:])
+	if _, err = e.w.Write(mapClose[:]); err == nil {
+		err = e.out.Pop()
+	}
 	return
 }
 
@@ -216,6 +228,17 @@ func (e *Emitter) EmitMapNext() (err error) {
 	return
 }
 
+// Flush writes the buffered output to the underlying writer.
+func (e *Emitter) Flush() error {
+	return e.out.Flush()
+}
+
+// Abort writes the buffered output to the underlying writer and discards the
+// state of the arrays and maps that were left open when encoding a value failed.
+func (e *Emitter) Abort() error {
+	return e.out.Abort()
+}
+
 func (e *Emitter) TextEmitter() bool {
 	return true
 }
@@ -224,6 +247,10 @@ func (e *Emitter) PrettyEmitter() objconv.Emitter {
 	return NewPrettyEmitter(e.w)
 }
 
+func (e *Emitter) CanonicalEmitter() objconv.Emitter {
+	return NewCanonicalEmitter(e.w)
+}
+
 func align(n int, a int) int {
 	if (n % a) == 0 {
 		return n
@@ -252,6 +279,12 @@ func (e *PrettyEmitter) Reset(w io.Writer) {
 	e.s = e.s[:0]
 }
 
+func (e *PrettyEmitter) Abort() error {
+	e.i = 0
+	e.s = e.s[:0]
+	return e.Emitter.Abort()
+}
+
 func (e *PrettyEmitter) EmitArrayBegin(n int) (err error) {
 	if err = e.Emitter.EmitArrayBegin(n); err != nil {
 		return
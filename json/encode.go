@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/objutil"
 )
 
 // NewEncoder returns a new JSON encoder that writes to w.
@@ -28,6 +29,18 @@ func NewPrettyStreamEncoder(w io.Writer) *objconv.StreamEncoder {
 	return objconv.NewStreamEncoder(NewPrettyEmitter(w))
 }
 
+// NewCanonicalEncoder returns a new JSON encoder that writes to w in the
+// canonical format defined by RFC 8785.
+func NewCanonicalEncoder(w io.Writer) *objconv.Encoder {
+	return objconv.NewEncoder(NewCanonicalEmitter(w))
+}
+
+// NewCanonicalStreamEncoder returns a new JSON stream encoder that writes to w
+// in the canonical format defined by RFC 8785.
+func NewCanonicalStreamEncoder(w io.Writer) *objconv.StreamEncoder {
+	return objconv.NewStreamEncoder(NewCanonicalEmitter(w))
+}
+
 // Marshal writes the JSON representation of v to a byte slice returned in b.
 func Marshal(v interface{}) (b []byte, err error) {
 	m := marshalerPool.Get().(*marshaler)
@@ -42,6 +55,27 @@ func Marshal(v interface{}) (b []byte, err error) {
 	return
 }
 
+// Append appends the JSON representation of v to dst and returns the extended
+// buffer. Unlike Marshal, the value is written directly to dst, so no copy is
+// made when dst has enough capacity. On error, dst is returned unchanged.
+func Append(dst []byte, v interface{}) (b []byte, err error) {
+	m := marshalerPool.Get().(*marshaler)
+	buf := m.b.Bytes()[:0]
+	m.b = *bytes.NewBuffer(dst)
+
+	if err = (objconv.Encoder{Emitter: m}).Encode(v); err == nil {
+		b = m.b.Bytes()
+	} else {
+		b = dst
+	}
+
+	// The buffer of the marshaler is restored so dst isn't retained by the
+	// pool.
+	m.b = *bytes.NewBuffer(buf)
+	marshalerPool.Put(m)
+	return
+}
+
 var marshalerPool = sync.Pool{
 	New: func() interface{} { return newMarshaler() },
 }
@@ -55,5 +89,6 @@ func newMarshaler() *marshaler {
 	m := &marshaler{}
 	m.s = m.a[:0]
 	m.w = &m.b
+	m.out = objutil.NewBufferedWriter(&m.b)
 	return m
 }
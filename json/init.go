@@ -18,6 +18,26 @@ var PrettyCodec = objconv.Codec{
 	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
 }
 
+// CanonicalCodec for the JSON format, producing the output defined by RFC 8785.
+var CanonicalCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewCanonicalEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+}
+
+// NDJSONCodec for newline-delimited JSON streams, where each value is written on
+// its own line.
+var NDJSONCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewNDJSONEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewNDJSONParser(r) },
+}
+
+// SequenceCodec for streams of concatenated JSON values, which may span multiple
+// lines. Stream encoders write each value on its own line, like NDJSONCodec.
+var SequenceCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewNDJSONEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewSequenceParser(r) },
+}
+
 func init() {
 	for _, name := range [...]string{
 		"application/json",
@@ -26,4 +46,12 @@ func init() {
 	} {
 		objconv.Register(name, Codec)
 	}
+
+	for _, name := range [...]string{
+		"application/x-ndjson",
+		"application/jsonl",
+		"ndjson",
+	} {
+		objconv.Register(name, NDJSONCodec)
+	}
 }
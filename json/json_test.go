@@ -1,11 +1,21 @@
 package json
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math"
+	"net"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/segmentio/objconv"
 	"github.com/segmentio/objconv/objtests"
 )
 
@@ -25,6 +35,111 @@ func BenchmarkPrettyCodec(b *testing.B) {
 	objtests.BenchmarkCodec(b, PrettyCodec)
 }
 
+func TestCanonicalCodec(t *testing.T) {
+	objtests.TestCodec(t, CanonicalCodec)
+}
+
+func TestNDJSONCodec(t *testing.T) {
+	objtests.TestCodec(t, NDJSONCodec)
+}
+
+func TestSequenceCodec(t *testing.T) {
+	objtests.TestCodec(t, SequenceCodec)
+}
+
+func TestTracingCodec(t *testing.T) {
+	trace := func(objconv.Token, error) {}
+	objtests.TestCodec(t, objconv.Codec{
+		NewEmitter: func(w io.Writer) objconv.Emitter { return objconv.NewTracingEmitter(NewEmitter(w), trace) },
+		NewParser:  func(r io.Reader) objconv.Parser { return objconv.NewTracingParser(NewParser(r), trace) },
+	})
+}
+
+func TestMetricsCodec(t *testing.T) {
+	m := &objconv.Metrics{}
+	objtests.TestCodec(t, objconv.Codec{
+		NewEmitter: func(w io.Writer) objconv.Emitter { return objconv.NewMetricsEmitter(NewEmitter(m.Writer(w)), m) },
+		NewParser:  func(r io.Reader) objconv.Parser { return objconv.NewMetricsParser(NewParser(m.Reader(r)), m) },
+	})
+}
+
+func TestWrappedEmitterOutput(t *testing.T) {
+	val := map[string]interface{}{"a": []interface{}{1, "2", []byte("3")}}
+
+	b1 := &bytes.Buffer{}
+	b2 := &bytes.Buffer{}
+	b3 := &bytes.Buffer{}
+	m := &objconv.Metrics{}
+
+	e := objconv.Emitter(objconv.NewTeeEmitter(
+		objconv.NewMetricsEmitter(NewEmitter(m.Writer(b2)), m),
+		objconv.NewTracingEmitter(NewEmitter(b3), objconv.TraceWriter(ioutil.Discard)),
+	))
+	e = e.(objconv.PrettyEmitter).PrettyEmitter()
+
+	if err := objconv.NewEncoder(NewPrettyEmitter(b1)).Encode(val); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := objconv.NewEncoder(e).Encode(val); err != nil {
+		t.Fatal(err)
+	}
+
+	if b1.String() != b2.String() || b1.String() != b3.String() {
+		t.Errorf("wrapped emitters produced different outputs:\n%s\n%s\n%s", b1, b2, b3)
+	}
+
+	if n := m.BytesWritten(); n != int64(b1.Len()) {
+		t.Errorf("%d bytes were counted instead of %d", n, b1.Len())
+	}
+}
+
+func TestCanonicalEncoding(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		s string
+	}{
+		// Numbers from the examples of appendix B of RFC 8785.
+		{0.0, `0`},
+		{math.Copysign(0, -1), `0`},
+		{5e-324, `5e-324`},
+		{1.7976931348623157e308, `1.7976931348623157e+308`},
+		{9007199254740992.0, `9007199254740992`},
+		{295147905179352830000.0, `295147905179352830000`},
+		{1e21, `1e+21`},
+		{1e-7, `1e-7`},
+		{0.000001, `0.000001`},
+		{333333333.3333332, `333333333.3333332`},
+		{-1.5, `-1.5`},
+		{0.5, `0.5`},
+		{uint64(18446744073709551615), `18446744073709551615`},
+
+		// Strings only escape the characters that must be escaped.
+		{"\u20ac$\u000f\nA'B\"\\/\u007f", "\"\u20ac$\\u000f\\nA'B\\\"\\\\/\u007f\""},
+
+		// Map keys are sorted by their UTF-16 code units.
+		{map[string]int{"\ufb33": 0, "\U0001f600": 1, "\u20ac": 2, "\u00f6": 3, "\u0080": 4, "1": 5, "\r": 6}, "{\"\\r\":6,\"1\":5,\"\u0080\":4,\"\u00f6\":3,\"\u20ac\":2,\"\U0001f600\":1,\"\ufb33\":0}"},
+		{struct {
+			B []interface{} `objconv:"b"`
+			A interface{}   `objconv:"a"`
+		}{[]interface{}{map[string]int{"y": 1, "x": 2}}, map[string]int{"d": 1, "c": 2}}, `{"a":{"c":2,"d":1},"b":[{"x":2,"y":1}]}`},
+	}
+
+	for _, test := range tests {
+		b := &bytes.Buffer{}
+
+		if err := NewCanonicalEncoder(b).Encode(test.v); err != nil {
+			t.Error(err)
+		} else if s := b.String(); s != test.s {
+			t.Errorf("%#v: %s != %s", test.v, s, test.s)
+		}
+	}
+
+	if err := NewCanonicalEncoder(&bytes.Buffer{}).Encode("\xff"); err == nil {
+		t.Error("expected an error encoding a string with invalid UTF-8")
+	}
+}
+
 func TestUnicode(t *testing.T) {
 	tests := []struct {
 		in  string
@@ -85,3 +200,1012 @@ func TestEmitImpossibleFloats(t *testing.T) {
 		})
 	}
 }
+
+func TestMarshalChan(t *testing.T) {
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+
+	i := 0
+	gen := objconv.GeneratorFunc(func() (interface{}, error) {
+		if i++; i > 2 {
+			return nil, objconv.End
+		}
+		return i, nil
+	})
+
+	b, err := Marshal(struct {
+		Chan <-chan int            `json:"chan"`
+		Gen  objconv.GeneratorFunc `json:"gen"`
+	}{ch, gen})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(b); s != `{"chan":[1,2,3],"gen":[1,2]}` {
+		t.Error(s)
+	}
+}
+
+func TestStreamContextInterruptsBlockedIO(t *testing.T) {
+	t.Run("Decoder", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		go c2.Write([]byte(`[1,`))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		d := Codec.NewStreamDecoderContext(ctx, c1)
+		v := 0
+
+		if err := d.Decode(&v); err != nil || v != 1 {
+			t.Fatal(v, err)
+		}
+
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		var e *objconv.StreamError
+
+		if err := d.Decode(&v); !errors.As(err, &e) || e.Index != 1 || !errors.Is(err, context.Canceled) {
+			t.Errorf("invalid error returned after canceling the context: %v", err)
+		}
+	})
+
+	t.Run("Encoder", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		e := Codec.NewStreamEncoderContext(ctx, c1)
+		e.FlushCount = 1
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		// Nothing reads from the other end of the pipe, the write blocks until
+		// the context is canceled.
+		if err := e.Encode(1); !errors.Is(err, context.Canceled) {
+			t.Errorf("invalid error returned after canceling the context: %v", err)
+		}
+	})
+}
+
+func TestCopyStream(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		n   int
+	}{
+		{``, ``, 0},
+		{`[]`, `[]`, 0},
+		{`42`, `42`, 1},
+		{`[1, "a", {"b": [], "a": null}]`, `[1,"a",{"b":[],"a":null}]`, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			n, err := objconv.CopyStream(NewEmitter(b), NewParser(strings.NewReader(test.in)))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n != test.n {
+				t.Errorf("invalid number of values copied: %d != %d", n, test.n)
+			}
+
+			if s := b.String(); s != test.out {
+				t.Errorf("%q != %q", s, test.out)
+			}
+		})
+	}
+}
+
+// writesRecorder records the buffers passed to each call to Write.
+type writesRecorder struct {
+	writes []string
+}
+
+func (w *writesRecorder) Write(b []byte) (int, error) {
+	w.writes = append(w.writes, string(b))
+	return len(b), nil
+}
+
+// failingValue is a value which fails to be encoded.
+type failingValue struct{}
+
+func (failingValue) EncodeValue(objconv.Encoder) error {
+	return errors.New("failing value")
+}
+
+func TestEncodeAfterError(t *testing.T) {
+	emitters := []struct {
+		name string
+		new  func(io.Writer) objconv.Emitter
+	}{
+		{"Emitter", func(w io.Writer) objconv.Emitter { return NewEmitter(w) }},
+		{"PrettyEmitter", func(w io.Writer) objconv.Emitter { return NewPrettyEmitter(w) }},
+		{"CanonicalEmitter", func(w io.Writer) objconv.Emitter { return NewCanonicalEmitter(w) }},
+	}
+
+	for _, x := range emitters {
+		t.Run(x.name, func(t *testing.T) {
+			v := map[string]interface{}{"a": []interface{}{1}}
+			b := &bytes.Buffer{}
+
+			if err := objconv.NewEncoder(x.new(b)).Encode(v); err != nil {
+				t.Fatal(err)
+			}
+
+			w := &writesRecorder{}
+			e := objconv.NewEncoder(x.new(w))
+
+			if err := e.Encode(map[string]interface{}{"a": []interface{}{1, failingValue{}}}); err == nil {
+				t.Fatal("expected an error when encoding a failing value")
+			}
+
+			w.writes = nil
+
+			// The arrays and maps left open by the failure must not hold the
+			// next values in the emitter.
+			if err := e.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+
+			if s := strings.Join(w.writes, ""); s != b.String() {
+				t.Errorf("%q != %q", s, b.String())
+			}
+		})
+	}
+}
+
+func TestNDJSONStreamEncoder(t *testing.T) {
+	w := &writesRecorder{}
+	e := NDJSONCodec.NewStreamEncoder(w)
+
+	for _, v := range []interface{}{
+		1,
+		"hello\nworld",
+		map[string]interface{}{"a": []int{1, 2}},
+		[]interface{}{},
+		nil,
+	} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each line must be written with a single call to Write.
+	expect := []string{
+		"1\n",
+		`"hello\nworld"` + "\n",
+		`{"a":[1,2]}` + "\n",
+		"[]\n",
+		"null\n",
+	}
+
+	if !reflect.DeepEqual(w.writes, expect) {
+		t.Errorf("%q != %q", w.writes, expect)
+	}
+}
+
+func TestEmitterBuffering(t *testing.T) {
+	w := &writesRecorder{}
+
+	if err := Codec.NewEncoder(w).Encode(map[string][]int{"a": {1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A complete value is written to the output with a single call to Write.
+	if expect := []string{`{"a":[1,2]}`}; !reflect.DeepEqual(w.writes, expect) {
+		t.Errorf("%q != %q", w.writes, expect)
+	}
+
+	w.writes = nil
+	e := Codec.NewStreamEncoder(w)
+
+	for _, v := range []int{1, 2, 3} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a flush policy each value of the stream is written when it was
+	// encoded.
+	if expect := []string{`[1`, `,2`, `,3`, `]`}; !reflect.DeepEqual(w.writes, expect) {
+		t.Errorf("%q != %q", w.writes, expect)
+	}
+
+	w.writes = nil
+	e = Codec.NewStreamEncoder(w)
+	e.FlushCount = 10
+
+	for _, v := range []int{1, 2, 3} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v == 2 {
+			if len(w.writes) != 0 {
+				t.Errorf("values of the stream were written before it was flushed: %q", w.writes)
+			}
+
+			if err := e.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expect := []string{`[1,2`, `,3]`}; !reflect.DeepEqual(w.writes, expect) {
+		t.Errorf("%q != %q", w.writes, expect)
+	}
+}
+
+func TestStreamEncoderPipe(t *testing.T) {
+	r, w := io.Pipe()
+	defer r.Close()
+
+	e := Codec.NewStreamEncoder(w)
+	b := make([]byte, 16)
+
+	for i, expect := range []string{`[1`, `,2`} {
+		errc := make(chan error, 1)
+		go func(v int) { errc <- e.Encode(v) }(i + 1)
+
+		// The value must be delivered to the reader without closing the stream
+		// or waiting for more values.
+		n, err := r.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s := string(b[:n]); s != expect {
+			t.Errorf("%q != %q", s, expect)
+		}
+
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNDJSONStreamDecoder(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []interface{}
+	}{
+		{``, []interface{}{}},
+		{"\n\n", []interface{}{}},
+		{"1\n2\n3\n", []interface{}{int64(1), int64(2), int64(3)}},
+		{"1\n\n  2\r\n3", []interface{}{int64(1), int64(2), int64(3)}},
+		{`{"a":1}{"a":2} [3]` + "\n", []interface{}{
+			map[interface{}]interface{}{"a": int64(1)},
+			map[interface{}]interface{}{"a": int64(2)},
+			[]interface{}{int64(3)},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			d := NDJSONCodec.NewStreamDecoder(strings.NewReader(test.in))
+			values := []interface{}{}
+
+			for {
+				var v interface{}
+
+				if err := d.Decode(&v); err != nil {
+					if err != objconv.End {
+						t.Fatal(err)
+					}
+					break
+				}
+
+				values = append(values, v)
+			}
+
+			if !reflect.DeepEqual(values, test.out) {
+				t.Errorf("%#v != %#v", values, test.out)
+			}
+		})
+	}
+}
+
+func TestNDJSONValueSpanningLines(t *testing.T) {
+	d := NDJSONCodec.NewStreamDecoder(strings.NewReader("[1,\n2]\n"))
+	v := []int{}
+
+	if err := d.Decode(&v); err != io.ErrUnexpectedEOF {
+		t.Errorf("invalid error returned for a value spanning multiple lines: %v", err)
+	}
+}
+
+func TestSequenceStreamDecoder(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []interface{}
+	}{
+		{``, []interface{}{}},
+		{"{\n\"a\": 1\n}\n{\"b\":2}", []interface{}{
+			map[interface{}]interface{}{"a": int64(1)},
+			map[interface{}]interface{}{"b": int64(2)},
+		}},
+		{"1 2\n[3,\n4]\"a\"", []interface{}{
+			int64(1),
+			int64(2),
+			[]interface{}{int64(3), int64(4)},
+			"a",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			d := SequenceCodec.NewStreamDecoder(strings.NewReader(test.in))
+			values := []interface{}{}
+
+			for {
+				var v interface{}
+
+				if err := d.Decode(&v); err != nil {
+					if err != objconv.End {
+						t.Fatal(err)
+					}
+					break
+				}
+
+				values = append(values, v)
+			}
+
+			if !reflect.DeepEqual(values, test.out) {
+				t.Errorf("%#v != %#v", values, test.out)
+			}
+		})
+	}
+
+	t.Run("Truncated", func(t *testing.T) {
+		d := SequenceCodec.NewStreamDecoder(strings.NewReader("[1] [2,\n"))
+		v := []int{}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := d.Decode(&v); err != io.ErrUnexpectedEOF {
+			t.Errorf("invalid error returned for a truncated value: %v", err)
+		}
+	})
+}
+
+func TestNDJSONRecover(t *testing.T) {
+	type point struct {
+		X int `objconv:"x"`
+	}
+
+	in := strings.Join([]string{
+		`{"x":1}`,
+		`{"x":`,
+		`{"x":2}`,
+		`not json`,
+		`{"x":[3]}`,
+		`{"x":4}`,
+	}, "\n")
+
+	decode := func(t *testing.T, d *objconv.StreamDecoder) {
+		skipped := []int{}
+		offsets := []int64{}
+		points := []point{}
+
+		d.Recover = func(e *objconv.StreamError) error {
+			skipped = append(skipped, e.Index)
+			offsets = append(offsets, e.Offset)
+			return nil
+		}
+
+		for {
+			p := point{}
+
+			if err := d.Decode(&p); err != nil {
+				if err != objconv.End {
+					t.Fatal(err)
+				}
+				break
+			}
+
+			points = append(points, p)
+		}
+
+		if expect := []int{1, 3, 4}; !reflect.DeepEqual(skipped, expect) {
+			t.Errorf("invalid indexes of skipped values: %v != %v", skipped, expect)
+		}
+
+		if expect := []int64{8, 22, 31}; !reflect.DeepEqual(offsets, expect) {
+			t.Errorf("invalid offsets of skipped values: %v != %v", offsets, expect)
+		}
+
+		if expect := []point{{1}, {2}, {4}}; !reflect.DeepEqual(points, expect) {
+			t.Errorf("invalid values decoded: %v != %v", points, expect)
+		}
+	}
+
+	decode(t, NDJSONCodec.NewStreamDecoder(strings.NewReader(in)))
+
+	t.Run("Traced", func(t *testing.T) {
+		// The tracing parser forwards the position in the input and the
+		// recovery to the NDJSON parser.
+		b := &bytes.Buffer{}
+		p := objconv.NewTracingParser(NewNDJSONParser(strings.NewReader(in)), objconv.TraceWriter(b))
+		decode(t, objconv.NewStreamDecoder(p))
+
+		if !strings.Contains(b.String(), "int 4\n") {
+			t.Errorf("the values were not traced:\n%s", b)
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		stop := errors.New("stop")
+
+		d := NDJSONCodec.NewStreamDecoder(strings.NewReader(in))
+		d.Recover = func(e *objconv.StreamError) error { return stop }
+
+		p := point{}
+
+		if err := d.Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := d.Decode(&p); err != stop {
+			t.Errorf("invalid error returned when stopping the recovery: %v", err)
+		}
+
+		if err := d.Decode(&p); err != stop {
+			t.Errorf("the error returned when stopping the recovery is not sticky: %v", err)
+		}
+	})
+}
+
+func TestStreamDecoderRecover(t *testing.T) {
+	type point struct {
+		X int `objconv:"x"`
+	}
+
+	in := `[{"x":1}, {"x":tru}, {"x":2}, [{"x":"}"}], {"x":3}, "x"]`
+
+	skipped := []int{}
+	offsets := []int64{}
+	points := []point{}
+
+	d := NewStreamDecoder(strings.NewReader(in))
+	d.Recover = func(e *objconv.StreamError) error {
+		skipped = append(skipped, e.Index)
+		offsets = append(offsets, e.Offset)
+		return nil
+	}
+
+	for {
+		p := point{}
+
+		if err := d.Decode(&p); err != nil {
+			if err != objconv.End {
+				t.Fatal(err)
+			}
+			break
+		}
+
+		points = append(points, p)
+	}
+
+	if expect := []int{1, 3, 5}; !reflect.DeepEqual(skipped, expect) {
+		t.Errorf("invalid indexes of skipped values: %v != %v", skipped, expect)
+	}
+
+	expect := []int64{
+		int64(strings.Index(in, `{"x":tru}`)),
+		int64(strings.Index(in, `[{"x":"}"}]`)),
+		int64(strings.Index(in, `"x"]`)),
+	}
+
+	if !reflect.DeepEqual(offsets, expect) {
+		t.Errorf("invalid offsets of skipped values: %v != %v", offsets, expect)
+	}
+
+	if expect := []point{{1}, {2}, {3}}; !reflect.DeepEqual(points, expect) {
+		t.Errorf("invalid values decoded: %v != %v", points, expect)
+	}
+
+	t.Run("Malformed", func(t *testing.T) {
+		for _, in := range []string{
+			`[1, tru, 3]`,
+			`[1, @, 3]`,
+			`[1, @"],[", 3]`,
+			`[1, @{"a":[",", "\"]"]}, 3]`,
+		} {
+			d := NewStreamDecoder(strings.NewReader(in))
+			skipped := []int{}
+			values := []int{}
+
+			d.Recover = func(e *objconv.StreamError) error {
+				skipped = append(skipped, e.Index)
+				return nil
+			}
+
+			for {
+				var v int
+
+				if err := d.Decode(&v); err != nil {
+					if err != objconv.End {
+						t.Fatalf("%s: %v", in, err)
+					}
+					break
+				}
+
+				values = append(values, v)
+			}
+
+			if !reflect.DeepEqual(skipped, []int{1}) || !reflect.DeepEqual(values, []int{1, 3}) {
+				t.Errorf("%s: skipped = %v, values = %v", in, skipped, values)
+			}
+		}
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		// The stream decoder isn't created by a codec so the values are not
+		// captured, the parser recovers in the middle of the second value.
+		d := objconv.NewStreamDecoder(NewParser(strings.NewReader(`[{"x":1}, {"x":tru, "y":[1,","]}, {"x":2}]`)))
+		d.Recover = func(e *objconv.StreamError) error { return nil }
+
+		points := []point{}
+
+		for {
+			p := point{}
+
+			if err := d.Decode(&p); err != nil {
+				if err != objconv.End {
+					t.Fatal(err)
+				}
+				break
+			}
+
+			points = append(points, p)
+		}
+
+		if expect := []point{{1}, {2}}; !reflect.DeepEqual(points, expect) {
+			t.Errorf("invalid values decoded: %v != %v", points, expect)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		d := NewStreamDecoder(strings.NewReader(`[{"x":1}, {"x":`))
+		d.Recover = func(e *objconv.StreamError) error { return nil }
+
+		p := point{}
+
+		if err := d.Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := d.Decode(&p); err == nil || err == objconv.End {
+			t.Errorf("truncated values must not be recovered from: %v", err)
+		}
+	})
+}
+
+func TestNDJSONParallelDecoder(t *testing.T) {
+	values := []int{}
+
+	err := NDJSONCodec.NewParallelDecoder(strings.NewReader("1\n2\n3 4\n")).Decode(reflect.TypeOf(0), func(i int, v interface{}) error {
+		values = append(values, v.(int))
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expect := []int{1, 2, 3, 4}; !reflect.DeepEqual(values, expect) {
+		t.Errorf("%v != %v", values, expect)
+	}
+}
+
+func TestCopyNDJSONStream(t *testing.T) {
+	b := &bytes.Buffer{}
+	n, err := objconv.CopyStream(NewEmitter(b), NewNDJSONParser(strings.NewReader("1\n[2]\n{}\n")))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 3 {
+		t.Errorf("invalid number of values copied: %d != 3", n)
+	}
+
+	if s := b.String(); s != `[1,[2],{}]` {
+		t.Errorf("%q != %q", s, `[1,[2],{}]`)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	long := strings.Repeat(`\"]}`, 100)
+
+	tests := []string{
+		`null`,
+		`true`,
+		`-1.5e10`,
+		`"hello \"world\""`,
+		`"` + long + `"`,
+		`[]`,
+		`{}`,
+		`[1, "]", {"a": "}", "b": [[], {}]}]`,
+		`{"` + long + `": ["` + long + `"]}`,
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			var v int
+
+			d := objconv.NewDecoder(NewParser(strings.NewReader(test + ` 42`)))
+
+			if err := d.Decode(nil); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if err := d.Decode(&v); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if v != 42 {
+				t.Error("invalid value decoded after skipping:", v)
+			}
+		})
+	}
+}
+
+func TestDecodePathStopsReading(t *testing.T) {
+	var id string
+
+	// The document is truncated after the target value, which would cause an
+	// error if the decoder attempted to read past it.
+	d := objconv.NewDecoder(NewParser(strings.NewReader(`{"skip":{"a":[1,2,"}"]},"meta":{"request_id":"1234","x":`)))
+
+	if err := d.DecodePath(".meta.request_id", &id); err != nil {
+		t.Fatal(err)
+	}
+
+	if id != "1234" {
+		t.Error("bad value:", id)
+	}
+}
+
+func TestDocumentKeyOrder(t *testing.T) {
+	const input = `{"z":1,"a":{"y":[true,null],"b":"x"},"m":-1.5}`
+
+	var v objconv.Value
+
+	if err := Unmarshal([]byte(input), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if x := v.Get("a").Get("y").Index(0); !x.Bool() {
+		t.Error("bad value:", x.Type())
+	}
+
+	b, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(b); s != input {
+		t.Error(s)
+	}
+}
+
+func TestHashDecodedDocument(t *testing.T) {
+	type item struct {
+		ID   uint64   `objconv:"id"`
+		Tags []string `objconv:"tags"`
+	}
+
+	const input = `{"tags":["a","b"],"id":42}`
+
+	var v1 interface{}
+	var v2 objconv.Value
+	var v3 item
+
+	for _, v := range []interface{}{&v1, &v2, &v3} {
+		if err := Unmarshal([]byte(input), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h0 := sha256.New()
+	objconv.Hash(h0, item{ID: 42, Tags: []string{"a", "b"}})
+
+	for _, v := range []interface{}{v1, v2, v3} {
+		h := sha256.New()
+
+		if err := objconv.Hash(h, v); err != nil {
+			t.Error(err)
+		} else if !bytes.Equal(h.Sum(nil), h0.Sum(nil)) {
+			t.Errorf("bad hash for %#v", v)
+		}
+	}
+}
+
+func TestHashDecodedNestedDocument(t *testing.T) {
+	type leaf struct {
+		E int `objconv:"e"`
+	}
+	type level3 struct {
+		D leaf `objconv:"d"`
+	}
+	type level2 struct {
+		C level3 `objconv:"c"`
+	}
+	type level1 struct {
+		B level2            `objconv:"b"`
+		X map[string]string `objconv:"x"`
+	}
+	type root struct {
+		A level1 `objconv:"a"`
+	}
+
+	const input = `{"a":{"x":{"k":"v"},"b":{"c":{"d":{"e":1}}}}}`
+
+	var v1 interface{}
+	var v2 objconv.Value
+	var v3 root
+
+	for _, v := range []interface{}{&v1, &v2, &v3} {
+		if err := Unmarshal([]byte(input), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": map[string]interface{}{
+				"c": map[string]interface{}{
+					"d": map[string]interface{}{"e": 1},
+				},
+			},
+			"x": map[string]interface{}{"k": "v"},
+		},
+	}
+
+	h0 := sha256.New()
+
+	if err := objconv.Hash(h0, root{A: level1{B: level2{C: level3{D: leaf{E: 1}}}, X: map[string]string{"k": "v"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []interface{}{m, v1, v2, v3} {
+		h := sha256.New()
+
+		if err := objconv.Hash(h, v); err != nil {
+			t.Error(err)
+		} else if !bytes.Equal(h.Sum(nil), h0.Sum(nil)) {
+			t.Errorf("bad hash for %#v", v)
+		}
+	}
+}
+
+func TestDecodeWeakCoercions(t *testing.T) {
+	type config struct {
+		Port    uint16   `objconv:"port"`
+		Debug   bool     `objconv:"debug"`
+		Ratio   float64  `objconv:"ratio"`
+		Hosts   []string `objconv:"hosts"`
+		Version string   `objconv:"version"`
+	}
+
+	const input = `{"port":"8080","debug":"yes","ratio":"0.5","hosts":"localhost","version":2}`
+
+	var c config
+	d := NewDecoder(strings.NewReader(input))
+
+	if err := d.Decode(&c); err == nil {
+		t.Error("expected an error decoding without coercions")
+	}
+
+	d = NewDecoder(strings.NewReader(input))
+	d.Coerce = objconv.WeakCoercions
+
+	if err := d.Decode(&c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Port != 8080 || !c.Debug || c.Ratio != 0.5 || len(c.Hosts) != 1 || c.Hosts[0] != "localhost" || c.Version != "2" {
+		t.Errorf("bad config: %#v", c)
+	}
+
+	d = NewDecoder(strings.NewReader(`{"port":"65536"}`))
+	d.Coerce = objconv.WeakCoercions
+
+	if err := d.Decode(&c); err == nil {
+		t.Error("expected an error decoding a port out of range")
+	}
+}
+
+func TestDecodeCollectErrors(t *testing.T) {
+	type config struct {
+		Name  string            `objconv:"name"`
+		Port  uint16            `objconv:"port"`
+		Hosts []string          `objconv:"hosts"`
+		Tags  map[string]string `objconv:"tags"`
+	}
+
+	var c config
+	d := NewDecoder(strings.NewReader(`{"port":true,"name":"api","hosts":["a",{"b":[1,2]},"c"],"tags":{"x/y":[1]}}`))
+	d.CollectErrors = true
+
+	err := d.Decode(&c)
+	errs, ok := err.(objconv.DecodeErrors)
+
+	if !ok || len(errs) != 3 || errs[0].Path != "/port" || errs[1].Path != "/hosts/1" || errs[2].Path != "/tags/x~1y" {
+		t.Fatal("bad decode errors:", err)
+	}
+
+	if c.Name != "api" || len(c.Hosts) != 3 || c.Hosts[2] != "c" {
+		t.Errorf("bad config: %#v", c)
+	}
+
+	d = NewDecoder(strings.NewReader(`{"port":true,"hosts":["a",}`))
+	d.CollectErrors = true
+
+	if _, ok := d.Decode(&c).(objconv.DecodeErrors); ok {
+		t.Error("syntax errors must abort the decoding")
+	}
+}
+
+func TestAppend(t *testing.T) {
+	v := []interface{}{"Hello World!", 42}
+
+	b1, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := append(make([]byte, 0, 1024), "..."...)
+
+	b2, err := Append(dst, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b2[:3], dst) || !bytes.Equal(b2[3:], b1) {
+		t.Errorf("%q != %q + %q", b2, dst, b1)
+	}
+
+	if &b2[0] != &dst[0] {
+		t.Error("the value was not written to the buffer passed to Append")
+	}
+}
+
+func TestResetStreamContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b1 := &bytes.Buffer{}
+	b2 := &bytes.Buffer{}
+	enc := Codec.NewStreamEncoderContext(ctx, b1)
+	dec := Codec.NewStreamDecoderContext(ctx, b1)
+
+	for i, b := range []*bytes.Buffer{b1, b2} {
+		enc.Reset(b)
+
+		if err := enc.Encode(i); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if s := b1.String() + b2.String(); s != "[0][1]" {
+		t.Errorf("bad streams: %q", s)
+	}
+
+	dec.Reset(b2)
+	cancel()
+
+	var v int
+	if err := dec.Decode(&v); !errors.Is(err, context.Canceled) {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
+// deadlineBuffer records the deadlines set on it.
+type deadlineBuffer struct {
+	bytes.Buffer
+	deadlines chan time.Time
+}
+
+func newDeadlineBuffer(s string) *deadlineBuffer {
+	b := &deadlineBuffer{deadlines: make(chan time.Time, 10)}
+	b.WriteString(s)
+	return b
+}
+
+func (b *deadlineBuffer) SetReadDeadline(t time.Time) error {
+	b.deadlines <- t
+	return nil
+}
+
+func (b *deadlineBuffer) SetWriteDeadline(t time.Time) error {
+	b.deadlines <- t
+	return nil
+}
+
+func TestStreamContextReleasesInputAndOutput(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r1 := newDeadlineBuffer(`[1]`)
+	r2 := newDeadlineBuffer(`[2]`)
+	r3 := newDeadlineBuffer(`[3]`)
+	dec := Codec.NewStreamDecoderContext(ctx, r1)
+
+	var v int
+	for dec.Decode(&v) == nil {
+	}
+
+	if err := dec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stream of r1 ended, r2 is replaced by r3.
+	dec.Reset(r2)
+	dec.Reset(r3)
+
+	w1 := newDeadlineBuffer(``)
+	w2 := newDeadlineBuffer(``)
+	w3 := newDeadlineBuffer(``)
+	enc := Codec.NewStreamEncoderContext(ctx, w1)
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	enc.Reset(w2)
+	enc.Reset(w3)
+	cancel()
+
+	// Only the input and output in use when the context is canceled get their
+	// deadline set.
+	for _, b := range []*deadlineBuffer{r3, w3} {
+		select {
+		case <-b.deadlines:
+		case <-time.After(time.Second):
+			t.Fatal("the deadline was not set when the context was canceled")
+		}
+	}
+
+	for _, b := range []*deadlineBuffer{r1, r2, w1, w2} {
+		select {
+		case d := <-b.deadlines:
+			t.Errorf("deadline set after the stream ended: %v", d)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
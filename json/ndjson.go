@@ -0,0 +1,282 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// NDJSONEmitter implements an emitter for newline-delimited JSON streams, where
+// each value is written on its own line.
+//
+// Values are buffered until they are complete, then written to the output with
+// their trailing newline in a single call to Write, so a line is never split
+// across writes and is sent as soon as the value ends.
+//
+// Stream encoders don't wrap the values they write to an NDJSON emitter in an
+// array.
+type NDJSONEmitter struct {
+	Emitter
+	w     io.Writer    // the output writer, while e.Emitter.w points to the buffer
+	b     bytes.Buffer // buffer where the current line is written
+	depth int          // depth of the containers being written
+}
+
+// NewNDJSONEmitter returns a new emitter which writes newline-delimited JSON
+// values to w.
+func NewNDJSONEmitter(w io.Writer) *NDJSONEmitter {
+	e := &NDJSONEmitter{Emitter: *NewEmitter(w)}
+	e.Emitter.s = e.Emitter.a[:0]
+	e.w = e.Emitter.w
+	e.Emitter.w = &e.b
+	return e
+}
+
+func (e *NDJSONEmitter) Reset(w io.Writer) {
+	e.Emitter.Reset(w)
+	e.w = e.Emitter.w
+	e.Emitter.w = &e.b
+	e.b.Reset()
+	e.depth = 0
+}
+
+func (e *NDJSONEmitter) EmitNil() error {
+	return e.line(e.Emitter.EmitNil())
+}
+
+func (e *NDJSONEmitter) EmitBool(v bool) error {
+	return e.line(e.Emitter.EmitBool(v))
+}
+
+func (e *NDJSONEmitter) EmitInt(v int64, bitSize int) error {
+	return e.line(e.Emitter.EmitInt(v, bitSize))
+}
+
+func (e *NDJSONEmitter) EmitUint(v uint64, bitSize int) error {
+	return e.line(e.Emitter.EmitUint(v, bitSize))
+}
+
+func (e *NDJSONEmitter) EmitFloat(v float64, bitSize int) error {
+	return e.line(e.Emitter.EmitFloat(v, bitSize))
+}
+
+func (e *NDJSONEmitter) EmitString(v string) error {
+	return e.line(e.Emitter.EmitString(v))
+}
+
+func (e *NDJSONEmitter) EmitBytes(v []byte) error {
+	return e.line(e.Emitter.EmitBytes(v))
+}
+
+func (e *NDJSONEmitter) EmitTime(v time.Time) error {
+	return e.line(e.Emitter.EmitTime(v))
+}
+
+func (e *NDJSONEmitter) EmitDuration(v time.Duration) error {
+	return e.line(e.Emitter.EmitDuration(v))
+}
+
+func (e *NDJSONEmitter) EmitError(v error) error {
+	return e.line(e.Emitter.EmitError(v))
+}
+
+func (e *NDJSONEmitter) EmitArrayBegin(n int) (err error) {
+	if err = e.Emitter.EmitArrayBegin(n); err == nil {
+		e.depth++
+	}
+	return
+}
+
+func (e *NDJSONEmitter) EmitArrayEnd() (err error) {
+	if err = e.Emitter.EmitArrayEnd(); err == nil {
+		e.depth--
+	}
+	return e.line(err)
+}
+
+func (e *NDJSONEmitter) EmitMapBegin(n int) (err error) {
+	if err = e.Emitter.EmitMapBegin(n); err == nil {
+		e.depth++
+	}
+	return
+}
+
+func (e *NDJSONEmitter) EmitMapEnd() (err error) {
+	if err = e.Emitter.EmitMapEnd(); err == nil {
+		e.depth--
+	}
+	return e.line(err)
+}
+
+// PrettyEmitter returns e, the values of NDJSON streams must be written on a
+// single line.
+func (e *NDJSONEmitter) PrettyEmitter() objconv.Emitter {
+	return e
+}
+
+// CanonicalEmitter returns e, the values of NDJSON streams are already written
+// without whitespaces.
+func (e *NDJSONEmitter) CanonicalEmitter() objconv.Emitter {
+	return e
+}
+
+func (e *NDJSONEmitter) SequenceEmitter() bool {
+	return true
+}
+
+// line writes the buffered line to the output when a top-level value was
+// completed.
+func (e *NDJSONEmitter) line(err error) error {
+	if err != nil || e.depth != 0 {
+		return err
+	}
+	e.b.WriteByte('\n')
+	_, err = e.w.Write(e.b.Bytes())
+	e.b.Reset()
+	return err
+}
+
+// NDJSONParser implements a parser for newline-delimited JSON streams, where
+// each line holds one value. Multiple values written on the same line, or blank
+// lines, are also accepted.
+//
+// Values cannot span multiple lines, which allows stream decoders to skip the
+// lines that cannot be parsed and resume at the next one, see
+// objconv.StreamDecoder.Recover. Streams of concatenated values spanning
+// multiple lines can be read with SequenceParser.
+type NDJSONParser struct {
+	Parser
+	r     *bufio.Reader
+	b     bytes.Reader // reader of the current line
+	line  []byte
+	off   int64 // offset of the current line in the input
+	depth int   // depth of the containers being parsed
+	err   error // error returned by r
+}
+
+// NewNDJSONParser returns a new parser which reads newline-delimited JSON values
+// from r.
+func NewNDJSONParser(r io.Reader) *NDJSONParser {
+	p := &NDJSONParser{r: bufio.NewReader(r)}
+	p.Parser.r = &p.b
+	p.Parser.s = p.Parser.c[:0]
+	return p
+}
+
+func (p *NDJSONParser) Reset(r io.Reader) {
+	p.r.Reset(r)
+	p.depth = 0
+	p.err = nil
+	p.load(p.line[:0])
+	p.off = 0
+}
+
+func (p *NDJSONParser) Buffered() io.Reader {
+	b, _ := p.r.Peek(p.r.Buffered())
+	return io.MultiReader(p.Parser.Buffered(), bytes.NewReader(p.line[len(p.line)-p.b.Len():]), bytes.NewReader(b))
+}
+
+// InputOffset returns the number of bytes of the input that were consumed by
+// the parser.
+func (p *NDJSONParser) InputOffset() int64 {
+	return p.off + p.Parser.InputOffset()
+}
+
+// ParseType returns the type of the next value, moving on to the next line when
+// the current one has no more values.
+func (p *NDJSONParser) ParseType() (objconv.Type, error) {
+	for {
+		t, err := p.Parser.ParseType()
+
+		if err != io.EOF || p.depth != 0 {
+			return t, err
+		}
+
+		if err = p.next(); err != nil {
+			return t, err
+		}
+	}
+}
+
+func (p *NDJSONParser) ParseArrayBegin() (n int, err error) {
+	if n, err = p.Parser.ParseArrayBegin(); err == nil {
+		p.depth++
+	}
+	return
+}
+
+func (p *NDJSONParser) ParseArrayEnd(n int) (err error) {
+	if err = p.Parser.ParseArrayEnd(n); err == nil {
+		p.depth--
+	}
+	return
+}
+
+func (p *NDJSONParser) ParseMapBegin() (n int, err error) {
+	if n, err = p.Parser.ParseMapBegin(); err == nil {
+		p.depth++
+	}
+	return
+}
+
+func (p *NDJSONParser) ParseMapEnd(n int) (err error) {
+	if err = p.Parser.ParseMapEnd(n); err == nil {
+		p.depth--
+	}
+	return
+}
+
+// Recover discards the rest of the current line, the next value is read from
+// the next line. Errors returned by the underlying reader cannot be recovered
+// from.
+func (p *NDJSONParser) Recover() error {
+	if p.err != nil {
+		return p.err
+	}
+	p.depth = 0
+	p.load(p.line[:0])
+	return nil
+}
+
+func (p *NDJSONParser) SequenceParser() bool {
+	return true
+}
+
+// next reads the next line of the input.
+func (p *NDJSONParser) next() error {
+	line := p.line[:0]
+
+	for {
+		b, err := p.r.ReadSlice('\n')
+		line = append(line, b...)
+
+		switch err {
+		case nil:
+			p.load(line)
+			return nil
+
+		case bufio.ErrBufferFull:
+
+		case io.EOF:
+			if len(line) != 0 {
+				p.load(line)
+				return nil
+			}
+			return io.EOF
+
+		default:
+			p.err = err
+			return err
+		}
+	}
+}
+
+func (p *NDJSONParser) load(line []byte) {
+	p.off += int64(len(p.line))
+	p.line = line
+	p.b.Reset(line)
+	p.Parser.Reset(&p.b)
+}
@@ -3,6 +3,7 @@ package json
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -23,6 +24,18 @@ type Parser struct {
 	j int       // offset of the last byte in b
 	b [128]byte // buffer where bytes are loaded from the reader
 	c [128]byte // initial backend array for s
+	n int64     // number of bytes read from r
+
+	// State of ParseRaw, the bytes of the value are accumulated in raw when
+	// the read buffer is refilled, k is the offset of the first byte of the
+	// value that remains in b.
+	raw     []byte
+	k       int
+	capture bool
+
+	// Depth of the arrays and maps being parsed, used by Recover to find the
+	// end of the value of the top-level array that failed to be parsed.
+	depth int
 }
 
 func NewParser(r io.Reader) *Parser {
@@ -35,12 +48,20 @@ func (p *Parser) Reset(r io.Reader) {
 	p.r = r
 	p.i = 0
 	p.j = 0
+	p.n = 0
+	p.depth = 0
 }
 
 func (p *Parser) Buffered() io.Reader {
 	return bytes.NewReader(p.b[p.i:p.j])
 }
 
+// InputOffset returns the number of bytes of the input that were consumed by
+// the parser.
+func (p *Parser) InputOffset() int64 {
+	return p.n - int64(p.j-p.i)
+}
+
 func (p *Parser) ParseType() (t objconv.Type, err error) {
 	var b byte
 
@@ -150,9 +171,8 @@ func (p *Parser) ParseString() (v []byte, err error) {
 	if p.i != p.j && p.b[p.i] == '"' {
 		chunk := p.b[p.i+1 : p.j]
 		off1 := bytes.IndexByte(chunk, '"')
-		off2 := bytes.IndexByte(chunk, '\\')
 
-		if off1 >= 0 && off2 < 0 {
+		if off1 >= 0 && bytes.IndexByte(chunk[:off1], '\\') < 0 {
 			v = p.b[p.i+1 : p.i+1+off1]
 			p.i += off1 + 2
 			return
@@ -247,14 +267,20 @@ func (p *Parser) ParseError() (v error, err error) {
 }
 
 func (p *Parser) ParseArrayBegin() (n int, err error) {
-	return -1, p.readByte('[')
+	if err = p.readByte('['); err == nil {
+		p.depth++
+	}
+	return -1, err
 }
 
 func (p *Parser) ParseArrayEnd(n int) (err error) {
 	if err = p.skipSpaces(); err != nil {
 		return
 	}
-	return p.readByte(']')
+	if err = p.readByte(']'); err == nil {
+		p.depth--
+	}
+	return
 }
 
 func (p *Parser) ParseArrayNext(n int) (err error) {
@@ -283,14 +309,20 @@ func (p *Parser) ParseArrayNext(n int) (err error) {
 }
 
 func (p *Parser) ParseMapBegin() (n int, err error) {
-	return -1, p.readByte('{')
+	if err = p.readByte('{'); err == nil {
+		p.depth++
+	}
+	return -1, err
 }
 
 func (p *Parser) ParseMapEnd(n int) (err error) {
 	if err = p.skipSpaces(); err != nil {
 		return
 	}
-	return p.readByte('}')
+	if err = p.readByte('}'); err == nil {
+		p.depth--
+	}
+	return
 }
 
 func (p *Parser) ParseMapValue(n int) (err error) {
@@ -325,6 +357,107 @@ func (p *Parser) ParseMapNext(n int) (err error) {
 	return
 }
 
+// Skip discards the next value, strings and containers are scanned up to their
+// closing quote or bracket without being decoded.
+func (p *Parser) Skip() (err error) {
+	var b byte
+
+	if err = p.skipSpaces(); err != nil {
+		return
+	}
+
+	if b, err = p.peekByteAt(0); err != nil {
+		return
+	}
+
+	switch {
+	case b == '"':
+		err = p.skipString()
+
+	case b == '{' || b == '[':
+		err = p.skipContainer()
+
+	case b == 'n':
+		err = p.ParseNil()
+
+	case b == 't' || b == 'f':
+		_, err = p.ParseBool()
+
+	case b == '-' || (b >= '0' && b <= '9'):
+		// The error is ignored because reaching the end of the input is valid
+		// after a number, the same way ParseType does.
+		chunk, _ := p.peekNumber()
+		p.i += len(chunk)
+
+	default:
+		err = fmt.Errorf("objconv/json: expected token but found '%c'", b)
+	}
+
+	return
+}
+
+// ParseRaw appends the bytes of the next value to b and returns the extended
+// slice, the value is consumed without being decoded.
+func (p *Parser) ParseRaw(b []byte) ([]byte, error) {
+	if err := p.skipSpaces(); err != nil {
+		return b, err
+	}
+
+	p.raw, p.k, p.capture = b, p.i, true
+	err := p.Skip()
+	b = append(p.raw, p.b[p.k:p.i]...)
+	p.raw, p.k, p.capture = nil, 0, false
+	return b, err
+}
+
+// Recover discards the input up to the ',' or ']' following the value of the
+// top-level array that failed to be parsed, so a stream decoder can resume at
+// the next value. Strings are skipped without interpreting the characters they
+// contain. Values that are not in an array cannot be recovered from.
+func (p *Parser) Recover() (err error) {
+	if p.depth == 0 {
+		return errors.New("objconv/json: cannot recover from an error outside of an array")
+	}
+
+	depth := p.depth
+
+	for {
+		if p.i == p.j {
+			if err = p.fill(); err != nil {
+				return
+			}
+		}
+
+		switch b := p.b[p.i]; b {
+		case '"':
+			if err = p.skipString(); err != nil {
+				return
+			}
+			continue
+
+		case '{', '[':
+			depth++
+
+		case '}', ']':
+			if depth == 1 && b == ']' {
+				p.depth = 1
+				return
+			}
+			if depth > 1 {
+				depth--
+			}
+
+		case ',':
+			if depth == 1 {
+				p.depth = 1
+				return
+			}
+		}
+
+		p.i++
+	}
+}
+
 func (p *Parser) TextParser() bool {
 	return true
 }
@@ -349,6 +482,13 @@ func (p *Parser) peek(n int) (b []byte, err error) {
 }
 
 func (p *Parser) peekByteAt(i int) (b byte, err error) {
+	if (p.i + i) < p.j {
+		return p.b[p.i+i], nil
+	}
+	return p.peekByteAtSlow(i)
+}
+
+func (p *Parser) peekByteAtSlow(i int) (b byte, err error) {
 	for (p.i + i + 1) > p.j {
 		if err = p.fill(); err != nil {
 			return
@@ -443,7 +583,72 @@ func (p *Parser) readUnicode() (r rune, err error) {
 	return
 }
 
+// skipString discards the string that the parser is positioned on.
+func (p *Parser) skipString() (err error) {
+	escaped := false
+	p.i++ // opening quote
+
+	for {
+		if p.i == p.j {
+			if err = p.fill(); err != nil {
+				return
+			}
+		}
+
+		for i, b := range p.b[p.i:p.j] {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				p.i += i + 1
+				return
+			}
+		}
+
+		p.i = p.j
+	}
+}
+
+// skipContainer discards the array or map that the parser is positioned on.
+func (p *Parser) skipContainer() (err error) {
+	depth := 0
+
+	for {
+		if p.i == p.j {
+			if err = p.fill(); err != nil {
+				return
+			}
+		}
+
+		switch p.b[p.i] {
+		case '"':
+			if err = p.skipString(); err != nil {
+				return
+			}
+			continue
+
+		case '{', '[':
+			depth++
+
+		case '}', ']':
+			if depth--; depth == 0 {
+				p.i++
+				return
+			}
+		}
+
+		p.i++
+	}
+}
+
 func (p *Parser) skipSpaces() (err error) {
+	// fast path: most tokens aren't preceded by spaces.
+	if p.i != p.j && p.b[p.i] > ' ' {
+		return
+	}
+
 	for {
 		if p.i == p.j {
 			if err = p.fill(); err != nil {
@@ -468,6 +673,11 @@ func (p *Parser) skipSpaces() (err error) {
 }
 
 func (p *Parser) fill() (err error) {
+	if p.capture {
+		p.raw = append(p.raw, p.b[p.k:p.i]...)
+		p.k = 0
+	}
+
 	n := p.j - p.i
 	copy(p.b[:n], p.b[p.i:p.j])
 	p.i = 0
@@ -476,6 +686,7 @@ func (p *Parser) fill() (err error) {
 	if n, err = p.r.Read(p.b[p.j:]); n > 0 {
 		err = nil
 		p.j += n
+		p.n += int64(n)
 	} else if err != nil {
 		return
 	} else {
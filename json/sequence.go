@@ -0,0 +1,27 @@
+package json
+
+import "io"
+
+// SequenceParser implements a parser for streams of concatenated JSON values,
+// stream decoders read values until the end of the input instead of expecting
+// an array.
+//
+// Values may be separated by white spaces or not separated at all when they
+// are self-delimited (like objects, arrays or strings), and may span multiple
+// lines. Unlike NDJSONParser, the parser cannot resume at the next line after
+// a malformed value.
+type SequenceParser struct {
+	Parser
+}
+
+// NewSequenceParser returns a new parser which reads concatenated JSON values
+// from r.
+func NewSequenceParser(r io.Reader) *SequenceParser {
+	p := &SequenceParser{Parser: Parser{r: r}}
+	p.Parser.s = p.Parser.c[:0]
+	return p
+}
+
+func (p *SequenceParser) SequenceParser() bool {
+	return true
+}
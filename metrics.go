@@ -0,0 +1,482 @@
+package objconv
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics collects statistics about the values written to emitters or read from
+// parsers wrapped by NewMetricsEmitter and NewMetricsParser, and the bytes
+// going through the writers and readers returned by its Writer and Reader
+// methods.
+//
+// It is safe to use a Metrics value concurrently from multiple goroutines, so
+// a single value can aggregate the statistics of many encoders or decoders.
+// The zero-value is ready to use.
+type Metrics struct {
+	values       [Map + 1]int64
+	bytesRead    int64
+	bytesWritten int64
+	maxDepth     int64
+	time         int64
+}
+
+// Values returns the number of values of type t that were written or read.
+// Arrays and maps are counted once, in addition to the values they contain.
+func (m *Metrics) Values(t Type) int64 {
+	if t < 0 || int(t) >= len(m.values) {
+		return 0
+	}
+	return atomic.LoadInt64(&m.values[t])
+}
+
+// BytesRead returns the number of bytes read from the readers returned by the
+// Reader method.
+func (m *Metrics) BytesRead() int64 {
+	return atomic.LoadInt64(&m.bytesRead)
+}
+
+// BytesWritten returns the number of bytes written to the writers returned by
+// the Writer method.
+func (m *Metrics) BytesWritten() int64 {
+	return atomic.LoadInt64(&m.bytesWritten)
+}
+
+// MaxDepth returns the maximum nesting of arrays and maps that was observed, a
+// top-level array has a depth of 1.
+func (m *Metrics) MaxDepth() int {
+	return int(atomic.LoadInt64(&m.maxDepth))
+}
+
+// Time returns the time spent in the methods of the emitters and parsers.
+//
+// Emitters and parsers wrapped by the same Metrics value may be used by
+// multiple goroutines at the same time, in which case the time spent in each
+// of them is added.
+func (m *Metrics) Time() time.Duration {
+	return time.Duration(atomic.LoadInt64(&m.time))
+}
+
+// Writer returns a writer which counts the bytes written to w.
+//
+// Writers which don't need to be buffered by emitters (like *bytes.Buffer or
+// writers with a Flush method) are still recognized once wrapped.
+func (m *Metrics) Writer(w io.Writer) io.Writer {
+	switch w.(type) {
+	case *bytes.Buffer, interface{ Flush() error }, interface{ Flush() }:
+		return &metricsFlushWriter{metricsWriter{w: w, m: m}}
+	default:
+		return &metricsWriter{w: w, m: m}
+	}
+}
+
+// Reader returns a reader which counts the bytes read from r.
+func (m *Metrics) Reader(r io.Reader) io.Reader {
+	return &metricsReader{r: r, m: m}
+}
+
+func (m *Metrics) value(t Type) {
+	atomic.AddInt64(&m.values[t], 1)
+}
+
+func (m *Metrics) depth(depth int) {
+	for {
+		max := atomic.LoadInt64(&m.maxDepth)
+
+		if int64(depth) <= max || atomic.CompareAndSwapInt64(&m.maxDepth, max, int64(depth)) {
+			return
+		}
+	}
+}
+
+func (m *Metrics) since(start time.Time) {
+	atomic.AddInt64(&m.time, int64(time.Since(start)))
+}
+
+type metricsWriter struct {
+	w io.Writer
+	m *Metrics
+}
+
+func (w *metricsWriter) Write(b []byte) (n int, err error) {
+	n, err = w.w.Write(b)
+	atomic.AddInt64(&w.m.bytesWritten, int64(n))
+	return
+}
+
+type metricsFlushWriter struct {
+	metricsWriter
+}
+
+func (w *metricsFlushWriter) Flush() error {
+	switch f := w.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
+
+type metricsReader struct {
+	r io.Reader
+	m *Metrics
+}
+
+func (r *metricsReader) Read(b []byte) (n int, err error) {
+	n, err = r.r.Read(b)
+	atomic.AddInt64(&r.m.bytesRead, int64(n))
+	return
+}
+
+// MetricsEmitter is an emitter which collects statistics about the values
+// written to the emitter it wraps.
+//
+// Wrapping an emitter in a MetricsEmitter doesn't change its output. The bytes
+// written by the emitter are counted when its output is a writer returned by
+// the Writer method of the Metrics value.
+type MetricsEmitter struct {
+	wrappedEmitter
+
+	// Metrics collects the statistics of the emitter.
+	Metrics *Metrics
+
+	depth int
+}
+
+// NewMetricsEmitter returns a new emitter which records statistics about the
+// values written to e in m.
+//
+// The function panics if e or m is nil.
+func NewMetricsEmitter(e Emitter, m *Metrics) *MetricsEmitter {
+	if e == nil {
+		panic("objconv: the emitter is nil")
+	}
+	if m == nil {
+		panic("objconv: the metrics are nil")
+	}
+	return &MetricsEmitter{wrappedEmitter: wrappedEmitter{e}, Metrics: m}
+}
+
+func (e *MetricsEmitter) EmitNil() error {
+	defer e.Metrics.since(time.Now())
+	e.Metrics.value(Nil)
+	return e.Emitter.EmitNil()
+}
+
+func (e *MetricsEmitter) EmitBool(v bool) error {
+	defer e.Metrics.since(time.Now())
+	e.Metrics.value(Bool)
+	return e.Emitter.EmitBool(v)
+}
+
+func (e *MetricsEmitter) EmitInt(v int64, bitSize int) error {
+	defer e.Metrics.since(time.Now())
+	e.Metrics.value(Int)
+	return e.Emitter.EmitInt(v, bitSize)
+}
+
+func (e *MetricsEmitter) EmitUint(v uint64, bitSize int) error {
+	defer e.Metrics.since(time.Now())
+	e.Metrics.value(Uint)
+	return e.Emitter.EmitUint(v, bitSize)
+}
+
+func (e *MetricsEmitter) EmitFloat(v float64, bitSize int) error {
+	defer e.Metrics.since(time.Now())
+	e.Metrics.value(Float)
+	return e.Emitter.EmitFloat(v, bitSize)
+}
+
+func (e *MetricsEmitter) EmitString(v string) error {
+	defer e.Metrics.since(time.Now())
+	e.Metrics.value(String)
+	return e.Emitter.EmitString(v)
+}
+
+func (e *MetricsEmitter) EmitBytes(v []byte) error {
+	defer e.Metrics.since(time.Now())
+	e.Metrics.value(Bytes)
+	return e.Emitter.EmitBytes(v)
+}
+
+func (e *MetricsEmitter) EmitTime(v time.Time) error {
+	defer e.Metrics.since(time.Now())
+	e.Metrics.value(Time)
+	return e.Emitter.EmitTime(v)
+}
+
+func (e *MetricsEmitter) EmitDuration(v time.Duration) error {
+	defer e.Metrics.since(time.Now())
+	e.Metrics.value(Duration)
+	return e.Emitter.EmitDuration(v)
+}
+
+func (e *MetricsEmitter) EmitError(v error) error {
+	defer e.Metrics.since(time.Now())
+	e.Metrics.value(Error)
+	return e.Emitter.EmitError(v)
+}
+
+func (e *MetricsEmitter) EmitArrayBegin(n int) error {
+	defer e.Metrics.since(time.Now())
+	e.begin(Array)
+	return e.Emitter.EmitArrayBegin(n)
+}
+
+func (e *MetricsEmitter) EmitArrayEnd() error {
+	defer e.Metrics.since(time.Now())
+	e.depth--
+	return e.Emitter.EmitArrayEnd()
+}
+
+func (e *MetricsEmitter) EmitArrayNext() error {
+	defer e.Metrics.since(time.Now())
+	return e.Emitter.EmitArrayNext()
+}
+
+func (e *MetricsEmitter) EmitMapBegin(n int) error {
+	defer e.Metrics.since(time.Now())
+	e.begin(Map)
+	return e.Emitter.EmitMapBegin(n)
+}
+
+func (e *MetricsEmitter) EmitMapEnd() error {
+	defer e.Metrics.since(time.Now())
+	e.depth--
+	return e.Emitter.EmitMapEnd()
+}
+
+func (e *MetricsEmitter) EmitMapValue() error {
+	defer e.Metrics.since(time.Now())
+	return e.Emitter.EmitMapValue()
+}
+
+func (e *MetricsEmitter) EmitMapNext() error {
+	defer e.Metrics.since(time.Now())
+	return e.Emitter.EmitMapNext()
+}
+
+// PrettyEmitter returns a new emitter which collects statistics about the
+// values written to the pretty emitter of the underlying emitter, or e if it
+// has none.
+func (e *MetricsEmitter) PrettyEmitter() Emitter {
+	if p, ok := e.Emitter.(PrettyEmitter); ok {
+		return NewMetricsEmitter(p.PrettyEmitter(), e.Metrics)
+	}
+	return e
+}
+
+// CanonicalEmitter returns a new emitter which collects statistics about the
+// values written to the canonical emitter of the underlying emitter, or e if it
+// has none.
+func (e *MetricsEmitter) CanonicalEmitter() Emitter {
+	if c, ok := e.Emitter.(CanonicalEmitter); ok {
+		return NewMetricsEmitter(c.CanonicalEmitter(), e.Metrics)
+	}
+	return e
+}
+
+func (e *MetricsEmitter) begin(t Type) {
+	e.depth++
+	e.Metrics.value(t)
+	e.Metrics.depth(e.depth)
+}
+
+// MetricsParser is a parser which collects statistics about the values read
+// from the parser it wraps.
+//
+// Wrapping a parser in a MetricsParser doesn't change the decoded values. The
+// bytes read by the parser are counted when its input is a reader returned by
+// the Reader method of the Metrics value.
+type MetricsParser struct {
+	wrappedParser
+
+	// Metrics collects the statistics of the parser.
+	Metrics *Metrics
+
+	depth int
+	typ   Type // type returned by the last call to ParseType
+}
+
+// NewMetricsParser returns a new parser which records statistics about the
+// values read from p in m.
+//
+// The function panics if p or m is nil.
+func NewMetricsParser(p Parser, m *Metrics) *MetricsParser {
+	if p == nil {
+		panic("objconv: the parser is nil")
+	}
+	if m == nil {
+		panic("objconv: the metrics are nil")
+	}
+	return &MetricsParser{wrappedParser: wrappedParser{p}, Metrics: m}
+}
+
+func (p *MetricsParser) ParseType() (t Type, err error) {
+	defer p.Metrics.since(time.Now())
+	t, err = p.Parser.ParseType()
+	p.typ = t
+	return
+}
+
+func (p *MetricsParser) ParseNil() (err error) {
+	defer p.Metrics.since(time.Now())
+	err = p.Parser.ParseNil()
+	p.value(Nil, err)
+	return
+}
+
+func (p *MetricsParser) ParseBool() (v bool, err error) {
+	defer p.Metrics.since(time.Now())
+	v, err = p.Parser.ParseBool()
+	p.value(Bool, err)
+	return
+}
+
+func (p *MetricsParser) ParseInt() (v int64, err error) {
+	defer p.Metrics.since(time.Now())
+	v, err = p.Parser.ParseInt()
+	p.value(Int, err)
+	return
+}
+
+func (p *MetricsParser) ParseUint() (v uint64, err error) {
+	defer p.Metrics.since(time.Now())
+	v, err = p.Parser.ParseUint()
+	p.value(Uint, err)
+	return
+}
+
+func (p *MetricsParser) ParseFloat() (v float64, err error) {
+	defer p.Metrics.since(time.Now())
+	v, err = p.Parser.ParseFloat()
+	p.value(Float, err)
+	return
+}
+
+func (p *MetricsParser) ParseString() (v []byte, err error) {
+	defer p.Metrics.since(time.Now())
+	v, err = p.Parser.ParseString()
+	p.value(String, err)
+	return
+}
+
+func (p *MetricsParser) ParseBytes() (v []byte, err error) {
+	defer p.Metrics.since(time.Now())
+	v, err = p.Parser.ParseBytes()
+	p.value(Bytes, err)
+	return
+}
+
+func (p *MetricsParser) ParseTime() (v time.Time, err error) {
+	defer p.Metrics.since(time.Now())
+	v, err = p.Parser.ParseTime()
+	p.value(Time, err)
+	return
+}
+
+func (p *MetricsParser) ParseDuration() (v time.Duration, err error) {
+	defer p.Metrics.since(time.Now())
+	v, err = p.Parser.ParseDuration()
+	p.value(Duration, err)
+	return
+}
+
+func (p *MetricsParser) ParseError() (v error, err error) {
+	defer p.Metrics.since(time.Now())
+	v, err = p.Parser.ParseError()
+	p.value(Error, err)
+	return
+}
+
+func (p *MetricsParser) ParseArrayBegin() (n int, err error) {
+	defer p.Metrics.since(time.Now())
+	if n, err = p.Parser.ParseArrayBegin(); err == nil {
+		p.begin(Array)
+	}
+	return
+}
+
+func (p *MetricsParser) ParseArrayEnd(n int) (err error) {
+	defer p.Metrics.since(time.Now())
+	if err = p.Parser.ParseArrayEnd(n); err == nil {
+		p.depth--
+	}
+	return
+}
+
+func (p *MetricsParser) ParseArrayNext(n int) error {
+	defer p.Metrics.since(time.Now())
+	return p.Parser.ParseArrayNext(n)
+}
+
+func (p *MetricsParser) ParseMapBegin() (n int, err error) {
+	defer p.Metrics.since(time.Now())
+	if n, err = p.Parser.ParseMapBegin(); err == nil {
+		p.begin(Map)
+	}
+	return
+}
+
+func (p *MetricsParser) ParseMapEnd(n int) (err error) {
+	defer p.Metrics.since(time.Now())
+	if err = p.Parser.ParseMapEnd(n); err == nil {
+		p.depth--
+	}
+	return
+}
+
+func (p *MetricsParser) ParseMapValue(n int) error {
+	defer p.Metrics.since(time.Now())
+	return p.Parser.ParseMapValue(n)
+}
+
+func (p *MetricsParser) ParseMapNext(n int) error {
+	defer p.Metrics.since(time.Now())
+	return p.Parser.ParseMapNext(n)
+}
+
+// Skip satisfies the skipParser interface, the skipped value is counted but the
+// values it contains aren't when the underlying parser supports skipping
+// values.
+func (p *MetricsParser) Skip() (err error) {
+	if s, ok := p.Parser.(skipParser); ok {
+		defer p.Metrics.since(time.Now())
+		err = s.Skip()
+		p.value(p.typ, err)
+		return
+	}
+	return (Decoder{Parser: p}).decodeInterfaceFromType(p.typ, reflect.Value{})
+}
+
+// ParseRaw forwards to the underlying parser, the extracted value is counted
+// but the values it contains aren't.
+func (p *MetricsParser) ParseRaw(b []byte) (v []byte, err error) {
+	defer p.Metrics.since(time.Now())
+	v, err = p.wrappedParser.ParseRaw(b)
+	p.value(p.typ, err)
+	return
+}
+
+// Reset forwards to the underlying parser and discards the state of p, the
+// metrics aren't reset.
+func (p *MetricsParser) Reset(r io.Reader) {
+	p.wrappedParser.Reset(r)
+	p.depth, p.typ = 0, Unknown
+}
+
+func (p *MetricsParser) value(t Type, err error) {
+	if err == nil {
+		p.Metrics.value(t)
+	}
+}
+
+func (p *MetricsParser) begin(t Type) {
+	p.depth++
+	p.Metrics.value(t)
+	p.Metrics.depth(p.depth)
+}
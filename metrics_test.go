@@ -0,0 +1,84 @@
+package objconv
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	val := map[string]interface{}{"A": []interface{}{int64(1), "2", []interface{}{nil}}}
+
+	check := func(t *testing.T, m *Metrics) {
+		for typ, n := range map[Type]int64{
+			Nil:    1,
+			Int:    1,
+			String: 2,
+			Array:  2,
+			Map:    1,
+			Bool:   0,
+		} {
+			if c := m.Values(typ); c != n {
+				t.Errorf("%d values of type %s were counted instead of %d", c, typ, n)
+			}
+		}
+
+		if d := m.MaxDepth(); d != 3 {
+			t.Errorf("bad max depth: %d", d)
+		}
+
+		if m.Time() <= 0 {
+			t.Error("no time was recorded")
+		}
+	}
+
+	t.Run("Emitter", func(t *testing.T) {
+		m := &Metrics{}
+
+		if err := NewEncoder(NewMetricsEmitter(NewValueEmitter(), m)).Encode(val); err != nil {
+			t.Fatal(err)
+		}
+
+		check(t, m)
+	})
+
+	t.Run("Parser", func(t *testing.T) {
+		m := &Metrics{}
+
+		var v interface{}
+		if err := NewDecoder(NewMetricsParser(NewValueParser(val), m)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		check(t, m)
+	})
+}
+
+func TestMetricsBytes(t *testing.T) {
+	m := &Metrics{}
+	b := &bytes.Buffer{}
+
+	w := m.Writer(b)
+	w.Write([]byte("Hello"))
+	w.Write([]byte(" World!"))
+
+	if _, ok := w.(interface{ Flush() error }); !ok {
+		t.Error("writers wrapping a *bytes.Buffer must be flushable")
+	}
+
+	if _, ok := m.Writer(ioutil.Discard).(interface{ Flush() error }); ok {
+		t.Error("writers wrapping ioutil.Discard must not be flushable")
+	}
+
+	if n := m.BytesWritten(); n != 12 {
+		t.Errorf("%d bytes were written instead of 12", n)
+	}
+
+	if _, err := ioutil.ReadAll(m.Reader(b)); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := m.BytesRead(); n != 12 {
+		t.Errorf("%d bytes were read instead of 12", n)
+	}
+}
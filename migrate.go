@@ -0,0 +1,185 @@
+package objconv
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// DefaultVersionKey is the map key used to carry the version of a value when
+// the Key field of a Versioning is empty.
+const DefaultVersionKey = "_version"
+
+// A Migration upgrades the generic representation of a value from one schema
+// version to another.
+type Migration struct {
+	// From is the version that the migration applies to.
+	From int
+
+	// To is the version that the value has after the migration was applied,
+	// it must be greater than From.
+	To int
+
+	// Func is called with the generic representation of the value, it is
+	// expected to modify the map in place to match the layout of the To
+	// version.
+	Func func(map[string]interface{}) error
+}
+
+// Versioning describes the schema versions of a struct type and how to upgrade
+// values serialized with older versions of the type.
+//
+// Encoders stamp values of a versioned type with the current version, decoders
+// load the value in its generic form, read the version, run the chain of
+// migrations to bring it up to the current version, then decode it into the
+// struct.
+//
+// Values that don't carry a version (typically because they were serialized
+// before versioning was installed on the type) are considered to be at version
+// zero.
+type Versioning struct {
+	// Version is the current version of the type.
+	Version int
+
+	// Key is the name of the map key where the version is stored, defaults to
+	// DefaultVersionKey.
+	Key string
+
+	// Migrations is the list of functions that are used to upgrade values from
+	// older versions of the type.
+	Migrations []Migration
+}
+
+func (v *Versioning) key() string {
+	if len(v.Key) == 0 {
+		return DefaultVersionKey
+	}
+	return v.Key
+}
+
+func (v *Versioning) migration(from int) *Migration {
+	for i := range v.Migrations {
+		if m := &v.Migrations[i]; m.From == from {
+			return m
+		}
+	}
+	return nil
+}
+
+// migrate runs the chain of migrations on m, starting at version from.
+func (v *Versioning) migrate(m map[string]interface{}, from int) (err error) {
+	for from != v.Version {
+		if from > v.Version {
+			return fmt.Errorf("objconv: cannot decode a value at version %d which is newer than the current version %d", from, v.Version)
+		}
+
+		x := v.migration(from)
+
+		if x == nil {
+			return fmt.Errorf("objconv: no migration found to upgrade a value from version %d", from)
+		}
+
+		if err = x.Func(m); err != nil {
+			return fmt.Errorf("objconv: migrating from version %d to %d: %s", x.From, x.To, err)
+		}
+
+		from = x.To
+	}
+	return
+}
+
+// InstallVersioning installs schema versioning for typ, which must be a struct
+// type.
+//
+// The function panics if typ is not a struct type, if one of the migrations
+// doesn't move forward, or if two migrations apply to the same version.
+//
+// Versioning is implemented with an adapter, which means it replaces any
+// adapter previously installed for typ, and like adapters it is expected to be
+// installed during the package initialization phase.
+func InstallVersioning(typ reflect.Type, v Versioning) {
+	if typ.Kind() != reflect.Struct {
+		panic("objconv: versioning can only be installed on struct types, got " + typ.String())
+	}
+
+	seen := make(map[int]bool, len(v.Migrations))
+
+	for _, m := range v.Migrations {
+		if m.Func == nil {
+			panic("objconv: the function of a migration cannot be nil")
+		}
+		if m.To <= m.From {
+			panic(fmt.Sprintf("objconv: invalid migration of %s from version %d to %d", typ, m.From, m.To))
+		}
+		if seen[m.From] {
+			panic(fmt.Sprintf("objconv: multiple migrations of %s from version %d", typ, m.From))
+		}
+		seen[m.From] = true
+	}
+
+	v.Migrations = append([]Migration(nil), v.Migrations...)
+	ver := &v
+
+	versioningMutex.Lock()
+	versioningStore[typ] = v
+	versioningMutex.Unlock()
+
+	Install(typ, Adapter{
+		Encode: func(e Encoder, v reflect.Value) error {
+			return e.encodeStructVersionWith(v, structCache.lookup(v.Type()), ver)
+		},
+		Decode: func(d Decoder, to reflect.Value) error {
+			return d.decodeVersioned(to, ver)
+		},
+	})
+}
+
+// VersioningOf returns the versioning installed on typ, setting ok to true if
+// one was found, false otherwise.
+func VersioningOf(typ reflect.Type) (v Versioning, ok bool) {
+	versioningMutex.RLock()
+	v, ok = versioningStore[typ]
+	versioningMutex.RUnlock()
+	return
+}
+
+func (d Decoder) decodeVersioned(to reflect.Value, ver *Versioning) (err error) {
+	var m map[string]interface{}
+	var v int64
+
+	// Nested maps are loaded as map[string]interface{} as well so the migration
+	// functions have a consistent view of the value.
+	md := d.derive(d.Parser)
+	if md.MapType == nil {
+		md.MapType = mapStringInterfaceType
+	}
+
+	if err = md.Decode(&m); err != nil {
+		return
+	}
+
+	if m == nil || !to.IsValid() {
+		return
+	}
+
+	if x, ok := m[ver.key()]; ok {
+		if err = (Decoder{Parser: NewValueParser(x)}).Decode(&v); err != nil {
+			return fmt.Errorf("objconv: invalid version found in %s: %s", to.Type(), err)
+		}
+		delete(m, ver.key())
+	}
+
+	if err = ver.migrate(m, int(v)); err != nil {
+		return
+	}
+
+	// The migrated value is fed back through the regular decoding algorithm,
+	// bypassing the adapter so the struct fields are set directly.
+	_, err = d.derive(NewValueParser(m)).decodeStruct(to)
+	return
+}
+
+var (
+	versioningMutex sync.RWMutex
+	versioningStore = make(map[reflect.Type]Versioning)
+)
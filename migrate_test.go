@@ -0,0 +1,171 @@
+package objconv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type versionedUser struct {
+	FirstName string
+	LastName  string
+	Age       int
+}
+
+func init() {
+	InstallVersioning(reflect.TypeOf(versionedUser{}), Versioning{
+		Version: 2,
+		Migrations: []Migration{
+			{
+				// version 0 had a single Name field
+				From: 0,
+				To:   1,
+				Func: func(m map[string]interface{}) error {
+					name, _ := m["Name"].(string)
+					i := strings.IndexByte(name, ' ')
+					if i < 0 {
+						return fmt.Errorf("cannot split name %q", name)
+					}
+					m["FirstName"], m["LastName"] = name[:i], name[i+1:]
+					delete(m, "Name")
+					return nil
+				},
+			},
+			{
+				// version 1 stored the age as a string
+				From: 1,
+				To:   2,
+				Func: func(m map[string]interface{}) error {
+					if age, ok := m["Age"].(string); ok {
+						m["Age"] = len(age)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestVersioningEncode(t *testing.T) {
+	e := NewValueEmitter()
+
+	if err := NewEncoder(e).Encode(versionedUser{"Luke", "Skywalker", 19}); err != nil {
+		t.Fatal(err)
+	}
+
+	v := e.Value()
+	x := map[interface{}]interface{}{
+		DefaultVersionKey: int64(2),
+		"FirstName":       "Luke",
+		"LastName":        "Skywalker",
+		"Age":             int64(19),
+	}
+
+	if !reflect.DeepEqual(v, x) {
+		t.Errorf("%#v != %#v", v, x)
+	}
+}
+
+func TestVersioningDecode(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out versionedUser
+	}{
+		{
+			in:  map[string]interface{}{"Name": "Luke Skywalker"},
+			out: versionedUser{FirstName: "Luke", LastName: "Skywalker"},
+		},
+		{
+			in:  map[string]interface{}{DefaultVersionKey: 1, "FirstName": "Luke", "LastName": "Skywalker", "Age": "abc"},
+			out: versionedUser{FirstName: "Luke", LastName: "Skywalker", Age: 3},
+		},
+		{
+			in:  map[string]interface{}{DefaultVersionKey: uint(2), "FirstName": "Luke", "LastName": "Skywalker", "Age": 19},
+			out: versionedUser{FirstName: "Luke", LastName: "Skywalker", Age: 19},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.in), func(t *testing.T) {
+			var u versionedUser
+
+			if err := NewDecoder(NewValueParser(test.in)).Decode(&u); err != nil {
+				t.Fatal(err)
+			}
+
+			if u != test.out {
+				t.Errorf("%#v != %#v", u, test.out)
+			}
+		})
+	}
+}
+
+func TestVersioningRoundTrip(t *testing.T) {
+	type container struct {
+		Users []*versionedUser
+	}
+
+	e := NewValueEmitter()
+	c1 := container{Users: []*versionedUser{{"Luke", "Skywalker", 19}, {"Leia", "Organa", 19}}}
+	c2 := container{}
+
+	if err := NewEncoder(e).Encode(c1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewDecoder(NewValueParser(e.Value())).Decode(&c2); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(c1, c2) {
+		t.Errorf("%#v != %#v", c1, c2)
+	}
+}
+
+func TestVersioningErrors(t *testing.T) {
+	tests := []interface{}{
+		map[string]interface{}{DefaultVersionKey: 3},
+		map[string]interface{}{DefaultVersionKey: -1},
+		map[string]interface{}{DefaultVersionKey: "A"},
+		map[string]interface{}{"Name": "Luke"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test), func(t *testing.T) {
+			var u versionedUser
+
+			if err := NewDecoder(NewValueParser(test)).Decode(&u); err == nil {
+				t.Error("no error was returned")
+			}
+		})
+	}
+}
+
+func TestVersioningDecodeOptions(t *testing.T) {
+	var v struct {
+		U versionedUser `objconv:"u"`
+	}
+
+	src := map[string]interface{}{
+		"u": map[string]interface{}{
+			DefaultVersionKey: 2,
+			"FirstName":       []interface{}{1},
+			"LastName":        "Skywalker",
+			"Age":             "19",
+		},
+	}
+
+	// The migrated value is decoded with the options of the decoder.
+	d := Decoder{Parser: NewValueParser(src), Coerce: DefaultCoercions | StringToNumber, CollectErrors: true}
+	err := d.Decode(&v)
+
+	errs, ok := err.(DecodeErrors)
+	if !ok || len(errs) != 1 || errs[0].Path != "/u/FirstName" {
+		t.Fatal("expected a single error for /u/FirstName but got", err)
+	}
+
+	if v.U != (versionedUser{LastName: "Skywalker", Age: 19}) {
+		t.Errorf("bad value: %#v", v.U)
+	}
+}
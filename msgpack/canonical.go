@@ -0,0 +1,146 @@
+package msgpack
+
+import (
+	"io"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/objutil"
+)
+
+// CanonicalEmitter implements a MessagePack emitter that produces a canonical
+// representation of values.
+//
+// Integers are written with the smallest encoding that can represent them,
+// regardless of their signedness, floating point values are written in single
+// precision when it doesn't lose information, and map keys are sorted by the
+// bytewise lexicographic order of their encoding.
+//
+// Maps, and arrays that begin with an unknown length, are buffered in memory
+// until they end. Contrary to the standard emitter, maps of unknown length are
+// supported.
+type CanonicalEmitter struct {
+	Emitter
+	c objutil.CanonicalWriter // the emitter writes to c, which writes to the output
+}
+
+// NewCanonicalEmitter returns a new emitter which writes the canonical
+// MessagePack representation of values to w.
+func NewCanonicalEmitter(w io.Writer) *CanonicalEmitter {
+	e := &CanonicalEmitter{Emitter: *NewEmitter(w)}
+	e.c.Reset(e.Emitter.w)
+	e.Emitter.w = &e.c
+	return e
+}
+
+func (e *CanonicalEmitter) Reset(w io.Writer) {
+	e.Emitter.Reset(w)
+	e.c.Reset(e.Emitter.w)
+	e.Emitter.w = &e.c
+}
+
+func (e *CanonicalEmitter) Abort() error {
+	e.c.Abort()
+	return e.Emitter.Abort()
+}
+
+func (e *CanonicalEmitter) EmitInt(v int64, bitSize int) (err error) {
+	if v >= 0 {
+		return e.EmitUint(uint64(v), bitSize)
+	}
+
+	n := 0
+
+	switch {
+	case v >= -32:
+		e.b[0] = byte(v) | NegativeFixintTag
+		n = 1
+
+	case v >= objutil.Int8Min:
+		e.b[0] = Int8
+		e.b[1] = byte(v)
+		n = 2
+
+	case v >= objutil.Int16Min:
+		e.b[0] = Int16
+		putUint16(e.b[1:], uint16(v))
+		n = 3
+
+	case v >= objutil.Int32Min:
+		e.b[0] = Int32
+		putUint32(e.b[1:], uint32(v))
+		n = 5
+
+	default:
+		e.b[0] = Int64
+		putUint64(e.b[1:], uint64(v))
+		n = 9
+	}
+
+	_, err = e.Emitter.w.Write(e.b[:n])
+	return
+}
+
+func (e *CanonicalEmitter) EmitUint(v uint64, bitSize int) (err error) {
+	if v <= objutil.Int8Max {
+		e.b[0] = byte(v) | PositiveFixintTag
+		_, err = e.Emitter.w.Write(e.b[:1])
+		return
+	}
+	return e.Emitter.EmitUint(v, bitSize)
+}
+
+func (e *CanonicalEmitter) EmitFloat(v float64, _ int) (err error) {
+	if float64(float32(v)) == v || v != v {
+		return e.Emitter.EmitFloat(v, 32)
+	}
+	return e.Emitter.EmitFloat(v, 64)
+}
+
+func (e *CanonicalEmitter) EmitArrayBegin(n int) (err error) {
+	e.c.BeginArray(n)
+
+	if n >= 0 {
+		e.out.Push()
+		err = e.emitArray(n)
+	}
+
+	return
+}
+
+func (e *CanonicalEmitter) EmitArrayEnd() (err error) {
+	var buffered bool
+
+	if buffered, err = e.c.EndArray(e.emitArray); buffered {
+		return
+	}
+
+	return e.out.Pop()
+}
+
+func (e *CanonicalEmitter) EmitArrayNext() (err error) {
+	e.c.ArrayNext()
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapBegin(_ int) (err error) {
+	e.c.BeginMap()
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapEnd() (err error) {
+	return e.c.EndMap(e.emitMap)
+}
+
+func (e *CanonicalEmitter) EmitMapValue() (err error) {
+	e.c.MapValue()
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapNext() (err error) {
+	e.c.MapNext()
+	return
+}
+
+func (e *CanonicalEmitter) CanonicalEmitter() objconv.Emitter {
+	return e
+}
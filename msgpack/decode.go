@@ -1,7 +1,6 @@
 package msgpack
 
 import (
-	"bytes"
 	"io"
 	"sync"
 
@@ -15,37 +14,38 @@ func NewDecoder(r io.Reader) *objconv.Decoder {
 
 // NewStreamDecoder returns a new MessagePack stream decoder that parses values from r.
 func NewStreamDecoder(r io.Reader) *objconv.StreamDecoder {
-	return objconv.NewStreamDecoder(NewParser(r))
+	return Codec.NewStreamDecoder(r)
 }
 
 // Unmarshal decodes a MessagePack representation of v from b.
 func Unmarshal(b []byte, v interface{}) error {
-	u := unmarshalerPool.Get().(*unmarshaler)
-	u.reset(b)
-
-	err := (objconv.Decoder{Parser: u}).Decode(v)
-
-	u.reset(nil)
-	unmarshalerPool.Put(u)
-	return err
+	return unmarshal(b, v, false)
 }
 
-var unmarshalerPool = sync.Pool{
-	New: func() interface{} { return newUnmarshaler() },
+// UnmarshalNoCopy decodes a MessagePack representation of v from b, like Unmarshal,
+// but the strings and byte slices decoded in v point into b instead of being
+// copied, which saves memory allocations and copies when decoding payloads
+// that are already in memory.
+//
+// The content of b must not be modified for as long as v, or any of the values
+// that were decoded in it, are in use. This is usually the case when b is not
+// reused after being decoded, a buffer obtained from a pool must not be
+// returned to it for example.
+func UnmarshalNoCopy(b []byte, v interface{}) error {
+	return unmarshal(b, v, true)
 }
 
-type unmarshaler struct {
-	Parser
-	b bytes.Buffer
-}
+func unmarshal(b []byte, v interface{}, noCopy bool) error {
+	p := unmarshalerPool.Get().(*Parser)
+	p.ResetBytes(b)
+
+	err := (objconv.Decoder{Parser: p, NoCopy: noCopy}).Decode(v)
 
-func newUnmarshaler() *unmarshaler {
-	u := &unmarshaler{}
-	u.r = &u.b
-	return u
+	p.ResetBytes(nil)
+	unmarshalerPool.Put(p)
+	return err
 }
 
-func (u *unmarshaler) reset(b []byte) {
-	u.b = *bytes.NewBuffer(b)
-	u.Reset(&u.b)
+var unmarshalerPool = sync.Pool{
+	New: func() interface{} { return NewBytesParser(nil) },
 }
@@ -8,14 +8,19 @@ import (
 	"sync"
 	"time"
 
+	"github.com/segmentio/objconv"
 	"github.com/segmentio/objconv/objutil"
 )
 
 // Emitter implements a MessagePack emitter that satisfies the objconv.Emitter
 // interface.
+//
+// The output is buffered while arrays and maps are being emitted, see
+// objutil.BufferedWriter.
 type Emitter struct {
-	w io.Writer
-	b [240]byte
+	w   io.Writer
+	out *objutil.BufferedWriter
+	b   [240]byte
 
 	// This stack is used to cache arrays that are emitted in streaming mode,
 	// where the length of the array is not known before outputing all the
@@ -34,13 +39,15 @@ type context struct {
 }
 
 func NewEmitter(w io.Writer) *Emitter {
-	e := &Emitter{w: w}
+	b := objutil.NewBufferedWriter(w)
+	e := &Emitter{w: b, out: b}
 	e.stack = e.sback[:0]
 	return e
 }
 
 func (e *Emitter) Reset(w io.Writer) {
-	e.w = w
+	e.out.Reset(w)
+	e.w = e.out
 	e.stack = e.stack[:0]
 }
 
@@ -285,6 +292,8 @@ func (e *Emitter) EmitError(v error) (err error) {
 func (e *Emitter) EmitArrayBegin(n int) (err error) {
 	var c *context
 
+	e.out.Push()
+
 	if n < 0 {
 		c = contextPool.Get().(*context)
 		c.b.Truncate(0)
@@ -316,9 +325,13 @@ func (e *Emitter) EmitArrayEnd() (err error) {
 		}
 
 		contextPool.Put(c)
+
+		if err != nil {
+			return
+		}
 	}
 
-	return
+	return e.out.Pop()
 }
 
 func (e *Emitter) EmitArrayNext() (err error) {
@@ -334,23 +347,66 @@ func (e *Emitter) EmitMapBegin(n int) (err error) {
 		return
 	}
 
+	e.out.Push()
+	return e.emitMap(n)
+}
+
+func (e *Emitter) EmitMapEnd() (err error) {
+	return e.out.Pop()
+}
+
+func (e *Emitter) EmitMapValue() (err error) {
+	return
+}
+
+func (e *Emitter) EmitMapNext() (err error) {
+	return
+}
+
+// Flush writes the buffered output to the underlying writer.
+func (e *Emitter) Flush() error {
+	return e.out.Flush()
+}
+
+// Abort writes the buffered output to the underlying writer and discards the
+// state of the arrays and maps that were left open when encoding a value failed.
+func (e *Emitter) Abort() error {
+	for i := len(e.stack) - 1; i >= 0; i-- {
+		if c := e.stack[i]; c != nil {
+			e.w = c.w
+			contextPool.Put(c)
+		}
+	}
+	e.stack = e.stack[:0]
+	return e.out.Abort()
+}
+
+func (e *Emitter) LengthEmitter() bool {
+	return true
+}
+
+func (e *Emitter) CanonicalEmitter() objconv.Emitter {
+	return NewCanonicalEmitter(e.w)
+}
+
+func (e *Emitter) emitArray(n int) (err error) {
 	switch {
 	case n <= 15:
-		e.b[0] = byte(n) | FixmapTag
+		e.b[0] = byte(n) | FixarrayTag
 		n = 1
 
 	case n <= objutil.Uint16Max:
-		e.b[0] = Map16
+		e.b[0] = Array16
 		putUint16(e.b[1:], uint16(n))
 		n = 3
 
 	case n <= objutil.Uint32Max:
-		e.b[0] = Map32
+		e.b[0] = Array32
 		putUint32(e.b[1:], uint32(n))
 		n = 5
 
 	default:
-		err = fmt.Errorf("objconv/msgpack: map of length %d is too long to be encoded", n)
+		err = fmt.Errorf("objconv/msgpack: array of length %d is too long to be encoded", n)
 		return
 	}
 
@@ -358,36 +414,24 @@ func (e *Emitter) EmitMapBegin(n int) (err error) {
 	return
 }
 
-func (e *Emitter) EmitMapEnd() (err error) {
-	return
-}
-
-func (e *Emitter) EmitMapValue() (err error) {
-	return
-}
-
-func (e *Emitter) EmitMapNext() (err error) {
-	return
-}
-
-func (e *Emitter) emitArray(n int) (err error) {
+func (e *Emitter) emitMap(n int) (err error) {
 	switch {
 	case n <= 15:
-		e.b[0] = byte(n) | FixarrayTag
+		e.b[0] = byte(n) | FixmapTag
 		n = 1
 
 	case n <= objutil.Uint16Max:
-		e.b[0] = Array16
+		e.b[0] = Map16
 		putUint16(e.b[1:], uint16(n))
 		n = 3
 
 	case n <= objutil.Uint32Max:
-		e.b[0] = Array32
+		e.b[0] = Map32
 		putUint32(e.b[1:], uint32(n))
 		n = 5
 
 	default:
-		err = fmt.Errorf("objconv/msgpack: array of length %d is too long to be encoded", n)
+		err = fmt.Errorf("objconv/msgpack: map of length %d is too long to be encoded", n)
 		return
 	}
 
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/objutil"
 )
 
 // NewEncoder returns a new MessagePack encoder that writes to w.
@@ -18,6 +19,18 @@ func NewStreamEncoder(w io.Writer) *objconv.StreamEncoder {
 	return objconv.NewStreamEncoder(NewEmitter(w))
 }
 
+// NewCanonicalEncoder returns a new MessagePack encoder that writes to w using
+// the canonical representation of values.
+func NewCanonicalEncoder(w io.Writer) *objconv.Encoder {
+	return objconv.NewEncoder(NewCanonicalEmitter(w))
+}
+
+// NewCanonicalStreamEncoder returns a new MessagePack stream encoder that
+// writes to w using the canonical representation of values.
+func NewCanonicalStreamEncoder(w io.Writer) *objconv.StreamEncoder {
+	return objconv.NewStreamEncoder(NewCanonicalEmitter(w))
+}
+
 // Marshal writes the MessagePack representation of v to a byte slice returned in b.
 func Marshal(v interface{}) (b []byte, err error) {
 	m := marshalerPool.Get().(*marshaler)
@@ -32,6 +45,27 @@ func Marshal(v interface{}) (b []byte, err error) {
 	return
 }
 
+// Append appends the MessagePack representation of v to dst and returns the extended
+// buffer. Unlike Marshal, the value is written directly to dst, so no copy is
+// made when dst has enough capacity. On error, dst is returned unchanged.
+func Append(dst []byte, v interface{}) (b []byte, err error) {
+	m := marshalerPool.Get().(*marshaler)
+	buf := m.b.Bytes()[:0]
+	m.b = *bytes.NewBuffer(dst)
+
+	if err = (objconv.Encoder{Emitter: m}).Encode(v); err == nil {
+		b = m.b.Bytes()
+	} else {
+		b = dst
+	}
+
+	// The buffer of the marshaler is restored so dst isn't retained by the
+	// pool.
+	m.b = *bytes.NewBuffer(buf)
+	marshalerPool.Put(m)
+	return
+}
+
 var marshalerPool = sync.Pool{
 	New: func() interface{} { return newMarshaler() },
 }
@@ -44,5 +78,6 @@ type marshaler struct {
 func newMarshaler() *marshaler {
 	m := &marshaler{}
 	m.w = &m.b
+	m.out = objutil.NewBufferedWriter(&m.b)
 	return m
 }
@@ -12,6 +12,20 @@ var Codec = objconv.Codec{
 	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
 }
 
+// CanonicalCodec for the MessagePack format, producing the canonical
+// representation of values.
+var CanonicalCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewCanonicalEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+}
+
+// SequenceCodec for streams of MessagePack values written one after the other
+// instead of being wrapped in an array.
+var SequenceCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewSequenceEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewSequenceParser(r) },
+}
+
 func init() {
 	for _, name := range [...]string{
 		"application/msgpack",
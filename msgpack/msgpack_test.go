@@ -1,8 +1,16 @@
 package msgpack
 
 import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/json"
 	"github.com/segmentio/objconv/objtests"
 )
 
@@ -13,3 +21,444 @@ func TestCodec(t *testing.T) {
 func BenchmarkCodec(b *testing.B) {
 	objtests.BenchmarkCodec(b, Codec)
 }
+
+func TestSequenceCodec(t *testing.T) {
+	objtests.TestCodec(t, SequenceCodec)
+}
+
+func TestSequenceStream(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := SequenceCodec.NewStreamEncoder(b)
+
+	for _, v := range []interface{}{1, "a", []interface{}{true, nil}} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The values are written one after the other, not wrapped in an array.
+	if s := hex.EncodeToString(b.Bytes()); s != "01a16192c3c0" {
+		t.Error(s)
+	}
+
+	d := SequenceCodec.NewStreamDecoder(b)
+	values := []interface{}{}
+
+	for {
+		var v interface{}
+
+		if err := d.Decode(&v); err != nil {
+			if err != objconv.End {
+				t.Fatal(err)
+			}
+			break
+		}
+
+		values = append(values, v)
+	}
+
+	expect := []interface{}{int64(1), "a", []interface{}{true, nil}}
+
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("%#v != %#v", values, expect)
+	}
+}
+
+func TestSequenceTruncated(t *testing.T) {
+	d := SequenceCodec.NewStreamDecoder(bytes.NewReader([]byte{0x01, 0x92, 0x01}))
+	var v interface{}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(v, err)
+	}
+
+	if err := d.Decode(&v); err != io.ErrUnexpectedEOF {
+		t.Errorf("invalid error returned for a truncated value: %v", err)
+	}
+}
+
+func TestStreamDecoderRecover(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec objconv.Codec
+		offs  []int64
+	}{
+		{"Array", Codec, []int64{2, 5}},
+		{"Sequence", SequenceCodec, []int64{1, 4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			e := test.codec.NewStreamEncoder(b)
+
+			for _, v := range []interface{}{1, "a", 2, []int{3}, 4} {
+				if err := e.Encode(v); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := e.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			skipped := []int{}
+			offsets := []int64{}
+			values := []int{}
+
+			d := test.codec.NewStreamDecoder(b)
+			d.Recover = func(e *objconv.StreamError) error {
+				skipped = append(skipped, e.Index)
+				offsets = append(offsets, e.Offset)
+				return nil
+			}
+
+			for {
+				v := 0
+
+				if err := d.Decode(&v); err != nil {
+					if err != objconv.End {
+						t.Fatal(err)
+					}
+					break
+				}
+
+				values = append(values, v)
+			}
+
+			if expect := []int{1, 3}; !reflect.DeepEqual(skipped, expect) {
+				t.Errorf("invalid indexes of skipped values: %v != %v", skipped, expect)
+			}
+
+			if !reflect.DeepEqual(offsets, test.offs) {
+				t.Errorf("invalid offsets of skipped values: %v != %v", offsets, test.offs)
+			}
+
+			if expect := []int{1, 2, 4}; !reflect.DeepEqual(values, expect) {
+				t.Errorf("invalid values decoded: %v != %v", values, expect)
+			}
+		})
+	}
+}
+
+func TestCanonicalCodec(t *testing.T) {
+	objtests.TestCodec(t, CanonicalCodec)
+}
+
+func TestCanonicalEncoding(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		s string
+	}{
+		{int64(1), "01"},
+		{uint64(200), "ccc8"},
+		{int64(200), "ccc8"},
+		{int64(-32), "e0"},
+		{int64(-33), "d0df"},
+		{int64(300), "cd012c"},
+		{1.5, "ca3fc00000"},
+		{1.1, "cb3ff199999999999a"},
+		{map[interface{}]int{"aa": 1, "b": 2, 10: 3}, "830a03a16202a2616101"},
+		{struct {
+			B int `objconv:"b"`
+			A int `objconv:"a"`
+		}{1, 2}, "82a16102a16201"},
+	}
+
+	for _, test := range tests {
+		b := &bytes.Buffer{}
+
+		if err := NewCanonicalEncoder(b).Encode(test.v); err != nil {
+			t.Error(err)
+		} else if s := hex.EncodeToString(b.Bytes()); s != test.s {
+			t.Errorf("%#v: %s != %s", test.v, s, test.s)
+		}
+	}
+}
+
+func TestCanonicalIndefiniteLength(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewCanonicalEmitter(b)
+
+	e.EmitMapBegin(-1)
+	e.EmitString("b")
+	e.EmitMapValue()
+	e.EmitArrayBegin(-1)
+	e.EmitInt(1, 0)
+	e.EmitArrayNext()
+	e.EmitInt(2, 0)
+	e.EmitArrayEnd()
+	e.EmitMapNext()
+	e.EmitString("a")
+	e.EmitMapValue()
+	e.EmitArrayBegin(-1)
+	e.EmitArrayEnd()
+	e.EmitMapEnd()
+
+	if s := hex.EncodeToString(b.Bytes()); s != "82a16190a162920102" {
+		t.Error(s)
+	}
+}
+
+func TestEncodeUnknownLength(t *testing.T) {
+	ch := make(chan int, 2)
+	ch <- 1
+	ch <- 2
+	close(ch)
+
+	b := &bytes.Buffer{}
+	i := 0
+
+	enc := NewEncoder(b)
+	enc.BufferUnknownLengths = true
+
+	if err := enc.EncodeMap(-1, func(k objconv.Encoder, v objconv.Encoder) (err error) {
+		if i++; i > 1 {
+			return objconv.End
+		}
+		if err = k.Encode("a"); err == nil {
+			err = v.Encode(ch)
+		}
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := hex.EncodeToString(b.Bytes()); s != "81a161920102" {
+		t.Error(s)
+	}
+}
+
+// failingValue is a value which fails to be encoded.
+type failingValue struct{}
+
+func (failingValue) EncodeValue(objconv.Encoder) error {
+	return fmt.Errorf("failing value")
+}
+
+func TestEncodeAfterError(t *testing.T) {
+	emitters := []struct {
+		name string
+		new  func(io.Writer) objconv.Emitter
+	}{
+		{"Emitter", func(w io.Writer) objconv.Emitter { return NewEmitter(w) }},
+		{"CanonicalEmitter", func(w io.Writer) objconv.Emitter { return NewCanonicalEmitter(w) }},
+	}
+
+	for _, x := range emitters {
+		t.Run(x.name, func(t *testing.T) {
+			v := map[string]int{"a": 1}
+			b1 := &bytes.Buffer{}
+			b2 := &bytes.Buffer{}
+
+			if err := objconv.NewEncoder(x.new(b1)).Encode(v); err != nil {
+				t.Fatal(err)
+			}
+
+			// The writer hides the type of the buffer so the output of the
+			// emitter is buffered.
+			e := objconv.NewEncoder(x.new(struct{ io.Writer }{b2}))
+
+			if err := e.Encode([]interface{}{1, map[string]interface{}{"b": failingValue{}}}); err == nil {
+				t.Fatal("expected an error when encoding a failing value")
+			}
+
+			b2.Reset()
+
+			if err := e.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(b1.Bytes(), b2.Bytes()) {
+				t.Errorf("%x != %x", b2.Bytes(), b1.Bytes())
+			}
+		})
+	}
+}
+
+func TestMarshalChanBitSize(t *testing.T) {
+	f := make(chan float32, 2)
+	f <- 1.5
+	f <- -2
+	close(f)
+
+	i := make(chan int8, 2)
+	i <- -1
+	i <- 100
+	close(i)
+
+	m := make(chan map[string]uint16, 1)
+	m <- map[string]uint16{"a": 1000}
+	close(m)
+
+	tests := []struct {
+		slice interface{}
+		ch    interface{}
+	}{
+		{[]float32{1.5, -2}, f},
+		{[]int8{-1, 100}, i},
+		{[]map[string]uint16{{"a": 1000}}, m},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T", test.ch), func(t *testing.T) {
+			b1, err := Marshal(test.slice)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The elements received from the channel are buffered until the
+			// channel is closed, they must be written with their bit size.
+			b2, err := Marshal(test.ch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(b1, b2) {
+				t.Errorf("%x != %x", b1, b2)
+			}
+		})
+	}
+}
+
+func TestCopyFromJSON(t *testing.T) {
+	b := &bytes.Buffer{}
+	p := json.NewParser(strings.NewReader(`{"b":[1,{"c":null,"a":[]}],"a":"hello"}`))
+
+	if err := objconv.Copy(NewEmitter(b), p); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading the tokens validates that the lengths were set and the order of
+	// the keys was preserved.
+	r := objconv.NewTokenReader(NewParser(b))
+	tokens := []objconv.Token{}
+
+	for i := 0; i != 15; i++ {
+		tok, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, tok)
+	}
+
+	expect := []objconv.Token{
+		{Kind: objconv.MapBeginToken, Type: objconv.Map, Len: 2},
+		{Kind: objconv.ValueToken, Type: objconv.String, Value: "b"},
+		{Kind: objconv.ArrayBeginToken, Type: objconv.Array, Len: 2},
+		{Kind: objconv.ValueToken, Type: objconv.Int, Value: int64(1)},
+		{Kind: objconv.MapBeginToken, Type: objconv.Map, Len: 2},
+		{Kind: objconv.ValueToken, Type: objconv.String, Value: "c"},
+		{Kind: objconv.ValueToken, Type: objconv.Nil},
+		{Kind: objconv.ValueToken, Type: objconv.String, Value: "a"},
+		{Kind: objconv.ArrayBeginToken, Type: objconv.Array, Len: 0},
+		{Kind: objconv.ArrayEndToken, Type: objconv.Array, Len: 0},
+		{Kind: objconv.MapEndToken, Type: objconv.Map, Len: 2},
+		{Kind: objconv.ArrayEndToken, Type: objconv.Array, Len: 2},
+		{Kind: objconv.ValueToken, Type: objconv.String, Value: "a"},
+		{Kind: objconv.ValueToken, Type: objconv.String, Value: "hello"},
+		{Kind: objconv.MapEndToken, Type: objconv.Map, Len: 2},
+	}
+
+	if !reflect.DeepEqual(tokens, expect) {
+		t.Errorf("\n%#v\n%#v", tokens, expect)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	v := []interface{}{"Hello World!", 42}
+
+	b1, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := append(make([]byte, 0, 1024), "..."...)
+
+	b2, err := Append(dst, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b2[:3], dst) || !bytes.Equal(b2[3:], b1) {
+		t.Errorf("%q != %q + %q", b2, dst, b1)
+	}
+
+	if &b2[0] != &dst[0] {
+		t.Error("the value was not written to the buffer passed to Append")
+	}
+}
+
+func TestCopyBitSize(t *testing.T) {
+	b1, err := Marshal([]interface{}{float32(1.1), int8(-100), uint16(1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The numbers are written with the sizes they were encoded with.
+	b2 := &bytes.Buffer{}
+
+	if err := objconv.Copy(NewEmitter(b2), NewParser(bytes.NewReader(b1))); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b1, b2.Bytes()) {
+		t.Errorf("%x != %x", b1, b2.Bytes())
+	}
+
+	// float32 values keep their shortest representation when transcoded to
+	// JSON instead of the one of their float64 conversion.
+	b3 := &bytes.Buffer{}
+
+	if err := objconv.Copy(json.NewEmitter(b3), NewParser(bytes.NewReader(b1))); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := b3.String(); s != "[1.1,-100,1000]" {
+		t.Error(s)
+	}
+}
+
+func TestUnmarshalNoCopy(t *testing.T) {
+	type T struct {
+		S string
+		B []byte
+	}
+
+	b, err := Marshal(T{S: "Hello", B: []byte("World")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v1, v2 T
+
+	if err := Unmarshal(b, &v1); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalNoCopy(b, &v2); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v1, v2) {
+		t.Fatalf("%#v != %#v", v1, v2)
+	}
+
+	if cap(v2.B) != len(v2.B) {
+		t.Errorf("the capacity of the byte slice was not limited to its length: %d != %d", cap(v2.B), len(v2.B))
+	}
+
+	for i := range b {
+		b[i] = 0
+	}
+
+	if v1.S != "Hello" || string(v1.B) != "World" {
+		t.Errorf("Unmarshal returned values referencing the input: %#v", v1)
+	}
+	if v2.S == "Hello" || string(v2.B) == "World" {
+		t.Errorf("UnmarshalNoCopy returned values copied from the input: %#v", v2)
+	}
+}
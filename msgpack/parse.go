@@ -15,23 +15,82 @@ type Parser struct {
 	i int       // offset of the first unread byte in b
 	j int       // offset + 1 of the last unread byte in b
 	s []byte    // string buffer
-	b [240]byte // read buffer
+	b []byte    // read buffer, a or the input of parsers created by NewBytesParser
+	a [240]byte // backing array of the read buffer
+	n int64     // number of bytes read from r
+
+	// Size in bits of the last number parsed, see BitSize.
+	bits int
+
+	// Set when the parser reads from an in-memory input, the byte slices that
+	// it returns are views of the input.
+	stable bool
+
+	// State of ParseRaw, the bytes of the value are accumulated in raw when
+	// the read buffer is refilled, k is the offset of the first byte of the
+	// value that remains in b.
+	raw     []byte
+	k       int
+	capture bool
 }
 
 func NewParser(r io.Reader) *Parser {
 	return &Parser{r: r}
 }
 
+// NewBytesParser returns a new parser which reads values from b.
+//
+// The strings and byte slices returned by the parser are views of b, which
+// decoders configured with NoCopy retain in the values they decode instead of
+// copying them. The content of b must not be modified while those values are
+// in use.
+func NewBytesParser(b []byte) *Parser {
+	p := &Parser{}
+	p.ResetBytes(b)
+	return p
+}
+
 func (p *Parser) Reset(r io.Reader) {
 	p.r = r
 	p.i = 0
 	p.j = 0
+	p.n = 0
+	p.b = nil
+	p.stable = false
+}
+
+// ResetBytes resets the parser to read values from b, see NewBytesParser.
+func (p *Parser) ResetBytes(b []byte) {
+	p.r = nil
+	p.i = 0
+	p.j = len(b)
+	p.n = int64(len(b))
+	p.b = b
+	p.stable = true
+}
+
+// StableParser returns true if the parser reads from an in-memory input, see
+// NewBytesParser.
+func (p *Parser) StableParser() bool {
+	return p.stable
+}
+
+// BitSize returns the size in bits of the last number parsed, which is the size
+// of the integer or floating-point type it was encoded with.
+func (p *Parser) BitSize() int {
+	return p.bits
 }
 
 func (p *Parser) Buffered() io.Reader {
 	return bytes.NewReader(p.b[p.i:p.j])
 }
 
+// InputOffset returns the number of bytes of the input that were consumed by
+// the parser.
+func (p *Parser) InputOffset() int64 {
+	return p.n - int64(p.j-p.i)
+}
+
 func (p *Parser) ParseType() (objconv.Type, error) {
 	b, err := p.peek(1)
 	if err != nil {
@@ -144,8 +203,10 @@ func (p *Parser) ParseInt() (v int64, err error) {
 
 	switch {
 	case (tag & PositiveFixintMask) == PositiveFixintTag:
+		p.bits = 8
 		return int64(int8(tag)), nil
 	case (tag & NegativeFixintMask) == NegativeFixintTag:
+		p.bits = 8
 		return int64(int8(tag)), nil
 	}
 
@@ -176,6 +237,7 @@ func (p *Parser) ParseInt() (v int64, err error) {
 	}
 
 	p.i += n
+	p.bits = 8 * n
 	return
 }
 
@@ -213,6 +275,7 @@ func (p *Parser) ParseUint() (v uint64, err error) {
 	}
 
 	p.i += n
+	p.bits = 8 * n
 	return
 }
 
@@ -237,9 +300,11 @@ func (p *Parser) ParseFloat() (v float64, err error) {
 	case Float32:
 		v = float64(math.Float32frombits(getUint32(b)))
 		p.i += 4
+		p.bits = 32
 	default:
 		v = math.Float64frombits(getUint64(b))
 		p.i += 8
+		p.bits = 64
 	}
 
 	return
@@ -460,13 +525,179 @@ func (p *Parser) ParseMapNext(n int) (err error) {
 	return
 }
 
+// Skip discards the next value, strings, binaries and extensions are jumped
+// over using their length prefix.
+func (p *Parser) Skip() (err error) {
+	// n is the number of values remaining to be skipped, it grows when arrays
+	// and maps are found.
+	for n := 1; n != 0; n-- {
+		var b []byte
+		var size int
+
+		if b, err = p.peek(1); err != nil {
+			return
+		}
+
+		tag := b[0]
+		p.i++
+
+		switch {
+		case (tag & PositiveFixintMask) == PositiveFixintTag:
+			continue
+
+		case (tag & NegativeFixintMask) == NegativeFixintTag:
+			continue
+
+		case (tag & FixstrMask) == FixstrTag:
+			err = p.discard(int(tag & ^byte(FixstrMask)))
+
+		case (tag & FixarrayMask) == FixarrayTag:
+			n += int(tag & ^byte(FixarrayMask))
+
+		case (tag & FixmapMask) == FixmapTag:
+			n += 2 * int(tag & ^byte(FixmapMask))
+
+		default:
+			switch tag {
+			case Nil, False, True:
+
+			case Uint8, Int8:
+				err = p.discard(1)
+
+			case Uint16, Int16:
+				err = p.discard(2)
+
+			case Uint32, Int32, Float32:
+				err = p.discard(4)
+
+			case Uint64, Int64, Float64:
+				err = p.discard(8)
+
+			case Fixext1, Fixext2, Fixext4, Fixext8, Fixext16:
+				// extension type followed by 1, 2, 4, 8, or 16 bytes
+				err = p.discard(1 + (1 << (tag - Fixext1)))
+
+			case Str8, Bin8:
+				if size, err = p.readLength(1); err == nil {
+					err = p.discard(size)
+				}
+
+			case Str16, Bin16:
+				if size, err = p.readLength(2); err == nil {
+					err = p.discard(size)
+				}
+
+			case Str32, Bin32:
+				if size, err = p.readLength(4); err == nil {
+					err = p.discard(size)
+				}
+
+			case Ext8:
+				if size, err = p.readLength(1); err == nil {
+					err = p.discard(size + 1)
+				}
+
+			case Ext16:
+				if size, err = p.readLength(2); err == nil {
+					err = p.discard(size + 1)
+				}
+
+			case Ext32:
+				if size, err = p.readLength(4); err == nil {
+					err = p.discard(size + 1)
+				}
+
+			case Array16:
+				if size, err = p.readLength(2); err == nil {
+					n += size
+				}
+
+			case Array32:
+				if size, err = p.readLength(4); err == nil {
+					n += size
+				}
+
+			case Map16:
+				if size, err = p.readLength(2); err == nil {
+					n += 2 * size
+				}
+
+			case Map32:
+				if size, err = p.readLength(4); err == nil {
+					n += 2 * size
+				}
+
+			default:
+				err = fmt.Errorf("objconv/msgpack: unknown tag '%#x'", tag)
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// ParseRaw appends the bytes of the next value to b and returns the extended
+// slice, the value is consumed without being decoded.
+func (p *Parser) ParseRaw(b []byte) ([]byte, error) {
+	p.raw, p.k, p.capture = b, p.i, true
+	err := p.Skip()
+	b = append(p.raw, p.b[p.k:p.i]...)
+	p.raw, p.k, p.capture = nil, 0, false
+	return b, err
+}
+
+// readLength reads a big-endian length prefix of n bytes.
+func (p *Parser) readLength(n int) (size int, err error) {
+	var b []byte
+
+	if b, err = p.peek(n); err != nil {
+		return
+	}
+
+	switch n {
+	case 1:
+		size = int(b[0])
+	case 2:
+		size = int(getUint16(b))
+	default:
+		size = int(getUint32(b))
+	}
+
+	p.i += n
+	return
+}
+
+// discard drops the next n bytes from the input.
+func (p *Parser) discard(n int) (err error) {
+	for n > (p.j - p.i) {
+		n -= p.j - p.i
+		p.i = p.j
+
+		if err = p.fill(); err != nil {
+			return
+		}
+	}
+
+	p.i += n
+	return
+}
+
 func (p *Parser) read(n int) (b []byte, err error) {
 	if n <= (p.j - p.i) { // check if the string is already buffered
-		b = p.b[p.i : p.i+n]
+		b = p.b[p.i : p.i+n : p.i+n]
 		p.i += n
 		return
 	}
 
+	if p.stable { // the whole input is buffered
+		err = io.ErrUnexpectedEOF
+		return
+	}
+
 	if n <= len(p.b) { // check if the string can be loaded in the read buffer
 		if b, err = p.peek(n); err != nil {
 			return
@@ -486,7 +717,10 @@ func (p *Parser) read(n int) (b []byte, err error) {
 	p.i = 0
 	p.j = 0
 
-	if _, err = io.ReadFull(p.r, p.s[n:]); err != nil {
+	m, err := io.ReadFull(p.r, p.s[n:])
+	p.n += int64(m)
+
+	if err != nil {
 		return
 	}
 
@@ -505,6 +739,19 @@ func (p *Parser) peek(n int) (b []byte, err error) {
 }
 
 func (p *Parser) fill() (err error) {
+	if p.stable {
+		return io.EOF
+	}
+
+	if p.b == nil {
+		p.b = p.a[:]
+	}
+
+	if p.capture {
+		p.raw = append(p.raw, p.b[p.k:p.i]...)
+		p.k = 0
+	}
+
 	n := p.j - p.i
 	copy(p.b[:], p.b[p.i:p.j])
 	p.i = 0
@@ -513,6 +760,7 @@ func (p *Parser) fill() (err error) {
 	if n, err = p.r.Read(p.b[n:]); n > 0 {
 		err = nil
 		p.j += n
+		p.n += int64(n)
 	} else if err != nil {
 		return
 	} else {
@@ -0,0 +1,37 @@
+package msgpack
+
+import "io"
+
+// SequenceEmitter implements a MessagePack emitter for streams written as sequences
+// of values, stream encoders write the values one after the other instead of
+// wrapping them in an array.
+type SequenceEmitter struct {
+	Emitter
+}
+
+// NewSequenceEmitter returns a new emitter which writes sequences of MessagePack
+// values to w.
+func NewSequenceEmitter(w io.Writer) *SequenceEmitter {
+	return &SequenceEmitter{Emitter: *NewEmitter(w)}
+}
+
+func (e *SequenceEmitter) SequenceEmitter() bool {
+	return true
+}
+
+// SequenceParser implements a MessagePack parser for streams read as sequences of
+// values, stream decoders read values until the end of the input instead of
+// expecting an array.
+type SequenceParser struct {
+	Parser
+}
+
+// NewSequenceParser returns a new parser which reads sequences of MessagePack values
+// from r.
+func NewSequenceParser(r io.Reader) *SequenceParser {
+	return &SequenceParser{Parser: *NewParser(r)}
+}
+
+func (p *SequenceParser) SequenceParser() bool {
+	return true
+}
@@ -0,0 +1,608 @@
+// Code generated by objconv-gen. DO NOT EDIT.
+
+package objtests
+
+import (
+	"time"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/objutil"
+)
+
+// EncodeValue satisfies the objconv.ValueEncoder interface.
+func (x genScalars) EncodeValue(e objconv.Encoder) error {
+	n := 20
+	return e.EncodeStruct(n, func(e objconv.Encoder) (err error) {
+		i := 0
+		if err = e.EncodeField(i, "bool"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitBool(x.Bool); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "int"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitInt(int64(x.Int), 0); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "int8"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitInt(int64(x.Int8), 8); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "int16"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitInt(int64(x.Int16), 16); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "int32"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitInt(int64(x.Int32), 32); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "int64"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitInt(x.Int64, 64); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "uint"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitUint(uint64(x.Uint), 0); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "uint8"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitUint(uint64(x.Uint8), 8); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "uint16"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitUint(uint64(x.Uint16), 16); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "uint32"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitUint(uint64(x.Uint32), 32); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "uint64"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitUint(x.Uint64, 64); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "uintptr"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitUint(uint64(x.Uintptr), 0); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "float32"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitFloat(float64(x.Float32), 32); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "float64"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitFloat(x.Float64, 64); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "string"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitString(x.String); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "bytes"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitBytes(x.Bytes); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "time"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitTime(x.Time); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "duration"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitDuration(x.Duration); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "NoTag"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitString(x.NoTag); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "json"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitString(x.JSON); err != nil {
+			return
+		}
+		i++
+		return
+	})
+}
+
+// DecodeValue satisfies the objconv.ValueDecoder interface.
+func (x *genScalars) DecodeValue(d objconv.Decoder) (err error) {
+	if err = d.DecodeStruct("objtests.genScalars", func(d objconv.Decoder, name []byte) (bool, error) {
+		var err error
+		switch string(name) {
+		case "bool":
+			var v bool
+			if v, err = d.DecodeBool(); err == nil {
+				x.Bool = v
+			}
+		case "int":
+			var v int64
+			if v, err = d.DecodeInt(0); err == nil {
+				x.Int = int(v)
+			}
+		case "int8":
+			var v int64
+			if v, err = d.DecodeInt(8); err == nil {
+				x.Int8 = int8(v)
+			}
+		case "int16":
+			var v int64
+			if v, err = d.DecodeInt(16); err == nil {
+				x.Int16 = int16(v)
+			}
+		case "int32":
+			var v int64
+			if v, err = d.DecodeInt(32); err == nil {
+				x.Int32 = int32(v)
+			}
+		case "int64":
+			var v int64
+			if v, err = d.DecodeInt(64); err == nil {
+				x.Int64 = v
+			}
+		case "uint":
+			var v uint64
+			if v, err = d.DecodeUint(0); err == nil {
+				x.Uint = uint(v)
+			}
+		case "uint8":
+			var v uint64
+			if v, err = d.DecodeUint(8); err == nil {
+				x.Uint8 = uint8(v)
+			}
+		case "uint16":
+			var v uint64
+			if v, err = d.DecodeUint(16); err == nil {
+				x.Uint16 = uint16(v)
+			}
+		case "uint32":
+			var v uint64
+			if v, err = d.DecodeUint(32); err == nil {
+				x.Uint32 = uint32(v)
+			}
+		case "uint64":
+			var v uint64
+			if v, err = d.DecodeUint(64); err == nil {
+				x.Uint64 = v
+			}
+		case "uintptr":
+			var v uint64
+			if v, err = d.DecodeUint(64); err == nil {
+				x.Uintptr = uintptr(v)
+			}
+		case "float32":
+			var v float64
+			if v, err = d.DecodeFloat(); err == nil {
+				x.Float32 = float32(v)
+			}
+		case "float64":
+			var v float64
+			if v, err = d.DecodeFloat(); err == nil {
+				x.Float64 = v
+			}
+		case "string":
+			var v string
+			if v, err = d.DecodeString(); err == nil {
+				x.String = v
+			}
+		case "bytes":
+			var v []byte
+			if v, err = d.DecodeBytes(); err == nil {
+				x.Bytes = v
+			}
+		case "time":
+			var v time.Time
+			if v, err = d.DecodeTime(); err == nil {
+				x.Time = v
+			}
+		case "duration":
+			var v time.Duration
+			if v, err = d.DecodeDuration(); err == nil {
+				x.Duration = v
+			}
+		case "NoTag":
+			var v string
+			if v, err = d.DecodeString(); err == nil {
+				x.NoTag = v
+			}
+		case "json":
+			var v string
+			if v, err = d.DecodeString(); err == nil {
+				x.JSON = v
+			}
+		default:
+			return false, nil
+		}
+		return true, err
+	}); err != nil {
+		*x = genScalars{}
+	}
+	return
+}
+
+// EncodeValue satisfies the objconv.ValueEncoder interface.
+func (x genComposite) EncodeValue(e objconv.Encoder) error {
+	n := 12
+	return e.EncodeStruct(n, func(e objconv.Encoder) (err error) {
+		i := 0
+		if err = e.EncodeField(i, "strings"); err != nil {
+			return
+		}
+		if err = e.Encode(x.Strings); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "ints"); err != nil {
+			return
+		}
+		if err = e.Encode(x.Ints); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "map"); err != nil {
+			return
+		}
+		if err = e.Encode(x.Map); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "nested"); err != nil {
+			return
+		}
+		if err = x.Nested.EncodeValue(e); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "ptr"); err != nil {
+			return
+		}
+		if err = e.Encode(x.Ptr); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "list"); err != nil {
+			return
+		}
+		if err = e.Encode(x.List); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "any"); err != nil {
+			return
+		}
+		if err = e.Encode(x.Any); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "int_ptr"); err != nil {
+			return
+		}
+		if err = e.Encode(x.IntPtr); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "enum"); err != nil {
+			return
+		}
+		if err = e.Encode(x.Enum); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "ip"); err != nil {
+			return
+		}
+		if err = e.Encode(x.IP); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "point"); err != nil {
+			return
+		}
+		if err = e.Encode(x.Point); err != nil {
+			return
+		}
+		i++
+		if err = e.EncodeField(i, "labels"); err != nil {
+			return
+		}
+		if err = e.Encode(x.Labels); err != nil {
+			return
+		}
+		i++
+		return
+	})
+}
+
+// DecodeValue satisfies the objconv.ValueDecoder interface.
+func (x *genComposite) DecodeValue(d objconv.Decoder) (err error) {
+	if err = d.DecodeStruct("objtests.genComposite", func(d objconv.Decoder, name []byte) (bool, error) {
+		var err error
+		switch string(name) {
+		case "strings":
+			err = d.Decode(&x.Strings)
+		case "ints":
+			err = d.Decode(&x.Ints)
+		case "map":
+			err = d.Decode(&x.Map)
+		case "nested":
+			err = x.Nested.DecodeValue(d)
+		case "ptr":
+			err = d.Decode(&x.Ptr)
+		case "list":
+			err = d.Decode(&x.List)
+		case "any":
+			err = d.Decode(&x.Any)
+		case "int_ptr":
+			err = d.Decode(&x.IntPtr)
+		case "enum":
+			err = d.Decode(&x.Enum)
+		case "ip":
+			err = d.Decode(&x.IP)
+		case "point":
+			err = d.Decode(&x.Point)
+		case "labels":
+			err = d.Decode(&x.Labels)
+		default:
+			return false, nil
+		}
+		return true, err
+	}); err != nil {
+		*x = genComposite{}
+	}
+	return
+}
+
+// EncodeValue satisfies the objconv.ValueEncoder interface.
+func (x genOmit) EncodeValue(e objconv.Encoder) error {
+	n := 1
+	if x.Bool {
+		n++
+	}
+	if x.Int != 0 {
+		n++
+	}
+	if len(x.String) != 0 {
+		n++
+	}
+	if len(x.Slice) != 0 {
+		n++
+	}
+	if x.ZeroSlice != nil {
+		n++
+	}
+	if len(x.Map) != 0 {
+		n++
+	}
+	if x.Ptr != nil {
+		n++
+	}
+	if !objutil.IsZero(x.Time) {
+		n++
+	}
+	if !objutil.IsZero(x.Struct) {
+		n++
+	}
+	if !objutil.IsZero(x.Array) {
+		n++
+	}
+	if x.Enum != 0 {
+		n++
+	}
+	return e.EncodeStruct(n, func(e objconv.Encoder) (err error) {
+		i := 0
+		if x.Bool {
+			if err = e.EncodeField(i, "bool"); err != nil {
+				return
+			}
+			if err = e.Emitter.EmitBool(x.Bool); err != nil {
+				return
+			}
+			i++
+		}
+		if x.Int != 0 {
+			if err = e.EncodeField(i, "int"); err != nil {
+				return
+			}
+			if err = e.Emitter.EmitInt(int64(x.Int), 0); err != nil {
+				return
+			}
+			i++
+		}
+		if len(x.String) != 0 {
+			if err = e.EncodeField(i, "String"); err != nil {
+				return
+			}
+			if err = e.Emitter.EmitString(x.String); err != nil {
+				return
+			}
+			i++
+		}
+		if len(x.Slice) != 0 {
+			if err = e.EncodeField(i, "slice"); err != nil {
+				return
+			}
+			if err = e.Encode(x.Slice); err != nil {
+				return
+			}
+			i++
+		}
+		if x.ZeroSlice != nil {
+			if err = e.EncodeField(i, "zero_slice"); err != nil {
+				return
+			}
+			if err = e.Encode(x.ZeroSlice); err != nil {
+				return
+			}
+			i++
+		}
+		if len(x.Map) != 0 {
+			if err = e.EncodeField(i, "map"); err != nil {
+				return
+			}
+			if err = e.Encode(x.Map); err != nil {
+				return
+			}
+			i++
+		}
+		if x.Ptr != nil {
+			if err = e.EncodeField(i, "ptr"); err != nil {
+				return
+			}
+			if err = e.Encode(x.Ptr); err != nil {
+				return
+			}
+			i++
+		}
+		if !objutil.IsZero(x.Time) {
+			if err = e.EncodeField(i, "time"); err != nil {
+				return
+			}
+			if err = e.Emitter.EmitTime(x.Time); err != nil {
+				return
+			}
+			i++
+		}
+		if !objutil.IsZero(x.Struct) {
+			if err = e.EncodeField(i, "struct"); err != nil {
+				return
+			}
+			if err = x.Struct.EncodeValue(e); err != nil {
+				return
+			}
+			i++
+		}
+		if !objutil.IsZero(x.Array) {
+			if err = e.EncodeField(i, "array"); err != nil {
+				return
+			}
+			if err = e.Encode(x.Array); err != nil {
+				return
+			}
+			i++
+		}
+		if x.Enum != 0 {
+			if err = e.EncodeField(i, "enum"); err != nil {
+				return
+			}
+			if err = e.Encode(x.Enum); err != nil {
+				return
+			}
+			i++
+		}
+		if err = e.EncodeField(i, "json"); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitInt(int64(x.JSON), 0); err != nil {
+			return
+		}
+		i++
+		return
+	})
+}
+
+// DecodeValue satisfies the objconv.ValueDecoder interface.
+func (x *genOmit) DecodeValue(d objconv.Decoder) (err error) {
+	if err = d.DecodeStruct("objtests.genOmit", func(d objconv.Decoder, name []byte) (bool, error) {
+		var err error
+		switch string(name) {
+		case "bool":
+			var v bool
+			if v, err = d.DecodeBool(); err == nil {
+				x.Bool = v
+			}
+		case "int":
+			var v int64
+			if v, err = d.DecodeInt(0); err == nil {
+				x.Int = int(v)
+			}
+		case "String":
+			var v string
+			if v, err = d.DecodeString(); err == nil {
+				x.String = v
+			}
+		case "slice":
+			err = d.Decode(&x.Slice)
+		case "zero_slice":
+			err = d.Decode(&x.ZeroSlice)
+		case "map":
+			err = d.Decode(&x.Map)
+		case "ptr":
+			err = d.Decode(&x.Ptr)
+		case "time":
+			var v time.Time
+			if v, err = d.DecodeTime(); err == nil {
+				x.Time = v
+			}
+		case "struct":
+			err = x.Struct.DecodeValue(d)
+		case "array":
+			err = d.Decode(&x.Array)
+		case "enum":
+			err = d.Decode(&x.Enum)
+		case "json":
+			var v int64
+			if v, err = d.DecodeInt(0); err == nil {
+				x.JSON = int(v)
+			}
+		default:
+			return false, nil
+		}
+		return true, err
+	}); err != nil {
+		*x = genOmit{}
+	}
+	return
+}
@@ -163,6 +163,39 @@ var TestValues = [...]interface{}{
 	&point{1, 2},
 }
 
+// sampleValues is a small set of values representative of TestValues, used by
+// the tests of parallel decoders which start goroutines for every sub-test.
+var sampleValues = [...]interface{}{
+	nil,
+	true,
+	-1,
+	int64(objutil.Int64Min),
+	uint32(objutil.Uint32Max),
+	float64(0.5),
+	"Hello\"World!",
+	"你好",
+	strings.Repeat("A", objutil.Uint8Max+1),
+	[]byte("Hello World!"),
+	time.Second,
+	time.Unix(1, 42).In(time.UTC),
+	errors.New("Hello World!"),
+	[]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+	make([]int, objutil.Uint8Max+1),
+	[]interface{}{nil, true, false, 0.5, "Hello World!"},
+	makeMap(15),
+	struct {
+		A int
+		T time.Time
+		S string
+	}{42, time.Date(2016, 12, 20, 0, 20, 1, 0, time.UTC), "Hello World!"},
+	net.TCPAddr{
+		IP:   net.ParseIP("::1"),
+		Port: 4242,
+		Zone: "zone",
+	},
+	&point{1, 2},
+}
+
 func makeMap(n int) map[string]string {
 	m := make(map[string]string, n)
 	for i := 0; i != n; i++ {
@@ -186,6 +219,14 @@ func testName(v interface{}) string {
 func TestCodec(t *testing.T, codec objconv.Codec) {
 	t.Run("Values", func(t *testing.T) { testCodecValues(t, codec) })
 	t.Run("Stream", func(t *testing.T) { testCodecStream(t, codec) })
+	t.Run("Reset", func(t *testing.T) { testCodecReset(t, codec) })
+	t.Run("Tokens", func(t *testing.T) { testCodecTokens(t, codec) })
+	t.Run("Copy", func(t *testing.T) { testCodecCopy(t, codec) })
+	t.Run("Skip", func(t *testing.T) { testCodecSkip(t, codec) })
+	t.Run("Parallel", func(t *testing.T) { testCodecParallel(t, codec) })
+	t.Run("Path", func(t *testing.T) { testCodecPath(t, codec) })
+	t.Run("Document", func(t *testing.T) { testCodecDocument(t, codec) })
+	t.Run("Generated", func(t *testing.T) { testCodecGenerated(t, codec) })
 }
 
 func newValue(model interface{}) reflect.Value {
@@ -227,6 +268,455 @@ func testCodecValues(t *testing.T, codec objconv.Codec) {
 	}
 }
 
+func testCodecTokens(t *testing.T, codec objconv.Codec) {
+	b1 := &bytes.Buffer{}
+	b2 := &bytes.Buffer{}
+
+	for _, v1 := range TestValues {
+		t.Run(testName(v1), func(t *testing.T) {
+			b1.Reset()
+			b2.Reset()
+
+			if err := objconv.NewEncoder(codec.NewEmitter(b1)).Encode(v1); err != nil {
+				t.Error(err)
+				return
+			}
+
+			r := objconv.NewTokenReader(codec.NewParser(b1))
+			w := objconv.NewTokenWriter(codec.NewEmitter(b2))
+
+			for depth := 0; ; {
+				tok, err := r.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if err := w.WriteToken(tok); err != nil {
+					t.Error(err)
+					return
+				}
+
+				switch tok.Kind {
+				case objconv.ArrayBeginToken, objconv.MapBeginToken:
+					depth++
+				case objconv.ArrayEndToken, objconv.MapEndToken:
+					depth--
+				}
+
+				if depth == 0 {
+					break
+				}
+			}
+
+			v2 := newValue(v1)
+
+			if err := objconv.NewDecoder(codec.NewParser(b2)).Decode(v2.Interface()); err != nil {
+				t.Error(err)
+				return
+			}
+
+			x1 := v1
+			x2 := v2.Elem().Interface()
+
+			if !reflect.DeepEqual(x1, x2) {
+				t.Errorf("%#v", x2)
+			}
+		})
+	}
+}
+
+func testCodecCopy(t *testing.T, codec objconv.Codec) {
+	t.Run("Values", func(t *testing.T) { testCodecCopyValues(t, codec) })
+	t.Run("Stream", func(t *testing.T) { testCodecCopyStream(t, codec) })
+}
+
+func testCodecCopyValues(t *testing.T, codec objconv.Codec) {
+	b1 := &bytes.Buffer{}
+	b2 := &bytes.Buffer{}
+
+	for _, v1 := range TestValues {
+		t.Run(testName(v1), func(t *testing.T) {
+			b1.Reset()
+			b2.Reset()
+
+			if err := objconv.NewEncoder(codec.NewEmitter(b1)).Encode(v1); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if err := objconv.Copy(codec.NewEmitter(b2), codec.NewParser(b1)); err != nil {
+				t.Error(err)
+				return
+			}
+
+			v2 := newValue(v1)
+
+			if err := objconv.NewDecoder(codec.NewParser(b2)).Decode(v2.Interface()); err != nil {
+				t.Error(err)
+				return
+			}
+
+			x1 := v1
+			x2 := v2.Elem().Interface()
+
+			if !reflect.DeepEqual(x1, x2) {
+				t.Errorf("%#v", x2)
+			}
+		})
+	}
+}
+
+func testCodecCopyStream(t *testing.T, codec objconv.Codec) {
+	b1 := &bytes.Buffer{}
+	b2 := &bytes.Buffer{}
+	e := objconv.NewStreamEncoder(codec.NewEmitter(b1))
+
+	for _, v := range TestValues {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := objconv.CopyStream(codec.NewEmitter(b2), codec.NewParser(b1))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(TestValues) {
+		t.Errorf("invalid number of values copied: %d != %d", n, len(TestValues))
+	}
+
+	d := objconv.NewStreamDecoder(codec.NewParser(b2))
+
+	for _, v1 := range TestValues {
+		v2 := newValue(v1)
+
+		if err := d.Decode(v2.Interface()); err != nil {
+			t.Fatal(err)
+		}
+
+		x1 := v1
+		x2 := v2.Elem().Interface()
+
+		if !reflect.DeepEqual(x1, x2) {
+			t.Errorf("%#v", x2)
+		}
+	}
+
+	var v interface{}
+	if err := d.Decode(&v); err == nil {
+		t.Error("too many values decoded from the stream")
+	}
+
+	if err := d.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func testCodecSkip(t *testing.T, codec objconv.Codec) {
+	type skipped struct {
+		A interface{}
+		B int
+	}
+
+	type decoded struct {
+		B int
+	}
+
+	b := &bytes.Buffer{}
+
+	for _, v := range TestValues {
+		t.Run(testName(v), func(t *testing.T) {
+			b.Reset()
+
+			if err := objconv.NewEncoder(codec.NewEmitter(b)).Encode(skipped{A: v, B: 42}); err != nil {
+				t.Error(err)
+				return
+			}
+
+			var x decoded
+
+			if err := objconv.NewDecoder(codec.NewParser(b)).Decode(&x); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if x.B != 42 {
+				t.Errorf("invalid value decoded after skipping a field: %d", x.B)
+			}
+		})
+	}
+
+	t.Run("Stream", func(t *testing.T) {
+		b.Reset()
+		e := objconv.NewStreamEncoder(codec.NewEmitter(b))
+
+		for _, v := range TestValues {
+			if err := e.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.Encode(len(testName(v))); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := e.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		d := objconv.NewStreamDecoder(codec.NewParser(b))
+
+		for _, v := range TestValues {
+			var n int
+
+			if err := d.Decode(nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := d.Decode(&n); err != nil {
+				t.Fatal(err)
+			}
+
+			if n != len(testName(v)) {
+				t.Errorf("invalid value decoded after skipping %s: %d", testName(v), n)
+			}
+		}
+	})
+}
+
+func testCodecParallel(t *testing.T, codec objconv.Codec) {
+	b := &bytes.Buffer{}
+	e := objconv.NewStreamEncoder(codec.NewEmitter(b))
+
+	for _, v := range sampleValues {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	input := append([]byte{}, b.Bytes()...)
+
+	// The values are expected to be the same than the ones produced by a
+	// stream decoder.
+	var values []interface{}
+	d := objconv.NewStreamDecoder(codec.NewParser(bytes.NewReader(input)))
+
+	for {
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			break
+		}
+		values = append(values, v)
+	}
+
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			p := codec.NewParallelDecoder(bytes.NewReader(input))
+			p.Workers = workers
+			n := 0
+
+			if err := p.Decode(nil, func(i int, v interface{}) error {
+				if i != n {
+					t.Errorf("value decoded out of order: expected index %d but got %d", n, i)
+				}
+				if i < len(values) && !reflect.DeepEqual(v, values[i]) {
+					t.Errorf("invalid value at index %d:\n%#v\n%#v", i, values[i], v)
+				}
+				n++
+				return nil
+			}); err != nil {
+				t.Error(err)
+			}
+
+			if n != len(values) {
+				t.Errorf("invalid number of values decoded: expected %d but got %d", len(values), n)
+			}
+		})
+	}
+
+	t.Run("Type", func(t *testing.T) {
+		type point struct {
+			X int
+			Y int
+		}
+
+		b.Reset()
+		e := objconv.NewStreamEncoder(codec.NewEmitter(b))
+
+		for i := 0; i != 100; i++ {
+			if err := e.Encode(point{X: i, Y: -i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := e.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		n := 0
+
+		if err := codec.NewParallelDecoder(b).Decode(reflect.TypeOf(point{}), func(i int, v interface{}) error {
+			if p := v.(point); p.X != i || p.Y != -i {
+				t.Errorf("invalid value at index %d: %+v", i, p)
+			}
+			n++
+			return nil
+		}); err != nil {
+			t.Error(err)
+		}
+
+		if n != 100 {
+			t.Errorf("invalid number of values decoded: %d", n)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		b.Reset()
+		e := objconv.NewStreamEncoder(codec.NewEmitter(b))
+
+		for _, v := range []interface{}{1, 2, []int{3}, 4} {
+			if err := e.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := e.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var values []int
+
+		err := codec.NewParallelDecoder(b).Decode(reflect.TypeOf(0), func(i int, v interface{}) error {
+			values = append(values, v.(int))
+			return nil
+		})
+
+		if e, ok := err.(*objconv.StreamError); !ok || e.Index != 2 {
+			t.Errorf("invalid error returned: %v", err)
+		}
+
+		if !reflect.DeepEqual(values, []int{1, 2}) {
+			t.Errorf("invalid values decoded before the error: %v", values)
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		stop := errors.New("stop")
+		n := 0
+
+		err := codec.NewParallelDecoder(bytes.NewReader(input)).Decode(nil, func(int, interface{}) error {
+			if n++; n == 3 {
+				return stop
+			}
+			return nil
+		})
+
+		if err != stop {
+			t.Errorf("invalid error returned: %v", err)
+		}
+
+		if n != 3 {
+			t.Errorf("the callback was called after returning an error")
+		}
+	})
+}
+
+func testCodecPath(t *testing.T, codec objconv.Codec) {
+	type document struct {
+		A []interface{}
+		B interface{}
+		C interface{}
+	}
+
+	b := &bytes.Buffer{}
+
+	// values that must be skipped to reach the ones being decoded
+	skip := []interface{}{"A", map[string]interface{}{"y": []int{1, 2, 3}}, nil}
+
+	for _, v1 := range TestValues {
+		t.Run(testName(v1), func(t *testing.T) {
+			b.Reset()
+
+			doc := document{
+				A: []interface{}{skip, v1},
+				B: map[string]interface{}{"x": skip, "y": v1},
+				C: v1,
+			}
+
+			if err := objconv.NewEncoder(codec.NewEmitter(b)).Encode(doc); err != nil {
+				t.Error(err)
+				return
+			}
+
+			v2 := newValue(v1)
+			v3 := newValue(v1)
+			v4 := newValue(v1)
+
+			if err := objconv.NewDecoder(codec.NewParser(b)).DecodePaths(map[string]interface{}{
+				"A[1]": v2.Interface(),
+				"/B/y": v3.Interface(),
+				".C":   v4.Interface(),
+			}); err != nil {
+				t.Error(err)
+				return
+			}
+
+			for _, v := range []reflect.Value{v2, v3, v4} {
+				if x := v.Elem().Interface(); !reflect.DeepEqual(v1, x) {
+					t.Errorf("%#v", x)
+				}
+			}
+		})
+	}
+}
+
+func testCodecDocument(t *testing.T, codec objconv.Codec) {
+	b := &bytes.Buffer{}
+
+	for _, v1 := range TestValues {
+		t.Run(testName(v1), func(t *testing.T) {
+			b.Reset()
+
+			if err := objconv.NewEncoder(codec.NewEmitter(b)).Encode(v1); err != nil {
+				t.Error(err)
+				return
+			}
+
+			var doc objconv.Value
+
+			if err := objconv.NewDecoder(codec.NewParser(b)).Decode(&doc); err != nil {
+				t.Error(err)
+				return
+			}
+
+			b.Reset()
+			v2 := newValue(v1)
+
+			if err := objconv.NewEncoder(codec.NewEmitter(b)).Encode(doc); err != nil {
+				t.Error(err)
+			} else if err := objconv.NewDecoder(codec.NewParser(b)).Decode(v2.Interface()); err != nil {
+				t.Error(err)
+			} else if x := v2.Elem().Interface(); !reflect.DeepEqual(v1, x) {
+				t.Errorf("%#v", x)
+			}
+		})
+	}
+}
+
 func testCodecStream(t *testing.T, codec objconv.Codec) {
 	t.Run("Values", func(t *testing.T) { testCodecStreamValues(t, codec) })
 	t.Run("Empty", func(t *testing.T) { testCodecStreamEmpty(t, codec) })
@@ -300,6 +790,80 @@ func testCodecStreamEmpty(t *testing.T, codec objconv.Codec) {
 	}
 }
 
+func testCodecReset(t *testing.T, codec objconv.Codec) {
+	t.Run("Encoder", func(t *testing.T) {
+		b1 := &bytes.Buffer{}
+		b2 := &bytes.Buffer{}
+		enc := codec.NewEncoder(b1)
+		dec := codec.NewDecoder(b1)
+
+		for i, v1 := range []interface{}{"Hello World!", map[string]int{"answer": 42}} {
+			b := []*bytes.Buffer{b1, b2}[i]
+			v2 := newValue(v1)
+
+			enc.Reset(b)
+			dec.Reset(b)
+
+			if err := enc.Encode(v1); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := dec.Decode(v2.Interface()); err != nil {
+				t.Fatal(err)
+			}
+
+			if x2 := v2.Elem().Interface(); !reflect.DeepEqual(v1, x2) {
+				t.Errorf("value %d: %#v != %#v", i, v1, x2)
+			}
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		b1 := &bytes.Buffer{}
+		b2 := &bytes.Buffer{}
+		enc := codec.NewStreamEncoder(b1)
+		dec := codec.NewStreamDecoder(b1)
+
+		for i, b := range []*bytes.Buffer{b1, b2} {
+			enc.Reset(b)
+
+			for j := 0; j <= i; j++ {
+				if err := enc.Encode(j); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for i, b := range []*bytes.Buffer{b1, b2} {
+			dec.Reset(b)
+			n := 0
+
+			for {
+				var v int
+				if err := dec.Decode(&v); err != nil {
+					break
+				}
+				if v != n {
+					t.Errorf("stream %d: value %d decoded instead of %d", i, v, n)
+				}
+				n++
+			}
+
+			if err := dec.Err(); err != nil {
+				t.Error(err)
+			}
+
+			if n != i+1 {
+				t.Errorf("stream %d: %d values decoded instead of %d", i, n, i+1)
+			}
+		}
+	})
+}
+
 type counter struct {
 	n int
 }
@@ -317,6 +881,8 @@ func BenchmarkCodec(b *testing.B, codec objconv.Codec) {
 	b.Run("Decoder", func(b *testing.B) { benchmarkDecoder(b, codec) })
 	b.Run("StreamEncoder", func(b *testing.B) { benchmarkStreamEncoder(b, codec) })
 	b.Run("StreamDecoder", func(b *testing.B) { benchmarkStreamDecoder(b, codec) })
+	b.Run("Generated", func(b *testing.B) { benchmarkGenerated(b, codec) })
+	b.Run("Struct", func(b *testing.B) { benchmarkStruct(b, codec) })
 }
 
 func benchmarkEncoder(b *testing.B, codec objconv.Codec) {
@@ -405,6 +971,97 @@ func benchmarkStreamDecoder(b *testing.B, codec objconv.Codec) {
 	}
 }
 
+// benchRecord is the struct-heavy value of the Struct benchmarks, decoded to
+// its own type instead of an empty interface.
+type benchRecord struct {
+	ID      int64
+	Name    string
+	Email   string
+	Score   float64
+	Active  bool
+	Tags    []string
+	Address benchAddress
+	Items   []benchItem
+	Counts  map[string]int
+	Created time.Time
+}
+
+type benchAddress struct {
+	Street  string
+	City    string
+	Zip     string
+	Country string
+}
+
+type benchItem struct {
+	SKU      string
+	Quantity int
+	Price    float64
+}
+
+func benchRecords(n int) []benchRecord {
+	records := make([]benchRecord, n)
+
+	for i := range records {
+		records[i] = benchRecord{
+			ID:     int64(i),
+			Name:   "Luke Skywalker",
+			Email:  "luke@example.com",
+			Score:  float64(i) / 3,
+			Active: i%2 == 0,
+			Tags:   []string{"jedi", "pilot", "rebel"},
+			Address: benchAddress{
+				Street:  "1 Lars Homestead",
+				City:    "Anchorhead",
+				Zip:     "12345",
+				Country: "Tatooine",
+			},
+			Items: []benchItem{
+				{SKU: "X-34", Quantity: 1, Price: 2000},
+				{SKU: "T-16", Quantity: 2, Price: 1500.5},
+			},
+			Counts:  map[string]int{"wins": i, "losses": 1},
+			Created: time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+	}
+
+	return records
+}
+
+// benchmarkStruct measures the encoding and decoding of a slice of nested
+// structs, which is where the compiled codecs of Go types matter the most.
+func benchmarkStruct(b *testing.B, codec objconv.Codec) {
+	v := benchRecords(10)
+
+	b.Run("Encoder", func(b *testing.B) {
+		c := &counter{}
+		e := objconv.NewEncoder(codec.NewEmitter(c))
+
+		for i := 0; i != b.N; i++ {
+			e.Encode(v)
+		}
+
+		b.SetBytes(int64(c.n / b.N))
+	})
+
+	b.Run("Decoder", func(b *testing.B) {
+		a := &bytes.Buffer{}
+		objconv.NewEncoder(codec.NewEmitter(a)).Encode(v)
+
+		s := a.Bytes()
+		r := bytes.NewReader(s)
+		d := objconv.NewDecoder(codec.NewParser(r))
+
+		for i := 0; i != b.N; i++ {
+			var x []benchRecord
+			d.Decode(&x)
+			r.Reset(s)
+		}
+
+		b.SetBytes(int64(len(s)))
+	})
+}
+
 func parseURL(s string) url.URL {
 	u, _ := url.Parse(s)
 	return *u
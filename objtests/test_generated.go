@@ -0,0 +1,322 @@
+package objtests
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+//go:generate go run ../cmd/objconv-gen -type genScalars,genComposite,genOmit
+
+type genScalars struct {
+	Bool     bool          `objconv:"bool"`
+	Int      int           `objconv:"int"`
+	Int8     int8          `objconv:"int8"`
+	Int16    int16         `objconv:"int16"`
+	Int32    int32         `objconv:"int32"`
+	Int64    int64         `objconv:"int64"`
+	Uint     uint          `objconv:"uint"`
+	Uint8    uint8         `objconv:"uint8"`
+	Uint16   uint16        `objconv:"uint16"`
+	Uint32   uint32        `objconv:"uint32"`
+	Uint64   uint64        `objconv:"uint64"`
+	Uintptr  uintptr       `objconv:"uintptr"`
+	Float32  float32       `objconv:"float32"`
+	Float64  float64       `objconv:"float64"`
+	String   string        `objconv:"string"`
+	Bytes    []byte        `objconv:"bytes"`
+	Time     time.Time     `objconv:"time"`
+	Duration time.Duration `objconv:"duration"`
+	NoTag    string
+	JSON     string `json:"json"`
+	Skip     int    `objconv:"-"`
+	private  int
+}
+
+type genEnum int
+
+type genComposite struct {
+	Strings []string          `objconv:"strings"`
+	Ints    [2]int            `objconv:"ints"`
+	Map     map[string]int    `objconv:"map"`
+	Nested  genScalars        `objconv:"nested"`
+	Ptr     *genScalars       `objconv:"ptr"`
+	List    []genScalars      `objconv:"list"`
+	Any     interface{}       `objconv:"any"`
+	IntPtr  *int              `objconv:"int_ptr"`
+	Enum    genEnum           `objconv:"enum"`
+	IP      net.IP            `objconv:"ip"`
+	Point   point             `objconv:"point"`
+	Labels  map[string]string `objconv:"labels"`
+}
+
+type genOmit struct {
+	Bool      bool              `objconv:"bool,omitempty"`
+	Int       int               `objconv:"int,omitzero"`
+	String    string            `objconv:",omitempty"`
+	Slice     []int             `objconv:"slice,omitempty"`
+	ZeroSlice []int             `objconv:"zero_slice,omitzero"`
+	Map       map[string]string `objconv:"map,omitempty"`
+	Ptr       *int              `objconv:"ptr,omitempty"`
+	Time      time.Time         `objconv:"time,omitzero"`
+	Struct    genScalars        `objconv:"struct,omitempty,omitzero"`
+	Array     [2]int            `objconv:"array,omitzero"`
+	Enum      genEnum           `objconv:"enum,omitempty"`
+	JSON      int               `json:"json,omitzero"`
+}
+
+// The plain types have the same fields than the generated ones but no methods,
+// so they are encoded and decoded using reflection.
+//
+// Fields of generated types use the generated methods in both cases, but each
+// generated type is also tested on its own, so the equivalence of all types is
+// verified.
+type (
+	plainGenScalars   genScalars
+	plainGenComposite genComposite
+	plainGenOmit      genOmit
+)
+
+func genValues() []interface{} {
+	date := time.Date(2016, 12, 20, 0, 20, 1, 0, time.UTC)
+	answer := 42
+
+	scalars := genScalars{
+		Bool:     true,
+		Int:      -1,
+		Int8:     -8,
+		Int16:    -16,
+		Int32:    -32,
+		Int64:    -64,
+		Uint:     1,
+		Uint8:    8,
+		Uint16:   16,
+		Uint32:   32,
+		Uint64:   64,
+		Uintptr:  128,
+		Float32:  0.5,
+		Float64:  0.25,
+		String:   "Hello World!",
+		Bytes:    []byte("Hello World!"),
+		Time:     date,
+		Duration: time.Second,
+		NoTag:    "A",
+		JSON:     "B",
+	}
+
+	return []interface{}{
+		genScalars{},
+		scalars,
+		genComposite{IP: net.IPv4(10, 0, 0, 1)},
+		genComposite{
+			Strings: []string{"A", "B", "C"},
+			Ints:    [2]int{1, 2},
+			Map:     map[string]int{"A": 1, "B": 2},
+			Nested:  scalars,
+			Ptr:     &scalars,
+			List:    []genScalars{scalars, {}},
+			Any:     "Hello World!",
+			IntPtr:  &answer,
+			Enum:    genEnum(3),
+			IP:      net.IPv4(127, 0, 0, 1),
+			Point:   point{1, 2},
+			Labels:  map[string]string{"A": "1"},
+		},
+		genOmit{},
+		genOmit{
+			Bool:      true,
+			Int:       1,
+			String:    "A",
+			Slice:     []int{},
+			ZeroSlice: []int{},
+			Map:       map[string]string{"A": "B"},
+			Ptr:       &answer,
+			Time:      date,
+			Struct:    genScalars{Int: 1},
+			Array:     [2]int{0, 1},
+			Enum:      genEnum(1),
+			JSON:      0,
+		},
+	}
+}
+
+// plainValueOf returns a copy of v converted to the plain type matching the
+// generated type of v.
+func plainValueOf(v interface{}) interface{} {
+	var t reflect.Type
+
+	switch v.(type) {
+	case genScalars:
+		t = reflect.TypeOf(plainGenScalars{})
+	case genComposite:
+		t = reflect.TypeOf(plainGenComposite{})
+	case genOmit:
+		t = reflect.TypeOf(plainGenOmit{})
+	}
+
+	return reflect.ValueOf(v).Convert(t).Interface()
+}
+
+// testCodecGenerated verifies that the code generated by objconv-gen produces
+// the same output than the reflection-based encoder, and that decoding values
+// with the generated code and with reflection produces the same results.
+func testCodecGenerated(t *testing.T, codec objconv.Codec) {
+	t.Run("Values", func(t *testing.T) { testCodecGeneratedValues(t, codec) })
+	t.Run("Decode", func(t *testing.T) { testCodecGeneratedDecode(t, codec) })
+}
+
+func testCodecGeneratedValues(t *testing.T, codec objconv.Codec) {
+	for _, v := range genValues() {
+		p := plainValueOf(v)
+
+		t.Run(testName(v), func(t *testing.T) {
+			b1 := encodeSorted(t, codec, v)
+			b2 := encodeSorted(t, codec, p)
+
+			if !bytes.Equal(b1, b2) {
+				t.Errorf("the generated code and reflection produced different outputs:\n%q\n%q", b1, b2)
+			}
+
+			v1 := newValue(v)
+			v2 := newValue(p)
+
+			if err := objconv.NewDecoder(codec.NewParser(bytes.NewReader(b1))).Decode(v1.Interface()); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if err := objconv.NewDecoder(codec.NewParser(bytes.NewReader(b1))).Decode(v2.Interface()); err != nil {
+				t.Error(err)
+				return
+			}
+
+			x1 := v1.Elem().Interface()
+			x2 := v2.Elem().Convert(v1.Elem().Type()).Interface()
+
+			if !reflect.DeepEqual(x1, x2) {
+				t.Errorf("the generated code and reflection decoded different values:\n%#v\n%#v", x1, x2)
+			}
+		})
+	}
+}
+
+func testCodecGeneratedDecode(t *testing.T, codec objconv.Codec) {
+	// Values that need conversions, unknown fields, and nil values.
+	inputs := []interface{}{
+		nil,
+		map[string]interface{}{
+			"int":      "42",
+			"uint16":   "16",
+			"float64":  1,
+			"string":   123,
+			"bool":     nil,
+			"bytes":    nil,
+			"duration": "1s",
+			"unknown":  []interface{}{1, map[string]interface{}{"A": true}},
+			"NoTag":    "A",
+			"json":     "B",
+			"Skip":     1,
+			"private":  2,
+		},
+		map[string]interface{}{
+			"nested": map[string]interface{}{"int": 1},
+			"ptr":    nil,
+			"list":   []interface{}{map[string]interface{}{"string": "A"}, nil},
+			"enum":   2,
+		},
+		map[string]interface{}{
+			"int8": 1000, // out of range
+		},
+		map[string]interface{}{
+			"time": 1, // not a time
+			"any":  map[string]interface{}{"A": 1},
+		},
+	}
+
+	models := []interface{}{genScalars{}, genComposite{}, genOmit{}}
+
+	for _, in := range inputs {
+		b := encodeSorted(t, codec, in)
+
+		for _, m := range models {
+			t.Run(testName(m)+"/"+testName(in), func(t *testing.T) {
+				v1 := newValue(m)
+				v2 := newValue(plainValueOf(m))
+
+				err1 := objconv.NewDecoder(codec.NewParser(bytes.NewReader(b))).Decode(v1.Interface())
+				err2 := objconv.NewDecoder(codec.NewParser(bytes.NewReader(b))).Decode(v2.Interface())
+
+				if (err1 == nil) != (err2 == nil) {
+					t.Errorf("the generated code and reflection returned different errors: %v, %v", err1, err2)
+				}
+
+				x1 := v1.Elem().Interface()
+				x2 := v2.Elem().Convert(v1.Elem().Type()).Interface()
+
+				if !reflect.DeepEqual(x1, x2) {
+					t.Errorf("the generated code and reflection decoded different values:\n%#v\n%#v", x1, x2)
+				}
+			})
+		}
+	}
+}
+
+func encodeSorted(t *testing.T, codec objconv.Codec, v interface{}) []byte {
+	b := &bytes.Buffer{}
+	e := objconv.NewEncoder(codec.NewEmitter(b))
+	e.SortMapKeys = true
+
+	if err := e.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.Bytes()
+}
+
+// benchmarkGenerated compares the performance of the code generated by
+// objconv-gen with the reflection-based encoder and decoder.
+func benchmarkGenerated(b *testing.B, codec objconv.Codec) {
+	v := genValues()[1]
+
+	for _, x := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"Generated", v},
+		{"Reflection", plainValueOf(v)},
+	} {
+		x := x
+
+		b.Run("Encoder/"+x.name, func(b *testing.B) {
+			c := &counter{}
+			e := objconv.NewEncoder(codec.NewEmitter(c))
+
+			for i := 0; i != b.N; i++ {
+				e.Encode(x.value)
+			}
+
+			b.SetBytes(int64(c.n / b.N))
+		})
+
+		b.Run("Decoder/"+x.name, func(b *testing.B) {
+			a := &bytes.Buffer{}
+			objconv.NewEncoder(codec.NewEmitter(a)).Encode(x.value)
+
+			s := a.Bytes()
+			r := bytes.NewReader(s)
+			d := objconv.NewDecoder(codec.NewParser(r))
+			p := newValue(x.value).Interface()
+
+			for i := 0; i != b.N; i++ {
+				d.Decode(p)
+				r.Reset(s)
+			}
+
+			b.SetBytes(int64(len(s)))
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package objutil
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// CanonicalWriter is used by the canonical emitters of binary formats to write
+// arrays and maps which must be rewritten before being output.
+//
+// Maps, and arrays that begin with an unknown length, are buffered in memory
+// until they end. The entries of maps are then sorted by the bytewise
+// lexicographic order of the encoding of their keys, and the containers are
+// written after a header holding their length. The buffer is written to the
+// output when the outermost buffered container ends, other writes are passed
+// through.
+type CanonicalWriter struct {
+	w       io.Writer
+	buf     bytes.Buffer // buffer where the containers are written before being sorted
+	c       []byte       // copy of a container being rewritten
+	frames  []canonicalFrame
+	entries []canonicalEntry
+	buffers int // number of containers being buffered
+}
+
+type canonicalFrame struct {
+	size  int   // length of the container when it began, or -1
+	start int   // offset of the container content in the buffer
+	count int   // number of calls to ArrayNext
+	offs  []int // offsets of map keys and values in the buffer
+}
+
+type canonicalEntry struct {
+	key   []byte
+	entry []byte
+}
+
+// NewCanonicalWriter returns a new CanonicalWriter which outputs to w.
+func NewCanonicalWriter(w io.Writer) *CanonicalWriter {
+	return &CanonicalWriter{w: w}
+}
+
+// Reset discards the buffered data and sets the output of w to out.
+func (w *CanonicalWriter) Reset(out io.Writer) {
+	w.w = out
+	w.buf.Reset()
+	w.frames = w.frames[:0]
+	w.buffers = 0
+}
+
+// Write satisfies the io.Writer interface.
+func (w *CanonicalWriter) Write(b []byte) (int, error) {
+	if w.buffers == 0 {
+		return w.w.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// BeginArray must be called when the emitter begins an array, n is the length
+// of the array or -1 if it is unknown, in which case the array is buffered.
+func (w *CanonicalWriter) BeginArray(n int) {
+	if n >= 0 {
+		w.frames = append(w.frames, canonicalFrame{size: n})
+	} else {
+		w.begin()
+	}
+}
+
+// ArrayNext must be called when the emitter moves to the next element of an
+// array.
+func (w *CanonicalWriter) ArrayNext() {
+	w.top().count++
+}
+
+// EndArray must be called when the emitter ends an array. If the array was
+// buffered, header is called to write the header of an array of the number of
+// elements that it contains, followed by the elements.
+//
+// The method returns false if the array had a known length and wasn't
+// buffered.
+func (w *CanonicalWriter) EndArray(header func(int) error) (bool, error) {
+	f := w.pop()
+
+	if f.size >= 0 {
+		return false, nil
+	}
+
+	n := 0
+
+	if w.buf.Len() != f.start {
+		n = f.count + 1
+	}
+
+	w.c = append(w.c[:0], w.buf.Bytes()[f.start:]...)
+	w.buf.Truncate(f.start)
+
+	if err := header(n); err != nil {
+		return true, err
+	}
+
+	w.buf.Write(w.c)
+	return true, w.end()
+}
+
+// BeginMap must be called when the emitter begins a map, maps are always
+// buffered.
+func (w *CanonicalWriter) BeginMap() {
+	f := w.begin()
+	f.offs = append(f.offs, f.start)
+}
+
+// MapValue must be called when the emitter moves from a key to its value.
+func (w *CanonicalWriter) MapValue() {
+	f := w.top()
+	f.offs = append(f.offs, w.buf.Len())
+}
+
+// MapNext must be called when the emitter moves to the next key of a map.
+func (w *CanonicalWriter) MapNext() {
+	f := w.top()
+	f.offs = append(f.offs, w.buf.Len())
+}
+
+// EndMap must be called when the emitter ends a map. The method calls header
+// to write the header of a map of the number of entries that it contains, then
+// writes the entries sorted by the encoding of their keys.
+func (w *CanonicalWriter) EndMap(header func(int) error) error {
+	f := w.pop()
+	w.c = append(w.c[:0], w.buf.Bytes()[f.start:]...)
+	w.buf.Truncate(f.start)
+
+	entries := w.entries[:0]
+
+	if len(w.c) != 0 {
+		for i, n := 0, len(f.offs)/2; i != n; i++ {
+			k := f.offs[2*i] - f.start
+			v := f.offs[2*i+1] - f.start
+			end := len(w.c)
+
+			if j := 2*i + 2; j < len(f.offs) {
+				end = f.offs[j] - f.start
+			}
+
+			entries = append(entries, canonicalEntry{key: w.c[k:v], entry: w.c[k:end]})
+		}
+	}
+
+	sort.Slice(entries, func(i int, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	if err := header(len(entries)); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		w.buf.Write(entry.entry)
+	}
+
+	w.entries = entries[:0]
+	return w.end()
+}
+
+// Abort must be called when the emitter stops emitting a value before reaching
+// its end, the containers being buffered are discarded since they can't be
+// completed.
+func (w *CanonicalWriter) Abort() {
+	w.Reset(w.w)
+}
+
+// begin starts buffering a container, the writes are redirected to the buffer
+// if they weren't already.
+func (w *CanonicalWriter) begin() *canonicalFrame {
+	w.buffers++
+	w.frames = append(w.frames, canonicalFrame{size: -1, start: w.buf.Len()})
+	return w.top()
+}
+
+// end terminates a buffered container, flushing the buffer to the output if it
+// was the outermost one.
+func (w *CanonicalWriter) end() (err error) {
+	if w.buffers--; w.buffers == 0 {
+		_, err = w.buf.WriteTo(w.w)
+		w.buf.Reset()
+	}
+	return
+}
+
+func (w *CanonicalWriter) pop() canonicalFrame {
+	i := len(w.frames) - 1
+	f := w.frames[i]
+	w.frames = w.frames[:i]
+	return f
+}
+
+func (w *CanonicalWriter) top() *canonicalFrame {
+	return &w.frames[len(w.frames)-1]
+}
@@ -0,0 +1,52 @@
+package objutil
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestCanonicalWriter(t *testing.T) {
+	w := &writesRecorder{}
+	c := NewCanonicalWriter(w)
+
+	// The headers are written as the length of the containers between braces.
+	header := func(n int) error {
+		_, err := c.Write([]byte("{" + strconv.Itoa(n) + "}"))
+		return err
+	}
+
+	c.Write([]byte("1"))
+
+	c.BeginMap()
+	c.Write([]byte("b"))
+	c.MapValue()
+	c.BeginArray(-1)
+	c.Write([]byte("2"))
+	c.ArrayNext()
+	c.Write([]byte("3"))
+	c.EndArray(header)
+	c.MapNext()
+	c.Write([]byte("a"))
+	c.MapValue()
+	c.BeginArray(1)
+	c.Write([]byte("{1}4"))
+
+	if buffered, _ := c.EndArray(header); buffered {
+		t.Error("an array of known length was buffered")
+	}
+
+	if err := c.EndMap(header); err != nil {
+		t.Fatal(err)
+	}
+
+	c.BeginMap()
+
+	if err := c.EndMap(header); err != nil {
+		t.Fatal(err)
+	}
+
+	if expect := []string{"1", "{2}a{1}4b{2}23", "{0}"}; !reflect.DeepEqual(w.writes, expect) {
+		t.Errorf("%q != %q", w.writes, expect)
+	}
+}
@@ -0,0 +1,126 @@
+package objutil
+
+import (
+	"bytes"
+	"io"
+)
+
+// BufferedWriterSize is the size of the buffer of a BufferedWriter.
+const BufferedWriterSize = 4096
+
+// BufferedWriter is used by emitters to group the small writes they make while
+// emitting a value into fewer writes to the output.
+//
+// The writes made while the emitter is within an array or a map, between calls
+// to Push and Pop, are buffered. The buffer is written to the output when the
+// outermost container ends, when it is full, when Flush is called, or when the
+// emitter aborts a value that it failed to encode with Abort. Writes made
+// outside of containers are passed through.
+//
+// The values of a stream are emitted within the array that wraps the stream,
+// they remain in the buffer until the emitter is flushed, which
+// objconv.StreamEncoder does after each value unless configured otherwise.
+//
+// Writers which already buffer their input, like *bytes.Buffer, *bufio.Writer
+// or http.ResponseWriter, are not buffered again.
+type BufferedWriter struct {
+	w     io.Writer
+	b     []byte
+	depth int
+	pass  bool // the output doesn't need to be buffered
+}
+
+// NewBufferedWriter returns a new BufferedWriter which outputs to w.
+func NewBufferedWriter(w io.Writer) *BufferedWriter {
+	b := &BufferedWriter{}
+	b.Reset(w)
+	return b
+}
+
+// Reset discards the buffered data and sets the output of b to w.
+func (b *BufferedWriter) Reset(w io.Writer) {
+	b.w = w
+	b.b = b.b[:0]
+	b.depth = 0
+
+	switch w.(type) {
+	case *bytes.Buffer, interface{ Flush() error }, interface{ Flush() }:
+		b.pass = true
+	default:
+		b.pass = false
+	}
+}
+
+// Write satisfies the io.Writer interface.
+func (b *BufferedWriter) Write(p []byte) (int, error) {
+	if b.pass {
+		return b.w.Write(p)
+	}
+
+	if b.depth == 0 || len(b.b)+len(p) > cap(b.b) {
+		if err := b.flush(); err != nil {
+			return 0, err
+		}
+
+		if b.depth == 0 || len(p) >= BufferedWriterSize {
+			return b.w.Write(p)
+		}
+
+		if b.b == nil {
+			b.b = make([]byte, 0, BufferedWriterSize)
+		}
+	}
+
+	b.b = append(b.b, p...)
+	return len(p), nil
+}
+
+// Push must be called when the emitter begins an array or a map.
+func (b *BufferedWriter) Push() {
+	b.depth++
+}
+
+// Pop must be called when the emitter ends an array or a map, the buffered data
+// is written to the output when the outermost container ends.
+func (b *BufferedWriter) Pop() error {
+	if b.depth != 0 {
+		if b.depth--; b.depth == 0 {
+			return b.flush()
+		}
+	}
+	return nil
+}
+
+// Abort must be called when the emitter stops emitting a value before reaching
+// its end, the buffered data is written to the output and the containers that
+// were left open are forgotten, so the writes of the next values aren't held in
+// the buffer.
+func (b *BufferedWriter) Abort() error {
+	b.depth = 0
+	return b.flush()
+}
+
+// Flush writes the buffered data to the output, then flushes the output if it
+// has a Flush method.
+func (b *BufferedWriter) Flush() error {
+	if err := b.flush(); err != nil {
+		return err
+	}
+
+	switch w := b.w.(type) {
+	case interface{ Flush() error }:
+		return w.Flush()
+	case interface{ Flush() }:
+		w.Flush()
+	}
+
+	return nil
+}
+
+func (b *BufferedWriter) flush() (err error) {
+	if len(b.b) != 0 {
+		_, err = b.w.Write(b.b)
+		b.b = b.b[:0]
+	}
+	return
+}
@@ -0,0 +1,127 @@
+package objutil
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writesRecorder records the buffers passed to each call to Write.
+type writesRecorder struct {
+	writes  []string
+	flushes int
+}
+
+func (w *writesRecorder) Write(b []byte) (int, error) {
+	w.writes = append(w.writes, string(b))
+	return len(b), nil
+}
+
+type flushWritesRecorder struct {
+	writesRecorder
+}
+
+func (w *flushWritesRecorder) Flush() {
+	w.flushes++
+}
+
+func TestBufferedWriter(t *testing.T) {
+	w := &writesRecorder{}
+	b := NewBufferedWriter(w)
+
+	b.Write([]byte("1"))
+	b.Push()
+	b.Write([]byte("["))
+	b.Push()
+	b.Write([]byte("["))
+	b.Write([]byte("2"))
+	b.Write([]byte("]"))
+	b.Pop()
+	b.Write([]byte(","))
+
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Write([]byte("3"))
+	b.Write([]byte("]"))
+	b.Pop()
+
+	if expect := []string{"1", "[[2],", "3]"}; !reflect.DeepEqual(w.writes, expect) {
+		t.Errorf("%q != %q", w.writes, expect)
+	}
+}
+
+func TestBufferedWriterAbort(t *testing.T) {
+	w := &writesRecorder{}
+	b := NewBufferedWriter(w)
+
+	b.Push()
+	b.Write([]byte("["))
+	b.Push()
+	b.Write([]byte("["))
+
+	if err := b.Abort(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The containers left open are forgotten, writes are passed through again.
+	b.Write([]byte("1"))
+
+	if expect := []string{"[[", "1"}; !reflect.DeepEqual(w.writes, expect) {
+		t.Errorf("%q != %q", w.writes, expect)
+	}
+}
+
+func TestBufferedWriterLargeWrites(t *testing.T) {
+	w := &writesRecorder{}
+	b := NewBufferedWriter(w)
+	s := strings.Repeat("A", BufferedWriterSize)
+
+	b.Push()
+	b.Write([]byte("["))
+	b.Write([]byte(s))
+	b.Write([]byte("]"))
+	b.Pop()
+
+	if expect := []string{"[", s, "]"}; !reflect.DeepEqual(w.writes, expect) {
+		t.Errorf("%d writes were made instead of %d", len(w.writes), len(expect))
+	}
+}
+
+func TestBufferedWriterPassThrough(t *testing.T) {
+	t.Run("bytes.Buffer", func(t *testing.T) {
+		if b := NewBufferedWriter(&bytes.Buffer{}); !b.pass {
+			t.Error("*bytes.Buffer must not be buffered")
+		}
+	})
+
+	t.Run("bufio.Writer", func(t *testing.T) {
+		if b := NewBufferedWriter(bufio.NewWriter(&bytes.Buffer{})); !b.pass {
+			t.Error("*bufio.Writer must not be buffered")
+		}
+	})
+
+	t.Run("Flush", func(t *testing.T) {
+		w := &flushWritesRecorder{}
+		b := NewBufferedWriter(w)
+
+		b.Push()
+		b.Write([]byte("["))
+		b.Write([]byte("1"))
+
+		if err := b.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		if expect := []string{"[", "1"}; !reflect.DeepEqual(w.writes, expect) {
+			t.Errorf("%q != %q", w.writes, expect)
+		}
+
+		if w.flushes != 1 {
+			t.Errorf("the writer was flushed %d times instead of 1", w.flushes)
+		}
+	})
+}
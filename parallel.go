@@ -0,0 +1,287 @@
+package objconv
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// ParallelDecoder decodes the values of a stream concurrently, spreading the
+// work over multiple goroutines.
+//
+// A single goroutine reads the stream and extracts the bytes of each value
+// without decoding them, while a pool of workers decodes the values. Results
+// are delivered in the order they appear in the stream. Only a few values per
+// worker are held in memory, reading the stream is paused when the consumer of
+// the values doesn't keep up.
+//
+// Parsers able to extract the bytes of a value without decoding it, like the
+// json, msgpack and cbor parsers, give the best performance. Values read by
+// other parsers are copied to a buffer with the emitter of the codec.
+type ParallelDecoder struct {
+	// Codec of the stream, its NewParser function is used to create the parser
+	// reading the stream and the parsers decoding each value.
+	Codec Codec
+
+	// Workers is the number of goroutines decoding values, it defaults to
+	// runtime.GOMAXPROCS(0) when zero or negative.
+	Workers int
+
+	// MapType is used to override the type of maps produced by the decoder when
+	// there is not destination type (when decoding to an empty interface).
+	MapType reflect.Type
+
+	// Coerce configures the conversions applied to values which don't match
+	// the type of their destination, see Decoder.Coerce.
+	Coerce Coercion
+
+	r io.Reader
+}
+
+// NewParallelDecoder returns a new parallel decoder that reads a stream in the
+// format of c from r.
+//
+// The function panics if r is nil.
+func NewParallelDecoder(c Codec, r io.Reader) *ParallelDecoder {
+	if r == nil {
+		panic("objconv: the reader is nil")
+	}
+	return &ParallelDecoder{Codec: c, r: r}
+}
+
+// Decode reads the stream and decodes each of its values to a new value of
+// type typ, then calls f with the index of the value in the stream and the
+// decoded value. The calls to f are made by the goroutine that called Decode,
+// in the order in which the values appear in the stream.
+//
+// If typ is nil the values are decoded the same way than when decoding to an
+// empty interface.
+//
+// Decoding stops at the first error, which is either the error returned by f,
+// or a *StreamError if reading or decoding a value of the stream failed. The
+// method returns nil if the stream is empty.
+func (d *ParallelDecoder) Decode(typ reflect.Type, f func(int, interface{}) error) error {
+	workers := d.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	if typ == nil {
+		typ = emptyInterface
+	}
+
+	queue := make(chan *parallelJob, 2*workers)
+	jobs := make(chan *parallelJob, workers)
+	stop := make(chan struct{})
+	wait := sync.WaitGroup{}
+
+	wait.Add(1 + workers)
+
+	go func() {
+		defer wait.Done()
+		d.split(queue, jobs, stop)
+	}()
+
+	for i := 0; i != workers; i++ {
+		go func() {
+			defer wait.Done()
+			d.work(typ, jobs)
+		}()
+	}
+
+	var err error
+
+	for job := range queue {
+		<-job.done
+
+		if err = job.err; err == nil {
+			err = f(job.index, job.value.Interface())
+		}
+
+		job.release()
+
+		if err != nil {
+			break
+		}
+	}
+
+	close(stop)
+	wait.Wait()
+	return err
+}
+
+// split reads the values of the stream and submits them to the workers, the
+// jobs are also pushed to queue so the results can be delivered in order.
+func (d *ParallelDecoder) split(queue chan<- *parallelJob, jobs chan<- *parallelJob, stop <-chan struct{}) {
+	defer close(queue)
+	defer close(jobs)
+
+	s := splitter{p: d.Codec.NewParser(d.r), codec: d.Codec}
+
+	submit := func(i int, err error) bool {
+		job := newParallelJob(i)
+
+		if err == nil {
+			job.raw, err = s.parseRaw(job.raw)
+		}
+
+		if err != nil {
+			job.err = &StreamError{Index: i, Err: err}
+			job.done <- struct{}{}
+		}
+
+		select {
+		case queue <- job:
+		case <-stop:
+			return false
+		}
+
+		if job.err != nil {
+			return false
+		}
+
+		select {
+		case jobs <- job:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	if isSequenceParser(s.p) {
+		for i := 0; ; i++ {
+			if _, err := s.p.ParseType(); err != nil {
+				if err != io.EOF {
+					submit(i, err)
+				}
+				return
+			}
+
+			if !submit(i, nil) {
+				return
+			}
+		}
+	}
+
+	t, err := s.p.ParseType()
+
+	switch {
+	case err == io.EOF: // empty stream
+		return
+	case err != nil || t != Array:
+		submit(0, err)
+		return
+	}
+
+	n, err := s.p.ParseArrayBegin()
+	i := 0
+
+	for err == nil && (n < 0 || i < n) {
+		if n < 0 || i != 0 {
+			if err = s.p.ParseArrayNext(i); err != nil {
+				if err == End {
+					err = nil
+				}
+				break
+			}
+		}
+
+		if _, err = s.p.ParseType(); err != nil {
+			break
+		}
+
+		if !submit(i, nil) {
+			return
+		}
+
+		i++
+	}
+
+	if err == nil {
+		if err = s.p.ParseArrayEnd(i); err == nil {
+			return
+		}
+	}
+
+	submit(i, err)
+}
+
+// work decodes the values submitted to the jobs channel until it is closed.
+func (d *ParallelDecoder) work(typ reflect.Type, jobs <-chan *parallelJob) {
+	r := bytes.NewReader(nil)
+	p := d.Codec.NewParser(r)
+	dec := Decoder{MapType: d.MapType, Coerce: d.Coerce}
+
+	for job := range jobs {
+		r.Reset(job.raw)
+
+		p = resetParser(p, r, d.Codec.NewParser)
+
+		v := reflect.New(typ)
+		err := dec.derive(p).Decode(v.Interface())
+
+		if err != nil {
+			job.err = &StreamError{Index: job.index, Err: err}
+		}
+
+		job.value = v.Elem()
+		job.done <- struct{}{}
+	}
+}
+
+// splitter extracts the bytes of values from a parser.
+type splitter struct {
+	p     Parser
+	codec Codec
+	buf   bytes.Buffer
+	emt   Emitter
+}
+
+// parseRaw appends the bytes of the next value to b, after ParseType was
+// called on the parser.
+func (s *splitter) parseRaw(b []byte) ([]byte, error) {
+	if p, ok := asRawParser(s.p); ok {
+		return p.ParseRaw(b)
+	}
+
+	if s.emt == nil {
+		s.emt = s.codec.NewEmitter(&s.buf)
+	}
+
+	s.buf.Reset()
+
+	if err := Copy(s.emt, s.p); err != nil {
+		return b, err
+	}
+
+	return append(b, s.buf.Bytes()...), nil
+}
+
+// parallelJob carries the bytes of a value from the goroutine reading the
+// stream to a worker, and the decoded value from the worker to the consumer.
+type parallelJob struct {
+	index int
+	raw   []byte
+	value reflect.Value
+	err   error
+	done  chan struct{}
+}
+
+var parallelJobPool = sync.Pool{
+	New: func() interface{} { return &parallelJob{done: make(chan struct{}, 1)} },
+}
+
+func newParallelJob(index int) *parallelJob {
+	job := parallelJobPool.Get().(*parallelJob)
+	job.index = index
+	return job
+}
+
+func (job *parallelJob) release() {
+	job.raw = job.raw[:0]
+	job.value = reflect.Value{}
+	job.err = nil
+	parallelJobPool.Put(job)
+}
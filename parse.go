@@ -1,6 +1,9 @@
 package objconv
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 // The Parser interface must be implemented by types that provide decoding of a
 // specific format (like json, resp, ...).
@@ -113,6 +116,53 @@ type Parser interface {
 	ParseMapNext(int) error
 }
 
+// The ResetParser interface is implemented by parsers that can be reused to
+// read from a different reader. The parsers of all the codecs provided by the
+// objconv subpackages implement it.
+type ResetParser interface {
+	// Reset discards the state of the parser and sets its input to r.
+	Reset(r io.Reader)
+}
+
+// resetParser resets p to read from r, or calls newParser to create a new
+// parser if p doesn't implement ResetParser. The function panics if neither is
+// possible.
+func resetParser(p Parser, r io.Reader, newParser func(io.Reader) Parser) Parser {
+	if x, ok := p.(ResetParser); ok && isResetParser(unwrapParser(p)) {
+		x.Reset(r)
+		return p
+	}
+	if newParser == nil {
+		panic("objconv: the parser cannot be reset")
+	}
+	return newParser(r)
+}
+
+func isResetParser(p Parser) bool {
+	_, ok := p.(ResetParser)
+	return ok
+}
+
+// The parserWrapper interface is implemented by parsers that wrap another
+// parser (like TracingParser or MetricsParser). They implement the optional
+// interfaces of parsers and forward them to the parser they wrap, which may
+// not support them.
+type parserWrapper interface {
+	unwrap() Parser
+}
+
+// unwrapParser returns the parser wrapped by p, or p if it doesn't wrap another
+// parser.
+func unwrapParser(p Parser) Parser {
+	for {
+		w, ok := p.(parserWrapper)
+		if !ok {
+			return p
+		}
+		p = w.unwrap()
+	}
+}
+
 // The bytesDecoder interface may optionnaly be implemented by a Parser to
 // provide an extra step in decoding a byte slice. This is sometimes necessary
 // if the associated Emitter has transformed bytes slices because the format is
@@ -124,6 +174,102 @@ type bytesDecoder interface {
 	DecodeBytes([]byte) ([]byte, error)
 }
 
+// The skipParser interface may be implemented by parsers that are able to
+// discard values more efficiently than by parsing them, for example by jumping
+// over length-prefixed data or scanning the input without decoding it.
+type skipParser interface {
+	// Skip is called by a decoder to discard the next value, after ParseType
+	// was called. When the value is an array or a map the whole container is
+	// discarded.
+	Skip() error
+}
+
+// The rawParser interface may be implemented by parsers that are able to
+// extract the bytes of a value from their input without decoding it.
+type rawParser interface {
+	// ParseRaw is called by a decoder to append the bytes of the next value to
+	// b, after ParseType was called. The returned bytes can be given to a new
+	// parser of the same format to decode the value.
+	ParseRaw(b []byte) ([]byte, error)
+}
+
+// asRawParser returns p as a rawParser if it is able to extract the bytes of
+// values, which wrapping parsers are only able to do if the parser they wrap
+// is.
+func asRawParser(p Parser) (r rawParser, ok bool) {
+	if r, ok = p.(rawParser); ok {
+		_, ok = unwrapParser(p).(rawParser)
+	}
+	return
+}
+
+// The sequenceParser interface may be implemented by parsers of formats that
+// represent streams as a sequence of values read one after the other until the
+// end of the input (like NDJSON or CBOR sequences) instead of an array.
+type sequenceParser interface {
+	// SequenceParser returns true if the parser reads streams as sequences of
+	// values.
+	SequenceParser() bool
+}
+
+func isSequenceParser(parser Parser) bool {
+	p, _ := parser.(sequenceParser)
+	return p != nil && p.SequenceParser()
+}
+
+// The recoverParser interface may be implemented by parsers of formats where
+// the beginning of the next value of a stream can be found after a value failed
+// to be parsed, like NDJSON where each value is written on its own line.
+type recoverParser interface {
+	// Recover is called by a stream decoder after an error occurred, it
+	// discards the input up to the beginning of the next value of the stream.
+	Recover() error
+}
+
+// The offsetParser interface may be implemented by parsers able to report their
+// position in the input.
+type offsetParser interface {
+	// InputOffset returns the number of bytes of the input that were consumed
+	// by the parser.
+	InputOffset() int64
+}
+
+// The stableParser interface may be implemented by parsers which don't reuse
+// the memory of the byte slices returned by ParseString and ParseBytes, for
+// example because they are views of an in-memory input. Decoders configured
+// with NoCopy retain such slices in the values they decode instead of copying
+// them.
+type stableParser interface {
+	// StableParser returns true if the byte slices returned by the parser
+	// remain valid and unmodified after the next calls to the parser.
+	StableParser() bool
+}
+
+func isStableParser(parser Parser) bool {
+	p, _ := parser.(stableParser)
+	return p != nil && p.StableParser()
+}
+
+// The bitSizeParser interface may be implemented by parsers of formats which
+// encode numbers with different sizes. Copy passes the sizes to the emitter so
+// the numbers keep their precision when they are transcoded.
+type bitSizeParser interface {
+	// BitSize returns the size in bits of the number returned by the last call
+	// to ParseInt, ParseUint or ParseFloat, or zero if it is unknown.
+	BitSize() int
+}
+
+// bitSizeOf returns the size in bits of the last number parsed by parser, which
+// defaults to 64.
+func bitSizeOf(parser Parser) int {
+	if p, ok := parser.(bitSizeParser); ok {
+		if n := p.BitSize(); n != 0 {
+			return n
+		}
+	}
+	return 64
+}
+
 // The textParser interface may be implemented by parsers of human-readable
 // formats. Such parsers instruct the encoder to prefer using
 // encoding.TextUnmarshaler over encoding.BinaryUnmarshaler for example.
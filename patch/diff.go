@@ -0,0 +1,103 @@
+package patch
+
+import (
+	"strconv"
+
+	"github.com/segmentio/objconv"
+)
+
+// Diff returns a patch which transforms a into b when applied to it.
+//
+// Maps are compared key by key and arrays element by element, values that
+// differ in type are replaced entirely. The patch produced only contains add,
+// remove and replace operations.
+func Diff(a objconv.Value, b objconv.Value) Patch {
+	d := differ{}
+	d.diff(a, b)
+	return d.patch
+}
+
+type differ struct {
+	patch Patch
+	path  []string
+}
+
+func (d *differ) diff(a objconv.Value, b objconv.Value) {
+	if a.Equal(b) {
+		return
+	}
+
+	switch {
+	case a.Type() == objconv.Map && b.Type() == objconv.Map:
+		d.diffMap(a, b)
+
+	case a.Type() == objconv.Array && b.Type() == objconv.Array:
+		d.diffArray(a, b)
+
+	default:
+		d.emit(Replace, b)
+	}
+}
+
+func (d *differ) diffMap(a objconv.Value, b objconv.Value) {
+	for _, item := range a.Items() {
+		if key := keyOf(item.Key); !b.Has(key) {
+			d.push(key)
+			d.emit(Remove, objconv.Value{})
+			d.pop()
+		}
+	}
+
+	for _, item := range b.Items() {
+		key := keyOf(item.Key)
+		d.push(key)
+
+		if a.Has(key) {
+			d.diff(a.Get(key), item.Value)
+		} else {
+			d.emit(Add, item.Value)
+		}
+
+		d.pop()
+	}
+}
+
+func (d *differ) diffArray(a objconv.Value, b objconv.Value) {
+	n := a.Len()
+
+	if b.Len() < n {
+		n = b.Len()
+	}
+
+	for i := 0; i != n; i++ {
+		d.push(strconv.Itoa(i))
+		d.diff(a.Index(i), b.Index(i))
+		d.pop()
+	}
+
+	// Removing elements from the end so the indexes stay valid.
+	for i := a.Len() - 1; i >= n; i-- {
+		d.push(strconv.Itoa(i))
+		d.emit(Remove, objconv.Value{})
+		d.pop()
+	}
+
+	for i := n; i < b.Len(); i++ {
+		d.push("-")
+		d.emit(Add, b.Index(i))
+		d.pop()
+	}
+}
+
+// emit adds an operation on the current path to the patch.
+func (d *differ) emit(op string, v objconv.Value) {
+	d.patch = append(d.patch, Operation{
+		Op:    op,
+		Path:  formatPointer(d.path),
+		Value: v.Clone(),
+	})
+}
+
+func (d *differ) push(elem string) { d.path = append(d.path, elem) }
+
+func (d *differ) pop() { d.path = d.path[:len(d.path)-1] }
@@ -0,0 +1,17 @@
+// Package patch implements JSON Patch (RFC 6902) and JSON Merge Patch
+// (RFC 7396) on top of objconv's format-neutral documents.
+//
+// Patches are decoded with objconv decoders and applied to objconv.Value
+// documents or Go values, which means a patch received in JSON can be applied
+// to a document stored in msgpack or CBOR, for example:
+//
+//	var p patch.Patch
+//
+//	if err := json.Unmarshal(request, &p); err != nil {
+//		...
+//	}
+//
+//	if err := p.ApplyTo(&user); err != nil {
+//		...
+//	}
+package patch
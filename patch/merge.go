@@ -0,0 +1,59 @@
+package patch
+
+import (
+	"strconv"
+
+	"github.com/segmentio/objconv"
+)
+
+// Merge applies the JSON Merge Patch (RFC 7396) patch to doc and returns the
+// resulting document, doc is not modified.
+//
+// Maps of the patch are merged recursively into the document, nil values
+// remove the corresponding keys, and any other value replaces the value found
+// in the document.
+func Merge(doc objconv.Value, patch objconv.Value) objconv.Value {
+	if patch.Type() != objconv.Map {
+		return patch.Clone()
+	}
+
+	if doc.Type() != objconv.Map {
+		doc = objconv.MapValue()
+	} else {
+		doc = doc.Clone()
+	}
+
+	for _, item := range patch.Items() {
+		key := keyOf(item.Key)
+
+		if item.Value.IsNil() {
+			doc.Delete(key)
+		} else {
+			doc.Set(key, Merge(doc.Get(key), item.Value))
+		}
+	}
+
+	return doc
+}
+
+// MergeTo applies the JSON Merge Patch (RFC 7396) patch to the Go value pointed
+// by v, which may be of any type supported by objconv encoders and decoders.
+func MergeTo(v interface{}, patch objconv.Value) error {
+	return update(v, func(doc objconv.Value) (objconv.Value, error) {
+		return Merge(doc, patch), nil
+	})
+}
+
+// keyOf returns the string representation of a map key, formats like msgpack
+// or CBOR may use keys that aren't strings.
+func keyOf(k objconv.Value) string {
+	switch k.Type() {
+	case objconv.Bytes:
+		return string(k.Bytes())
+	case objconv.Int:
+		return strconv.FormatInt(k.Int(), 10)
+	case objconv.Uint:
+		return strconv.FormatUint(k.Uint(), 10)
+	}
+	return k.String()
+}
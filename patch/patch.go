@@ -0,0 +1,420 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/segmentio/objconv"
+)
+
+// Names of the operations defined in RFC 6902.
+const (
+	Add     = "add"
+	Remove  = "remove"
+	Replace = "replace"
+	Move    = "move"
+	Copy    = "copy"
+	Test    = "test"
+)
+
+var (
+	// ErrTestFailed is the error reported when the value of a test operation
+	// didn't match the document.
+	ErrTestFailed = errors.New("test failed")
+
+	// ErrPathNotFound is the error reported when an operation refers to a
+	// location that doesn't exist in the document.
+	ErrPathNotFound = objconv.ErrPathNotFound
+)
+
+// Operation represents a single operation of a JSON Patch.
+//
+// The Value field is left invalid (see objconv.Value.IsValid) for operations
+// that have no value, it is different from a nil value.
+type Operation struct {
+	Op    string        `objconv:"op"`
+	Path  string        `objconv:"path"`
+	From  string        `objconv:"from,omitempty"`
+	Value objconv.Value `objconv:"value,omitzero"`
+}
+
+// Validate checks that o is a well-formed operation.
+func (o Operation) Validate() error {
+	switch o.Op {
+	case Add, Replace, Test:
+		if !o.Value.IsValid() {
+			return fmt.Errorf("objconv/patch: missing value in %q operation", o.Op)
+		}
+	case Move, Copy:
+		if _, err := parsePointer(o.From); err != nil {
+			return err
+		}
+	case Remove:
+	default:
+		return fmt.Errorf("objconv/patch: unknown operation %q", o.Op)
+	}
+
+	_, err := parsePointer(o.Path)
+	return err
+}
+
+// Patch is a JSON Patch document, which is a list of operations applied in
+// order.
+type Patch []Operation
+
+// DecodeValue satisfies the objconv.ValueDecoder interface, it validates the
+// operations after decoding them.
+func (p *Patch) DecodeValue(d objconv.Decoder) error {
+	// The operations are first decoded with pointers to strings so missing
+	// members can be told apart from empty JSON pointers.
+	var ops []struct {
+		Op    string        `objconv:"op"`
+		Path  *string       `objconv:"path"`
+		From  *string       `objconv:"from"`
+		Value objconv.Value `objconv:"value"`
+	}
+
+	if err := d.Decode(&ops); err != nil {
+		return err
+	}
+
+	patch := make(Patch, len(ops))
+
+	for i, op := range ops {
+		switch {
+		case op.Path == nil:
+			return fmt.Errorf("objconv/patch: missing path in %q operation at index %d", op.Op, i)
+		case op.From == nil && (op.Op == Move || op.Op == Copy):
+			return fmt.Errorf("objconv/patch: missing from in %q operation at index %d", op.Op, i)
+		}
+
+		patch[i] = Operation{Op: op.Op, Path: *op.Path, Value: op.Value}
+
+		if op.From != nil {
+			patch[i].From = *op.From
+		}
+
+		if err := patch[i].Validate(); err != nil {
+			return fmt.Errorf("%s (operation at index %d)", err, i)
+		}
+	}
+
+	*p = patch
+	return nil
+}
+
+// Apply applies the operations of p to doc, returning the resulting document.
+//
+// Patches are applied atomically, doc is never modified and if one of the
+// operations fails the method returns an *Error and no document.
+func (p Patch) Apply(doc objconv.Value) (objconv.Value, error) {
+	doc = doc.Clone()
+
+	for i, op := range p {
+		if err := apply(&doc, op); err != nil {
+			err.Index = i
+			return objconv.Value{}, err
+		}
+	}
+
+	return doc, nil
+}
+
+// ApplyTo applies the operations of p to the Go value pointed by v, which may be
+// of any type supported by objconv encoders and decoders.
+//
+// The value pointed by v is left unchanged if an error occurs.
+func (p Patch) ApplyTo(v interface{}) error {
+	return update(v, p.Apply)
+}
+
+// Error is the error type returned when applying a patch fails.
+type Error struct {
+	// Index is the position of the failing operation in the patch.
+	Index int
+
+	// Op is the operation that failed.
+	Op Operation
+
+	// Path is the location that caused the failure, it is the from location
+	// of move and copy operations when they refer to a missing value.
+	Path string
+
+	// Err describes the reason of the failure, it is either ErrTestFailed,
+	// ErrPathNotFound, or an error describing an invalid operation.
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("objconv/patch: %s operation at index %d failed on %q: %s", e.Op.Op, e.Index, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func apply(doc *objconv.Value, op Operation) *Error {
+	fail := func(path string, err error) *Error {
+		return &Error{Op: op, Path: path, Err: err}
+	}
+
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return fail(op.Path, err)
+	}
+
+	switch op.Op {
+	case Add:
+		err = add(doc, path, op.Value.Clone())
+
+	case Remove:
+		_, err = remove(doc, path)
+
+	case Replace:
+		err = replace(doc, path, op.Value.Clone())
+
+	case Move, Copy:
+		var from []string
+		var v objconv.Value
+
+		if from, err = parsePointer(op.From); err != nil {
+			return fail(op.From, err)
+		}
+
+		if op.Op == Move {
+			if isPrefix(from, path) && len(from) != len(path) {
+				return fail(op.From, errors.New("a value cannot be moved into one of its children"))
+			}
+			v, err = remove(doc, from)
+		} else {
+			v, err = lookup(*doc, from)
+			v = v.Clone()
+		}
+
+		if err != nil {
+			return fail(op.From, err)
+		}
+
+		err = add(doc, path, v)
+
+	case Test:
+		var v objconv.Value
+		if v, err = lookup(*doc, path); err == nil && !v.Equal(op.Value) {
+			err = ErrTestFailed
+		}
+
+	default:
+		err = fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	if err != nil {
+		return fail(op.Path, err)
+	}
+
+	return nil
+}
+
+// add inserts v at path, as defined by the "add" operation of RFC 6902.
+func add(doc *objconv.Value, path []string, v objconv.Value) error {
+	if len(path) == 0 {
+		*doc = v
+		return nil
+	}
+
+	parent, err := lookup(*doc, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+
+	elem := path[len(path)-1]
+
+	switch parent.Type() {
+	case objconv.Array:
+		i, ok := parent.Len(), elem == "-"
+		if !ok {
+			i, ok = parseIndex(elem, parent.Len()+1)
+		}
+		if !ok {
+			return ErrPathNotFound
+		}
+		parent.Insert(i, v)
+
+	case objconv.Map:
+		parent.Set(elem, v)
+
+	default:
+		return fmt.Errorf("cannot add a member to a value of type %s", parent.Type())
+	}
+
+	return nil
+}
+
+// replace replaces the value at path with v, the value must exist.
+func replace(doc *objconv.Value, path []string, v objconv.Value) error {
+	if len(path) == 0 {
+		*doc = v
+		return nil
+	}
+
+	parent, err := lookup(*doc, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+
+	elem := path[len(path)-1]
+
+	if _, err = child(parent, elem); err != nil {
+		return err
+	}
+
+	if parent.Type() == objconv.Array {
+		i, _ := parseIndex(elem, parent.Len())
+		parent.SetIndex(i, v)
+	} else {
+		parent.Set(elem, v)
+	}
+
+	return nil
+}
+
+// remove removes the value at path and returns it.
+func remove(doc *objconv.Value, path []string) (v objconv.Value, err error) {
+	if len(path) == 0 {
+		v, *doc = *doc, objconv.NilValue()
+		return
+	}
+
+	var parent objconv.Value
+
+	if parent, err = lookup(*doc, path[:len(path)-1]); err != nil {
+		return
+	}
+
+	if v, err = child(parent, path[len(path)-1]); err != nil {
+		return
+	}
+
+	if parent.Type() == objconv.Array {
+		i, _ := parseIndex(path[len(path)-1], parent.Len())
+		parent.Remove(i)
+	} else {
+		parent.Delete(path[len(path)-1])
+	}
+
+	return
+}
+
+func lookup(doc objconv.Value, path []string) (v objconv.Value, err error) {
+	v = doc
+
+	for _, elem := range path {
+		if v, err = child(v, elem); err != nil {
+			break
+		}
+	}
+
+	return
+}
+
+func child(v objconv.Value, elem string) (objconv.Value, error) {
+	switch v.Type() {
+	case objconv.Array:
+		if i, ok := parseIndex(elem, v.Len()); ok {
+			return v.Index(i), nil
+		}
+
+	case objconv.Map:
+		if v.Has(elem) {
+			return v.Get(elem), nil
+		}
+	}
+
+	return objconv.Value{}, ErrPathNotFound
+}
+
+// parsePointer parses a JSON Pointer, which unlike objconv.ParsePath rejects
+// paths in dotted notation.
+func parsePointer(s string) ([]string, error) {
+	if len(s) != 0 && s[0] != '/' {
+		return nil, fmt.Errorf("objconv/patch: invalid JSON pointer %q", s)
+	}
+	return objconv.ParsePath(s)
+}
+
+// parseIndex parses an array index as defined by RFC 6901, which doesn't allow
+// leading zeros, and checks that it is lower than n.
+func parseIndex(s string, n int) (int, bool) {
+	if len(s) == 0 || len(s) > 18 || (s[0] == '0' && len(s) > 1) {
+		return 0, false
+	}
+
+	i := 0
+
+	for _, c := range []byte(s) {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		i = 10*i + int(c-'0')
+	}
+
+	return i, i < n
+}
+
+func isPrefix(prefix []string, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatPointer builds a JSON Pointer from a list of path elements.
+func formatPointer(path []string) string {
+	var b strings.Builder
+	r := strings.NewReplacer("~", "~0", "/", "~1")
+
+	for _, elem := range path {
+		b.WriteByte('/')
+		r.WriteString(&b, elem)
+	}
+
+	return b.String()
+}
+
+// update loads the Go value pointed by v as a document, calls f to transform
+// it, then stores the result back into v.
+func update(v interface{}, f func(objconv.Value) (objconv.Value, error)) error {
+	ptr := reflect.ValueOf(v)
+
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("objconv/patch: the destination must be a non-nil pointer, not %T", v)
+	}
+
+	doc, err := objconv.ValueOf(v)
+	if err != nil {
+		return err
+	}
+
+	if doc, err = f(doc); err != nil {
+		return err
+	}
+
+	// The value is decoded to a new object so fields that were removed from
+	// the document don't retain their previous values.
+	tmp := reflect.New(ptr.Type().Elem())
+
+	if err = doc.Decode(tmp.Interface()); err != nil {
+		return err
+	}
+
+	ptr.Elem().Set(tmp.Elem())
+	return nil
+}
@@ -0,0 +1,330 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/json"
+	"github.com/segmentio/objconv/msgpack"
+)
+
+func parse(t *testing.T, s string) objconv.Value {
+	var v objconv.Value
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func format(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// Test cases from the examples in appendix A of RFC 6902.
+var patchTests = []struct {
+	doc    string
+	patch  string
+	result string
+}{
+	{
+		doc:    `{"foo":"bar"}`,
+		patch:  `[{"op":"add","path":"/baz","value":"qux"}]`,
+		result: `{"foo":"bar","baz":"qux"}`,
+	},
+	{
+		doc:    `{"foo":["bar","baz"]}`,
+		patch:  `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+		result: `{"foo":["bar","qux","baz"]}`,
+	},
+	{
+		doc:    `{"baz":"qux","foo":"bar"}`,
+		patch:  `[{"op":"remove","path":"/baz"}]`,
+		result: `{"foo":"bar"}`,
+	},
+	{
+		doc:    `{"foo":["bar","qux","baz"]}`,
+		patch:  `[{"op":"remove","path":"/foo/1"}]`,
+		result: `{"foo":["bar","baz"]}`,
+	},
+	{
+		doc:    `{"baz":"qux","foo":"bar"}`,
+		patch:  `[{"op":"replace","path":"/baz","value":"boo"}]`,
+		result: `{"baz":"boo","foo":"bar"}`,
+	},
+	{
+		doc:    `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+		patch:  `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+		result: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+	},
+	{
+		doc:    `{"foo":["all","grass","cows","eat"]}`,
+		patch:  `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+		result: `{"foo":["all","cows","eat","grass"]}`,
+	},
+	{
+		doc:    `{"baz":"qux","foo":["a",2,"c"]}`,
+		patch:  `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+		result: `{"baz":"qux","foo":["a",2,"c"]}`,
+	},
+	{
+		doc:    `{"foo":"bar"}`,
+		patch:  `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+		result: `{"foo":"bar","child":{"grandchild":{}}}`,
+	},
+	{
+		doc:    `{"foo":["bar"]}`,
+		patch:  `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+		result: `{"foo":["bar",["abc","def"]]}`,
+	},
+	{
+		doc:    `{"/":0,"~":1}`,
+		patch:  `[{"op":"copy","from":"/~1","path":"/~0"},{"op":"add","path":"","value":null}]`,
+		result: `null`,
+	},
+}
+
+func TestApply(t *testing.T) {
+	for _, test := range patchTests {
+		t.Run(test.patch, func(t *testing.T) {
+			var p Patch
+
+			if err := json.Unmarshal([]byte(test.patch), &p); err != nil {
+				t.Fatal(err)
+			}
+
+			doc := parse(t, test.doc)
+			res, err := p.Apply(doc)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s := format(t, res); s != test.result {
+				t.Error(s)
+			}
+
+			if s := format(t, doc); s != test.doc {
+				t.Error("the original document was modified:", s)
+			}
+		})
+	}
+}
+
+func TestApplyError(t *testing.T) {
+	tests := []struct {
+		patch string
+		index int
+		path  string
+		err   error
+	}{
+		{
+			patch: `[{"op":"test","path":"/a","value":1},{"op":"test","path":"/b/0","value":"y"}]`,
+			index: 1,
+			path:  "/b/0",
+			err:   ErrTestFailed,
+		},
+		{
+			patch: `[{"op":"remove","path":"/c"}]`,
+			path:  "/c",
+			err:   ErrPathNotFound,
+		},
+		{
+			patch: `[{"op":"add","path":"/b/3","value":0}]`,
+			path:  "/b/3",
+			err:   ErrPathNotFound,
+		},
+		{
+			patch: `[{"op":"replace","path":"/b/01","value":0}]`,
+			path:  "/b/01",
+			err:   ErrPathNotFound,
+		},
+		{
+			patch: `[{"op":"copy","from":"/x/y","path":"/c"}]`,
+			path:  "/x/y",
+			err:   ErrPathNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.patch, func(t *testing.T) {
+			var p Patch
+
+			if err := json.Unmarshal([]byte(test.patch), &p); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := p.Apply(parse(t, `{"a":1,"b":["x"]}`))
+			e, ok := err.(*Error)
+
+			switch {
+			case !ok:
+				t.Fatal("bad error type:", err)
+			case e.Index != test.index:
+				t.Error("bad index:", e.Index)
+			case e.Path != test.path:
+				t.Error("bad path:", e.Path)
+			case !errors.Is(err, test.err):
+				t.Error("bad error:", err)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidPatch(t *testing.T) {
+	tests := []string{
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"move","path":"/a"}]`,
+		`[{"op":"remove","path":"a"}]`,
+		`[{"op":"delete","path":"/a"}]`,
+		`{"op":"remove","path":"/a"}`,
+	}
+
+	for _, test := range tests {
+		var p Patch
+
+		if err := json.Unmarshal([]byte(test), &p); err == nil {
+			t.Error("expected an error decoding", test)
+		}
+	}
+}
+
+func TestApplyToStruct(t *testing.T) {
+	type user struct {
+		Name  string   `objconv:"name"`
+		Email string   `objconv:"email,omitempty"`
+		Tags  []string `objconv:"tags"`
+		Age   uint8    `objconv:"age"`
+	}
+
+	// The document is stored in msgpack while the patch is received as JSON.
+	b, err := msgpack.Marshal(user{Name: "Luke", Email: "luke@example.com", Tags: []string{"a"}, Age: 18})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var u user
+
+	if err := msgpack.Unmarshal(b, &u); err != nil {
+		t.Fatal(err)
+	}
+
+	var p Patch
+
+	if err := json.Unmarshal([]byte(`[
+		{"op":"test","path":"/age","value":18},
+		{"op":"remove","path":"/email"},
+		{"op":"add","path":"/tags/0","value":"b"},
+		{"op":"replace","path":"/age","value":19}
+	]`), &p); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.ApplyTo(&u); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := format(t, u); s != `{"name":"Luke","tags":["b","a"],"age":19}` {
+		t.Error(s)
+	}
+
+	if err := (Patch{{Op: Test, Path: "/age", Value: objconv.IntValue(0)}}).ApplyTo(&u); !errors.Is(err, ErrTestFailed) {
+		t.Error("bad error:", err)
+	}
+
+	if u.Age != 19 {
+		t.Error("the value was modified by a failing patch")
+	}
+}
+
+// Test cases from the examples in appendix A of RFC 7396.
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		doc    string
+		patch  string
+		result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.doc+test.patch, func(t *testing.T) {
+			if s := format(t, Merge(parse(t, test.doc), parse(t, test.patch))); s != test.result {
+				t.Error(s)
+			}
+		})
+	}
+}
+
+func TestMergeTo(t *testing.T) {
+	type config struct {
+		Host  string            `objconv:"host"`
+		Port  int               `objconv:"port"`
+		Attrs map[string]string `objconv:"attrs"`
+	}
+
+	c := config{Host: "localhost", Port: 4242, Attrs: map[string]string{"a": "1", "b": "2"}}
+
+	if err := MergeTo(&c, parse(t, `{"port":8080,"attrs":{"a":null,"c":"3"}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Host != "localhost" || c.Port != 8080 || len(c.Attrs) != 2 || c.Attrs["b"] != "2" || c.Attrs["c"] != "3" {
+		t.Errorf("%#v", c)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		a     string
+		b     string
+		patch string
+	}{
+		{`{"a":1}`, `{"a":1.0}`, `[]`},
+		{`{"a":1,"b":[1,2,3]}`, `{"b":[1,4],"c":{"d":true}}`, `[{"op":"remove","path":"/a"},{"op":"replace","path":"/b/1","value":4},{"op":"remove","path":"/b/2"},{"op":"add","path":"/c","value":{"d":true}}]`},
+		{`[1]`, `[1,[2],3]`, `[{"op":"add","path":"/-","value":[2]},{"op":"add","path":"/-","value":3}]`},
+		{`{"a/b":{"~":0}}`, `{"a/b":{"~":null}}`, `[{"op":"replace","path":"/a~1b/~0","value":null}]`},
+		{`{"":1}`, `{}`, `[{"op":"remove","path":"/"}]`},
+		{`"a"`, `{}`, `[{"op":"replace","path":"","value":{}}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.a+test.b, func(t *testing.T) {
+			a := parse(t, test.a)
+			b := parse(t, test.b)
+			p := Diff(a, b)
+
+			if p == nil {
+				p = Patch{}
+			}
+
+			if s := format(t, p); s != test.patch {
+				t.Error(s)
+			}
+
+			if c, err := p.Apply(a); err != nil {
+				t.Error(err)
+			} else if !c.Equal(b) {
+				t.Error("applying the diff produced", format(t, c))
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package objconv
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrPathNotFound is the error wrapped by PathError when a path did not match
+// any value of the decoded document.
+var ErrPathNotFound = errors.New("path not found")
+
+// PathError is returned by the path decoding methods of Decoder when one of
+// the paths could not be decoded.
+type PathError struct {
+	Path string
+	Err  error
+}
+
+// Error satisfies the error interface.
+func (e *PathError) Error() string {
+	return fmt.Sprintf("objconv: %s: %s", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// ParsePath splits path into the list of map keys and array indexes that it
+// is made of.
+//
+// Two syntaxes are supported. Paths starting with a slash are interpreted as
+// JSON Pointers (RFC 6901), for example "/meta/request_id" or "/items/0".
+// Other paths use a dotted syntax where array indexes and keys that contain
+// dots may be written between brackets, for example ".meta.request_id",
+// "items[0].name" or `labels["app.kubernetes.io/name"]`.
+//
+// The empty path refers to the whole document and produces an empty list.
+func ParsePath(path string) ([]string, error) {
+	switch {
+	case len(path) == 0:
+		return []string{}, nil
+	case path[0] == '/':
+		return parseJSONPointer(path)
+	default:
+		return parseDottedPath(path)
+	}
+}
+
+func parseJSONPointer(path string) ([]string, error) {
+	elems := strings.Split(path[1:], "/")
+
+	for i, elem := range elems {
+		if strings.IndexByte(elem, '~') < 0 {
+			continue
+		}
+
+		var b []byte
+
+		for j := 0; j < len(elem); j++ {
+			if c := elem[j]; c != '~' {
+				b = append(b, c)
+				continue
+			}
+
+			if j++; j == len(elem) {
+				return nil, fmt.Errorf("objconv: invalid escape sequence at the end of the JSON pointer %q", path)
+			}
+
+			switch elem[j] {
+			case '0':
+				b = append(b, '~')
+			case '1':
+				b = append(b, '/')
+			default:
+				return nil, fmt.Errorf("objconv: invalid escape sequence '~%c' in the JSON pointer %q", elem[j], path)
+			}
+		}
+
+		elems[i] = string(b)
+	}
+
+	return elems, nil
+}
+
+func parseDottedPath(path string) ([]string, error) {
+	elems := []string{}
+	s := path
+
+	if s[0] == '.' {
+		s = s[1:]
+	}
+
+	for len(s) != 0 {
+		var elem string
+
+		if s[0] == '[' {
+			i := strings.IndexByte(s, ']')
+
+			if i < 0 {
+				return nil, fmt.Errorf("objconv: missing closing bracket in path %q", path)
+			}
+
+			if elem = s[1:i]; len(elem) != 0 && elem[0] == '"' {
+				// Quoted keys may contain closing brackets.
+				q, err := strconv.QuotedPrefix(s[1:])
+				if err != nil || !strings.HasPrefix(s[1+len(q):], "]") {
+					return nil, fmt.Errorf("objconv: invalid quoted key in path %q", path)
+				}
+				elem, _ = strconv.Unquote(q)
+				i = 1 + len(q)
+			}
+
+			s = s[i+1:]
+		} else {
+			i := strings.IndexAny(s, ".[")
+
+			if i < 0 {
+				i = len(s)
+			}
+
+			if i == 0 {
+				return nil, fmt.Errorf("objconv: empty element in path %q", path)
+			}
+
+			elem, s = s[:i], s[i:]
+		}
+
+		elems = append(elems, elem)
+
+		if len(s) != 0 && s[0] == '.' {
+			if s = s[1:]; len(s) == 0 {
+				return nil, fmt.Errorf("objconv: path %q cannot end with a dot", path)
+			}
+		}
+	}
+
+	return elems, nil
+}
+
+// DecodePath decodes the value found at path within the next value read from
+// the parser into v. The syntax of path is described in ParsePath.
+//
+// All values that are not on the path are skipped without being decoded, and
+// the parser stops reading as soon as the target was decoded, which means the
+// rest of the document is left unread.
+//
+// If the path doesn't match any value the method returns a *PathError wrapping
+// ErrPathNotFound and v is left unchanged.
+func (d Decoder) DecodePath(path string, v interface{}) error {
+	return d.DecodePaths(map[string]interface{}{path: v})
+}
+
+// DecodePaths is like DecodePath but decodes the values of multiple paths in a
+// single pass over the document. The paths argument maps each path to the
+// destination where its value is decoded.
+//
+// When a path is a prefix of another one the value is loaded in memory so both
+// destinations can be decoded.
+//
+// If some paths did not match any value the first of them, in lexical order,
+// is reported in the returned error. The destinations of the paths that were
+// found are decoded regardless.
+//
+// When the decoder collects errors, the values that could not be converted are
+// reported in a DecodeErrors value with their path in the document.
+func (d Decoder) DecodePaths(paths map[string]interface{}) (err error) {
+	root := &pathNode{}
+	targets := make([]*pathTarget, 0, len(paths))
+
+	for path, v := range paths {
+		elems, err := ParsePath(path)
+		if err != nil {
+			return err
+		}
+		target := &pathTarget{path: path, value: v}
+		root.insert(elems, target)
+		targets = append(targets, target)
+	}
+
+	sort.Slice(targets, func(i int, j int) bool {
+		return targets[i].path < targets[j].path
+	})
+
+	w := pathWalker{remain: len(targets)}
+	errs := DecodeErrors{}
+
+	if d.CollectErrors && d.errs == nil {
+		d = d.collecting(nil, &errs)
+	}
+
+	if err = w.walk(d, root); err == errPathDone {
+		err = nil
+	}
+
+	if err != nil {
+		return
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+
+	for _, target := range targets {
+		if !target.found {
+			return &PathError{Path: target.path, Err: ErrPathNotFound}
+		}
+	}
+
+	return
+}
+
+// errPathDone is used internally to interrupt the decoding algorithms once all
+// the paths were decoded.
+var errPathDone = errors.New("objconv: all paths were decoded")
+
+type pathTarget struct {
+	path  string
+	value interface{}
+	found bool
+}
+
+// pathNode is a node of the trie of paths being decoded.
+type pathNode struct {
+	targets []*pathTarget
+	keys    map[string]*pathNode
+	indexes map[int]*pathNode
+}
+
+func (n *pathNode) insert(elems []string, target *pathTarget) {
+	for _, elem := range elems {
+		child := n.keys[elem]
+
+		if child == nil {
+			child = &pathNode{}
+
+			if n.keys == nil {
+				n.keys = make(map[string]*pathNode)
+			}
+			n.keys[elem] = child
+
+			if i, err := strconv.Atoi(elem); err == nil && i >= 0 {
+				if n.indexes == nil {
+					n.indexes = make(map[int]*pathNode)
+				}
+				n.indexes[i] = child
+			}
+		}
+
+		n = child
+	}
+
+	n.targets = append(n.targets, target)
+}
+
+type pathWalker struct {
+	remain int // number of targets left to decode
+}
+
+func (w *pathWalker) walk(d Decoder, n *pathNode) (err error) {
+	if len(n.targets) != 0 {
+		return w.decode(d, n)
+	}
+
+	if d.off != 0 {
+		if d.off, err = 0, d.Parser.ParseMapValue(d.off-1); err != nil {
+			return
+		}
+	}
+
+	var t Type
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	switch t {
+	case Array:
+		i := 0
+		err = d.decodeArrayImpl(t, func(d Decoder) error {
+			child := n.indexes[i]
+			i++
+			if child == nil {
+				return d.Decode(nil)
+			}
+			return w.walkElem(d, i-1, child)
+		})
+
+	case Map:
+		var a [32]byte
+		err = d.decodeMapImpl(t, func(kd Decoder, vd Decoder) error {
+			k, ok, err := kd.decodePathKey(a[:0])
+			if err != nil {
+				return err
+			}
+			var child *pathNode
+			if ok {
+				child = n.keys[string(k)]
+			}
+			if child == nil {
+				return vd.Decode(nil)
+			}
+			return w.walkElem(vd, string(k), child)
+		})
+
+	default:
+		err = d.skip(t)
+	}
+
+	return
+}
+
+// walkElem walks the child n of an array or a map, elem is the index or key of
+// the child, which is part of the path of errors when d collects them.
+func (w *pathWalker) walkElem(d Decoder, elem interface{}, n *pathNode) error {
+	if c := d.errs; c != nil {
+		c.path = append(c.path, elem)
+		defer func() { c.path = c.path[:len(c.path)-1] }()
+	}
+	return w.walk(d, n)
+}
+
+// decode decodes the value the parser is positioned on into the targets of n,
+// then continues walking the children of n if it has any.
+func (w *pathWalker) decode(d Decoder, n *pathNode) (err error) {
+	targets := n.targets
+
+	if len(n.keys) != 0 || len(targets) > 1 {
+		// The value must be decoded multiple times, load it in memory first.
+		var v interface{}
+
+		if err = d.Decode(&v); err != nil {
+			return
+		}
+
+		for _, target := range targets {
+			if err = d.derive(NewValueParser(v)).decodeCollected(target.value); err != nil {
+				return
+			}
+			target.found = true
+			w.remain--
+		}
+
+		if len(n.keys) != 0 {
+			n.targets = nil
+			err = w.walk(d.derive(NewValueParser(v)), n)
+			n.targets = targets
+			return
+		}
+	} else {
+		if err = d.decodeCollected(targets[0].value); err != nil {
+			return
+		}
+		targets[0].found = true
+		w.remain--
+	}
+
+	if w.remain == 0 {
+		err = errPathDone
+	}
+
+	return
+}
+
+// decodePathKey decodes a map key as a byte slice which can be compared to
+// path elements, b is used as buffer to format numeric keys. The ok flag is
+// false if the key has a type that cannot match a path element.
+func (d Decoder) decodePathKey(b []byte) (k []byte, ok bool, err error) {
+	var t Type
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	switch t {
+	case String:
+		k, err = d.Parser.ParseString()
+
+	case Bytes:
+		k, err = d.Parser.ParseBytes()
+
+	case Int:
+		var v int64
+		if v, err = d.Parser.ParseInt(); err == nil {
+			k = strconv.AppendInt(b, v, 10)
+		}
+
+	case Uint:
+		var v uint64
+		if v, err = d.Parser.ParseUint(); err == nil {
+			k = strconv.AppendUint(b, v, 10)
+		}
+
+	default:
+		return nil, false, d.skip(t)
+	}
+
+	ok = err == nil
+	return
+}
@@ -0,0 +1,179 @@
+package objconv
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path  string
+		elems []string
+	}{
+		{``, []string{}},
+		{`/`, []string{""}},
+		{`/meta/request_id`, []string{"meta", "request_id"}},
+		{`/a~1b/m~0n/0`, []string{"a/b", "m~n", "0"}},
+		{`.meta.request_id`, []string{"meta", "request_id"}},
+		{`meta.request_id`, []string{"meta", "request_id"}},
+		{`items[0].name`, []string{"items", "0", "name"}},
+		{`items.0.name`, []string{"items", "0", "name"}},
+		{`[1][2]`, []string{"1", "2"}},
+		{`labels["app.kubernetes.io/name"]`, []string{"labels", "app.kubernetes.io/name"}},
+		{`labels["]"].x`, []string{"labels", "]", "x"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			elems, err := ParsePath(test.path)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(elems, test.elems) {
+				t.Errorf("%#v != %#v", elems, test.elems)
+			}
+		})
+	}
+}
+
+func TestParsePathError(t *testing.T) {
+	tests := []string{
+		`/a~`,
+		`/a~2`,
+		`a..b`,
+		`a.`,
+		`a[0`,
+		`a["b]`,
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			if _, err := ParsePath(test); err == nil {
+				t.Error("no error was returned")
+			}
+		})
+	}
+}
+
+func TestDecodePath(t *testing.T) {
+	doc := map[string]interface{}{
+		"meta": map[string]interface{}{
+			"request_id": "1234",
+			"tags":       []string{"a", "b", "c"},
+		},
+		"items": []interface{}{
+			map[string]interface{}{"name": "first"},
+			map[string]interface{}{"name": "second"},
+		},
+		"count": 2,
+	}
+
+	tests := []struct {
+		path  string
+		value interface{}
+	}{
+		{"meta.request_id", "1234"},
+		{"/meta/request_id", "1234"},
+		{"meta.tags[2]", "c"},
+		{"items[1].name", "second"},
+		{"/items/0/name", "first"},
+		{"count", 2},
+		{"meta.tags", []string{"a", "b", "c"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			v := reflect.New(reflect.TypeOf(test.value))
+
+			if err := NewDecoder(NewValueParser(doc)).DecodePath(test.path, v.Interface()); err != nil {
+				t.Fatal(err)
+			}
+
+			if x := v.Elem().Interface(); !reflect.DeepEqual(x, test.value) {
+				t.Errorf("%#v != %#v", x, test.value)
+			}
+		})
+	}
+
+	t.Run("not-found", func(t *testing.T) {
+		var s string
+
+		for _, path := range []string{"meta.missing", "items[2]", "count.x", "meta.tags.x"} {
+			err := NewDecoder(NewValueParser(doc)).DecodePath(path, &s)
+
+			if !errors.Is(err, ErrPathNotFound) {
+				t.Errorf("%s: bad error: %v", path, err)
+			}
+		}
+	})
+
+	t.Run("multi", func(t *testing.T) {
+		var id string
+		var tag string
+		var tags []string
+		var meta map[string]interface{}
+		var name1 string
+		var name2 string
+
+		if err := NewDecoder(NewValueParser(doc)).DecodePaths(map[string]interface{}{
+			"meta":            &meta,
+			"meta.request_id": &id,
+			"meta.tags":       &tags,
+			"/meta/tags/1":    &tag,
+			"items[0].name":   &name1,
+			"/items/0/name":   &name2,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if id != "1234" || tag != "b" || name1 != "first" || name2 != "first" {
+			t.Error("bad values:", id, tag, name1, name2)
+		}
+
+		if !reflect.DeepEqual(tags, []string{"a", "b", "c"}) {
+			t.Error("bad tags:", tags)
+		}
+
+		if meta["request_id"] != "1234" {
+			t.Error("bad meta:", meta)
+		}
+	})
+
+	t.Run("collect-errors", func(t *testing.T) {
+		var a struct{ A, B int }
+		var b []int
+		var b1 int
+
+		err := Decoder{
+			Parser:        NewValueParser(map[string]interface{}{"a": map[string]interface{}{"A": "x", "B": 1}, "b": []interface{}{1, "y"}}),
+			CollectErrors: true,
+		}.DecodePaths(map[string]interface{}{
+			"/a":   &a,
+			"/b":   &b,
+			"/b/1": &b1,
+		})
+
+		errs, ok := err.(DecodeErrors)
+		if !ok {
+			t.Fatal("expected DecodeErrors but got", err)
+		}
+
+		paths := make([]string, len(errs))
+		for i, e := range errs {
+			paths[i] = e.Path
+		}
+		sort.Strings(paths)
+
+		if expect := []string{"/a/A", "/b/1", "/b/1"}; !reflect.DeepEqual(paths, expect) {
+			t.Errorf("bad error paths: %q != %q (%v)", paths, expect, err)
+		}
+
+		if a.B != 1 || !reflect.DeepEqual(b, []int{1, 0}) {
+			t.Error("bad values:", a, b)
+		}
+	})
+}
@@ -116,3 +116,27 @@ func BenchmarkDecoder(b *testing.B) {
 		})
 	}
 }
+
+func TestSkip(t *testing.T) {
+	for _, test := range respDecodeTests {
+		t.Run(testName(test.s), func(t *testing.T) {
+			var v int
+
+			d := objconv.NewDecoder(NewParser(strings.NewReader(test.s + ":42\r\n")))
+
+			if err := d.Decode(nil); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if err := d.Decode(&v); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if v != 42 {
+				t.Error("invalid value decoded after skipping:", v)
+			}
+		})
+	}
+}
@@ -21,8 +21,12 @@ var (
 
 // Emitter implements a RESP emitter that satisfies the objconv.Emitter
 // interface.
+//
+// The output is buffered while arrays and maps are being emitted, see
+// objutil.BufferedWriter.
 type Emitter struct {
-	w io.Writer
+	w   io.Writer
+	out *objutil.BufferedWriter
 
 	// This byte slice is used as a local buffer to format values before they
 	// are written to the output.
@@ -50,14 +54,16 @@ type context struct {
 }
 
 func NewEmitter(w io.Writer) *Emitter {
-	e := &Emitter{w: w}
+	b := objutil.NewBufferedWriter(w)
+	e := &Emitter{w: b, out: b}
 	e.s = e.a[:0]
 	e.stack = e.sback[:0]
 	return e
 }
 
 func (e *Emitter) Reset(w io.Writer) {
-	e.w = w
+	e.out.Reset(w)
+	e.w = e.out
 
 	if e.stack == nil {
 		e.stack = e.stack[:0]
@@ -220,6 +226,8 @@ func (e *Emitter) EmitError(v error) (err error) {
 func (e *Emitter) EmitArrayBegin(n int) (err error) {
 	var c *context
 
+	e.out.Push()
+
 	if n < 0 {
 		c = contextPool.Get().(*context)
 		c.b.Truncate(0)
@@ -251,9 +259,13 @@ func (e *Emitter) EmitArrayEnd() (err error) {
 		}
 
 		contextPool.Put(c)
+
+		if err != nil {
+			return
+		}
 	}
 
-	return
+	return e.out.Pop()
 }
 
 func (e *Emitter) EmitArrayNext() (err error) {
@@ -264,11 +276,12 @@ func (e *Emitter) EmitArrayNext() (err error) {
 }
 
 func (e *Emitter) EmitMapBegin(n int) (err error) {
+	e.out.Push()
 	return e.emitArray(n + n)
 }
 
 func (e *Emitter) EmitMapEnd() (err error) {
-	return
+	return e.out.Pop()
 }
 
 func (e *Emitter) EmitMapValue() (err error) {
@@ -279,6 +292,28 @@ func (e *Emitter) EmitMapNext() (err error) {
 	return
 }
 
+// Flush writes the buffered output to the underlying writer.
+func (e *Emitter) Flush() error {
+	return e.out.Flush()
+}
+
+// Abort writes the buffered output to the underlying writer and discards the
+// state of the arrays and maps that were left open when encoding a value failed.
+func (e *Emitter) Abort() error {
+	for i := len(e.stack) - 1; i >= 0; i-- {
+		if c := e.stack[i]; c != nil {
+			e.w = c.w
+			contextPool.Put(c)
+		}
+	}
+	e.stack = e.stack[:0]
+	return e.out.Abort()
+}
+
+func (e *Emitter) LengthEmitter() bool {
+	return true
+}
+
 func (e *Emitter) emitArray(n int) (err error) {
 	s := e.s[:0]
 
@@ -338,7 +373,8 @@ type ClientEmitter struct {
 
 func NewClientEmitter(w io.Writer) *ClientEmitter {
 	e := &ClientEmitter{}
-	e.w = w
+	e.out = objutil.NewBufferedWriter(w)
+	e.w = e.out
 	e.s = e.a[:0]
 	e.stack = e.sback[:0]
 	return e
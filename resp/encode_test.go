@@ -140,3 +140,45 @@ func BenchmarkMarshal(b *testing.B) {
 func testName(s string) string {
 	return strings.Replace(s, "\r\n", "", -1)
 }
+
+func TestMarshalChan(t *testing.T) {
+	ch := make(chan interface{}, 3)
+	ch <- 1
+	ch <- []int{2, 3}
+	ch <- "A"
+	close(ch)
+
+	b, err := Marshal(struct{ A <-chan interface{} }{ch})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(b); s != "*2\r\n+A\r\n*3\r\n:1\r\n*2\r\n:2\r\n:3\r\n+A\r\n" {
+		t.Errorf("%#v", s)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	v := []interface{}{"Hello World!", 42}
+
+	b1, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := append(make([]byte, 0, 1024), "..."...)
+
+	b2, err := Append(dst, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b2[:3], dst) || !bytes.Equal(b2[3:], b1) {
+		t.Errorf("%q != %q + %q", b2, dst, b1)
+	}
+
+	if &b2[0] != &dst[0] {
+		t.Error("the value was not written to the buffer passed to Append")
+	}
+}
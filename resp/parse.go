@@ -290,6 +290,52 @@ func (p *Parser) ParseMapNext(n int) (err error) {
 	panic("objconv/resp: ParseMapNext should never be called because RESP has no map type, this is likely a bug in the decoder code")
 }
 
+// Skip discards the next value, arrays are skipped by counting their elements
+// without decoding them.
+func (p *Parser) Skip() (err error) {
+	for n := 1; n != 0; n-- {
+		var line []byte
+		var size int
+
+		if line, err = p.peekLine(); err != nil {
+			return
+		}
+
+		if len(line) == 0 {
+			err = errors.New("objconv/resp: invalid empty line at the beginning of a value")
+			return
+		}
+
+		switch line[0] {
+		case '+', '-', ':':
+			p.skipLine()
+
+		case '$':
+			if bytes.Equal(line[1:], null[:]) {
+				p.skipLine()
+			} else {
+				_, err = p.ParseBytes()
+			}
+
+		case '*':
+			if bytes.Equal(line[1:], null[:]) {
+				p.skipLine()
+			} else if size, err = p.ParseArrayBegin(); err == nil {
+				n += size
+			}
+
+		default:
+			err = fmt.Errorf("objconv/resp: expected type token but found %#v", string(line))
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
 func (p *Parser) peekLine() (line []byte, err error) {
 	if p.i != 0 {
 		line = p.s[p.n : p.i-2]
@@ -0,0 +1,32 @@
+// Package schema generates JSON Schema (draft 2020-12) documents describing the
+// representation of Go types produced by the objconv encoders.
+//
+// The generator walks Go types using the same rules as the objconv package:
+// struct fields are named after their objconv or json tags, fields with the
+// omitempty or omitzero options are optional, time.Time values are strings
+// formatted as dates, and so on. Recursive types are described in the $defs
+// section of the document and referenced with $ref.
+//
+// Types may declare their own schema by implementing the Schemer interface,
+// and packages installing adapters on objconv may associate a schema with the
+// types they support by calling Register, for example:
+//
+//	s, err := schema.Of(User{})
+//	if err != nil {
+//		...
+//	}
+//	b, err := json.Marshal(s)
+//
+// Documents can be validated against a schema with a Validator, which checks
+// the values read from any objconv parser while they are streamed and reports
+// all the violations it found, each with the path to the invalid value. The
+// parser returned by Validator.NewParser can be placed in front of a decoder
+// so invalid documents are rejected by the decoding operation when the first
+// invalid token is read:
+//
+//	v, err := schema.NewValidator(s)
+//	if err != nil {
+//		...
+//	}
+//	d := objconv.NewDecoder(v.NewParser(json.NewParser(r)))
+package schema
@@ -0,0 +1,303 @@
+package schema
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// Of returns the schema describing the representation of v's type.
+func Of(v interface{}) (*Schema, error) {
+	return Generate(reflect.TypeOf(v))
+}
+
+// Generate returns the schema describing the representation of values of type
+// t, as produced by the objconv encoders.
+//
+// The schema describes the representation of values in JSON, where byte slices
+// are encoded as base64 strings, and time.Time and time.Duration values are
+// encoded as strings.
+//
+// The function returns an error if t or one of the types it refers to can't
+// be encoded by objconv, like channels or functions.
+func Generate(t reflect.Type) (*Schema, error) {
+	if t == nil {
+		return &Schema{Schema: Draft}, nil
+	}
+
+	g := generator{
+		types: make(map[reflect.Type]*definition),
+		names: make(map[string]reflect.Type),
+	}
+
+	s, err := g.schemaOf(t)
+	if err != nil {
+		return nil, err
+	}
+
+	root := *s
+	root.Schema = Draft
+	root.Defs = g.defs
+	return &root, nil
+}
+
+type generator struct {
+	types map[reflect.Type]*definition
+	names map[string]reflect.Type
+	defs  Properties
+}
+
+type definition struct {
+	name      string
+	schema    *Schema
+	visiting  bool
+	recursive bool
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	bytesType    = reflect.TypeOf([]byte(nil))
+
+	schemerInterface         = reflect.TypeOf((*Schemer)(nil)).Elem()
+	valueEncoderInterface    = reflect.TypeOf((*objconv.ValueEncoder)(nil)).Elem()
+	binaryMarshalerInterface = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	textMarshalerInterface   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	errorInterface           = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+func (g *generator) schemaOf(t reflect.Type) (*Schema, error) {
+	if s, ok := lookup(t); ok {
+		return s.JSONSchema(), nil
+	}
+
+	switch {
+	case t.Implements(schemerInterface):
+		if t.Kind() == reflect.Ptr {
+			return reflect.New(t.Elem()).Interface().(Schemer).JSONSchema(), nil
+		}
+		return reflect.Zero(t).Interface().(Schemer).JSONSchema(), nil
+
+	case reflect.PtrTo(t).Implements(schemerInterface):
+		return reflect.New(t).Interface().(Schemer).JSONSchema(), nil
+	}
+
+	if _, ok := objconv.AdapterOf(t); ok {
+		// The representation of types supported by adapters that did not
+		// register a schema is unknown, any value is accepted.
+		return &Schema{}, nil
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: Types{String}, Format: "date-time"}, nil
+
+	case durationType:
+		return &Schema{Type: Types{String}}, nil
+
+	case bytesType:
+		return &Schema{Type: Types{String}, ContentEncoding: "base64"}, nil
+	}
+
+	switch {
+	case t.Implements(valueEncoderInterface):
+		return &Schema{}, nil
+
+	case t.Implements(textMarshalerInterface):
+		return &Schema{Type: Types{String}}, nil
+
+	case t.Implements(binaryMarshalerInterface):
+		return &Schema{Type: Types{String}, ContentEncoding: "base64"}, nil
+
+	case t.Implements(errorInterface):
+		return &Schema{Type: Types{String}}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Types{Boolean}}, nil
+
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: Types{Integer}}, nil
+
+	case reflect.Int8:
+		return integer(-1<<7, 1<<7-1), nil
+
+	case reflect.Int16:
+		return integer(-1<<15, 1<<15-1), nil
+
+	case reflect.Int32:
+		return integer(-1<<31, 1<<31-1), nil
+
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: Types{Integer}, Minimum: 0}, nil
+
+	case reflect.Uint8:
+		return integer(0, 1<<8-1), nil
+
+	case reflect.Uint16:
+		return integer(0, 1<<16-1), nil
+
+	case reflect.Uint32:
+		return integer(0, 1<<32-1), nil
+
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{Number}}, nil
+
+	case reflect.String:
+		return &Schema{Type: Types{String}}, nil
+
+	case reflect.Interface:
+		return &Schema{}, nil
+
+	case reflect.Ptr:
+		s, err := g.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return nullable(s), nil
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{String}, ContentEncoding: "base64"}, nil
+		}
+		return g.define(t, g.arraySchema)
+
+	case reflect.Array:
+		return g.define(t, g.arraySchema)
+
+	case reflect.Map:
+		return g.define(t, g.mapSchema)
+
+	case reflect.Struct:
+		return g.define(t, g.structSchema)
+	}
+
+	return nil, fmt.Errorf("objconv/schema: the type %s cannot be represented by a schema", t)
+}
+
+// define generates the schema of the composite type t with f, detecting
+// recursive types and moving their schema to the definitions of the document.
+func (g *generator) define(t reflect.Type, f func(reflect.Type) (*Schema, error)) (*Schema, error) {
+	if t.Kind() != reflect.Struct && len(t.Name()) == 0 {
+		// Unnamed composite types can only be recursive through a named
+		// type, no need to track them.
+		return f(t)
+	}
+
+	if d := g.types[t]; d != nil {
+		if d.visiting || d.recursive {
+			d.recursive = true
+			return g.ref(t, d), nil
+		}
+		return d.schema, nil
+	}
+
+	d := &definition{visiting: true}
+	g.types[t] = d
+
+	s, err := f(t)
+	if err != nil {
+		return nil, err
+	}
+
+	d.visiting = false
+	d.schema = s
+
+	if d.recursive {
+		g.defs = append(g.defs, Property{Name: d.name, Schema: s})
+		return g.ref(t, d), nil
+	}
+
+	return s, nil
+}
+
+func (g *generator) ref(t reflect.Type, d *definition) *Schema {
+	if len(d.name) == 0 {
+		d.name = t.Name()
+
+		if other, ok := g.names[d.name]; ok && other != t {
+			d.name = strings.Replace(t.PkgPath(), "/", ".", -1) + "." + d.name
+		}
+
+		g.names[d.name] = t
+	}
+
+	name := strings.Replace(d.name, "~", "~0", -1)
+	name = strings.Replace(name, "/", "~1", -1)
+	return &Schema{Ref: "#/$defs/" + name}
+}
+
+func (g *generator) arraySchema(t reflect.Type) (*Schema, error) {
+	items, err := g.schemaOf(t.Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{Type: Types{Array}, Items: items}
+
+	if t.Kind() == reflect.Array {
+		n := t.Len()
+		s.MinItems, s.MaxItems = &n, &n
+	}
+
+	return s, nil
+}
+
+func (g *generator) mapSchema(t reflect.Type) (*Schema, error) {
+	values, err := g.schemaOf(t.Elem())
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{Type: Types{Object}, Additional: values}, nil
+}
+
+func (g *generator) structSchema(t reflect.Type) (*Schema, error) {
+	fields := objconv.StructFields(t)
+	s := &Schema{
+		Type:       Types{Object},
+		Properties: make(Properties, 0, len(fields)),
+	}
+
+	for _, f := range fields {
+		p, err := g.schemaOf(f.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Properties = append(s.Properties, Property{Name: f.Name, Schema: p})
+
+		if !f.Omitempty && !f.Omitzero {
+			s.Required = append(s.Required, f.Name)
+		}
+	}
+
+	return s, nil
+}
+
+func integer(min int64, max int64) *Schema {
+	return &Schema{Type: Types{Integer}, Minimum: min, Maximum: max}
+}
+
+// nullable returns a schema which accepts the same values as s, or null.
+func nullable(s *Schema) *Schema {
+	switch {
+	case reflect.DeepEqual(*s, Schema{}):
+		return s // already accepts any value
+
+	case s.Type.Has(Null):
+		return s
+
+	case len(s.Type) != 0 && len(s.Ref) == 0 && len(s.AnyOf) == 0 && len(s.Enum) == 0:
+		c := *s
+		c.Type = append(append(Types{}, s.Type...), Null)
+		return &c
+
+	default:
+		return &Schema{AnyOf: []*Schema{s, {Type: Types{Null}}}}
+	}
+}
@@ -0,0 +1,436 @@
+package schema
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// ValidatingParser is a parser which validates the values read from another
+// parser while they are being parsed.
+//
+// The parser is intended to be placed in front of a decoder, for example:
+//
+//	v, err := schema.NewValidator(s)
+//	...
+//	d := objconv.NewDecoder(v.NewParser(json.NewParser(r)))
+//
+//	if err := d.Decode(&x); err != nil {
+//		...
+//	}
+//
+// Violations are reported by a *ValidationError returned by the method call
+// that reads the invalid token, which makes the decoding fail before the token
+// is turned into a Go value. The type of the next value is checked by
+// ParseType, so the decoder doesn't get to convert values of the wrong type.
+// Violations that depend on the rest of the container, like missing required
+// properties, are reported when the container ends, the destination of the
+// decoder may have been partially modified at this point; programs that need
+// to validate documents before modifying any Go value may decode them into an
+// objconv.Value first, or call Validator.Validate on a buffered input.
+//
+// Values are validated in a streaming fashion, except for the containers that
+// are validated by schemas with the anyOf or enum keywords, or with $ref and
+// other keywords, which are loaded in memory until they end. Schemas with
+// anyOf where a single branch accepts the type of the container, like the
+// schemas of pointers to structs, are streamed using this branch.
+type ValidatingParser struct {
+	p     objconv.Parser
+	v     *Validator
+	stack []frame
+	errs  []Violation
+
+	// When set, the violations are collected and reported all together when
+	// the top-level value ends, this is used by Validator.Validate.
+	all bool
+}
+
+type frame struct {
+	typ   objconv.Type // Array or Map
+	node  *node        // schema of the container, may be nil
+	path  string       // path to the container
+	count int          // number of elements, or map entries
+	key   bool         // whether the next value is a map key
+	name  string       // the current map key
+	vnode *node        // schema of the current map value
+	seen  map[string]bool
+
+	// Containers that can't be validated in a streaming fashion are loaded
+	// in a value which is validated when the container ends.
+	capture bool
+	mapKey  bool          // the container is a map key
+	value   objconv.Value // the container being loaded
+	pending objconv.Value // the current map key
+}
+
+// Reset resets the parser's state and sets it to read from p.
+func (p *ValidatingParser) Reset(parser objconv.Parser) {
+	p.p = parser
+	p.stack = p.stack[:0]
+	p.errs = nil
+}
+
+func (p *ValidatingParser) ParseType() (t objconv.Type, err error) {
+	if t, err = p.p.ParseType(); err == nil && !p.all {
+		err = p.checkType(t)
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseNil() (err error) {
+	if err = p.p.ParseNil(); err == nil {
+		err = p.scalar(objconv.NilValue())
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseBool() (v bool, err error) {
+	if v, err = p.p.ParseBool(); err == nil {
+		err = p.scalar(objconv.BoolValue(v))
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseInt() (v int64, err error) {
+	if v, err = p.p.ParseInt(); err == nil {
+		err = p.scalar(objconv.IntValue(v))
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseUint() (v uint64, err error) {
+	if v, err = p.p.ParseUint(); err == nil {
+		err = p.scalar(objconv.UintValue(v))
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseFloat() (v float64, err error) {
+	if v, err = p.p.ParseFloat(); err == nil {
+		err = p.scalar(objconv.FloatValue(v))
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseString() (v []byte, err error) {
+	if v, err = p.p.ParseString(); err == nil {
+		err = p.scalar(objconv.StringValue(string(v)))
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseBytes() (v []byte, err error) {
+	if v, err = p.p.ParseBytes(); err == nil {
+		err = p.scalar(objconv.BytesValue(append([]byte(nil), v...)))
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseTime() (v time.Time, err error) {
+	if v, err = p.p.ParseTime(); err == nil {
+		err = p.scalar(objconv.TimeValue(v))
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseDuration() (v time.Duration, err error) {
+	if v, err = p.p.ParseDuration(); err == nil {
+		err = p.scalar(objconv.DurationValue(v))
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseError() (v error, err error) {
+	if v, err = p.p.ParseError(); err == nil {
+		err = p.scalar(objconv.ErrorValue(v))
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseArrayBegin() (n int, err error) {
+	if n, err = p.p.ParseArrayBegin(); err == nil {
+		err = p.begin(objconv.Array)
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseArrayEnd(n int) (err error) {
+	if err = p.p.ParseArrayEnd(n); err == nil {
+		err = p.end()
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseArrayNext(n int) error {
+	return p.p.ParseArrayNext(n)
+}
+
+func (p *ValidatingParser) ParseMapBegin() (n int, err error) {
+	if n, err = p.p.ParseMapBegin(); err == nil {
+		err = p.begin(objconv.Map)
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseMapEnd(n int) (err error) {
+	if err = p.p.ParseMapEnd(n); err == nil {
+		err = p.end()
+	}
+	return
+}
+
+func (p *ValidatingParser) ParseMapValue(n int) error {
+	return p.p.ParseMapValue(n)
+}
+
+func (p *ValidatingParser) ParseMapNext(n int) error {
+	return p.p.ParseMapNext(n)
+}
+
+// TextParser forwards the information of whether the underlying parser is
+// reading a human-readable format.
+func (p *ValidatingParser) TextParser() bool {
+	t, ok := p.p.(interface {
+		TextParser() bool
+	})
+	return ok && t.TextParser()
+}
+
+// DecodeBytes forwards to the underlying parser, so byte slices are decoded
+// the same way with and without validation.
+func (p *ValidatingParser) DecodeBytes(b []byte) ([]byte, error) {
+	if d, ok := p.p.(interface {
+		DecodeBytes([]byte) ([]byte, error)
+	}); ok {
+		return d.DecodeBytes(b)
+	}
+	return b, nil
+}
+
+func (p *ValidatingParser) top() *frame {
+	if i := len(p.stack) - 1; i >= 0 {
+		return &p.stack[i]
+	}
+	return nil
+}
+
+// next returns the schema and path of the next value, and whether it is a map
+// key.
+func (p *ValidatingParser) next() (*node, string, bool) {
+	f := p.top()
+
+	switch {
+	case f == nil:
+		return p.v.root, "", false
+
+	case f.typ == objconv.Array:
+		var items *node
+		if f.node != nil {
+			items = f.node.items
+		}
+		return items, f.path + "/" + strconv.Itoa(f.count), false
+
+	case f.key:
+		return nil, "", true
+
+	default:
+		return f.vnode, f.path + "/" + escape(f.name), false
+	}
+}
+
+func (p *ValidatingParser) scalar(x objconv.Value) error {
+	if f := p.top(); f != nil && f.capture {
+		f.add(x)
+		return nil
+	}
+
+	n, path, key := p.next()
+
+	if key {
+		p.setKey(stringOf(x))
+		return p.violations()
+	}
+
+	validate(n, x, path, &p.errs)
+	return p.done()
+}
+
+// checkType verifies that a value of type t is accepted by the schema of the
+// next value, before it is parsed.
+func (p *ValidatingParser) checkType(t objconv.Type) error {
+	if f := p.top(); f != nil && f.capture {
+		return nil
+	}
+
+	n, path, key := p.next()
+
+	if key || n == nil {
+		return nil
+	}
+
+	if n = p.narrow(n, t); n.needsValue() {
+		return nil
+	}
+
+	if n.reject {
+		p.errs = append(p.errs, Violation{path, "no value is allowed"})
+	} else if t != objconv.Float {
+		// Floats may be integers, this can only be checked on the value.
+		n.checkType(t, objconv.Value{}, path, &p.errs)
+	}
+
+	return p.violations()
+}
+
+// narrow returns the branch of n to use to validate a value of type t when n
+// only has anyOf branches and a single one accepts values of this type, so the
+// value can be validated in a streaming fashion. Otherwise n is returned.
+func (p *ValidatingParser) narrow(n *node, t objconv.Type) *node {
+	if p.all || !n.anyOfOnly() {
+		return n
+	}
+
+	var match *node
+
+	for _, b := range n.anyOf {
+		if b.reject || (len(b.types) != 0 && !b.acceptsType(t)) {
+			continue
+		}
+		if match != nil {
+			return n
+		}
+		match = b
+	}
+
+	if match == nil {
+		return n
+	}
+
+	return match
+}
+
+func (p *ValidatingParser) begin(t objconv.Type) error {
+	f := frame{typ: t, key: t == objconv.Map}
+
+	if parent := p.top(); parent != nil && parent.capture {
+		f.capture = true
+	} else if n, path, key := p.next(); key {
+		f.capture, f.mapKey = true, true
+	} else if n = p.narrow(n, t); n.needsValue() {
+		f.capture, f.node, f.path = true, n, path
+	} else {
+		f.path = path
+
+		if n != nil && (n.reject || n.checkType(t, objconv.Value{}, path, &p.errs)) {
+			if n.reject {
+				p.errs = append(p.errs, Violation{path, "no value is allowed"})
+			} else {
+				f.node = n
+			}
+		}
+
+		if f.node != nil && t == objconv.Map && len(f.node.required) != 0 {
+			f.seen = make(map[string]bool, len(f.node.required))
+		}
+	}
+
+	if f.capture {
+		if t == objconv.Array {
+			f.value = objconv.ArrayValue()
+		} else {
+			f.value = objconv.MapValue()
+		}
+	}
+
+	p.stack = append(p.stack, f)
+	return p.violations()
+}
+
+func (p *ValidatingParser) end() error {
+	i := len(p.stack) - 1
+	f := p.stack[i]
+	p.stack = p.stack[:i]
+
+	switch parent := p.top(); {
+	case parent != nil && parent.capture:
+		parent.add(f.value)
+		return nil
+
+	case f.mapKey:
+		p.setKey(stringOf(f.value))
+		return p.violations()
+
+	case f.capture:
+		validate(f.node, f.value, f.path, &p.errs)
+
+	case f.node != nil && f.typ == objconv.Array:
+		f.node.checkItems(f.count, f.path, &p.errs)
+
+	case f.node != nil && f.typ == objconv.Map:
+		f.node.checkRequired(f.seen, f.path, &p.errs)
+	}
+
+	return p.done()
+}
+
+func (p *ValidatingParser) setKey(name string) {
+	f := p.top()
+	f.key = false
+	f.name = name
+	f.vnode = nil
+
+	if f.node != nil {
+		f.vnode = f.node.property(name, f.path, &p.errs)
+	}
+
+	if f.seen != nil {
+		f.seen[name] = true
+	}
+}
+
+// done is called when a value was completely parsed, it returns the
+// violations found in the value.
+func (p *ValidatingParser) done() error {
+	f := p.top()
+
+	if f == nil {
+		return p.flush()
+	}
+
+	f.count++
+	f.key = f.typ == objconv.Map
+	return p.violations()
+}
+
+// violations returns the violations found so far, unless they are reported
+// when the top-level value ends.
+func (p *ValidatingParser) violations() error {
+	if p.all {
+		return nil
+	}
+	return p.flush()
+}
+
+func (p *ValidatingParser) flush() error {
+	if errs := p.errs; len(errs) != 0 {
+		p.errs = nil
+		return &ValidationError{Violations: errs}
+	}
+	return nil
+}
+
+func (f *frame) add(x objconv.Value) {
+	switch {
+	case f.typ == objconv.Array:
+		f.value.Append(x)
+
+	case f.key:
+		f.pending = x
+		f.key = false
+
+	default:
+		f.value.Set(stringOf(f.pending), x)
+		f.key = true
+	}
+}
@@ -0,0 +1,246 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/segmentio/objconv"
+)
+
+// Draft is the URI of the JSON Schema dialect used by the generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Names of the JSON Schema types.
+const (
+	Null    = "null"
+	Boolean = "boolean"
+	Integer = "integer"
+	Number  = "number"
+	String  = "string"
+	Array   = "array"
+	Object  = "object"
+)
+
+// Schema represents a JSON Schema document, or a fragment of it.
+//
+// Only the keywords used by the generator and the validator are represented,
+// which is a subset of the JSON Schema vocabulary.
+type Schema struct {
+	// Bool is set on boolean schemas, which are encoded as the values true and
+	// false, and respectively accept and reject any value. When Bool is set all
+	// other fields are ignored.
+	Bool *bool `objconv:"-"`
+
+	Schema          string        `objconv:"$schema,omitempty"`
+	ID              string        `objconv:"$id,omitempty"`
+	Ref             string        `objconv:"$ref,omitempty"`
+	Title           string        `objconv:"title,omitempty"`
+	Description     string        `objconv:"description,omitempty"`
+	Type            Types         `objconv:"type,omitempty"`
+	Format          string        `objconv:"format,omitempty"`
+	ContentEncoding string        `objconv:"contentEncoding,omitempty"`
+	Pattern         string        `objconv:"pattern,omitempty"`
+	Enum            []interface{} `objconv:"enum,omitempty"`
+	Minimum         interface{}   `objconv:"minimum,omitempty"`
+	Maximum         interface{}   `objconv:"maximum,omitempty"`
+	ExclusiveMin    interface{}   `objconv:"exclusiveMinimum,omitempty"`
+	ExclusiveMax    interface{}   `objconv:"exclusiveMaximum,omitempty"`
+	MinLength       *int          `objconv:"minLength,omitempty"`
+	MaxLength       *int          `objconv:"maxLength,omitempty"`
+	MinItems        *int          `objconv:"minItems,omitempty"`
+	MaxItems        *int          `objconv:"maxItems,omitempty"`
+	Items           *Schema       `objconv:"items,omitempty"`
+	Properties      Properties    `objconv:"properties,omitempty"`
+	Required        []string      `objconv:"required,omitempty"`
+	Additional      *Schema       `objconv:"additionalProperties,omitempty"`
+	AnyOf           []*Schema     `objconv:"anyOf,omitempty"`
+	Defs            Properties    `objconv:"$defs,omitempty"`
+}
+
+// schemaFields is used to encode and decode the fields of a Schema with the
+// default struct algorithms.
+type schemaFields Schema
+
+// Bool returns a boolean schema, which accepts any value if v is true and
+// rejects all values if v is false.
+func Bool(v bool) *Schema {
+	return &Schema{Bool: &v}
+}
+
+// EncodeValue satisfies the objconv.ValueEncoder interface.
+func (s *Schema) EncodeValue(e objconv.Encoder) error {
+	if s.Bool != nil {
+		return e.Encode(*s.Bool)
+	}
+	return e.Encode((*schemaFields)(s))
+}
+
+// DecodeValue satisfies the objconv.ValueDecoder interface.
+func (s *Schema) DecodeValue(d objconv.Decoder) error {
+	t, err := d.Parser.ParseType()
+	if err != nil {
+		return err
+	}
+
+	if t == objconv.Bool {
+		var v bool
+		if err := d.Decode(&v); err != nil {
+			return err
+		}
+		*s = Schema{Bool: &v}
+		return nil
+	}
+
+	return d.Decode((*schemaFields)(s))
+}
+
+// JSONSchema satisfies the Schemer interface, it returns a copy of s.
+func (s *Schema) JSONSchema() *Schema {
+	c := *s
+	return &c
+}
+
+// Types is the value of the "type" keyword of a schema. It is encoded as a
+// single string when it has only one element, and as an array otherwise.
+type Types []string
+
+// Has returns true if t contains typ.
+func (t Types) Has(typ string) bool {
+	for _, x := range t {
+		if x == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeValue satisfies the objconv.ValueEncoder interface.
+func (t Types) EncodeValue(e objconv.Encoder) error {
+	if len(t) == 1 {
+		return e.Encode(t[0])
+	}
+	return e.Encode([]string(t))
+}
+
+// DecodeValue satisfies the objconv.ValueDecoder interface.
+func (t *Types) DecodeValue(d objconv.Decoder) error {
+	var v interface{}
+
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+
+	switch x := v.(type) {
+	case string:
+		*t = Types{x}
+		return nil
+
+	case []interface{}:
+		l := make(Types, 0, len(x))
+
+		for _, elem := range x {
+			s, ok := elem.(string)
+			if !ok {
+				return errors.New("objconv/schema: the elements of a type list must be strings")
+			}
+			l = append(l, s)
+		}
+
+		*t = l
+		return nil
+
+	default:
+		return errors.New("objconv/schema: a type must be a string or a list of strings")
+	}
+}
+
+// Property associates a name with a schema, it is used to represent the
+// properties and definitions of a schema.
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Properties is an ordered list of named schemas, encoded as a map.
+type Properties []Property
+
+// Get returns the schema associated with name, or nil if none were found.
+func (p Properties) Get(name string) *Schema {
+	for _, x := range p {
+		if x.Name == name {
+			return x.Schema
+		}
+	}
+	return nil
+}
+
+// EncodeValue satisfies the objconv.ValueEncoder interface.
+func (p Properties) EncodeValue(e objconv.Encoder) error {
+	i := 0
+	return e.EncodeMap(len(p), func(ke objconv.Encoder, ve objconv.Encoder) error {
+		x := p[i]
+		i++
+		if err := ke.Encode(x.Name); err != nil {
+			return err
+		}
+		return ve.Encode(x.Schema)
+	})
+}
+
+// DecodeValue satisfies the objconv.ValueDecoder interface.
+func (p *Properties) DecodeValue(d objconv.Decoder) error {
+	var l Properties
+
+	if err := d.DecodeMap(func(kd objconv.Decoder, vd objconv.Decoder) error {
+		var x Property
+
+		if err := kd.Decode(&x.Name); err != nil {
+			return err
+		}
+
+		if err := vd.Decode(&x.Schema); err != nil {
+			return err
+		}
+
+		l = append(l, x)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	*p = l
+	return nil
+}
+
+// The Schemer interface may be implemented by types that declare their own
+// schema, typically types that implement objconv.ValueEncoder.
+//
+// The method is called on the zero value of the type, or on a pointer to a
+// new value if it's implemented on the pointer type.
+type Schemer interface {
+	JSONSchema() *Schema
+}
+
+// Register associates the schema of s with typ.
+//
+// This function is intended to be called by packages installing adapters on
+// objconv, for types that they don't own and therefore can't implement the
+// Schemer interface.
+func Register(typ reflect.Type, s Schemer) {
+	registryMutex.Lock()
+	registry[typ] = s
+	registryMutex.Unlock()
+}
+
+func lookup(typ reflect.Type) (s Schemer, ok bool) {
+	registryMutex.RLock()
+	s, ok = registry[typ]
+	registryMutex.RUnlock()
+	return
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[reflect.Type]Schemer)
+)
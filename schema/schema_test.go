@@ -0,0 +1,161 @@
+package schema_test
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/objconv"
+	_ "github.com/segmentio/objconv/adapters"
+	"github.com/segmentio/objconv/json"
+	"github.com/segmentio/objconv/schema"
+)
+
+type color int
+
+func (c color) JSONSchema() *schema.Schema {
+	return &schema.Schema{Type: schema.Types{schema.String}, Enum: []interface{}{"red", "green", "blue"}}
+}
+
+type user struct {
+	Name    string            `objconv:"name"`
+	Email   string            `objconv:"email,omitempty"`
+	Age     uint8             `objconv:"age,omitzero"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `objconv:"labels,omitempty"`
+	Created time.Time         `objconv:"created"`
+	TTL     time.Duration     `objconv:"ttl"`
+	Avatar  []byte            `objconv:"avatar,omitempty"`
+	Site    url.URL           `objconv:"site"`
+	Addr    net.IP            `objconv:"addr,omitempty"`
+	Color   color             `objconv:"color"`
+	Extra   interface{}       `objconv:"extra,omitempty"`
+	Ignored int               `objconv:"-"`
+	private int
+}
+
+type node struct {
+	Value    int     `objconv:"value"`
+	Children []*node `objconv:"children,omitempty"`
+	Next     *node   `objconv:"next"`
+}
+
+type tree map[string]tree
+
+func marshal(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		s string
+	}{
+		{
+			v: true,
+			s: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"boolean"}`,
+		},
+		{
+			v: int8(0),
+			s: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"integer","minimum":-128,"maximum":127}`,
+		},
+		{
+			v: [2]float64{},
+			s: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"array","minItems":2,"maxItems":2,"items":{"type":"number"}}`,
+		},
+		{
+			v: map[string]*string{},
+			s: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","additionalProperties":{"type":["string","null"]}}`,
+		},
+		{
+			v: user{},
+			s: `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object",` +
+				`"properties":{` +
+				`"name":{"type":"string"},` +
+				`"email":{"type":"string"},` +
+				`"age":{"type":"integer","minimum":0,"maximum":255},` +
+				`"tags":{"type":"array","items":{"type":"string"}},` +
+				`"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+				`"created":{"type":"string","format":"date-time"},` +
+				`"ttl":{"type":"string"},` +
+				`"avatar":{"type":"string","contentEncoding":"base64"},` +
+				`"site":{"type":"string","format":"uri-reference"},` +
+				`"addr":{"type":"string","anyOf":[{"format":"ipv4"},{"format":"ipv6"}]},` +
+				`"color":{"type":"string","enum":["red","green","blue"]},` +
+				`"extra":{}},` +
+				`"required":["name","tags","created","ttl","site","color"]}`,
+		},
+		{
+			v: node{},
+			s: `{"$schema":"https://json-schema.org/draft/2020-12/schema","$ref":"#/$defs/node","$defs":{"node":{"type":"object",` +
+				`"properties":{` +
+				`"value":{"type":"integer"},` +
+				`"children":{"type":"array","items":{"anyOf":[{"$ref":"#/$defs/node"},{"type":"null"}]}},` +
+				`"next":{"anyOf":[{"$ref":"#/$defs/node"},{"type":"null"}]}},` +
+				`"required":["value","next"]}}}`,
+		},
+		{
+			v: tree{},
+			s: `{"$schema":"https://json-schema.org/draft/2020-12/schema","$ref":"#/$defs/tree","$defs":{"tree":{"type":"object","additionalProperties":{"$ref":"#/$defs/tree"}}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(reflect.TypeOf(test.v).String(), func(t *testing.T) {
+			s, err := schema.Of(test.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b := marshal(t, s); b != test.s {
+				t.Error(b)
+			}
+
+			// The generated schema must survive a round trip.
+			var c schema.Schema
+
+			if err := json.Unmarshal([]byte(test.s), &c); err != nil {
+				t.Fatal(err)
+			}
+
+			if b := marshal(t, &c); b != test.s {
+				t.Error("bad round trip:", b)
+			}
+		})
+	}
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	type invalid struct {
+		C chan int `objconv:"c"`
+	}
+
+	if _, err := schema.Of(invalid{}); err == nil {
+		t.Error("expected an error generating the schema of a channel")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	type id struct{ hi, lo uint64 }
+
+	objconv.Install(reflect.TypeOf(id{}), objconv.Adapter{
+		Encode: func(e objconv.Encoder, v reflect.Value) error { return e.Encode("") },
+		Decode: func(d objconv.Decoder, v reflect.Value) error { return d.Decode(nil) },
+	})
+
+	if s, _ := schema.Of(id{}); marshal(t, s) != `{"$schema":"https://json-schema.org/draft/2020-12/schema"}` {
+		t.Error("bad schema for an adapter without registered schema:", marshal(t, s))
+	}
+
+	schema.Register(reflect.TypeOf(id{}), &schema.Schema{Type: schema.Types{schema.String}, Pattern: "^[0-9a-f]{32}$"})
+
+	if s, _ := schema.Of(id{}); marshal(t, s) != `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"string","pattern":"^[0-9a-f]{32}$"}` {
+		t.Error("bad schema for an adapter with a registered schema:", marshal(t, s))
+	}
+}
@@ -0,0 +1,586 @@
+package schema
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/objutil"
+)
+
+// Violation represents a value that did not satisfy a schema.
+type Violation struct {
+	// Path is the JSON Pointer (RFC 6901) to the invalid value in the
+	// document.
+	Path string
+
+	// Message describes which constraint was violated.
+	Message string
+}
+
+// String returns a human-readable representation of the violation.
+func (v Violation) String() string {
+	if len(v.Path) == 0 {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// ValidationError is returned when a value doesn't satisfy a schema, it
+// carries all the violations that were found in the value.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error satisfies the error interface.
+func (e *ValidationError) Error() string {
+	s := make([]string, len(e.Violations))
+
+	for i, v := range e.Violations {
+		s[i] = v.String()
+	}
+
+	return fmt.Sprintf("objconv/schema: %d schema violation(s): %s", len(s), strings.Join(s, "; "))
+}
+
+// Validator checks values against a JSON Schema.
+//
+// The keywords supported by the validator are $ref (for references to the
+// $defs of the schema), type, enum, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, minLength, maxLength, pattern, items, minItems, maxItems,
+// properties, required, additionalProperties and anyOf. Other keywords are
+// ignored.
+//
+// Values of the time, duration, error and bytes types are validated as strings
+// since this is how they are represented in JSON.
+//
+// Validators are safe to use concurrently from multiple goroutines.
+type Validator struct {
+	root *node
+}
+
+// NewValidator returns a validator for the given schema.
+//
+// The function returns an error if the schema contains invalid patterns or
+// references that could not be resolved.
+func NewValidator(s *Schema) (*Validator, error) {
+	c := compiler{root: s, defs: make(map[string]*node)}
+
+	n, err := c.compile(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Validator{root: n}, nil
+}
+
+// NewParser returns a parser which validates the values read from p while
+// they are parsed, see ValidatingParser for details.
+func (v *Validator) NewParser(p objconv.Parser) *ValidatingParser {
+	return &ValidatingParser{p: p, v: v}
+}
+
+// Validate reads the next value from p and validates it, all the violations
+// found in the value are reported. The value is discarded.
+func (v *Validator) Validate(p objconv.Parser) error {
+	return objconv.NewDecoder(&ValidatingParser{p: p, v: v, all: true}).Decode(nil)
+}
+
+// ValidateValue validates x.
+func (v *Validator) ValidateValue(x objconv.Value) error {
+	var errs []Violation
+	validate(v.root, x, "", &errs)
+
+	if len(errs) != 0 {
+		return &ValidationError{Violations: errs}
+	}
+
+	return nil
+}
+
+// node is the compiled representation of a schema.
+type node struct {
+	reject     bool // boolean schema "false"
+	ref        *node
+	types      Types
+	enum       []objconv.Value
+	minimum    *float64
+	maximum    *float64
+	exclMin    *float64
+	exclMax    *float64
+	minLength  int
+	maxLength  int
+	pattern    *regexp.Regexp
+	items      *node
+	minItems   int
+	maxItems   int
+	properties map[string]*node
+	required   []string
+	additional *node
+	anyOf      []*node
+}
+
+type compiler struct {
+	root *Schema
+	defs map[string]*node
+}
+
+func (c *compiler) compile(s *Schema) (*node, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	if s.Bool != nil {
+		if *s.Bool {
+			return nil, nil
+		}
+		return &node{reject: true}, nil
+	}
+
+	n := &node{minLength: -1, maxLength: -1, minItems: -1, maxItems: -1}
+
+	if len(s.Ref) != 0 {
+		ref, err := c.resolve(s.Ref)
+		if err != nil {
+			return nil, err
+		}
+
+		if isRefOnly(s) {
+			return ref, nil
+		}
+
+		n.ref = ref
+	}
+
+	n.types = s.Type
+	n.required = s.Required
+
+	for _, x := range s.Enum {
+		v, err := objconv.ValueOf(x)
+		if err != nil {
+			return nil, err
+		}
+		n.enum = append(n.enum, v)
+	}
+
+	var err error
+
+	for _, b := range [...]struct {
+		to   **float64
+		from interface{}
+	}{
+		{&n.minimum, s.Minimum},
+		{&n.maximum, s.Maximum},
+		{&n.exclMin, s.ExclusiveMin},
+		{&n.exclMax, s.ExclusiveMax},
+	} {
+		if b.from != nil {
+			f, ok := toFloat(reflect.ValueOf(b.from))
+			if !ok {
+				return nil, fmt.Errorf("objconv/schema: bad numeric bound: %#v", b.from)
+			}
+			*b.to = &f
+		}
+	}
+
+	if s.MinLength != nil {
+		n.minLength = *s.MinLength
+	}
+
+	if s.MaxLength != nil {
+		n.maxLength = *s.MaxLength
+	}
+
+	if s.MinItems != nil {
+		n.minItems = *s.MinItems
+	}
+
+	if s.MaxItems != nil {
+		n.maxItems = *s.MaxItems
+	}
+
+	if len(s.Pattern) != 0 {
+		if n.pattern, err = regexp.Compile(s.Pattern); err != nil {
+			return nil, fmt.Errorf("objconv/schema: bad pattern: %s", err)
+		}
+	}
+
+	if n.items, err = c.compile(s.Items); err != nil {
+		return nil, err
+	}
+
+	if n.additional, err = c.compile(s.Additional); err != nil {
+		return nil, err
+	}
+
+	if len(s.Properties) != 0 {
+		n.properties = make(map[string]*node, len(s.Properties))
+
+		for _, p := range s.Properties {
+			if n.properties[p.Name], err = c.compile(p.Schema); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, x := range s.AnyOf {
+		a, err := c.compile(x)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil { // one of the branches accepts any value
+			n.anyOf = nil
+			break
+		}
+		n.anyOf = append(n.anyOf, a)
+	}
+
+	return n, nil
+}
+
+func (c *compiler) resolve(ref string) (*node, error) {
+	if n, ok := c.defs[ref]; ok {
+		return n, nil
+	}
+
+	const prefix = "#/$defs/"
+
+	if !strings.HasPrefix(ref, prefix) {
+		return nil, fmt.Errorf("objconv/schema: unsupported reference: %q", ref)
+	}
+
+	s := c.root.Defs.Get(unescape(ref[len(prefix):]))
+
+	if s == nil {
+		return nil, fmt.Errorf("objconv/schema: unresolved reference: %q", ref)
+	}
+
+	// The node is registered before being compiled so recursive references
+	// resolve to the same node.
+	n := &node{}
+	c.defs[ref] = n
+
+	x, err := c.compile(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if x == nil {
+		*n = node{minLength: -1, maxLength: -1, minItems: -1, maxItems: -1}
+	} else {
+		*n = *x
+	}
+
+	return n, nil
+}
+
+func isRefOnly(s *Schema) bool {
+	c := Schema{Ref: s.Ref, Schema: s.Schema, ID: s.ID, Title: s.Title, Description: s.Description, Defs: s.Defs}
+	return reflect.DeepEqual(*s, c)
+}
+
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// validate checks x against n, appending the violations to errs.
+func validate(n *node, x objconv.Value, path string, errs *[]Violation) {
+	if n == nil {
+		return
+	}
+
+	if n.reject {
+		*errs = append(*errs, Violation{path, "no value is allowed"})
+		return
+	}
+
+	if n.ref != nil {
+		validate(n.ref, x, path, errs)
+	}
+
+	t := x.Type()
+
+	if !n.checkType(t, x, path, errs) {
+		return
+	}
+
+	if len(n.enum) != 0 {
+		n.checkEnum(x, path, errs)
+	}
+
+	switch t {
+	case objconv.Int, objconv.Uint, objconv.Float:
+		n.checkNumber(x, path, errs)
+
+	case objconv.String, objconv.Bytes, objconv.Time, objconv.Duration, objconv.Error:
+		n.checkString(stringOf(x), path, errs)
+
+	case objconv.Array:
+		for i, elem := range x.Elems() {
+			validate(n.items, elem, path+"/"+strconv.Itoa(i), errs)
+		}
+
+		n.checkItems(x.Len(), path, errs)
+
+	case objconv.Map:
+		seen := make(map[string]bool, x.Len())
+
+		for _, item := range x.Items() {
+			key := stringOf(item.Key)
+			seen[key] = true
+			validate(n.property(key, path, errs), item.Value, path+"/"+escape(key), errs)
+		}
+
+		n.checkRequired(seen, path, errs)
+	}
+
+	if len(n.anyOf) != 0 {
+		n.checkAnyOf(x, path, errs)
+	}
+}
+
+func (n *node) checkType(t objconv.Type, x objconv.Value, path string, errs *[]Violation) bool {
+	if len(n.types) == 0 {
+		return true
+	}
+
+	for _, typ := range n.types {
+		if matchType(typ, t, x) {
+			return true
+		}
+	}
+
+	*errs = append(*errs, Violation{path, fmt.Sprintf("expected %s but found %s", strings.Join(n.types, " or "), typeName(t))})
+	return false
+}
+
+func (n *node) checkEnum(x objconv.Value, path string, errs *[]Violation) {
+	for _, v := range n.enum {
+		if v.Equal(x) || (v.Type() == objconv.String && isStringType(x.Type()) && v.String() == stringOf(x)) {
+			return
+		}
+	}
+	*errs = append(*errs, Violation{path, "value is not one of the values of the enumeration"})
+}
+
+func (n *node) checkNumber(x objconv.Value, path string, errs *[]Violation) {
+	var f float64
+
+	switch x.Type() {
+	case objconv.Int:
+		f = float64(x.Int())
+	case objconv.Uint:
+		f = float64(x.Uint())
+	default:
+		f = x.Float()
+	}
+
+	if b := n.minimum; b != nil && f < *b {
+		*errs = append(*errs, Violation{path, fmt.Sprintf("value must be greater than or equal to %v", *b)})
+	}
+
+	if b := n.exclMin; b != nil && f <= *b {
+		*errs = append(*errs, Violation{path, fmt.Sprintf("value must be greater than %v", *b)})
+	}
+
+	if b := n.maximum; b != nil && f > *b {
+		*errs = append(*errs, Violation{path, fmt.Sprintf("value must be less than or equal to %v", *b)})
+	}
+
+	if b := n.exclMax; b != nil && f >= *b {
+		*errs = append(*errs, Violation{path, fmt.Sprintf("value must be less than %v", *b)})
+	}
+}
+
+func (n *node) checkString(s string, path string, errs *[]Violation) {
+	if n.minLength >= 0 || n.maxLength >= 0 {
+		if c := utf8.RuneCountInString(s); n.minLength >= 0 && c < n.minLength {
+			*errs = append(*errs, Violation{path, fmt.Sprintf("string must be at least %d characters long", n.minLength)})
+		} else if n.maxLength >= 0 && c > n.maxLength {
+			*errs = append(*errs, Violation{path, fmt.Sprintf("string must be at most %d characters long", n.maxLength)})
+		}
+	}
+
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		*errs = append(*errs, Violation{path, fmt.Sprintf("string doesn't match the pattern %q", n.pattern)})
+	}
+}
+
+func (n *node) checkItems(c int, path string, errs *[]Violation) {
+	if n.minItems >= 0 && c < n.minItems {
+		*errs = append(*errs, Violation{path, fmt.Sprintf("array must have at least %d elements", n.minItems)})
+	}
+
+	if n.maxItems >= 0 && c > n.maxItems {
+		*errs = append(*errs, Violation{path, fmt.Sprintf("array must have at most %d elements", n.maxItems)})
+	}
+}
+
+// property returns the schema of the map value for key.
+func (n *node) property(key string, path string, errs *[]Violation) *node {
+	if p, ok := n.properties[key]; ok {
+		return p
+	}
+
+	if n.additional != nil && n.additional.reject {
+		*errs = append(*errs, Violation{path + "/" + escape(key), "additional property is not allowed"})
+		return nil
+	}
+
+	return n.additional
+}
+
+func (n *node) checkRequired(seen map[string]bool, path string, errs *[]Violation) {
+	for _, name := range n.required {
+		if !seen[name] {
+			*errs = append(*errs, Violation{path + "/" + escape(name), "required property is missing"})
+		}
+	}
+}
+
+func (n *node) checkAnyOf(x objconv.Value, path string, errs *[]Violation) {
+	var tmp []Violation
+
+	for _, a := range n.anyOf {
+		tmp = tmp[:0]
+
+		if validate(a, x, path, &tmp); len(tmp) == 0 {
+			return
+		}
+	}
+
+	*errs = append(*errs, Violation{path, "value doesn't match any of the schemas of anyOf"})
+}
+
+// needsValue returns true if validating containers against n requires having
+// the whole value loaded in memory.
+// anyOfOnly returns true if anyOf is the only keyword of n.
+func (n *node) anyOfOnly() bool {
+	return len(n.anyOf) != 0 && !n.reject && n.ref == nil && len(n.types) == 0 && len(n.enum) == 0 &&
+		n.minimum == nil && n.maximum == nil && n.exclMin == nil && n.exclMax == nil &&
+		n.minLength < 0 && n.maxLength < 0 && n.pattern == nil &&
+		n.items == nil && n.minItems < 0 && n.maxItems < 0 &&
+		n.properties == nil && n.required == nil && n.additional == nil
+}
+
+// acceptsType returns true if values of type t may satisfy the type keyword of
+// n, which is assumed to be set.
+func (n *node) acceptsType(t objconv.Type) bool {
+	for _, typ := range n.types {
+		if matchType(typ, t, objconv.Value{}) || (typ == Integer && t == objconv.Float) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *node) needsValue() bool {
+	return n != nil && (len(n.anyOf) != 0 || len(n.enum) != 0 || n.ref != nil)
+}
+
+func matchType(typ string, t objconv.Type, x objconv.Value) bool {
+	switch typ {
+	case Null:
+		return t == objconv.Nil
+	case Boolean:
+		return t == objconv.Bool
+	case Integer:
+		switch t {
+		case objconv.Int, objconv.Uint:
+			return true
+		case objconv.Float:
+			f := x.Float()
+			return f == math.Trunc(f) && !math.IsInf(f, 0)
+		}
+		return false
+	case Number:
+		return t == objconv.Int || t == objconv.Uint || t == objconv.Float
+	case String:
+		return isStringType(t)
+	case Array:
+		return t == objconv.Array
+	case Object:
+		return t == objconv.Map
+	}
+	return false
+}
+
+func isStringType(t objconv.Type) bool {
+	switch t {
+	case objconv.String, objconv.Bytes, objconv.Time, objconv.Duration, objconv.Error:
+		return true
+	}
+	return false
+}
+
+func typeName(t objconv.Type) string {
+	switch t {
+	case objconv.Nil:
+		return Null
+	case objconv.Bool:
+		return Boolean
+	case objconv.Int, objconv.Uint:
+		return Integer
+	case objconv.Float:
+		return Number
+	case objconv.Array:
+		return Array
+	case objconv.Map:
+		return Object
+	default:
+		return String
+	}
+}
+
+// stringOf returns the representation of x as a JSON string.
+func stringOf(x objconv.Value) string {
+	switch x.Type() {
+	case objconv.String:
+		return x.String()
+	case objconv.Bytes:
+		return base64.StdEncoding.EncodeToString(x.Bytes())
+	case objconv.Time:
+		return x.Time().Format(time.RFC3339Nano)
+	case objconv.Duration:
+		return string(objutil.AppendDuration(nil, x.Duration()))
+	case objconv.Error:
+		return x.Err().Error()
+	case objconv.Int:
+		return strconv.FormatInt(x.Int(), 10)
+	case objconv.Uint:
+		return strconv.FormatUint(x.Uint(), 10)
+	case objconv.Float:
+		return strconv.FormatFloat(x.Float(), 'g', -1, 64)
+	case objconv.Bool:
+		return strconv.FormatBool(x.Bool())
+	default:
+		return fmt.Sprint(x.Interface())
+	}
+}
+
+// escape and unescape convert strings to and from their representation in the
+// components of JSON pointers.
+func escape(s string) string {
+	s = strings.Replace(s, "~", "~0", -1)
+	return strings.Replace(s, "/", "~1", -1)
+}
+
+func unescape(s string) string {
+	s = strings.Replace(s, "~1", "/", -1)
+	return strings.Replace(s, "~0", "~", -1)
+}
@@ -0,0 +1,324 @@
+package schema_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/json"
+	"github.com/segmentio/objconv/schema"
+)
+
+const personSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "maxLength": 8, "pattern": "^[a-z]+$"},
+		"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+		"friend": {"anyOf": [{"$ref": "#/$defs/person"}, {"type": "null"}]}
+	},
+	"required": ["name", "age"],
+	"additionalProperties": false,
+	"$defs": {
+		"person": {
+			"type": "object",
+			"properties": {"name": {"type": "string"}},
+			"required": ["name"]
+		}
+	}
+}`
+
+func loadValidator(t *testing.T, s string) *schema.Validator {
+	var x schema.Schema
+
+	if err := json.Unmarshal([]byte(s), &x); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := schema.NewValidator(&x)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+func violations(err error) []string {
+	e, ok := err.(*schema.ValidationError)
+	if !ok {
+		return nil
+	}
+
+	s := make([]string, len(e.Violations))
+
+	for i, v := range e.Violations {
+		s[i] = v.String()
+	}
+
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	v := loadValidator(t, personSchema)
+
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{
+			in:  `{"name":"bob","age":42,"role":"admin","tags":["a","b"],"friend":{"name":"alice"}}`,
+			out: nil,
+		},
+		{
+			in:  `{"name":"bob","age":42,"friend":null}`,
+			out: nil,
+		},
+		{
+			in: `{"name":"Bob","age":150,"role":"root","tags":["a",1,"c"],"other":true}`,
+			out: []string{
+				`/name: string doesn't match the pattern "^[a-z]+$"`,
+				`/age: value must be less than 150`,
+				`/role: value is not one of the values of the enumeration`,
+				`/tags/1: expected string but found integer`,
+				`/tags: array must have at most 2 elements`,
+				`/other: additional property is not allowed`,
+			},
+		},
+		{
+			in: `{"name":"","friend":{}}`,
+			out: []string{
+				`/name: string must be at least 1 characters long`,
+				`/name: string doesn't match the pattern "^[a-z]+$"`,
+				`/friend: value doesn't match any of the schemas of anyOf`,
+				`/age: required property is missing`,
+			},
+		},
+		{
+			in:  `[]`,
+			out: []string{`expected object but found array`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			// Streaming validation.
+			err := v.Validate(json.NewParser(strings.NewReader(test.in)))
+
+			if s := violations(err); !reflect.DeepEqual(s, test.out) {
+				t.Errorf("bad violations:\n%q\n%q (%v)", test.out, s, err)
+			}
+
+			// In-memory validation.
+			var x objconv.Value
+
+			if err := json.Unmarshal([]byte(test.in), &x); err != nil {
+				t.Fatal(err)
+			}
+
+			err = v.ValidateValue(x)
+
+			if s := violations(err); !reflect.DeepEqual(s, test.out) {
+				t.Errorf("bad violations of the value:\n%q\n%q (%v)", test.out, s, err)
+			}
+		})
+	}
+}
+
+func TestValidatingParserDecode(t *testing.T) {
+	type person struct {
+		Name string   `objconv:"name"`
+		Age  int      `objconv:"age"`
+		Tags []string `objconv:"tags"`
+	}
+
+	v := loadValidator(t, personSchema)
+
+	var p person
+	d := objconv.NewDecoder(v.NewParser(json.NewParser(strings.NewReader(`{"name":"bob","age":42,"tags":["a"]}`))))
+
+	if err := d.Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(p, person{Name: "bob", Age: 42, Tags: []string{"a"}}) {
+		t.Error("bad value:", p)
+	}
+
+	d = objconv.NewDecoder(v.NewParser(json.NewParser(strings.NewReader(`{"name":"bob","age":-1}`))))
+
+	if err := d.Decode(&p); violations(err) == nil {
+		t.Error("expected a validation error but got", err)
+	}
+}
+
+func TestValidatingParserTypedDestination(t *testing.T) {
+	type person struct {
+		Name string   `objconv:"name"`
+		Age  int      `objconv:"age"`
+		Tags []string `objconv:"tags"`
+	}
+
+	v := loadValidator(t, personSchema)
+
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{
+			in:  `{"name":1,"age":3,"tags":[1],"next":{"age":"x"}}`,
+			out: []string{`/name: expected string but found integer`},
+		},
+		{
+			in:  `{"name":"bob","age":"3"}`,
+			out: []string{`/age: expected integer but found string`},
+		},
+		{
+			in:  `{"name":"bob","age":3,"tags":[1]}`,
+			out: []string{`/tags/0: expected string but found integer`},
+		},
+		{
+			in:  `{"name":"bob","age":3,"next":{"age":"x"}}`,
+			out: []string{`/next: additional property is not allowed`},
+		},
+		{
+			in:  `{"name":"bob","friend":{"name":1}}`,
+			out: []string{`/friend/name: expected string but found integer`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var p person
+			err := objconv.NewDecoder(v.NewParser(json.NewParser(strings.NewReader(test.in)))).Decode(&p)
+
+			// The decoder must fail on the first violation, before converting
+			// the invalid value.
+			if s := violations(err); !reflect.DeepEqual(s, test.out) {
+				t.Errorf("bad violations:\n%q\n%q (%v)", test.out, s, err)
+			}
+
+			if p.Name != "" && p.Name != "bob" {
+				t.Error("the invalid value was decoded:", p.Name)
+			}
+		})
+	}
+}
+
+func TestValidatingParserRecursive(t *testing.T) {
+	s, err := schema.Of(node{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := schema.NewValidator(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var x node
+	in := `{"value":1,"next":{"value":"x","next":null}}`
+	err = objconv.NewDecoder(v.NewParser(json.NewParser(strings.NewReader(in)))).Decode(&x)
+
+	// The anyOf schema of the pointer is narrowed to the schema of the struct,
+	// so the value is validated before the decoder attempts to convert it.
+	if s := violations(err); !reflect.DeepEqual(s, []string{`/next/value: expected integer but found string`}) {
+		t.Errorf("bad violations: %q (%v)", s, err)
+	}
+}
+
+func TestValidateSequence(t *testing.T) {
+	v := loadValidator(t, `{"type":"integer","maximum":10}`)
+	d := objconv.NewDecoder(v.NewParser(json.NewParser(strings.NewReader(`1 20 3`))))
+
+	var l []int
+	var errs int
+
+	for {
+		var x int
+
+		if err := d.Decode(&x); err != nil {
+			if violations(err) == nil {
+				break
+			}
+			errs++
+			continue
+		}
+
+		l = append(l, x)
+	}
+
+	if !reflect.DeepEqual(l, []int{1, 3}) || errs != 1 {
+		t.Errorf("bad validation of a sequence of values: %v, %d error(s)", l, errs)
+	}
+}
+
+func TestValidateRecursive(t *testing.T) {
+	s, err := schema.Of(node{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := schema.NewValidator(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := `{"value":1,"next":null,"children":[{"value":2,"next":{"value":"3","next":null}}]}`
+	err = v.Validate(json.NewParser(strings.NewReader(in)))
+
+	if s := violations(err); !reflect.DeepEqual(s, []string{`/children/0: value doesn't match any of the schemas of anyOf`}) {
+		t.Errorf("bad violations: %q (%v)", s, err)
+	}
+}
+
+func TestValidateBoolSchema(t *testing.T) {
+	v := loadValidator(t, `{"properties":{"a":true,"b":false}}`)
+
+	err := v.Validate(json.NewParser(strings.NewReader(`{"a":[1,2],"b":{}}`)))
+
+	if s := violations(err); !reflect.DeepEqual(s, []string{`/b: no value is allowed`}) {
+		t.Errorf("bad violations: %q (%v)", s, err)
+	}
+
+	if s := marshal(t, schema.Bool(false)); s != `false` {
+		t.Error("bad encoding of a boolean schema:", s)
+	}
+}
+
+func TestDecodeBoolSchema(t *testing.T) {
+	var s schema.Schema
+
+	// The Items field is a nil *Schema, which implements objconv.ValueDecoder,
+	// the decoder allocates it before calling DecodeValue.
+	if err := json.Unmarshal([]byte(`{"items":false,"additionalProperties":{"type":"string"}}`), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Items == nil || s.Items.Bool == nil || *s.Items.Bool {
+		t.Errorf("bad items schema: %#v", s.Items)
+	}
+
+	if s.Additional == nil || !reflect.DeepEqual(s.Additional.Type, schema.Types{schema.String}) {
+		t.Errorf("bad additionalProperties schema: %#v", s.Additional)
+	}
+}
+
+func TestNewValidatorError(t *testing.T) {
+	for _, s := range []string{
+		`{"pattern":"("}`,
+		`{"$ref":"#/$defs/missing"}`,
+		`{"$ref":"http://example.com/schema.json"}`,
+	} {
+		var x schema.Schema
+
+		if err := json.Unmarshal([]byte(s), &x); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := schema.NewValidator(&x); err == nil {
+			t.Error("expected an error compiling", s)
+		}
+	}
+}
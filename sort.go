@@ -2,8 +2,10 @@ package objconv
 
 import (
 	"bytes"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 )
 
 type sortIntValues []reflect.Value
@@ -38,6 +40,172 @@ func (s sortBytesValues) Less(i int, j int) bool {
 	return bytes.Compare(s[i].Bytes(), s[j].Bytes()) < 0
 }
 
+type sortInterfaceValues []reflect.Value
+
+func (s sortInterfaceValues) Len() int               { return len(s) }
+func (s sortInterfaceValues) Swap(i int, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortInterfaceValues) Less(i int, j int) bool { return compareValues(s[i], s[j]) < 0 }
+
+// compareValues defines a total order on values of arbitrary types, which is
+// used to sort keys of maps of interface{} types.
+//
+// Values are first ordered by category (nil, booleans, numbers, strings, byte
+// slices, then other types), numbers are compared by value regardless of their
+// type. Values that compare equal but have different types are ordered by
+// their type names, and values of other types are ordered by their type names
+// and then by their default formatting.
+func compareValues(a reflect.Value, b reflect.Value) int {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+
+	c1, c2 := valueCategory(a), valueCategory(b)
+
+	if c1 != c2 {
+		return c1 - c2
+	}
+
+	switch c1 {
+	case 0: // nil
+		return 0
+
+	case 1: // bool
+		if a.Bool() != b.Bool() {
+			if a.Bool() {
+				return 1
+			}
+			return -1
+		}
+
+	case 2: // numbers
+		if c := compareNumbers(a, b); c != 0 {
+			return c
+		}
+
+	case 3: // string
+		if c := strings.Compare(a.String(), b.String()); c != 0 {
+			return c
+		}
+
+	case 4: // bytes
+		if c := bytes.Compare(a.Bytes(), b.Bytes()); c != 0 {
+			return c
+		}
+
+	default:
+		if c := strings.Compare(a.Type().String(), b.Type().String()); c != 0 {
+			return c
+		}
+		return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+	}
+
+	return strings.Compare(a.Type().String(), b.Type().String())
+}
+
+func valueCategory(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Invalid, reflect.Interface:
+		return 0
+	case reflect.Bool:
+		return 1
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return 2
+	case reflect.String:
+		return 3
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return 4
+		}
+	}
+	return 5
+}
+
+func compareNumbers(a reflect.Value, b reflect.Value) int {
+	switch {
+	case isIntKind(a.Kind()) && isIntKind(b.Kind()):
+		return compareInts(a.Int(), b.Int())
+
+	case isUintKind(a.Kind()) && isUintKind(b.Kind()):
+		return compareUints(a.Uint(), b.Uint())
+
+	case isIntKind(a.Kind()) && isUintKind(b.Kind()):
+		if a.Int() < 0 {
+			return -1
+		}
+		return compareUints(uint64(a.Int()), b.Uint())
+
+	case isUintKind(a.Kind()) && isIntKind(b.Kind()):
+		return -compareNumbers(b, a)
+	}
+
+	return compareFloats(numberToFloat(a), numberToFloat(b))
+}
+
+func numberToFloat(v reflect.Value) float64 {
+	switch {
+	case isIntKind(v.Kind()):
+		return float64(v.Int())
+	case isUintKind(v.Kind()):
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func compareInts(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareUints(a uint64, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	case a == b:
+		return 0
+	}
+	// NaN values are ordered after all other numbers.
+	switch a, b := a != a, b != b; {
+	case a && b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
 func sortValues(typ reflect.Type, v []reflect.Value) {
 	switch typ.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -56,6 +224,9 @@ func sortValues(typ reflect.Type, v []reflect.Value) {
 		if typ.Elem().Kind() == reflect.Uint8 {
 			sort.Sort(sortBytesValues(v))
 		}
+
+	case reflect.Interface:
+		sort.Sort(sortInterfaceValues(v))
 	}
 
 	// For all other types we give up on trying to sort the values,
@@ -13,6 +13,9 @@ type structField struct {
 	// The index of the field in the structure.
 	index []int
 
+	// The offset of the field in the structure.
+	offset uintptr
+
 	// The name of the field in the structure.
 	name string
 
@@ -47,6 +50,7 @@ func makeStructField(f reflect.StructField, c map[reflect.Type]*structType) stru
 
 	s := structField{
 		index:     f.Index,
+		offset:    f.Offset,
 		name:      f.Name,
 		omitempty: t.Omitempty,
 		omitzero:  t.Omitzero,
@@ -116,6 +120,46 @@ func newStructType(t reflect.Type, c map[reflect.Type]*structType) *structType {
 	return s
 }
 
+// StructField describes how a field of a struct type is represented by the
+// encoders and decoders of the objconv package.
+type StructField struct {
+	// The name of the field in the encoded representation.
+	Name string
+
+	// The index sequence of the field, as used by reflect.Value.FieldByIndex.
+	Index []int
+
+	// The Go type of the field.
+	Type reflect.Type
+
+	// Omitempty and Omitzero are set when the field has the corresponding
+	// options in its tag, which means it may not be present in the encoded
+	// representation of a struct.
+	Omitempty bool
+	Omitzero  bool
+}
+
+// StructFields returns the list of fields of t that are encoded and decoded by
+// the objconv package, in the order in which they are encoded.
+//
+// The function panics if t is not a struct type.
+func StructFields(t reflect.Type) []StructField {
+	s := structCache.lookup(t)
+	f := make([]StructField, len(s.fields))
+
+	for i, sf := range s.fields {
+		f[i] = StructField{
+			Name:      sf.name,
+			Index:     append([]int(nil), sf.index...),
+			Type:      t.FieldByIndex(sf.index).Type,
+			Omitempty: sf.omitempty,
+			Omitzero:  sf.omitzero,
+		}
+	}
+
+	return f
+}
+
 // structTypeCache is a simple cache for mapping Go types to Struct values.
 type structTypeCache struct {
 	mutex sync.RWMutex
@@ -0,0 +1,204 @@
+package objconv
+
+import "time"
+
+// TeeEmitter is an emitter which writes the values it receives to two emitters,
+// for example to send values over the network while logging them in a
+// human-readable format.
+//
+// The values are written to the main emitter first, then to the tee emitter.
+// The first error returned by either of them is returned to the caller.
+//
+// When one of the two emitters needs to know the length of arrays and maps when
+// they begin, encoders buffer containers of unknown length and both emitters
+// receive the lengths. Other optional behaviors, like whether text encodings
+// are preferred, are those of the main emitter.
+type TeeEmitter struct {
+	e   Emitter
+	tee Emitter
+}
+
+// NewTeeEmitter returns a new emitter which writes the values it receives to e
+// and tee.
+//
+// The function panics if e or tee is nil.
+func NewTeeEmitter(e Emitter, tee Emitter) *TeeEmitter {
+	if e == nil || tee == nil {
+		panic("objconv: the emitter is nil")
+	}
+	return &TeeEmitter{e: e, tee: tee}
+}
+
+func (e *TeeEmitter) EmitNil() (err error) {
+	if err = e.e.EmitNil(); err == nil {
+		err = e.tee.EmitNil()
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitBool(v bool) (err error) {
+	if err = e.e.EmitBool(v); err == nil {
+		err = e.tee.EmitBool(v)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitInt(v int64, bitSize int) (err error) {
+	if err = e.e.EmitInt(v, bitSize); err == nil {
+		err = e.tee.EmitInt(v, bitSize)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitUint(v uint64, bitSize int) (err error) {
+	if err = e.e.EmitUint(v, bitSize); err == nil {
+		err = e.tee.EmitUint(v, bitSize)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitFloat(v float64, bitSize int) (err error) {
+	if err = e.e.EmitFloat(v, bitSize); err == nil {
+		err = e.tee.EmitFloat(v, bitSize)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitString(v string) (err error) {
+	if err = e.e.EmitString(v); err == nil {
+		err = e.tee.EmitString(v)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitBytes(v []byte) (err error) {
+	if err = e.e.EmitBytes(v); err == nil {
+		err = e.tee.EmitBytes(v)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitTime(v time.Time) (err error) {
+	if err = e.e.EmitTime(v); err == nil {
+		err = e.tee.EmitTime(v)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitDuration(v time.Duration) (err error) {
+	if err = e.e.EmitDuration(v); err == nil {
+		err = e.tee.EmitDuration(v)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitError(v error) (err error) {
+	if err = e.e.EmitError(v); err == nil {
+		err = e.tee.EmitError(v)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitArrayBegin(n int) (err error) {
+	if err = e.e.EmitArrayBegin(n); err == nil {
+		err = e.tee.EmitArrayBegin(n)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitArrayEnd() (err error) {
+	if err = e.e.EmitArrayEnd(); err == nil {
+		err = e.tee.EmitArrayEnd()
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitArrayNext() (err error) {
+	if err = e.e.EmitArrayNext(); err == nil {
+		err = e.tee.EmitArrayNext()
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitMapBegin(n int) (err error) {
+	if err = e.e.EmitMapBegin(n); err == nil {
+		err = e.tee.EmitMapBegin(n)
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitMapEnd() (err error) {
+	if err = e.e.EmitMapEnd(); err == nil {
+		err = e.tee.EmitMapEnd()
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitMapValue() (err error) {
+	if err = e.e.EmitMapValue(); err == nil {
+		err = e.tee.EmitMapValue()
+	}
+	return
+}
+
+func (e *TeeEmitter) EmitMapNext() (err error) {
+	if err = e.e.EmitMapNext(); err == nil {
+		err = e.tee.EmitMapNext()
+	}
+	return
+}
+
+// PrettyEmitter returns a new emitter which writes to the pretty emitters of
+// the main and tee emitters, when they have one.
+func (e *TeeEmitter) PrettyEmitter() Emitter {
+	return &TeeEmitter{e: prettyEmitter(e.e), tee: prettyEmitter(e.tee)}
+}
+
+// CanonicalEmitter returns a new emitter which writes to the canonical emitters
+// of the main and tee emitters, when they have one.
+func (e *TeeEmitter) CanonicalEmitter() Emitter {
+	return &TeeEmitter{e: canonicalEmitter(e.e), tee: canonicalEmitter(e.tee)}
+}
+
+// Flush flushes the main and tee emitters if they buffer their output.
+func (e *TeeEmitter) Flush() (err error) {
+	if err = (wrappedEmitter{e.e}).Flush(); err == nil {
+		err = (wrappedEmitter{e.tee}).Flush()
+	}
+	return
+}
+
+// Abort forwards the failure of encoding a value to the main and tee emitters.
+func (e *TeeEmitter) Abort() (err error) {
+	err = abortEmitting(e.e)
+	if err2 := abortEmitting(e.tee); err == nil {
+		err = err2
+	}
+	return
+}
+
+func (e *TeeEmitter) TextEmitter() bool {
+	return isTextEmitter(e.e)
+}
+
+func (e *TeeEmitter) LengthEmitter() bool {
+	return isLengthEmitter(e.e) || isLengthEmitter(e.tee)
+}
+
+func (e *TeeEmitter) SequenceEmitter() bool {
+	return isSequenceEmitter(e.e)
+}
+
+func prettyEmitter(e Emitter) Emitter {
+	if p, ok := e.(PrettyEmitter); ok {
+		return p.PrettyEmitter()
+	}
+	return e
+}
+
+func canonicalEmitter(e Emitter) Emitter {
+	if c, ok := e.(CanonicalEmitter); ok {
+		return c.CanonicalEmitter()
+	}
+	return e
+}
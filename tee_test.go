@@ -0,0 +1,52 @@
+package objconv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTeeEmitter(t *testing.T) {
+	val := map[interface{}]interface{}{"A": []interface{}{int64(1), "2", nil}}
+
+	e1 := NewValueEmitter()
+	e2 := NewValueEmitter()
+
+	if err := NewEncoder(NewTeeEmitter(e1, e2)).Encode(val); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := e1.Value(); !reflect.DeepEqual(v, val) {
+		t.Errorf("the main emitter received %#v instead of %#v", v, val)
+	}
+
+	if v := e2.Value(); !reflect.DeepEqual(v, val) {
+		t.Errorf("the tee emitter received %#v instead of %#v", v, val)
+	}
+}
+
+func TestTeeEmitterLength(t *testing.T) {
+	e1 := NewValueEmitter()
+	e2 := &lengthValueEmitter{}
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+
+	enc := NewEncoder(NewTeeEmitter(e1, e2))
+	enc.BufferUnknownLengths = true
+
+	if err := enc.Encode(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []interface{}{int64(1), int64(2), int64(3)}
+
+	if v := e1.Value(); !reflect.DeepEqual(v, expect) {
+		t.Errorf("the main emitter received %#v", v)
+	}
+
+	if v := e2.Value(); !reflect.DeepEqual(v, expect) {
+		t.Errorf("the tee emitter received %#v", v)
+	}
+}
@@ -0,0 +1,612 @@
+package objconv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// TokenKind is an enumeration of the kinds of tokens produced by a TokenReader
+// and consumed by a TokenWriter.
+type TokenKind int
+
+const (
+	// ValueToken is the kind of tokens carrying scalar values.
+	ValueToken TokenKind = iota
+
+	// ArrayBeginToken is the kind of tokens starting arrays.
+	ArrayBeginToken
+
+	// ArrayEndToken is the kind of tokens ending arrays.
+	ArrayEndToken
+
+	// MapBeginToken is the kind of tokens starting maps.
+	MapBeginToken
+
+	// MapEndToken is the kind of tokens ending maps.
+	MapEndToken
+)
+
+// String returns a human readable representation of the token kind.
+func (k TokenKind) String() string {
+	switch k {
+	case ValueToken:
+		return "value"
+	case ArrayBeginToken:
+		return "array-begin"
+	case ArrayEndToken:
+		return "array-end"
+	case MapBeginToken:
+		return "map-begin"
+	case MapEndToken:
+		return "map-end"
+	default:
+		return "<token>"
+	}
+}
+
+// Token represents a single element of a flat token stream.
+//
+// Maps are represented as sequences of key and value tokens between the
+// MapBeginToken and MapEndToken tokens, keys and values may themselves be
+// arrays or maps.
+type Token struct {
+	// Kind is the kind of the token.
+	Kind TokenKind
+
+	// Type is the type of the value carried by the token, it is always Array or
+	// Map for tokens starting or ending containers.
+	Type Type
+
+	// Value is set on ValueToken tokens, its dynamic type depends on the Type
+	// field:
+	//
+	//	Nil      => nil
+	//	Bool     => bool
+	//	Int      => int64
+	//	Uint     => uint64
+	//	Float    => float64
+	//	String   => string
+	//	Bytes    => []byte
+	//	Time     => time.Time
+	//	Duration => time.Duration
+	//	Error    => error
+	//
+	Value interface{}
+
+	// BitSize is the size in bits of the numeric values carried by tokens of
+	// type Int, Uint or Float, it is passed to the emitter by TokenWriter.
+	// Zero means 64.
+	BitSize int
+
+	// Len is the number of elements in the container on tokens starting or
+	// ending arrays or maps. It is negative on begin tokens when the length is
+	// unknown.
+	Len int
+}
+
+// tokenFrame is used by TokenReader and TokenWriter to track the state of the
+// arrays and maps that they are traversing.
+type tokenFrame struct {
+	typ   Type // Array or Map
+	len   int  // length of the container, negative if unknown
+	cnt   int  // number of elements (or map entries) seen so far
+	value bool // true when the next token of a map is a value
+}
+
+// A TokenReader exposes the values produced by a parser as a flat stream of
+// tokens.
+//
+// The reader takes care of tracking the nesting of arrays and maps and calling
+// the parser methods with the right counters, which makes it a convenient base
+// for building streaming processors that work with any codec.
+//
+// Instances of TokenReader are not safe for use by multiple goroutines.
+type TokenReader struct {
+	// Parser to read tokens from.
+	Parser Parser
+
+	stack  []tokenFrame
+	peek   Token
+	peeked bool
+	err    error
+}
+
+// NewTokenReader returns a new TokenReader that reads tokens from p, will panic
+// if p is nil.
+func NewTokenReader(p Parser) *TokenReader {
+	if p == nil {
+		panic("objconv: the parser is nil")
+	}
+	return &TokenReader{Parser: p}
+}
+
+// Next returns the next token from the stream.
+//
+// Once an error was returned, all following calls return the same error. The
+// error is io.EOF when the parser reaches the end of its input.
+func (r *TokenReader) Next() (tok Token, err error) {
+	if r.peeked {
+		tok, r.peek, r.peeked = r.peek, Token{}, false
+		return
+	}
+
+	if err = r.err; err != nil {
+		return
+	}
+
+	var t Type
+
+	if t, tok, err = r.next(); err == nil && tok.Kind == ValueToken {
+		tok, err = r.parse(t)
+	}
+
+	if err != nil {
+		r.err = err
+	}
+	return
+}
+
+// Peek returns the next token from the stream without consuming it.
+func (r *TokenReader) Peek() (tok Token, err error) {
+	if !r.peeked {
+		if tok, err = r.Next(); err != nil {
+			return
+		}
+		r.peek, r.peeked = tok, true
+	}
+	return r.peek, nil
+}
+
+// Skip discards the next value from the stream. If the value is an array or a
+// map all tokens up to the end of the container are discarded.
+//
+// If the next token ends an array or a map it is consumed and Skip returns nil.
+func (r *TokenReader) Skip() (err error) {
+	var t Type
+	var tok Token
+	var end bool
+
+	if r.peeked {
+		tok, r.peek, r.peeked = r.peek, Token{}, false
+		return r.skipFrom(tok)
+	}
+
+	if err = r.err; err != nil {
+		return
+	}
+
+	if tok, end, err = r.advance(); err == nil && !end {
+		if t, err = r.Parser.ParseType(); err == nil {
+			err = (Decoder{Parser: r.Parser}).skip(t)
+		}
+	}
+
+	if err != nil {
+		r.err = err
+	}
+	return
+}
+
+// skipFrom discards tokens until the end of the value starting with tok.
+func (r *TokenReader) skipFrom(tok Token) (err error) {
+	for depth := 0; ; {
+		switch tok.Kind {
+		case ArrayBeginToken, MapBeginToken:
+			depth++
+		case ArrayEndToken, MapEndToken:
+			depth--
+		}
+
+		if depth <= 0 {
+			return
+		}
+
+		if tok, err = r.Next(); err != nil {
+			return
+		}
+	}
+}
+
+// next moves the parser to the position of the next token. When the method
+// returns a ValueToken the parser is positioned on a value of type t which
+// hasn't been parsed yet, other kinds of tokens are complete and returned as-is.
+func (r *TokenReader) next() (t Type, tok Token, err error) {
+	var end bool
+
+	if tok, end, err = r.advance(); err != nil || end {
+		return
+	}
+
+	if t, err = r.Parser.ParseType(); err != nil {
+		return
+	}
+
+	switch t {
+	case Array:
+		tok = Token{Kind: ArrayBeginToken, Type: Array}
+		if tok.Len, err = r.Parser.ParseArrayBegin(); err == nil {
+			r.stack = append(r.stack, tokenFrame{typ: Array, len: tok.Len})
+		}
+
+	case Map:
+		tok = Token{Kind: MapBeginToken, Type: Map}
+		if tok.Len, err = r.Parser.ParseMapBegin(); err == nil {
+			r.stack = append(r.stack, tokenFrame{typ: Map, len: tok.Len})
+		}
+
+	default:
+		tok = Token{Kind: ValueToken, Type: t}
+	}
+
+	return
+}
+
+// advance moves the parser past the separators preceding the next value. If
+// the container on top of the stack has no more elements it is completed and
+// the end token is returned with end set to true.
+func (r *TokenReader) advance() (tok Token, end bool, err error) {
+	n := len(r.stack)
+
+	if n == 0 {
+		return
+	}
+
+	f := &r.stack[n-1]
+
+	switch {
+	case f.value:
+		if err = r.Parser.ParseMapValue(f.cnt); err == nil {
+			f.value = false
+			f.cnt++
+		}
+
+	case f.len >= 0 && f.cnt >= f.len:
+		tok, err = r.end(f)
+		end = true
+
+	default:
+		if f.len < 0 || f.cnt != 0 {
+			if f.typ == Array {
+				err = r.Parser.ParseArrayNext(f.cnt)
+			} else {
+				err = r.Parser.ParseMapNext(f.cnt)
+			}
+			if err != nil {
+				if err == End {
+					tok, err = r.end(f)
+					end = true
+				}
+				return
+			}
+		}
+		if f.typ == Array {
+			f.cnt++
+		} else {
+			f.value = true
+		}
+	}
+
+	return
+}
+
+// end completes the container on top of the stack.
+func (r *TokenReader) end(f *tokenFrame) (tok Token, err error) {
+	tok = Token{Type: f.typ, Len: f.cnt}
+
+	if f.typ == Array {
+		tok.Kind, err = ArrayEndToken, r.Parser.ParseArrayEnd(f.cnt)
+	} else {
+		tok.Kind, err = MapEndToken, r.Parser.ParseMapEnd(f.cnt)
+	}
+
+	r.stack = r.stack[:len(r.stack)-1]
+	return
+}
+
+// parse loads the scalar value of type t that the parser is positioned on.
+func (r *TokenReader) parse(t Type) (tok Token, err error) {
+	var v interface{}
+
+	if err = (Decoder{Parser: r.Parser}).decodeInterfaceFromType(t, reflect.ValueOf(&v).Elem()); err == nil {
+		tok = Token{Kind: ValueToken, Type: t, Value: v}
+	}
+
+	return
+}
+
+// A TokenWriter outputs a flat stream of tokens to an emitter.
+//
+// The writer takes care of tracking the nesting of arrays and maps and calling
+// the emitter methods that separate elements, keys and values.
+//
+// Instances of TokenWriter are not safe for use by multiple goroutines.
+type TokenWriter struct {
+	// Emitter to write tokens to.
+	Emitter Emitter
+
+	stack []tokenFrame
+}
+
+// NewTokenWriter returns a new TokenWriter that writes tokens to e, will panic
+// if e is nil.
+func NewTokenWriter(e Emitter) *TokenWriter {
+	if e == nil {
+		panic("objconv: the emitter is nil")
+	}
+	return &TokenWriter{Emitter: e}
+}
+
+// WriteToken writes tok to the emitter.
+//
+// The method returns an error if the token doesn't fit at the current position
+// of the stream, for example when an array is closed by a MapEndToken or when
+// the number of elements doesn't match the length announced by the begin
+// token.
+func (w *TokenWriter) WriteToken(tok Token) (err error) {
+	switch tok.Kind {
+	case ArrayEndToken:
+		return w.end(Array)
+	case MapEndToken:
+		return w.end(Map)
+	}
+
+	if err = w.next(); err != nil {
+		return
+	}
+
+	switch tok.Kind {
+	case ValueToken:
+		err = w.emit(tok.Type, tok.Value, tok.BitSize)
+
+	case ArrayBeginToken:
+		if err = w.Emitter.EmitArrayBegin(tok.Len); err == nil {
+			w.stack = append(w.stack, tokenFrame{typ: Array, len: tok.Len})
+		}
+
+	case MapBeginToken:
+		if err = w.Emitter.EmitMapBegin(tok.Len); err == nil {
+			w.stack = append(w.stack, tokenFrame{typ: Map, len: tok.Len})
+		}
+
+	default:
+		err = fmt.Errorf("objconv: invalid token kind: %s", tok.Kind)
+	}
+
+	return
+}
+
+// next emits the separators expected before writing a new value.
+func (w *TokenWriter) next() (err error) {
+	n := len(w.stack)
+
+	if n == 0 {
+		return
+	}
+
+	f := &w.stack[n-1]
+
+	if f.value {
+		if err = w.Emitter.EmitMapValue(); err == nil {
+			f.value = false
+			f.cnt++
+		}
+		return
+	}
+
+	if f.len >= 0 && f.cnt >= f.len {
+		return fmt.Errorf("objconv: too many elements written to %s of length %d", f.typ, f.len)
+	}
+
+	if f.typ == Array {
+		if f.cnt != 0 {
+			err = w.Emitter.EmitArrayNext()
+		}
+		if err == nil {
+			f.cnt++
+		}
+	} else {
+		if f.cnt != 0 {
+			err = w.Emitter.EmitMapNext()
+		}
+		if err == nil {
+			f.value = true
+		}
+	}
+
+	return
+}
+
+// end closes the container on top of the stack, which must be of type t.
+func (w *TokenWriter) end(t Type) (err error) {
+	n := len(w.stack)
+
+	if n == 0 || w.stack[n-1].typ != t {
+		return fmt.Errorf("objconv: unexpected end of %s", t)
+	}
+
+	f := &w.stack[n-1]
+
+	if f.value {
+		return errors.New("objconv: map ended after a key with no value")
+	}
+
+	if f.len >= 0 && f.cnt != f.len {
+		return fmt.Errorf("objconv: %s of length %d ended after %d elements", t, f.len, f.cnt)
+	}
+
+	if t == Array {
+		err = w.Emitter.EmitArrayEnd()
+	} else {
+		err = w.Emitter.EmitMapEnd()
+	}
+
+	w.stack = w.stack[:n-1]
+	return
+}
+
+func (w *TokenWriter) emit(t Type, v interface{}, bitSize int) (err error) {
+	ok := true
+
+	if bitSize == 0 {
+		bitSize = 64
+	}
+
+	switch t {
+	case Nil:
+		ok = v == nil
+		err = w.Emitter.EmitNil()
+
+	case Bool:
+		var x bool
+		if x, ok = v.(bool); ok {
+			err = w.Emitter.EmitBool(x)
+		}
+
+	case Int:
+		var x int64
+		if x, ok = v.(int64); ok {
+			err = w.Emitter.EmitInt(x, bitSize)
+		}
+
+	case Uint:
+		var x uint64
+		if x, ok = v.(uint64); ok {
+			err = w.Emitter.EmitUint(x, bitSize)
+		}
+
+	case Float:
+		var x float64
+		if x, ok = v.(float64); ok {
+			err = w.Emitter.EmitFloat(x, bitSize)
+		}
+
+	case String:
+		var x string
+		if x, ok = v.(string); ok {
+			err = w.Emitter.EmitString(x)
+		}
+
+	case Bytes:
+		var x []byte
+		if x, ok = v.([]byte); ok {
+			err = w.Emitter.EmitBytes(x)
+		}
+
+	case Time:
+		var x time.Time
+		if x, ok = v.(time.Time); ok {
+			err = w.Emitter.EmitTime(x)
+		}
+
+	case Duration:
+		var x time.Duration
+		if x, ok = v.(time.Duration); ok {
+			err = w.Emitter.EmitDuration(x)
+		}
+
+	case Error:
+		var x error
+		if x, ok = v.(error); ok {
+			err = w.Emitter.EmitError(x)
+		}
+
+	default:
+		return fmt.Errorf("objconv: invalid type for a value token: %s", t)
+	}
+
+	if !ok {
+		err = fmt.Errorf("objconv: value token of type %s cannot carry a value of type %T", t, v)
+	}
+
+	return
+}
+
+// tokenBuffer is an emitter which records the values written to it as tokens.
+// When arrays and maps end, their length is set on the tokens that begin them,
+// so the tokens can be written to emitters that need to know the lengths ahead
+// of time.
+type tokenBuffer struct {
+	tokens []Token
+	stack  []tokenBufferFrame
+	text   bool // whether the buffer stands for a text emitter
+}
+
+type tokenBufferFrame struct {
+	begin int // index of the token beginning the container
+	cnt   int // number of values written to the container
+}
+
+func (b *tokenBuffer) EmitNil() error                         { return b.emit(Nil, nil, 0) }
+func (b *tokenBuffer) EmitBool(v bool) error                  { return b.emit(Bool, v, 0) }
+func (b *tokenBuffer) EmitInt(v int64, bitSize int) error     { return b.emit(Int, v, bitSize) }
+func (b *tokenBuffer) EmitUint(v uint64, bitSize int) error   { return b.emit(Uint, v, bitSize) }
+func (b *tokenBuffer) EmitFloat(v float64, bitSize int) error { return b.emit(Float, v, bitSize) }
+func (b *tokenBuffer) EmitString(v string) error              { return b.emit(String, v, 0) }
+func (b *tokenBuffer) EmitBytes(v []byte) error {
+	return b.emit(Bytes, append([]byte(nil), v...), 0)
+}
+func (b *tokenBuffer) EmitTime(v time.Time) error         { return b.emit(Time, v, 0) }
+func (b *tokenBuffer) EmitDuration(v time.Duration) error { return b.emit(Duration, v, 0) }
+func (b *tokenBuffer) EmitError(v error) error            { return b.emit(Error, v, 0) }
+func (b *tokenBuffer) EmitArrayBegin(int) error           { return b.begin(ArrayBeginToken, Array) }
+func (b *tokenBuffer) EmitArrayEnd() error                { return b.end(ArrayEndToken, Array) }
+func (b *tokenBuffer) EmitArrayNext() error               { return nil }
+func (b *tokenBuffer) EmitMapBegin(int) error             { return b.begin(MapBeginToken, Map) }
+func (b *tokenBuffer) EmitMapEnd() error                  { return b.end(MapEndToken, Map) }
+func (b *tokenBuffer) EmitMapValue() error                { return nil }
+func (b *tokenBuffer) EmitMapNext() error                 { return nil }
+func (b *tokenBuffer) TextEmitter() bool                  { return b.text }
+
+func (b *tokenBuffer) emit(t Type, v interface{}, bitSize int) error {
+	b.count()
+	b.tokens = append(b.tokens, Token{Kind: ValueToken, Type: t, Value: v, BitSize: bitSize})
+	return nil
+}
+
+func (b *tokenBuffer) begin(k TokenKind, t Type) error {
+	b.count()
+	b.stack = append(b.stack, tokenBufferFrame{begin: len(b.tokens)})
+	b.tokens = append(b.tokens, Token{Kind: k, Type: t})
+	return nil
+}
+
+func (b *tokenBuffer) end(k TokenKind, t Type) error {
+	i := len(b.stack) - 1
+
+	if i < 0 || b.tokens[b.stack[i].begin].Type != t {
+		return fmt.Errorf("objconv: unexpected end of %s", t)
+	}
+
+	f := b.stack[i]
+	n := f.cnt
+
+	if t == Map {
+		n /= 2
+	}
+
+	b.stack = b.stack[:i]
+	b.tokens[f.begin].Len = n
+	b.tokens = append(b.tokens, Token{Kind: k, Type: t, Len: n})
+	return nil
+}
+
+func (b *tokenBuffer) count() {
+	if i := len(b.stack) - 1; i >= 0 {
+		b.stack[i].cnt++
+	}
+}
+
+// writeTo writes the tokens recorded by b to e.
+func (b *tokenBuffer) writeTo(e Emitter) (err error) {
+	w := TokenWriter{Emitter: e}
+
+	for _, tok := range b.tokens {
+		if err = w.WriteToken(tok); err != nil {
+			return
+		}
+	}
+
+	return
+}
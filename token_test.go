@@ -0,0 +1,213 @@
+package objconv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenReader(t *testing.T) {
+	r := NewTokenReader(NewValueParser([]interface{}{
+		1,
+		map[string]interface{}{"A": []int{2, 3}},
+		"hello",
+	}))
+
+	tokens := []Token{}
+
+	// The value parser doesn't signal the end of its input, stop after the
+	// top-level array was closed.
+	for depth := 0; len(tokens) == 0 || depth != 0; {
+		tok, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch tok.Kind {
+		case ArrayBeginToken, MapBeginToken:
+			depth++
+		case ArrayEndToken, MapEndToken:
+			depth--
+		}
+		tokens = append(tokens, tok)
+	}
+
+	expect := []Token{
+		{Kind: ArrayBeginToken, Type: Array, Len: 3},
+		{Kind: ValueToken, Type: Int, Value: int64(1)},
+		{Kind: MapBeginToken, Type: Map, Len: 1},
+		{Kind: ValueToken, Type: String, Value: "A"},
+		{Kind: ArrayBeginToken, Type: Array, Len: 2},
+		{Kind: ValueToken, Type: Int, Value: int64(2)},
+		{Kind: ValueToken, Type: Int, Value: int64(3)},
+		{Kind: ArrayEndToken, Type: Array, Len: 2},
+		{Kind: MapEndToken, Type: Map, Len: 1},
+		{Kind: ValueToken, Type: String, Value: "hello"},
+		{Kind: ArrayEndToken, Type: Array, Len: 3},
+	}
+
+	if !reflect.DeepEqual(tokens, expect) {
+		t.Errorf("\n%#v\n%#v", tokens, expect)
+	}
+}
+
+func TestTokenReaderPeekAndSkip(t *testing.T) {
+	r := NewTokenReader(NewValueParser([]interface{}{
+		map[string]interface{}{"A": []int{1, 2}},
+		[]int{3},
+		4,
+	}))
+
+	if tok, _ := r.Next(); tok.Kind != ArrayBeginToken {
+		t.Fatal("bad first token:", tok)
+	}
+
+	// skip without peeking
+	if err := r.Skip(); err != nil {
+		t.Fatal(err)
+	}
+
+	// skip after peeking
+	if tok, _ := r.Peek(); tok.Kind != ArrayBeginToken {
+		t.Fatal("bad peeked token:", tok)
+	}
+
+	if err := r.Skip(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tok, _ := r.Peek(); tok.Value != int64(4) {
+		t.Fatal("bad peeked token:", tok)
+	}
+
+	if tok, _ := r.Next(); tok.Value != int64(4) {
+		t.Fatal("bad token after peek:", tok)
+	}
+
+	if tok, _ := r.Next(); tok.Kind != ArrayEndToken {
+		t.Fatal("bad last token:", tok)
+	}
+}
+
+func TestTokenWriter(t *testing.T) {
+	e := NewValueEmitter()
+	w := NewTokenWriter(e)
+
+	for _, tok := range []Token{
+		{Kind: MapBeginToken, Type: Map, Len: -1},
+		{Kind: ValueToken, Type: String, Value: "A"},
+		{Kind: ArrayBeginToken, Type: Array, Len: 2},
+		{Kind: ValueToken, Type: Int, Value: int64(1)},
+		{Kind: ValueToken, Type: Nil},
+		{Kind: ArrayEndToken, Type: Array},
+		{Kind: ValueToken, Type: String, Value: "B"},
+		{Kind: ValueToken, Type: Bool, Value: true},
+		{Kind: MapEndToken, Type: Map},
+	} {
+		if err := w.WriteToken(tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v := e.Value()
+	x := map[interface{}]interface{}{
+		"A": []interface{}{int64(1), nil},
+		"B": true,
+	}
+
+	if !reflect.DeepEqual(v, x) {
+		t.Errorf("%#v != %#v", v, x)
+	}
+}
+
+// bitSizeEmitter is a ValueEmitter which records the bit sizes of the numbers
+// that were emitted.
+type bitSizeEmitter struct {
+	ValueEmitter
+	sizes []int
+}
+
+func (e *bitSizeEmitter) EmitInt(v int64, bitSize int) error {
+	e.sizes = append(e.sizes, bitSize)
+	return e.ValueEmitter.EmitInt(v, bitSize)
+}
+
+func (e *bitSizeEmitter) EmitUint(v uint64, bitSize int) error {
+	e.sizes = append(e.sizes, bitSize)
+	return e.ValueEmitter.EmitUint(v, bitSize)
+}
+
+func (e *bitSizeEmitter) EmitFloat(v float64, bitSize int) error {
+	e.sizes = append(e.sizes, bitSize)
+	return e.ValueEmitter.EmitFloat(v, bitSize)
+}
+
+func TestTokenWriterBitSize(t *testing.T) {
+	e := &bitSizeEmitter{}
+	w := NewTokenWriter(e)
+
+	for _, tok := range []Token{
+		{Kind: ArrayBeginToken, Type: Array, Len: 4},
+		{Kind: ValueToken, Type: Int, Value: int64(1), BitSize: 8},
+		{Kind: ValueToken, Type: Uint, Value: uint64(2), BitSize: 16},
+		{Kind: ValueToken, Type: Float, Value: float64(0.5), BitSize: 32},
+		{Kind: ValueToken, Type: Float, Value: float64(0.5)},
+		{Kind: ArrayEndToken, Type: Array},
+	} {
+		if err := w.WriteToken(tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A zero bit size means 64 bits.
+	if x := []int{8, 16, 32, 64}; !reflect.DeepEqual(e.sizes, x) {
+		t.Errorf("%v != %v", e.sizes, x)
+	}
+}
+
+func TestTokenWriterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []Token
+	}{
+		{
+			name:   "end-without-begin",
+			tokens: []Token{{Kind: ArrayEndToken, Type: Array}},
+		},
+		{
+			name:   "mismatched-end",
+			tokens: []Token{{Kind: ArrayBeginToken, Type: Array, Len: -1}, {Kind: MapEndToken, Type: Map}},
+		},
+		{
+			name:   "key-without-value",
+			tokens: []Token{{Kind: MapBeginToken, Type: Map, Len: -1}, {Kind: ValueToken, Type: Nil}, {Kind: MapEndToken, Type: Map}},
+		},
+		{
+			name:   "too-many-elements",
+			tokens: []Token{{Kind: ArrayBeginToken, Type: Array}, {Kind: ValueToken, Type: Nil}},
+		},
+		{
+			name:   "too-few-elements",
+			tokens: []Token{{Kind: ArrayBeginToken, Type: Array, Len: 1}, {Kind: ArrayEndToken, Type: Array}},
+		},
+		{
+			name:   "bad-value",
+			tokens: []Token{{Kind: ValueToken, Type: Int, Value: "42"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewTokenWriter(NewValueEmitter())
+
+			var err error
+			for _, tok := range test.tokens {
+				if err = w.WriteToken(tok); err != nil {
+					break
+				}
+			}
+
+			if err == nil {
+				t.Error("no error was returned")
+			}
+		})
+	}
+}
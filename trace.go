@@ -0,0 +1,410 @@
+package objconv
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// TracingEmitter is an emitter which reports every token written to the
+// emitter it wraps, which is useful to debug encoders or observe what codecs
+// produce without modifying them.
+//
+// Wrapping an emitter in a TracingEmitter doesn't change its output.
+type TracingEmitter struct {
+	wrappedEmitter
+
+	// Trace is called with each token written to the emitter, and the error
+	// returned by the underlying emitter when writing it.
+	Trace func(Token, error)
+
+	stack []int // number of tokens written to each container being traced
+}
+
+// NewTracingEmitter returns a new emitter which calls trace with each token
+// written to e.
+//
+// The function panics if e or trace is nil.
+func NewTracingEmitter(e Emitter, trace func(Token, error)) *TracingEmitter {
+	if e == nil {
+		panic("objconv: the emitter is nil")
+	}
+	if trace == nil {
+		panic("objconv: the trace function is nil")
+	}
+	return &TracingEmitter{wrappedEmitter: wrappedEmitter{e}, Trace: trace}
+}
+
+func (e *TracingEmitter) EmitNil() (err error) {
+	err = e.Emitter.EmitNil()
+	e.value(Nil, nil, err)
+	return
+}
+
+func (e *TracingEmitter) EmitBool(v bool) (err error) {
+	err = e.Emitter.EmitBool(v)
+	e.value(Bool, v, err)
+	return
+}
+
+func (e *TracingEmitter) EmitInt(v int64, bitSize int) (err error) {
+	err = e.Emitter.EmitInt(v, bitSize)
+	e.value(Int, v, err)
+	return
+}
+
+func (e *TracingEmitter) EmitUint(v uint64, bitSize int) (err error) {
+	err = e.Emitter.EmitUint(v, bitSize)
+	e.value(Uint, v, err)
+	return
+}
+
+func (e *TracingEmitter) EmitFloat(v float64, bitSize int) (err error) {
+	err = e.Emitter.EmitFloat(v, bitSize)
+	e.value(Float, v, err)
+	return
+}
+
+func (e *TracingEmitter) EmitString(v string) (err error) {
+	err = e.Emitter.EmitString(v)
+	e.value(String, v, err)
+	return
+}
+
+func (e *TracingEmitter) EmitBytes(v []byte) (err error) {
+	err = e.Emitter.EmitBytes(v)
+	e.value(Bytes, v, err)
+	return
+}
+
+func (e *TracingEmitter) EmitTime(v time.Time) (err error) {
+	err = e.Emitter.EmitTime(v)
+	e.value(Time, v, err)
+	return
+}
+
+func (e *TracingEmitter) EmitDuration(v time.Duration) (err error) {
+	err = e.Emitter.EmitDuration(v)
+	e.value(Duration, v, err)
+	return
+}
+
+func (e *TracingEmitter) EmitError(v error) (err error) {
+	err = e.Emitter.EmitError(v)
+	e.value(Error, v, err)
+	return
+}
+
+func (e *TracingEmitter) EmitArrayBegin(n int) (err error) {
+	err = e.Emitter.EmitArrayBegin(n)
+	e.begin(Token{Kind: ArrayBeginToken, Type: Array, Len: n}, err)
+	return
+}
+
+func (e *TracingEmitter) EmitArrayEnd() (err error) {
+	err = e.Emitter.EmitArrayEnd()
+	e.end(Token{Kind: ArrayEndToken, Type: Array}, err)
+	return
+}
+
+func (e *TracingEmitter) EmitArrayNext() (err error) {
+	err = e.Emitter.EmitArrayNext()
+	e.error(err)
+	return
+}
+
+func (e *TracingEmitter) EmitMapBegin(n int) (err error) {
+	err = e.Emitter.EmitMapBegin(n)
+	e.begin(Token{Kind: MapBeginToken, Type: Map, Len: n}, err)
+	return
+}
+
+func (e *TracingEmitter) EmitMapEnd() (err error) {
+	err = e.Emitter.EmitMapEnd()
+	e.end(Token{Kind: MapEndToken, Type: Map}, err)
+	return
+}
+
+func (e *TracingEmitter) EmitMapValue() (err error) {
+	err = e.Emitter.EmitMapValue()
+	e.error(err)
+	return
+}
+
+func (e *TracingEmitter) EmitMapNext() (err error) {
+	err = e.Emitter.EmitMapNext()
+	e.error(err)
+	return
+}
+
+// PrettyEmitter returns a new emitter which traces the tokens written to the
+// pretty emitter of the underlying emitter, or e if it has none.
+func (e *TracingEmitter) PrettyEmitter() Emitter {
+	if p, ok := e.Emitter.(PrettyEmitter); ok {
+		return NewTracingEmitter(p.PrettyEmitter(), e.Trace)
+	}
+	return e
+}
+
+// CanonicalEmitter returns a new emitter which traces the tokens written to the
+// canonical emitter of the underlying emitter, or e if it has none.
+func (e *TracingEmitter) CanonicalEmitter() Emitter {
+	if c, ok := e.Emitter.(CanonicalEmitter); ok {
+		return NewTracingEmitter(c.CanonicalEmitter(), e.Trace)
+	}
+	return e
+}
+
+func (e *TracingEmitter) value(t Type, v interface{}, err error) {
+	e.count()
+	e.Trace(Token{Kind: ValueToken, Type: t, Value: v}, err)
+}
+
+func (e *TracingEmitter) begin(tok Token, err error) {
+	e.count()
+	e.stack = append(e.stack, 0)
+	e.Trace(tok, err)
+}
+
+func (e *TracingEmitter) end(tok Token, err error) {
+	if n := len(e.stack); n != 0 {
+		if tok.Len = e.stack[n-1]; tok.Type == Map {
+			tok.Len /= 2
+		}
+		e.stack = e.stack[:n-1]
+	}
+	e.Trace(tok, err)
+}
+
+func (e *TracingEmitter) count() {
+	if n := len(e.stack); n != 0 {
+		e.stack[n-1]++
+	}
+}
+
+func (e *TracingEmitter) error(err error) {
+	if err != nil {
+		e.Trace(Token{Type: Unknown}, err)
+	}
+}
+
+// TracingParser is a parser which reports every token read from the parser it
+// wraps, which is useful to debug decoders or observe what codecs read without
+// modifying them.
+//
+// Wrapping a parser in a TracingParser doesn't change the decoded values.
+// Values that the underlying parser skips or extracts without decoding them are
+// traced as a single value token, which holds the bytes of the value when it
+// was extracted.
+type TracingParser struct {
+	wrappedParser
+
+	// Trace is called with each token read from the parser, and the error
+	// returned by the underlying parser when reading it.
+	Trace func(Token, error)
+
+	typ Type // type returned by the last call to ParseType
+}
+
+// NewTracingParser returns a new parser which calls trace with each token read
+// from p.
+//
+// The function panics if p or trace is nil.
+func NewTracingParser(p Parser, trace func(Token, error)) *TracingParser {
+	if p == nil {
+		panic("objconv: the parser is nil")
+	}
+	if trace == nil {
+		panic("objconv: the trace function is nil")
+	}
+	return &TracingParser{wrappedParser: wrappedParser{p}, Trace: trace}
+}
+
+// ParseType forwards to the underlying parser, only errors are traced since the
+// method doesn't consume tokens. The io.EOF error that ends streams isn't
+// traced.
+func (p *TracingParser) ParseType() (t Type, err error) {
+	if t, err = p.Parser.ParseType(); err != io.EOF {
+		p.error(err)
+	}
+	p.typ = t
+	return
+}
+
+// Skip satisfies the skipParser interface, the value is parsed so its tokens
+// are traced if the underlying parser doesn't support skipping values.
+func (p *TracingParser) Skip() (err error) {
+	if s, ok := p.Parser.(skipParser); ok {
+		err = s.Skip()
+		p.value(p.typ, nil, err)
+		return
+	}
+	return (Decoder{Parser: p}).decodeInterfaceFromType(p.typ, reflect.Value{})
+}
+
+// ParseRaw forwards to the underlying parser and traces the bytes of the value.
+func (p *TracingParser) ParseRaw(b []byte) (v []byte, err error) {
+	var raw []byte
+	n := len(b)
+
+	if v, err = p.wrappedParser.ParseRaw(b); err == nil {
+		raw = append(raw, v[n:]...)
+	}
+
+	p.value(p.typ, raw, err)
+	return
+}
+
+func (p *TracingParser) ParseNil() (err error) {
+	err = p.Parser.ParseNil()
+	p.value(Nil, nil, err)
+	return
+}
+
+func (p *TracingParser) ParseBool() (v bool, err error) {
+	v, err = p.Parser.ParseBool()
+	p.value(Bool, v, err)
+	return
+}
+
+func (p *TracingParser) ParseInt() (v int64, err error) {
+	v, err = p.Parser.ParseInt()
+	p.value(Int, v, err)
+	return
+}
+
+func (p *TracingParser) ParseUint() (v uint64, err error) {
+	v, err = p.Parser.ParseUint()
+	p.value(Uint, v, err)
+	return
+}
+
+func (p *TracingParser) ParseFloat() (v float64, err error) {
+	v, err = p.Parser.ParseFloat()
+	p.value(Float, v, err)
+	return
+}
+
+func (p *TracingParser) ParseString() (v []byte, err error) {
+	v, err = p.Parser.ParseString()
+	p.value(String, string(v), err)
+	return
+}
+
+func (p *TracingParser) ParseBytes() (v []byte, err error) {
+	v, err = p.Parser.ParseBytes()
+	p.value(Bytes, append([]byte{}, v...), err)
+	return
+}
+
+func (p *TracingParser) ParseTime() (v time.Time, err error) {
+	v, err = p.Parser.ParseTime()
+	p.value(Time, v, err)
+	return
+}
+
+func (p *TracingParser) ParseDuration() (v time.Duration, err error) {
+	v, err = p.Parser.ParseDuration()
+	p.value(Duration, v, err)
+	return
+}
+
+func (p *TracingParser) ParseError() (v error, err error) {
+	v, err = p.Parser.ParseError()
+	p.value(Error, v, err)
+	return
+}
+
+func (p *TracingParser) ParseArrayBegin() (n int, err error) {
+	n, err = p.Parser.ParseArrayBegin()
+	p.Trace(Token{Kind: ArrayBeginToken, Type: Array, Len: n}, err)
+	return
+}
+
+func (p *TracingParser) ParseArrayEnd(n int) (err error) {
+	err = p.Parser.ParseArrayEnd(n)
+	p.Trace(Token{Kind: ArrayEndToken, Type: Array, Len: n}, err)
+	return
+}
+
+func (p *TracingParser) ParseArrayNext(n int) (err error) {
+	err = p.Parser.ParseArrayNext(n)
+	p.error(err)
+	return
+}
+
+func (p *TracingParser) ParseMapBegin() (n int, err error) {
+	n, err = p.Parser.ParseMapBegin()
+	p.Trace(Token{Kind: MapBeginToken, Type: Map, Len: n}, err)
+	return
+}
+
+func (p *TracingParser) ParseMapEnd(n int) (err error) {
+	err = p.Parser.ParseMapEnd(n)
+	p.Trace(Token{Kind: MapEndToken, Type: Map, Len: n}, err)
+	return
+}
+
+func (p *TracingParser) ParseMapValue(n int) (err error) {
+	err = p.Parser.ParseMapValue(n)
+	p.error(err)
+	return
+}
+
+func (p *TracingParser) ParseMapNext(n int) (err error) {
+	err = p.Parser.ParseMapNext(n)
+	p.error(err)
+	return
+}
+
+func (p *TracingParser) value(t Type, v interface{}, err error) {
+	p.Trace(Token{Kind: ValueToken, Type: t, Value: v}, err)
+}
+
+// error traces errors returned by methods which don't read tokens, End isn't
+// an error, it indicates that the end of an array or a map was reached.
+func (p *TracingParser) error(err error) {
+	if err != nil && err != End {
+		p.Trace(Token{Type: Unknown}, err)
+	}
+}
+
+// TraceWriter returns a trace function which writes a line describing each
+// token to w, it can be given to NewTracingEmitter or NewTracingParser.
+//
+// The function doesn't synchronize writes to w, emitters and parsers writing
+// to the same output from multiple goroutines must use a writer which does.
+func TraceWriter(w io.Writer) func(Token, error) {
+	return func(tok Token, err error) {
+		b := make([]byte, 0, 64)
+
+		switch {
+		case tok.Type == Unknown:
+		case tok.Kind == ValueToken:
+			b = append(b, tok.Type.String()...)
+			switch v := tok.Value.(type) {
+			case nil:
+			case string, []byte:
+				b = append(b, ' ')
+				b = append(b, fmt.Sprintf("%q", v)...)
+			default:
+				b = append(b, ' ')
+				b = append(b, fmt.Sprint(v)...)
+			}
+		default:
+			b = append(b, tok.Kind.String()...)
+			b = append(b, fmt.Sprintf(" %d", tok.Len)...)
+		}
+
+		if err != nil {
+			if len(b) != 0 {
+				b = append(b, ' ')
+			}
+			b = append(b, "error: "...)
+			b = append(b, err.Error()...)
+		}
+
+		w.Write(append(b, '\n'))
+	}
+}
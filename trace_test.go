@@ -0,0 +1,251 @@
+package objconv
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// tokenRecorder records the tokens and errors reported by tracing emitters and
+// parsers.
+type tokenRecorder struct {
+	tokens []Token
+	errors []error
+}
+
+func (r *tokenRecorder) trace(tok Token, err error) {
+	r.tokens = append(r.tokens, tok)
+	r.errors = append(r.errors, err)
+}
+
+var tracedTokens = []Token{
+	{Kind: MapBeginToken, Type: Map, Len: 1},
+	{Kind: ValueToken, Type: String, Value: "A"},
+	{Kind: ArrayBeginToken, Type: Array, Len: 3},
+	{Kind: ValueToken, Type: Int, Value: int64(1)},
+	{Kind: ValueToken, Type: String, Value: "2"},
+	{Kind: ValueToken, Type: Nil},
+	{Kind: ArrayEndToken, Type: Array, Len: 3},
+	{Kind: MapEndToken, Type: Map, Len: 1},
+}
+
+func TestTracingEmitter(t *testing.T) {
+	r := &tokenRecorder{}
+	e := NewValueEmitter()
+	val := map[interface{}]interface{}{"A": []interface{}{int64(1), "2", nil}}
+
+	if err := NewEncoder(NewTracingEmitter(e, r.trace)).Encode(val); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(r.tokens, tracedTokens) {
+		t.Errorf("bad tokens:\n%#v\n%#v", r.tokens, tracedTokens)
+	}
+
+	if v := e.Value(); !reflect.DeepEqual(v, val) {
+		t.Errorf("the emitter received %#v instead of %#v", v, val)
+	}
+}
+
+func TestTracingParser(t *testing.T) {
+	r := &tokenRecorder{}
+	p := NewValueParser(map[string]interface{}{"A": []interface{}{1, "2", nil}})
+
+	var v struct {
+		A []interface{}
+	}
+
+	if err := NewDecoder(NewTracingParser(p, r.trace)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(r.tokens, tracedTokens) {
+		t.Errorf("bad tokens:\n%#v\n%#v", r.tokens, tracedTokens)
+	}
+
+	for _, err := range r.errors {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestTraceWriter(t *testing.T) {
+	b := &bytes.Buffer{}
+	trace := TraceWriter(b)
+
+	for _, tok := range tracedTokens {
+		trace(tok, nil)
+	}
+	trace(Token{Kind: ValueToken, Type: Bytes, Value: []byte("?")}, errors.New("oops"))
+	trace(Token{}, errors.New("oops"))
+
+	const expect = `map-begin 1
+string "A"
+array-begin 3
+int 1
+string "2"
+nil
+array-end 3
+map-end 1
+bytes "?" error: oops
+error: oops
+`
+
+	if s := b.String(); s != expect {
+		t.Errorf("bad trace:\n%s", s)
+	}
+}
+
+// capableParser is a parser implementing the optional interfaces that wrapping
+// parsers forward, it records the calls to their methods.
+type capableParser struct {
+	*ValueParser
+	calls []string
+}
+
+func (p *capableParser) ParseRaw(b []byte) ([]byte, error) {
+	p.calls = append(p.calls, "raw")
+	return append(b, "raw"...), nil
+}
+
+func (p *capableParser) Recover() error {
+	p.calls = append(p.calls, "recover")
+	return nil
+}
+
+func (p *capableParser) InputOffset() int64 {
+	p.calls = append(p.calls, "offset")
+	return 42
+}
+
+func (p *capableParser) Reset(r io.Reader) {
+	p.calls = append(p.calls, "reset")
+}
+
+func (p *capableParser) Skip() error {
+	p.calls = append(p.calls, "skip")
+	return p.ValueParser.Skip()
+}
+
+func TestTracingParserForward(t *testing.T) {
+	r := &tokenRecorder{}
+	p := &capableParser{ValueParser: NewValueParser([]int{1, 2})}
+	tp := NewTracingParser(p, r.trace)
+
+	raw, ok := asRawParser(tp)
+	if !ok {
+		t.Fatal("the tracing parser doesn't forward ParseRaw")
+	}
+
+	if _, err := tp.ParseType(); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := raw.ParseRaw([]byte("x")); err != nil {
+		t.Error(err)
+	} else if string(b) != "xraw" {
+		t.Errorf("invalid bytes returned by ParseRaw: %q", b)
+	}
+
+	if err := tp.Skip(); err != nil {
+		t.Error(err)
+	}
+
+	if err := tp.Recover(); err != nil {
+		t.Error(err)
+	}
+
+	if off := tp.InputOffset(); off != 42 {
+		t.Errorf("invalid offset: %d", off)
+	}
+
+	if x := resetParser(tp, bytes.NewReader(nil), nil); x != tp {
+		t.Error("the tracing parser was not reset")
+	}
+
+	if expect := []string{"raw", "skip", "recover", "offset", "reset"}; !reflect.DeepEqual(p.calls, expect) {
+		t.Errorf("bad calls: %v != %v", p.calls, expect)
+	}
+
+	expect := []Token{
+		{Kind: ValueToken, Type: Array, Value: []byte("raw")},
+		{Kind: ValueToken, Type: Array},
+	}
+
+	if !reflect.DeepEqual(r.tokens, expect) {
+		t.Errorf("bad tokens:\n%#v\n%#v", r.tokens, expect)
+	}
+}
+
+func TestTracingParserUnsupported(t *testing.T) {
+	// Hide the Skip method of the value parser.
+	p := struct{ Parser }{NewValueParser(map[string]interface{}{"B": []int{2}})}
+	r := &tokenRecorder{}
+	tp := NewTracingParser(p, r.trace)
+
+	if _, ok := asRawParser(tp); ok {
+		t.Error("the tracing parser reports extracting raw values when the underlying parser can't")
+	}
+
+	if err := tp.Recover(); err == nil {
+		t.Error("the tracing parser reports recovering when the underlying parser can't")
+	}
+
+	if off := tp.InputOffset(); off != -1 {
+		t.Errorf("invalid offset: %d", off)
+	}
+
+	x := NewValueParser(nil)
+
+	if resetParser(tp, bytes.NewReader(nil), func(io.Reader) Parser { return x }) != x {
+		t.Error("a new parser was not created when the underlying parser cannot be reset")
+	}
+
+	var v struct{ A int }
+
+	if err := NewDecoder(tp).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	// The skipped value is parsed, so its tokens are traced.
+	var values []interface{}
+
+	for _, tok := range r.tokens {
+		if tok.Kind == ValueToken {
+			values = append(values, tok.Value)
+		}
+	}
+
+	if expect := []interface{}{"B", int64(2)}; !reflect.DeepEqual(values, expect) {
+		t.Errorf("bad values traced: %v != %v", values, expect)
+	}
+}
+
+func TestMetricsParserForward(t *testing.T) {
+	p := &capableParser{ValueParser: NewValueParser([]int{1, 2})}
+	m := &Metrics{}
+	mp := NewMetricsParser(p, m)
+
+	if _, err := mp.ParseType(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mp.ParseRaw(nil); err != nil {
+		t.Error(err)
+	}
+
+	if n := m.Values(Array); n != 1 {
+		t.Errorf("the extracted value was not counted: %d", n)
+	}
+
+	if resetParser(mp, bytes.NewReader(nil), nil) != mp {
+		t.Error("the metrics parser was not reset")
+	}
+
+	if expect := []string{"raw", "reset"}; !reflect.DeepEqual(p.calls, expect) {
+		t.Errorf("bad calls: %v != %v", p.calls, expect)
+	}
+}
@@ -142,38 +142,49 @@ func stringNoCopy(b []byte) string {
 //
 // This is mainly useful for testing the decoder algorithms.
 type ValueParser struct {
-	stack []reflect.Value
-	ctx   []valueParserContext
+	stack  []reflect.Value
+	ctx    []valueParserContext
+	buf    []byte
+	rename func(string) string // applied to map keys, may be nil
 }
 
 type valueParserContext struct {
 	value  reflect.Value
-	keys   []reflect.Value
+	iter   *reflect.MapIter
+	key    reflect.Value // reused to load the keys of maps
+	elem   reflect.Value // reused to load the values of maps
 	fields []structField
 }
 
 // NewValueParser creates a new parser that exposes the value v.
 func NewValueParser(v interface{}) *ValueParser {
-	return &ValueParser{
-		stack: []reflect.Value{reflect.ValueOf(v)},
+	p := &ValueParser{
+		stack: make([]reflect.Value, 1, 16),
+		ctx:   make([]valueParserContext, 0, 8),
 	}
+	p.stack[0] = reflect.ValueOf(v)
+	return p
 }
 
 func (p *ValueParser) ParseType() (Type, error) {
-	v := p.value()
+	v, err := p.resolve()
+
+	if err != nil {
+		return Nil, err
+	}
 
 	if !v.IsValid() {
 		return Nil, nil
 	}
 
-	switch v.Interface().(type) {
-	case time.Time:
+	switch t := v.Type(); {
+	case t == timeType:
 		return Time, nil
 
-	case time.Duration:
+	case t == durationType:
 		return Duration, nil
 
-	case error:
+	case t.Implements(errorInterface):
 		return Error, nil
 	}
 
@@ -208,7 +219,7 @@ func (p *ValueParser) ParseType() (Type, error) {
 	case reflect.Struct:
 		return Map, nil
 
-	case reflect.Interface:
+	case reflect.Interface, reflect.Ptr:
 		if v.IsNil() {
 			return Nil, nil
 		}
@@ -242,7 +253,8 @@ func (p *ValueParser) ParseFloat() (v float64, err error) {
 }
 
 func (p *ValueParser) ParseString() (v []byte, err error) {
-	v = []byte(p.value().String())
+	p.buf = append(p.buf[:0], p.value().String()...)
+	v = p.buf
 	return
 }
 
@@ -266,6 +278,12 @@ func (p *ValueParser) ParseError() (v error, err error) {
 	return
 }
 
+// Skip discards the next value, which only means moving on to the next value
+// for this parser since values are not decoded until they are parsed.
+func (p *ValueParser) Skip() (err error) {
+	return
+}
+
 func (p *ValueParser) ParseArrayBegin() (n int, err error) {
 	v := p.value()
 	n = v.Len()
@@ -298,10 +316,17 @@ func (p *ValueParser) ParseMapBegin() (n int, err error) {
 
 	if v.Kind() == reflect.Map {
 		n = v.Len()
-		k := v.MapKeys()
-		p.pushContext(valueParserContext{value: v, keys: k})
-		if n != 0 {
-			p.push(k[0])
+		t := v.Type()
+		c := valueParserContext{
+			value: v,
+			iter:  v.MapRange(),
+			key:   reflect.New(t.Key()).Elem(),
+			elem:  reflect.New(t.Elem()).Elem(),
+		}
+		p.pushContext(c)
+		if c.iter.Next() {
+			c.key.SetIterKey(c.iter)
+			p.pushKey(c.key)
 		}
 	} else {
 		c := valueParserContext{value: v}
@@ -316,7 +341,7 @@ func (p *ValueParser) ParseMapBegin() (n int, err error) {
 
 		p.pushContext(c)
 		if n != 0 {
-			p.push(reflect.ValueOf(c.fields[0].name))
+			p.pushKey(reflect.ValueOf(c.fields[0].name))
 		}
 	}
 
@@ -335,8 +360,9 @@ func (p *ValueParser) ParseMapValue(n int) (err error) {
 	ctx := p.context()
 	p.pop()
 
-	if ctx.keys != nil {
-		p.push(ctx.value.MapIndex(ctx.keys[n]))
+	if ctx.iter != nil {
+		ctx.elem.SetIterValue(ctx.iter)
+		p.push(ctx.elem)
 	} else {
 		p.push(ctx.value.FieldByIndex(ctx.fields[n].index))
 	}
@@ -348,10 +374,12 @@ func (p *ValueParser) ParseMapNext(n int) (err error) {
 	ctx := p.context()
 	p.pop()
 
-	if ctx.keys != nil {
-		p.push(ctx.keys[n])
+	if ctx.iter != nil {
+		ctx.iter.Next()
+		ctx.key.SetIterKey(ctx.iter)
+		p.pushKey(ctx.key)
 	} else {
-		p.push(reflect.ValueOf(ctx.fields[n].name))
+		p.pushKey(reflect.ValueOf(ctx.fields[n].name))
 	}
 
 	return
@@ -360,12 +388,7 @@ func (p *ValueParser) ParseMapNext(n int) (err error) {
 func (p *ValueParser) value() reflect.Value {
 	v := p.stack[len(p.stack)-1]
 
-	if !v.IsValid() {
-		return v
-	}
-
-	switch v.Interface().(type) {
-	case error:
+	if !v.IsValid() || v.Type().Implements(errorInterface) {
 		return v
 	}
 
@@ -381,10 +404,84 @@ dereference:
 	return v
 }
 
+// resolve returns the value at the top of the stack. When the value has a
+// custom encoding (an adapter, a ValueEncoder or a marshaler), it is replaced
+// by the value that an Encoder would produce, so the parser exposes the same
+// representation as a serialized form of the value.
+func (p *ValueParser) resolve() (reflect.Value, error) {
+	i := len(p.stack) - 1
+	v := p.stack[i]
+
+	for v.IsValid() {
+		k := v.Kind()
+
+		if (k == reflect.Interface || k == reflect.Ptr) && v.IsNil() {
+			break
+		}
+
+		if k != reflect.Interface && hasCustomEncoding(v.Type()) {
+			e := NewValueEmitter()
+
+			if err := (Encoder{Emitter: e}).Encode(v.Interface()); err != nil {
+				return reflect.Value{}, err
+			}
+
+			p.stack[i] = reflect.ValueOf(e.Value())
+			break
+		}
+
+		if k != reflect.Interface && k != reflect.Ptr {
+			break
+		}
+
+		v = v.Elem()
+	}
+
+	return p.value(), nil
+}
+
+var (
+	customEncodingCache = make(map[reflect.Type]bool)
+	customEncodingMutex sync.RWMutex
+)
+
+// hasCustomEncoding returns true if values of type t are not encoded based on
+// their kind, the result is cached since it's computed for every value read by
+// a ValueParser.
+func hasCustomEncoding(t reflect.Type) bool {
+	customEncodingMutex.RLock()
+	c, ok := customEncodingCache[t]
+	customEncodingMutex.RUnlock()
+
+	if !ok {
+		switch t {
+		case timeType, timePtrType, durationType:
+		default:
+			_, c = AdapterOf(t)
+			c = c ||
+				t.Implements(valueEncoderInterface) ||
+				t.Implements(binaryMarshalerInterface) ||
+				t.Implements(textMarshalerInterface)
+		}
+		customEncodingMutex.Lock()
+		customEncodingCache[t] = c
+		customEncodingMutex.Unlock()
+	}
+
+	return c
+}
+
 func (p *ValueParser) push(v reflect.Value) {
 	p.stack = append(p.stack, v)
 }
 
+func (p *ValueParser) pushKey(k reflect.Value) {
+	if p.rename != nil && k.Kind() == reflect.String {
+		k = reflect.ValueOf(p.rename(k.String()))
+	}
+	p.push(k)
+}
+
 func (p *ValueParser) pop() {
 	p.stack = p.stack[:len(p.stack)-1]
 }
@@ -0,0 +1,129 @@
+package objconv
+
+import (
+	"errors"
+	"io"
+)
+
+// wrappedEmitter is embedded by the emitters that wrap another emitter to
+// observe the values written to it (like TracingEmitter or MetricsEmitter).
+//
+// It forwards the optional interfaces which change how encoders write values,
+// so wrapping an emitter doesn't change its output.
+type wrappedEmitter struct {
+	Emitter
+}
+
+// TextEmitter forwards the information of whether the underlying emitter is
+// writing a human-readable format.
+func (e wrappedEmitter) TextEmitter() bool {
+	return isTextEmitter(e.Emitter)
+}
+
+// LengthEmitter forwards the information of whether the underlying emitter
+// needs to know the length of arrays and maps when they begin.
+func (e wrappedEmitter) LengthEmitter() bool {
+	return isLengthEmitter(e.Emitter)
+}
+
+// SequenceEmitter forwards the information of whether the underlying emitter
+// writes streams as sequences of values.
+func (e wrappedEmitter) SequenceEmitter() bool {
+	return isSequenceEmitter(e.Emitter)
+}
+
+// Flush flushes the underlying emitter if it buffers its output.
+func (e wrappedEmitter) Flush() error {
+	if f, ok := e.Emitter.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Abort forwards the failure of encoding a value to the underlying emitter.
+func (e wrappedEmitter) Abort() error {
+	return abortEmitting(e.Emitter)
+}
+
+// wrappedParser is embedded by the parsers that wrap another parser to observe
+// the values read from it (like TracingParser or MetricsParser).
+//
+// It forwards the optional interfaces which change how decoders load values, so
+// wrapping a parser doesn't change the decoded values.
+type wrappedParser struct {
+	Parser
+}
+
+// TextParser forwards the information of whether the underlying parser is
+// reading a human-readable format.
+func (p wrappedParser) TextParser() bool {
+	return isTextParser(p.Parser)
+}
+
+// SequenceParser forwards the information of whether the underlying parser
+// reads streams as sequences of values.
+func (p wrappedParser) SequenceParser() bool {
+	return isSequenceParser(p.Parser)
+}
+
+// StableParser forwards the information of whether the byte slices returned by
+// the underlying parser can be retained without being copied.
+func (p wrappedParser) StableParser() bool {
+	return isStableParser(p.Parser)
+}
+
+// BitSize forwards the size of the last number parsed by the underlying parser.
+func (p wrappedParser) BitSize() int {
+	return bitSizeOf(p.Parser)
+}
+
+// DecodeBytes forwards to the underlying parser, so byte slices are decoded the
+// same way whether the parser is wrapped or not.
+func (p wrappedParser) DecodeBytes(b []byte) ([]byte, error) {
+	if d, ok := p.Parser.(bytesDecoder); ok {
+		return d.DecodeBytes(b)
+	}
+	return b, nil
+}
+
+// ParseRaw forwards to the underlying parser. Decoders use asRawParser to check
+// whether the underlying parser is able to extract the bytes of values before
+// calling it.
+func (p wrappedParser) ParseRaw(b []byte) ([]byte, error) {
+	if r, ok := p.Parser.(rawParser); ok {
+		return r.ParseRaw(b)
+	}
+	return b, errors.New("objconv: the parser cannot extract the bytes of values")
+}
+
+// Recover forwards to the underlying parser, or returns an error if it cannot
+// recover from errors.
+func (p wrappedParser) Recover() error {
+	if r, ok := p.Parser.(recoverParser); ok {
+		return r.Recover()
+	}
+	return errors.New("objconv: the parser cannot recover from errors")
+}
+
+// InputOffset forwards to the underlying parser, or returns -1 if it cannot
+// report its position in the input.
+func (p wrappedParser) InputOffset() int64 {
+	if o, ok := p.Parser.(offsetParser); ok {
+		return o.InputOffset()
+	}
+	return -1
+}
+
+// Reset forwards to the underlying parser. Decoders use resetParser, which
+// creates a new parser instead when the underlying parser cannot be reset.
+func (p wrappedParser) Reset(r io.Reader) {
+	x, ok := p.Parser.(ResetParser)
+	if !ok {
+		panic("objconv: the parser cannot be reset")
+	}
+	x.Reset(r)
+}
+
+func (p wrappedParser) unwrap() Parser {
+	return p.Parser
+}
@@ -26,6 +26,11 @@ func (e *Emitter) Reset(w io.Writer) {
 	e.stack = e.stack[:0]
 }
 
+func (e *Emitter) Abort() error {
+	e.stack = e.stack[:0]
+	return nil
+}
+
 func (e *Emitter) EmitNil() error {
 	return e.emit(nil)
 }
@@ -32,6 +32,27 @@ func Marshal(v interface{}) (b []byte, err error) {
 	return
 }
 
+// Append appends the YAML representation of v to dst and returns the extended
+// buffer. Unlike Marshal, the value is written directly to dst, so no copy is
+// made when dst has enough capacity. On error, dst is returned unchanged.
+func Append(dst []byte, v interface{}) (b []byte, err error) {
+	m := marshalerPool.Get().(*marshaler)
+	buf := m.b.Bytes()[:0]
+	m.b = *bytes.NewBuffer(dst)
+
+	if err = (objconv.Encoder{Emitter: m}).Encode(v); err == nil {
+		b = m.b.Bytes()
+	} else {
+		b = dst
+	}
+
+	// The buffer of the marshaler is restored so dst isn't retained by the
+	// pool.
+	m.b = *bytes.NewBuffer(buf)
+	marshalerPool.Put(m)
+	return
+}
+
 var marshalerPool = sync.Pool{
 	New: func() interface{} { return newMarshaler() },
 }
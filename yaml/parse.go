@@ -182,6 +182,15 @@ func (p *Parser) ParseMapNext(n int) (err error) {
 	return
 }
 
+// Skip discards the next value, the document is already loaded in memory so
+// arrays and maps are dropped without being iterated.
+func (p *Parser) Skip() (err error) {
+	if _, err = p.ParseType(); err == nil {
+		p.pop()
+	}
+	return
+}
+
 func (p *Parser) TextParser() bool {
 	return true
 }
@@ -1,6 +1,7 @@
 package yaml
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/segmentio/objconv/objtests"
@@ -13,3 +14,27 @@ func TestCodec(t *testing.T) {
 func BenchmarkCodec(b *testing.B) {
 	objtests.BenchmarkCodec(b, Codec)
 }
+
+func TestAppend(t *testing.T) {
+	v := []interface{}{"Hello World!", 42}
+
+	b1, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := append(make([]byte, 0, 1024), "..."...)
+
+	b2, err := Append(dst, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b2[:3], dst) || !bytes.Equal(b2[3:], b1) {
+		t.Errorf("%q != %q + %q", b2, dst, b1)
+	}
+
+	if &b2[0] != &dst[0] {
+		t.Error("the value was not written to the buffer passed to Append")
+	}
+}